@@ -75,7 +75,7 @@ var addCmd = &cobra.Command{
 			"id": apkInfo.PackageID,
 		}))
 		fmt.Printf("%s\n", i18n.T("cmd.repoAdd.info.appName", map[string]interface{}{
-			"name": getDefaultName(apkInfo.AppName),
+			"name": localizedName(apkInfo.AppName),
 		}))
 		fmt.Printf("%s\n", i18n.T("cmd.repoAdd.info.version", map[string]interface{}{
 			"version": apkInfo.Version,
@@ -103,6 +103,11 @@ var addCmd = &cobra.Command{
 				"abis": strings.Join(apkInfo.ABIs, ", "),
 			}))
 		}
+		riskScore := apk.PermissionRiskScore(apkInfo.Permissions)
+		fmt.Printf("%s\n", i18n.T("cmd.info.risk", map[string]interface{}{
+			"score": riskScore, "level": riskLevelName(riskScore),
+		}))
+		printNewDangerousPermissions(repository, apkInfo)
 		fmt.Printf("\n%s\n", i18n.T("cmd.repoAdd.info.original", map[string]interface{}{
 			"name": filepath.Base(absAPKPath),
 		}))
@@ -113,6 +118,58 @@ var addCmd = &cobra.Command{
 			"path": filepath.Join("apks", normalizedName),
 		}))
 
+		// Make sure a new version keeps the signer of the versions already recorded
+		if err := checkRecordedSigner(repository, apkInfo, cfg.Repository.SignaturePolicy); err != nil {
+			return err
+		}
+
+		// Create APK info structure
+		modelAPKInfo := &models.APKInfo{
+			PackageID:             apkInfo.PackageID,
+			AppName:               apkInfo.AppName,
+			Version:               apkInfo.Version,
+			VersionCode:           apkInfo.VersionCode,
+			MinSDK:                apkInfo.MinSDK,
+			TargetSDK:             apkInfo.TargetSDK,
+			Size:                  apkInfo.Size,
+			SHA256:                apkInfo.SHA256,
+			SignatureInfo:         apkInfo.SignatureInfo,
+			SignatureVerification: apkInfo.SignatureVerification,
+			Permissions:           apkInfo.Permissions,
+			Features:              apkInfo.Features,
+			ABIs:                  apkInfo.ABIs,
+			Manifest:              apkInfo.Manifest,
+			Libraries:             apkInfo.Libraries,
+			AddedAt:               time.Now(),
+			UpdatedAt:             time.Now(),
+			OriginalName:          filepath.Base(absAPKPath),
+			FileName:              normalizedName,
+			FilePath:              filepath.Join("apks", normalizedName),
+		}
+
+		// An APK held back earlier stays in quarantine
+		if repository.HasQuarantineRecord(modelAPKInfo) {
+			fmt.Printf("\n%s\n", i18n.T("cmd.policy.alreadyQuarantined", map[string]interface{}{
+				"name": filepath.Base(absAPKPath),
+			}))
+			return nil
+		}
+
+		// Check the admission policy
+		policy, err := loadAdmissionPolicy(repository)
+		if err != nil {
+			return err
+		}
+		violations := repo.EvaluatePolicy(policy, modelAPKInfo)
+		if len(violations) > 0 {
+			fmt.Println()
+			printPolicyViolations(violations, "")
+		}
+		decision := repo.PolicyDecision(violations)
+		if decision == models.PolicyReject {
+			return fmt.Errorf("%s", i18n.T("cmd.policy.rejected"))
+		}
+
 		// Confirm addition
 		if !skipConfirm {
 			fmt.Print("\n" + i18n.T("cmd.repoAdd.confirm"))
@@ -124,25 +181,16 @@ var addCmd = &cobra.Command{
 			}
 		}
 
-		// Create APK info structure
-		modelAPKInfo := &models.APKInfo{
-			PackageID:     apkInfo.PackageID,
-			AppName:       apkInfo.AppName,
-			Version:       apkInfo.Version,
-			VersionCode:   apkInfo.VersionCode,
-			MinSDK:        apkInfo.MinSDK,
-			TargetSDK:     apkInfo.TargetSDK,
-			Size:          apkInfo.Size,
-			SHA256:        apkInfo.SHA256,
-			SignatureInfo: apkInfo.SignatureInfo,
-			Permissions:   apkInfo.Permissions,
-			Features:      apkInfo.Features,
-			ABIs:          apkInfo.ABIs,
-			AddedAt:       time.Now(),
-			UpdatedAt:     time.Now(),
-			OriginalName:  filepath.Base(absAPKPath),
-			FileName:      normalizedName,
-			FilePath:      filepath.Join("apks", normalizedName),
+		// A quarantined APK is kept out of the repository and the manifest
+		if decision == models.PolicyQuarantine {
+			quarantinePath, err := quarantineAPK(repository, absAPKPath, modelAPKInfo, violations, !copyFile)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("\n%s\n", i18n.T("cmd.policy.quarantined", map[string]interface{}{
+				"path": quarantinePath,
+			}))
+			return nil
 		}
 
 		// Copy or move APK to repository
@@ -156,6 +204,7 @@ var addCmd = &cobra.Command{
 		}
 
 		fmt.Printf("\n%s\n", i18n.T("cmd.repoAdd.copying"))
+		apkInfo.LoadIcon() // Read from the file before it is moved
 		if copyFile {
 			if err := copyAPKFile(absAPKPath, targetPath); err != nil {
 				return fmt.Errorf("%s: %w", i18n.T("cmd.repoAdd.errCopy"), err)
@@ -205,16 +254,88 @@ func init() {
 	addCmd.Flags().BoolVarP(&copyFile, "copy", "c", false, i18n.T("cmd.repoAdd.flag.copy"))
 }
 
-// getDefaultName returns the default name from multi-language map
-func getDefaultName(names map[string]string) string {
-	if name, ok := names["default"]; ok {
-		return name
+// checkRecordedSigner compares the APK's signer with the newest recorded version of
+// the package. A change without a v3 rotation proof is refused under the strict policy.
+func checkRecordedSigner(repository *repo.Repository, apkInfo *apk.APKInfo, signaturePolicy string) error {
+	if apkInfo.SignatureInfo == nil || apkInfo.SignatureInfo.SHA256 == "" {
+		return nil
 	}
-	if name, ok := names["en"]; ok {
-		return name
+
+	recorded, err := repository.FindLatestSignedAPKInfo(apkInfo.PackageID)
+	if err != nil || recorded == nil {
+		return nil
+	}
+
+	var lineage []string
+	if apkInfo.SignatureVerification != nil {
+		lineage = apkInfo.SignatureVerification.Lineage
+	}
+
+	if err := apk.CheckSignerContinuity(recorded.SignatureInfo.SHA256, apkInfo.SignatureInfo.SHA256, lineage); err != nil {
+		message := i18n.T("cmd.repoAdd.signerChanged", map[string]interface{}{
+			"version":  recorded.Version,
+			"recorded": recorded.SignatureInfo.SHA256,
+			"apk":      apkInfo.SignatureInfo.SHA256,
+		})
+		if strings.ToLower(signaturePolicy) == "strict" {
+			return fmt.Errorf("%s", message)
+		}
+		fmt.Printf("\n%s\n", i18n.T("cmd.repoAdd.signerChangedWarn", map[string]interface{}{
+			"message": message,
+		}))
+		return nil
+	}
+
+	if !strings.EqualFold(recorded.SignatureInfo.SHA256, apkInfo.SignatureInfo.SHA256) {
+		fmt.Printf("%s\n", i18n.T("cmd.repoAdd.signerRotated"))
+	}
+
+	return nil
+}
+
+// printNewDangerousPermissions highlights the dangerous permissions an APK
+// requests that the latest recorded version of its package did not, or all of
+// them for a new package
+func printNewDangerousPermissions(repository *repo.Repository, apkInfo *apk.APKInfo) {
+	dangerous := apk.DangerousPermissions(apkInfo.Permissions)
+	if len(dangerous) == 0 {
+		return
 	}
-	// Return first available name
-	for _, name := range names {
+
+	recorded, _ := repository.FindLatestAPKInfo(apkInfo.PackageID)
+	if recorded == nil {
+		fmt.Printf("\n%s\n", i18n.T("cmd.repoAdd.dangerousPermissions", map[string]interface{}{
+			"permissions": len(dangerous),
+		}))
+	} else {
+		previous := make(map[string]bool, len(recorded.Permissions))
+		for _, permission := range recorded.Permissions {
+			previous[permission] = true
+		}
+		var added []string
+		for _, permission := range dangerous {
+			if !previous[permission] {
+				added = append(added, permission)
+			}
+		}
+		if len(added) == 0 {
+			return
+		}
+		dangerous = added
+		fmt.Printf("\n%s\n", i18n.T("cmd.repoAdd.newDangerousPermissions", map[string]interface{}{
+			"permissions": len(dangerous), "version": recorded.Version,
+		}))
+	}
+
+	for _, permission := range dangerous {
+		fmt.Printf("  • %s%s\n", permission, permissionNotes(permission))
+	}
+}
+
+// localizedName returns the name from a multi-language map in the language
+// chosen with --lang or the locale environment
+func localizedName(names map[string]string) string {
+	if name := models.LocalizedName(names, i18n.PreferredLanguages()); name != "" {
 		return name
 	}
 	return "Unknown"
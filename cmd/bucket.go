@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/huanfeng/apkhub/internal/i18n"
 	"github.com/huanfeng/apkhub/pkg/client"
@@ -18,7 +21,10 @@ var bucketCmd = &cobra.Command{
 	Long:  i18n.T("cmd.bucket.long"),
 }
 
-var bucketVerifySignature bool
+var (
+	bucketVerifySignature bool
+	bucketPriority        int
+)
 
 var bucketListCmd = &cobra.Command{
 	Use:   "list",
@@ -38,9 +44,16 @@ var bucketListCmd = &cobra.Command{
 		// Display buckets in table format
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 		fmt.Fprintln(w, i18n.T("cmd.bucket.list.header"))
-		fmt.Fprintln(w, "----\t------------\t---\t-------\t------------")
+		fmt.Fprintln(w, "----\t------------\t---\t-------\t--------\t------------")
+
+		names := make([]string, 0, len(config.Buckets))
+		for name := range config.Buckets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
 
-		for name, bucket := range config.Buckets {
+		for _, name := range names {
+			bucket := config.Buckets[name]
 			enabled := "Yes"
 			if !bucket.Enabled {
 				enabled = "No"
@@ -56,8 +69,8 @@ var bucketListCmd = &cobra.Command{
 				marker = "*"
 			}
 
-			fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\n",
-				name, marker, bucket.Name, bucket.URL, enabled, lastUpdated)
+			fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%d\t%s\n",
+				name, marker, bucket.Name, bucket.URL, enabled, bucket.Priority, lastUpdated)
 		}
 
 		w.Flush()
@@ -68,6 +81,11 @@ var bucketListCmd = &cobra.Command{
 			}))
 		}
 
+		if len(config.Pins) > 0 {
+			fmt.Printf("\n%s\n", i18n.T("cmd.bucket.pin.listTitle"))
+			showPins(config)
+		}
+
 		return nil
 	},
 }
@@ -109,6 +127,12 @@ var bucketAddCmd = &cobra.Command{
 			return fmt.Errorf("%s: %w", i18n.T("cmd.bucket.errAdd"), err)
 		}
 
+		if bucketPriority != 0 {
+			if err := config.SetBucketPriority(name, bucketPriority); err != nil {
+				return fmt.Errorf("%s: %w", i18n.T("cmd.bucket.errAdd"), err)
+			}
+		}
+
 		fmt.Printf("%s\n", i18n.T("cmd.bucket.add.success", map[string]interface{}{
 			"name": name, "source": bucketURL,
 		}))
@@ -168,6 +192,7 @@ var bucketRemoveCmd = &cobra.Command{
 		// Clean up cache
 		cacheFile := fmt.Sprintf("%s/%s.json", config.Client.CacheDir, name)
 		os.Remove(cacheFile)
+		client.NewBucketManager(config).ForgetManifestState(name)
 
 		return nil
 	},
@@ -192,10 +217,15 @@ var bucketUpdateCmd = &cobra.Command{
 			fmt.Printf("%s\n", i18n.T("cmd.bucket.update.single", map[string]interface{}{
 				"name": name,
 			}))
-			if _, err := bucketMgr.FetchManifest(name); err != nil {
+			status, err := bucketMgr.UpdateManifest(name)
+			if err != nil {
 				return fmt.Errorf("%s: %w", i18n.T("cmd.bucket.errUpdate"), err)
 			}
-			fmt.Printf("%s\n", i18n.T("cmd.bucket.update.singleSuccess", map[string]interface{}{
+			key := "cmd.bucket.update.singleSuccess"
+			if status == client.ManifestUnchanged {
+				key = "cmd.bucket.update.singleUnchanged"
+			}
+			fmt.Printf("%s\n", i18n.T(key, map[string]interface{}{
 				"name": name,
 			}))
 		} else {
@@ -211,6 +241,101 @@ var bucketUpdateCmd = &cobra.Command{
 	},
 }
 
+var bucketPriorityCmd = &cobra.Command{
+	Use:   "priority <name> <priority>",
+	Short: i18n.T("cmd.bucket.priority.short"),
+	Long:  i18n.T("cmd.bucket.priority.long"),
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		priority, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf(i18n.T("cmd.bucket.priority.errInvalid", map[string]interface{}{
+				"value": args[1],
+			}))
+		}
+
+		config, err := client.Load()
+		if err != nil {
+			return fmt.Errorf("%s: %w", i18n.T("cmd.bucket.errLoadConfig"), err)
+		}
+
+		if err := config.SetBucketPriority(name, priority); err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\n", i18n.T("cmd.bucket.priority.success", map[string]interface{}{
+			"name": name, "priority": priority,
+		}))
+		return nil
+	},
+}
+
+var bucketPinCmd = &cobra.Command{
+	Use:   "pin [package-id] [bucket]",
+	Short: i18n.T("cmd.bucket.pin.short"),
+	Long:  i18n.T("cmd.bucket.pin.long"),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return fmt.Errorf(i18n.T("cmd.bucket.pin.errArgs"))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := client.Load()
+		if err != nil {
+			return fmt.Errorf("%s: %w", i18n.T("cmd.bucket.errLoadConfig"), err)
+		}
+
+		// Without arguments, list the current pins
+		if len(args) == 0 {
+			if len(config.Pins) == 0 {
+				fmt.Println(i18n.T("cmd.bucket.pin.empty"))
+				return nil
+			}
+			fmt.Println(i18n.T("cmd.bucket.pin.listTitle"))
+			showPins(config)
+			return nil
+		}
+
+		packageID, bucketName := args[0], args[1]
+		if err := config.PinPackage(packageID, bucketName); err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\n", i18n.T("cmd.bucket.pin.success", map[string]interface{}{
+			"id": packageID, "bucket": bucketName,
+		}))
+		if bucket := config.Buckets[bucketName]; !bucket.Enabled {
+			fmt.Printf("%s\n", i18n.T("cmd.bucket.pin.disabledWarn", map[string]interface{}{
+				"bucket": bucketName,
+			}))
+		}
+		return nil
+	},
+}
+
+var bucketUnpinCmd = &cobra.Command{
+	Use:   "unpin <package-id>",
+	Short: i18n.T("cmd.bucket.unpin.short"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := client.Load()
+		if err != nil {
+			return fmt.Errorf("%s: %w", i18n.T("cmd.bucket.errLoadConfig"), err)
+		}
+
+		if err := config.UnpinPackage(args[0]); err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\n", i18n.T("cmd.bucket.unpin.success", map[string]interface{}{
+			"id": args[0],
+		}))
+		return nil
+	},
+}
+
 var bucketEnableCmd = &cobra.Command{
 	Use:   "enable <name>",
 	Short: i18n.T("cmd.bucket.enable.short"),
@@ -310,6 +435,10 @@ var bucketStatusCmd = &cobra.Command{
 		bucketMgr.CheckAllHealth()
 		bucketMgr.PrintHealthStatus()
 
+		// Show accepted manifest versions (rollback/freeze protection)
+		fmt.Printf("\n%s\n", i18n.T("cmd.bucket.status.manifestTitle"))
+		showManifestStates(bucketMgr, config)
+
 		// Show cache status
 		fmt.Printf("\n%s\n", i18n.T("cmd.bucket.status.cacheTitle"))
 		showCacheStatus(config)
@@ -388,6 +517,65 @@ func printSingleBucketHealth(health *client.BucketHealth) {
 	}
 }
 
+// showPins lists package pins, marking pins to unknown buckets
+func showPins(config *client.Config) {
+	packageIDs := make([]string, 0, len(config.Pins))
+	for packageID := range config.Pins {
+		packageIDs = append(packageIDs, packageID)
+	}
+	sort.Strings(packageIDs)
+
+	for _, packageID := range packageIDs {
+		bucketName := config.Pins[packageID]
+		if _, exists := config.Buckets[bucketName]; !exists {
+			fmt.Printf("%s\n", i18n.T("cmd.bucket.pin.entryMissing", map[string]interface{}{
+				"id": packageID, "bucket": bucketName,
+			}))
+			continue
+		}
+		fmt.Printf("%s\n", i18n.T("cmd.bucket.pin.entry", map[string]interface{}{
+			"id": packageID, "bucket": bucketName,
+		}))
+	}
+}
+
+// showManifestStates shows the newest manifest accepted from each bucket
+func showManifestStates(bucketMgr *client.BucketManager, config *client.Config) {
+	states, err := bucketMgr.GetManifestStates()
+	if err != nil {
+		fmt.Printf(i18n.T("cmd.bucket.manifest.readErr")+"\n", err)
+		return
+	}
+
+	if len(states) == 0 {
+		fmt.Println(i18n.T("cmd.bucket.manifest.none"))
+		return
+	}
+
+	names := make([]string, 0, len(states))
+	for name := range states {
+		if _, exists := config.Buckets[name]; exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	for _, name := range names {
+		state := states[name]
+		fmt.Printf(i18n.T("cmd.bucket.manifest.bucket")+"\n", name, state.Version,
+			state.UpdatedAt.Local().Format("2006-01-02 15:04:05"))
+
+		if state.Expires == nil {
+			fmt.Println(i18n.T("cmd.bucket.manifest.noExpiry"))
+		} else if now.After(*state.Expires) {
+			fmt.Printf(i18n.T("cmd.bucket.manifest.expired")+"\n", state.Expires.Local().Format("2006-01-02 15:04:05"))
+		} else {
+			fmt.Printf(i18n.T("cmd.bucket.manifest.expires")+"\n", state.Expires.Local().Format("2006-01-02 15:04:05"))
+		}
+	}
+}
+
 // showCacheStatus shows cache file information
 func showCacheStatus(config *client.Config) {
 	cacheDir := config.Client.CacheDir
@@ -562,7 +750,11 @@ func init() {
 	bucketCmd.AddCommand(bucketDisableCmd)
 	bucketCmd.AddCommand(bucketHealthCmd)
 	bucketCmd.AddCommand(bucketStatusCmd)
+	bucketCmd.AddCommand(bucketPriorityCmd)
+	bucketCmd.AddCommand(bucketPinCmd)
+	bucketCmd.AddCommand(bucketUnpinCmd)
 
 	// Add flags
 	bucketRemoveCmd.Flags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip confirmation prompt")
+	bucketAddCmd.Flags().IntVar(&bucketPriority, "priority", 0, "Bucket priority; higher wins when versions tie")
 }
@@ -0,0 +1,504 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/huanfeng/apkhub/internal/config"
+	"github.com/huanfeng/apkhub/internal/i18n"
+	"github.com/huanfeng/apkhub/pkg/apk"
+	"github.com/huanfeng/apkhub/pkg/models"
+	"github.com/huanfeng/apkhub/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffFormat   string
+	diffOutput   string
+	diffTopFiles int
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: i18n.T("cmd.diff.short"),
+	Long:  i18n.T("cmd.diff.long"),
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch diffFormat {
+		case "text", "json", "md", "markdown":
+		default:
+			return fmt.Errorf("%s: %s", i18n.T("cmd.diff.errUnsupported"), diffFormat)
+		}
+
+		absWorkDir, err := filepath.Abs(workDir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", i18n.T("cmd.diff.errWorkDir"), err)
+		}
+
+		resolver := &diffResolver{}
+		oldPath, err := resolver.resolve(args[0])
+		if err != nil {
+			return err
+		}
+		newPath, err := resolver.resolve(args[1])
+		if err != nil {
+			return err
+		}
+
+		// Progress messages of the parser would corrupt JSON and Markdown output
+		parser := apk.NewParser(absWorkDir)
+		parser.SetOutput(os.Stderr)
+
+		oldInfo, err := parser.ParseAPK(oldPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", i18n.T("cmd.diff.errParse", map[string]interface{}{"path": oldPath}), err)
+		}
+		newInfo, err := parser.ParseAPK(newPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", i18n.T("cmd.diff.errParse", map[string]interface{}{"path": newPath}), err)
+		}
+
+		diff, err := apk.DiffAPKs(oldInfo, oldPath, newInfo, newPath, diffTopFiles)
+		if err != nil {
+			return fmt.Errorf("%s: %w", i18n.T("cmd.diff.errDiff"), err)
+		}
+
+		out := io.Writer(os.Stdout)
+		if diffOutput != "" {
+			file, err := os.Create(diffOutput)
+			if err != nil {
+				return fmt.Errorf("%s: %w", i18n.T("cmd.diff.errCreateFile"), err)
+			}
+			defer file.Close()
+			out = file
+		}
+
+		switch diffFormat {
+		case "json":
+			data, err := json.MarshalIndent(diff, "", "  ")
+			if err != nil {
+				return fmt.Errorf("%s: %w", i18n.T("cmd.diff.errMarshal"), err)
+			}
+			fmt.Fprintln(out, string(data))
+		case "md", "markdown":
+			writeDiffMarkdown(out, diff)
+		default:
+			writeDiffText(out, diff)
+		}
+
+		if diffOutput != "" {
+			fmt.Fprintf(os.Stderr, "%s\n", i18n.T("cmd.diff.written", map[string]interface{}{"path": diffOutput}))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVarP(&diffFormat, "format", "f", "text", i18n.T("cmd.diff.flag.format"))
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", "", i18n.T("cmd.diff.flag.output"))
+	diffCmd.Flags().IntVar(&diffTopFiles, "top", 10, i18n.T("cmd.diff.flag.top"))
+}
+
+// diffResolver resolves the arguments of diff to files, loading the
+// repository the first time a package@version reference needs it
+type diffResolver struct {
+	repository *repo.Repository
+	baseURL    string
+	infos      []*models.APKInfo
+	manifest   *models.ManifestIndex // nil if no manifest was published
+}
+
+// resolve returns the path of an APK file, or of the file of a
+// package@version in the repository. The version is a version name or code;
+// "package" and "package@latest" select the highest version code. infos/ only
+// records the latest version of a package, so earlier versions are looked up
+// in the manifest and, failing that, by the version code in the normalized
+// file names of the APK directory.
+func (r *diffResolver) resolve(ref string) (string, error) {
+	if info, err := os.Stat(ref); err == nil && !info.IsDir() {
+		return filepath.Abs(ref)
+	}
+
+	packageID, version, _ := strings.Cut(ref, "@")
+	if err := r.load(); err != nil {
+		return "", err
+	}
+
+	var match *models.APKInfo
+	for _, info := range r.infos {
+		if info.PackageID != packageID || info.FilePath == "" {
+			continue
+		}
+		if !diffVersionMatches(version, info.Version, info.VersionCode) {
+			continue
+		}
+		if match == nil || info.VersionCode > match.VersionCode {
+			match = info
+		}
+	}
+	if match == nil {
+		if apkPath, ok := r.findManifestVersion(packageID, version); ok {
+			return apkPath, nil
+		}
+		if apkPath, ok := r.findAPKFile(packageID, version); ok {
+			return apkPath, nil
+		}
+		return "", fmt.Errorf("%s", i18n.T("cmd.diff.errNotFound", map[string]interface{}{"ref": ref}))
+	}
+
+	apkPath := filepath.Join(r.repository.GetRootDir(), match.FilePath)
+	if _, err := os.Stat(apkPath); err != nil {
+		return "", fmt.Errorf("%s: %w", i18n.T("cmd.diff.errMissingFile", map[string]interface{}{"ref": ref}), err)
+	}
+	return apkPath, nil
+}
+
+// diffVersionMatches reports whether a version name and code match the version of a reference
+func diffVersionMatches(version, versionName string, versionCode int64) bool {
+	return version == "" || version == "latest" ||
+		versionName == version || strconv.FormatInt(versionCode, 10) == version
+}
+
+// findManifestVersion looks in the manifest for the version of a package and
+// returns its file in the repository, found from its download URL
+func (r *diffResolver) findManifestVersion(packageID, version string) (string, bool) {
+	if r.manifest == nil || r.manifest.Packages[packageID] == nil {
+		return "", false
+	}
+
+	var match *models.AppVersion
+	for _, candidate := range r.manifest.Packages[packageID].Versions {
+		if candidate == nil || !diffVersionMatches(version, candidate.Version, candidate.VersionCode) {
+			continue
+		}
+		if match == nil || candidate.VersionCode > match.VersionCode {
+			match = candidate
+		}
+	}
+	if match == nil {
+		return "", false
+	}
+
+	// Download URLs are relative to the repository, or start with its base URL
+	downloadURL := match.DownloadURL
+	if baseURL := strings.TrimRight(r.baseURL, "/"); baseURL != "" {
+		if rest, ok := strings.CutPrefix(downloadURL, baseURL+"/"); ok {
+			downloadURL = rest
+		}
+	}
+	localPath, ok := resolveLocalAPKPath(downloadURL)
+	if !ok {
+		return "", false
+	}
+	if !filepath.IsAbs(localPath) {
+		localPath = filepath.Join(r.repository.GetRootDir(), localPath)
+	}
+	if info, err := os.Stat(localPath); err != nil || info.IsDir() {
+		return "", false
+	}
+	return localPath, true
+}
+
+// findAPKFile looks in the APK directory for the file of a version of a
+// package. Files are named packageid_versioncode[_signature][_variant].ext, so
+// a version code is matched by name and a version name by parsing the files of
+// the package.
+func (r *diffResolver) findAPKFile(packageID, version string) (string, bool) {
+	if version == "" || version == "latest" {
+		return "", false
+	}
+
+	entries, err := os.ReadDir(r.repository.GetAPKPath(""))
+	if err != nil {
+		return "", false
+	}
+	var candidates []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !apk.IsAPKFile(name) {
+			continue
+		}
+		rest, ok := strings.CutPrefix(strings.TrimSuffix(name, filepath.Ext(name)), packageID+"_")
+		if !ok {
+			continue
+		}
+		if versionCode, _, _ := strings.Cut(rest, "_"); versionCode == version {
+			return r.repository.GetAPKPath(name), true
+		}
+		candidates = append(candidates, r.repository.GetAPKPath(name))
+	}
+
+	parser := apk.NewParser(r.repository.GetRootDir())
+	parser.SetOutput(io.Discard)
+	for _, candidate := range candidates {
+		info, err := parser.ParseAPK(candidate)
+		if err == nil && info.PackageID == packageID && info.Version == version {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// load loads the APK infos and the manifest of the repository
+func (r *diffResolver) load() error {
+	if r.repository != nil {
+		return nil
+	}
+
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("%s: %w", i18n.T("cmd.diff.errLoadConfig"), err)
+	}
+	repository, err := repo.NewRepository(workDir, cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", i18n.T("cmd.diff.errCreateRepo"), err)
+	}
+	infos, err := repository.LoadAllAPKInfos()
+	if err != nil {
+		return fmt.Errorf("%s: %w", i18n.T("cmd.diff.errLoadInfos"), err)
+	}
+
+	r.repository = repository
+	r.baseURL = cfg.Repository.BaseURL
+	r.infos = infos
+	// Without a manifest, earlier versions are still found by file name
+	r.manifest, _ = repository.LoadManifest()
+	return nil
+}
+
+// formatSizeDelta formats a change of size with its sign
+func formatSizeDelta(delta int64) string {
+	switch {
+	case delta > 0:
+		return "+" + formatBytes(delta)
+	case delta < 0:
+		return "-" + formatBytes(-delta)
+	default:
+		return "±0 B"
+	}
+}
+
+// diffTargetLabel describes one side of a diff
+func diffTargetLabel(target apk.DiffTarget) string {
+	return fmt.Sprintf("%s %s (%d)", target.PackageID, target.Version, target.VersionCode)
+}
+
+// writeDiffText writes a diff for the terminal
+func writeDiffText(out io.Writer, diff *apk.APKDiff) {
+	fmt.Fprintf(out, "%s\n\n", i18n.T("cmd.diff.title"))
+	fmt.Fprintf(out, "%s\n", i18n.T("cmd.diff.old", map[string]interface{}{
+		"target": diffTargetLabel(diff.Old), "path": diff.Old.Path,
+	}))
+	fmt.Fprintf(out, "%s\n", i18n.T("cmd.diff.new", map[string]interface{}{
+		"target": diffTargetLabel(diff.New), "path": diff.New.Path,
+	}))
+
+	if !diff.Changed() {
+		fmt.Fprintf(out, "\n%s\n", i18n.T("cmd.diff.noChanges"))
+		return
+	}
+
+	fmt.Fprintf(out, "%s\n", i18n.T("cmd.diff.size", sizeChangeParams(diff.Size)))
+	if diff.MinSDK != nil {
+		fmt.Fprintf(out, "%s\n", i18n.T("cmd.diff.minSDK", map[string]interface{}{
+			"old": diff.MinSDK.Old, "new": diff.MinSDK.New,
+		}))
+	}
+	if diff.TargetSDK != nil {
+		fmt.Fprintf(out, "%s\n", i18n.T("cmd.diff.targetSDK", map[string]interface{}{
+			"old": diff.TargetSDK.Old, "new": diff.TargetSDK.New,
+		}))
+	}
+
+	if diff.Signer != nil {
+		fmt.Fprintf(out, "\n%s\n\n", i18n.T("cmd.diff.signerTitle"))
+		if diff.Signer.Rotated {
+			fmt.Fprintf(out, "%s\n", i18n.T("cmd.diff.signerRotated"))
+		} else {
+			fmt.Fprintf(out, "%s\n", i18n.T("cmd.diff.signerChanged"))
+		}
+		fmt.Fprintf(out, "  - %s\n", diff.Signer.Old)
+		fmt.Fprintf(out, "  + %s\n", diff.Signer.New)
+	}
+
+	writeListChangeText(out, "cmd.diff.permissionsTitle", diff.Permissions)
+	writeListChangeText(out, "cmd.diff.abisTitle", diff.ABIs)
+	writeComponentChangeText(out, "cmd.diff.componentsTitle", diff.Components)
+	writeComponentChangeText(out, "cmd.diff.exportedTitle", diff.ExportedComponents)
+	writeListChangeText(out, "cmd.diff.librariesTitle", diff.Libraries)
+
+	fmt.Fprintf(out, "\n%s\n\n", i18n.T("cmd.diff.codeTitle"))
+	fmt.Fprintf(out, "%s\n", i18n.T("cmd.diff.dex", sizeChangeParams(diff.Dex)))
+	fmt.Fprintf(out, "%s\n", i18n.T("cmd.diff.nativeLibraries", sizeChangeParams(diff.NativeLibraries)))
+
+	if len(diff.Files) > 0 {
+		fmt.Fprintf(out, "\n%s\n\n", i18n.T("cmd.diff.filesTitle"))
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, file := range diff.Files {
+			fmt.Fprintf(w, "  %s %s\t%s\t%s\n", fileChangeMarker(file.Status), file.Name,
+				fileChangeSizes(file), formatSizeDelta(file.Delta))
+		}
+		w.Flush()
+	}
+}
+
+// sizeChangeParams returns the template parameters of a size change
+func sizeChangeParams(change apk.SizeChange) map[string]interface{} {
+	return map[string]interface{}{
+		"old":   formatBytes(change.Old),
+		"new":   formatBytes(change.New),
+		"delta": formatSizeDelta(change.Delta),
+	}
+}
+
+// writeListChangeText writes the values added and removed under a title
+func writeListChangeText(out io.Writer, titleKey string, change apk.ListChange) {
+	if change.Empty() {
+		return
+	}
+	fmt.Fprintf(out, "\n%s\n\n", i18n.T(titleKey))
+	for _, value := range change.Added {
+		fmt.Fprintf(out, "  + %s\n", value)
+	}
+	for _, value := range change.Removed {
+		fmt.Fprintf(out, "  - %s\n", value)
+	}
+}
+
+// writeComponentChangeText writes the components added and removed under a
+// title
+func writeComponentChangeText(out io.Writer, titleKey string, change apk.ComponentChange) {
+	if change.Empty() {
+		return
+	}
+	fmt.Fprintf(out, "\n%s\n\n", i18n.T(titleKey))
+	for _, ref := range change.Added {
+		fmt.Fprintf(out, "  + %s (%s)\n", ref.Name, ref.Kind)
+	}
+	for _, ref := range change.Removed {
+		fmt.Fprintf(out, "  - %s (%s)\n", ref.Name, ref.Kind)
+	}
+}
+
+// fileChangeMarker returns the marker of a file change: +, - or ~
+func fileChangeMarker(status string) string {
+	switch status {
+	case apk.FileAdded:
+		return "+"
+	case apk.FileRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// fileChangeSizes describes the sizes of a changed file
+func fileChangeSizes(file apk.FileChange) string {
+	switch file.Status {
+	case apk.FileAdded:
+		return formatBytes(file.NewSize)
+	case apk.FileRemoved:
+		return formatBytes(file.OldSize)
+	default:
+		return formatBytes(file.OldSize) + " → " + formatBytes(file.NewSize)
+	}
+}
+
+// writeDiffMarkdown writes a diff as Markdown for release reviews
+func writeDiffMarkdown(out io.Writer, diff *apk.APKDiff) {
+	fmt.Fprintf(out, "## APK diff: %s → %s\n\n", diffTargetLabel(diff.Old), diffTargetLabel(diff.New))
+	fmt.Fprintf(out, "- **Old:** `%s`\n", filepath.Base(diff.Old.Path))
+	fmt.Fprintf(out, "- **New:** `%s`\n\n", filepath.Base(diff.New.Path))
+
+	if !diff.Changed() {
+		fmt.Fprintf(out, "No differences found.\n")
+		return
+	}
+
+	fmt.Fprintf(out, "| | Old | New | Change |\n")
+	fmt.Fprintf(out, "|---|---|---|---|\n")
+	writeSizeRowMarkdown(out, "Size", diff.Size)
+	if diff.MinSDK != nil {
+		fmt.Fprintf(out, "| Min SDK | %d | %d | %+d |\n", diff.MinSDK.Old, diff.MinSDK.New, diff.MinSDK.New-diff.MinSDK.Old)
+	}
+	if diff.TargetSDK != nil {
+		fmt.Fprintf(out, "| Target SDK | %d | %d | %+d |\n", diff.TargetSDK.Old, diff.TargetSDK.New, diff.TargetSDK.New-diff.TargetSDK.Old)
+	}
+	writeSizeRowMarkdown(out, "DEX", diff.Dex)
+	writeSizeRowMarkdown(out, "Native libraries", diff.NativeLibraries)
+
+	if diff.Signer != nil {
+		fmt.Fprintf(out, "\n### Signer\n\n")
+		if diff.Signer.Rotated {
+			fmt.Fprintf(out, "Signing certificate rotated; the new lineage includes the old certificate.\n\n")
+		} else {
+			fmt.Fprintf(out, "⚠️ **Signing certificate changed.** Devices with the old version installed cannot update.\n\n")
+		}
+		fmt.Fprintf(out, "- Old: `%s`\n", diff.Signer.Old)
+		fmt.Fprintf(out, "- New: `%s`\n", diff.Signer.New)
+	}
+
+	writeListChangeMarkdown(out, "Permissions", diff.Permissions)
+	writeListChangeMarkdown(out, "ABIs", diff.ABIs)
+	writeComponentChangeMarkdown(out, "Components", diff.Components)
+	writeComponentChangeMarkdown(out, "Exported components", diff.ExportedComponents)
+	writeListChangeMarkdown(out, "SDKs", diff.Libraries)
+
+	if len(diff.Files) > 0 {
+		fmt.Fprintf(out, "\n### Largest changed files\n\n")
+		fmt.Fprintf(out, "| File | Status | Old | New | Change |\n")
+		fmt.Fprintf(out, "|------|--------|-----|-----|--------|\n")
+		for _, file := range diff.Files {
+			oldSize, newSize := "—", "—"
+			if file.Status != apk.FileAdded {
+				oldSize = formatBytes(file.OldSize)
+			}
+			if file.Status != apk.FileRemoved {
+				newSize = formatBytes(file.NewSize)
+			}
+			fmt.Fprintf(out, "| `%s` | %s | %s | %s | %s |\n", file.Name, file.Status, oldSize, newSize, formatSizeDelta(file.Delta))
+		}
+	}
+}
+
+// writeSizeRowMarkdown writes a size change as a table row
+func writeSizeRowMarkdown(out io.Writer, label string, change apk.SizeChange) {
+	fmt.Fprintf(out, "| %s | %s | %s | %s |\n", label, formatBytes(change.Old), formatBytes(change.New), formatSizeDelta(change.Delta))
+}
+
+// writeListChangeMarkdown writes the values added and removed as a section
+func writeListChangeMarkdown(out io.Writer, title string, change apk.ListChange) {
+	if change.Empty() {
+		return
+	}
+	fmt.Fprintf(out, "\n### %s\n\n", title)
+	for _, value := range change.Added {
+		fmt.Fprintf(out, "- ➕ `%s`\n", value)
+	}
+	for _, value := range change.Removed {
+		fmt.Fprintf(out, "- ➖ `%s`\n", value)
+	}
+}
+
+// writeComponentChangeMarkdown writes the components added and removed as a
+// section
+func writeComponentChangeMarkdown(out io.Writer, title string, change apk.ComponentChange) {
+	if change.Empty() {
+		return
+	}
+	fmt.Fprintf(out, "\n### %s\n\n", title)
+	for _, ref := range change.Added {
+		fmt.Fprintf(out, "- ➕ `%s` (%s)\n", ref.Name, ref.Kind)
+	}
+	for _, ref := range change.Removed {
+		fmt.Fprintf(out, "- ➖ `%s` (%s)\n", ref.Name, ref.Kind)
+	}
+}
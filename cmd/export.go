@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/huanfeng/apkhub/internal/config"
@@ -137,7 +138,7 @@ func exportCSV(manifest *models.ManifestIndex, output string) error {
 				case "package_id":
 					row = append(row, packageID)
 				case "app_name":
-					row = append(row, getDefaultName(pkg.Name))
+					row = append(row, localizedName(pkg.Name))
 				case "version":
 					row = append(row, version.Version)
 				case "version_code":
@@ -160,6 +161,10 @@ func exportCSV(manifest *models.ManifestIndex, output string) error {
 					row = append(row, strings.Join(version.ABIs, ";"))
 				case "release_date":
 					row = append(row, version.ReleaseDate.Format("2006-01-02"))
+				case "launcher_activity", "debuggable", "allow_backup", "uses_cleartext_traffic",
+					"network_security_config", "large_heap", "shared_user_id", "install_location",
+					"deep_links", "exported_components", "activities", "services", "receivers", "providers":
+					row = append(row, manifestCSVField(version.Manifest, field))
 				default:
 					row = append(row, "")
 				}
@@ -174,6 +179,47 @@ func exportCSV(manifest *models.ManifestIndex, output string) error {
 	return nil
 }
 
+// manifestCSVField formats a component or application flag field for CSV export;
+// it is empty for versions without manifest details
+func manifestCSVField(manifest *models.ManifestInfo, field string) string {
+	if manifest == nil {
+		return ""
+	}
+
+	switch field {
+	case "launcher_activity":
+		return manifest.LauncherActivity
+	case "debuggable":
+		return strconv.FormatBool(manifest.Debuggable)
+	case "allow_backup":
+		return strconv.FormatBool(manifest.AllowBackup)
+	case "uses_cleartext_traffic":
+		return strconv.FormatBool(manifest.UsesCleartextTraffic)
+	case "network_security_config":
+		return manifest.NetworkSecurityConfig
+	case "large_heap":
+		return strconv.FormatBool(manifest.LargeHeap)
+	case "shared_user_id":
+		return manifest.SharedUserID
+	case "install_location":
+		return manifest.InstallLocation
+	case "deep_links":
+		return strings.Join(manifest.DeepLinks(), ";")
+	case "exported_components":
+		return strings.Join(manifest.ExportedComponents(), ";")
+	case "activities":
+		return strconv.Itoa(len(manifest.Activities))
+	case "services":
+		return strconv.Itoa(len(manifest.Services))
+	case "receivers":
+		return strconv.Itoa(len(manifest.Receivers))
+	case "providers":
+		return strconv.Itoa(len(manifest.Providers))
+	default:
+		return ""
+	}
+}
+
 func exportMarkdown(manifest *models.ManifestIndex, output string) error {
 	file, err := os.Create(output)
 	if err != nil {
@@ -195,9 +241,18 @@ func exportMarkdown(manifest *models.ManifestIndex, output string) error {
 
 	for packageID, pkg := range manifest.Packages {
 		fmt.Fprintf(file, "### %s\n\n", packageID)
-		fmt.Fprintf(file, "**Name:** %s\n", getDefaultName(pkg.Name))
+		fmt.Fprintf(file, "**Name:** %s\n", localizedName(pkg.Name))
 		fmt.Fprintf(file, "**Versions:** %d\n", len(pkg.Versions))
-		fmt.Fprintf(file, "**Latest:** %s\n\n", pkg.Latest)
+		fmt.Fprintf(file, "**Latest:** %s\n", pkg.Latest)
+		if latest, exists := pkg.Versions[pkg.Latest]; exists && latest.Manifest != nil {
+			if latest.Manifest.LauncherActivity != "" {
+				fmt.Fprintf(file, "**Launcher:** %s\n", latest.Manifest.LauncherActivity)
+			}
+			if links := latest.Manifest.DeepLinks(); len(links) > 0 {
+				fmt.Fprintf(file, "**Deep links:** %s\n", strings.Join(links, ", "))
+			}
+		}
+		fmt.Fprintf(file, "\n")
 
 		// Version table
 		fmt.Fprintf(file, "| Version | Code | Size | Min SDK | SHA256 |\n")
@@ -244,7 +299,7 @@ func exportFDroid(manifest *models.ManifestIndex, output string) error {
 		// Add app entry
 		app := map[string]interface{}{
 			"packageName": packageID,
-			"name":        getDefaultName(pkg.Name),
+			"name":        localizedName(pkg.Name),
 			"added":       manifest.UpdatedAt.Unix() * 1000,
 			"lastUpdated": manifest.UpdatedAt.Unix() * 1000,
 		}
@@ -107,9 +107,15 @@ var importCmd = &cobra.Command{
 			}
 		}
 
+		// Load the admission policy
+		policy, err := loadAdmissionPolicy(repository)
+		if err != nil {
+			return err
+		}
+
 		// Import APKs
 		fmt.Printf("\n%s\n", i18n.T("cmd.import.importing"))
-		var imported, skipped, failed int
+		var imported, skipped, failed, rejected, quarantined int
 
 		for _, apkInfo := range importedPackages {
 			fmt.Printf("\n%s\n", i18n.T("cmd.import.importingItem", map[string]interface{}{
@@ -133,6 +139,34 @@ var importCmd = &cobra.Command{
 				continue
 			}
 
+			// An entry held back on an earlier run stays in quarantine
+			if repository.HasQuarantineRecord(apkInfo) {
+				fmt.Printf("  %s\n", i18n.T("cmd.policy.alreadyQuarantined", map[string]interface{}{"name": apkInfo.OriginalName}))
+				skipped++
+				continue
+			}
+
+			// Check the admission policy
+			violations := repo.EvaluatePolicy(policy, apkInfo)
+			printPolicyViolations(violations, "  ")
+			switch repo.PolicyDecision(violations) {
+			case models.PolicyReject:
+				fmt.Printf("  %s\n", i18n.T("cmd.import.policyRejected"))
+				rejected++
+				continue
+			case models.PolicyQuarantine:
+				// Imported entries have no APK file, only the record is kept
+				recordPath, err := quarantineAPK(repository, "", apkInfo, violations, false)
+				if err != nil {
+					fmt.Printf("  %s\n", i18n.T("cmd.import.errSave", map[string]interface{}{"error": err}))
+					failed++
+					continue
+				}
+				fmt.Printf("  %s\n", i18n.T("cmd.policy.quarantined", map[string]interface{}{"path": recordPath}))
+				quarantined++
+				continue
+			}
+
 			// Save APK info
 			if err := repository.SaveAPKInfo(apkInfo); err != nil {
 				fmt.Printf("  %s\n", i18n.T("cmd.import.errSave", map[string]interface{}{"error": err}))
@@ -162,6 +196,10 @@ var importCmd = &cobra.Command{
 		fmt.Printf("%s\n", i18n.T("cmd.import.summaryImported", map[string]interface{}{"count": imported}))
 		fmt.Printf("%s\n", i18n.T("cmd.import.summarySkipped", map[string]interface{}{"count": skipped}))
 		fmt.Printf("%s\n", i18n.T("cmd.import.summaryFailed", map[string]interface{}{"count": failed}))
+		if rejected > 0 || quarantined > 0 {
+			fmt.Printf("%s\n", i18n.T("cmd.import.summaryRejected", map[string]interface{}{"rejected": rejected}))
+			fmt.Printf("%s\n", i18n.T("cmd.import.summaryQuarantined", map[string]interface{}{"quarantined": quarantined}))
+		}
 		fmt.Printf("\n%s\n", i18n.T("cmd.import.success"))
 
 		return nil
@@ -201,6 +239,8 @@ func importFromApkHub(data []byte) ([]*models.APKInfo, error) {
 				Permissions:   version.Permissions,
 				Features:      version.Features,
 				ABIs:          version.ABIs,
+				Manifest:      version.Manifest,
+				Libraries:     version.Libraries,
 				AddedAt:       time.Now(),
 				UpdatedAt:     time.Now(),
 				OriginalName:  fmt.Sprintf("%s_%s.apk", packageID, versionKey),
@@ -54,8 +54,9 @@ var infoCmd = &cobra.Command{
 		// Display package information
 		fmt.Printf("%s\n\n", i18n.T("cmd.info.title"))
 		fmt.Printf("%s\n", i18n.T("cmd.info.packageID", map[string]interface{}{"id": pkg.PackageID}))
-		fmt.Printf("%s\n", i18n.T("cmd.info.name", map[string]interface{}{"name": getDefaultName(pkg.Name)}))
-		if desc := getDefaultName(pkg.Description); desc != "" {
+		fmt.Printf("%s\n", i18n.T("cmd.info.name", map[string]interface{}{"name": localizedName(pkg.Name)}))
+		printLocalizedNames(pkg.Name)
+		if desc := localizedName(pkg.Description); desc != "" {
 			fmt.Printf("%s\n", i18n.T("cmd.info.description", map[string]interface{}{"desc": desc}))
 		}
 		if pkg.Category != "" {
@@ -74,6 +75,25 @@ var infoCmd = &cobra.Command{
 				fmt.Printf("%s\n", i18n.T("cmd.info.sdk", map[string]interface{}{
 					"min": latestVer.MinSDK, "target": latestVer.TargetSDK,
 				}))
+				if len(latestVer.Permissions) > 0 {
+					riskScore := client.VersionRiskScore(latestVer)
+					fmt.Printf("%s\n", i18n.T("cmd.info.risk", map[string]interface{}{
+						"score": riskScore, "level": riskLevelName(riskScore),
+					}))
+				}
+				if latestVer.Bucket != "" {
+					fmt.Printf("%s\n", i18n.T("cmd.info.bucket", map[string]interface{}{
+						"bucket": latestVer.Bucket,
+					}))
+				}
+				if latestVer.Manifest != nil {
+					fmt.Println()
+					printManifestInfo(latestVer.Manifest)
+				}
+				if len(latestVer.Libraries) > 0 {
+					fmt.Println()
+					printLibraries(latestVer.Libraries)
+				}
 			}
 		}
 
@@ -90,7 +110,11 @@ var infoCmd = &cobra.Command{
 			versions = append(versions, versionEntry{k, v})
 		}
 		sort.Slice(versions, func(i, j int) bool {
-			return versions[i].version.VersionCode > versions[j].version.VersionCode
+			if versions[i].version.VersionCode != versions[j].version.VersionCode {
+				return versions[i].version.VersionCode > versions[j].version.VersionCode
+			}
+			// The merged latest goes first among equal version codes
+			return versions[i].key == pkg.Latest
 		})
 
 		// Display versions in table
@@ -101,9 +125,9 @@ var infoCmd = &cobra.Command{
 		for _, entry := range versions {
 			ver := entry.version
 
-			// Extract bucket name from key
-			bucketName := ""
-			if strings.Contains(entry.key, "_") {
+			// Source bucket, falling back to the key prefix
+			bucketName := ver.Bucket
+			if bucketName == "" && strings.Contains(entry.key, "_") {
 				parts := strings.SplitN(entry.key, "_", 2)
 				bucketName = parts[0]
 			}
@@ -159,7 +183,8 @@ func isLocalAPKFile(target string) bool {
 
 	// Check for other APK-related extensions
 	lowerTarget := strings.ToLower(target)
-	if strings.HasSuffix(lowerTarget, ".xapk") || strings.HasSuffix(lowerTarget, ".apkm") {
+	if strings.HasSuffix(lowerTarget, ".xapk") || strings.HasSuffix(lowerTarget, ".apkm") ||
+		strings.HasSuffix(lowerTarget, ".aab") || strings.HasSuffix(lowerTarget, ".apks") {
 		return true
 	}
 
@@ -215,10 +240,11 @@ func showLocalAPKInfo(apkPath string) error {
 	fmt.Printf("%s\n", i18n.T("cmd.info.local.packageID", map[string]interface{}{
 		"id": apkInfo.PackageID,
 	}))
-	if appName := getDefaultName(apkInfo.AppName); appName != "" {
+	if appName := localizedName(apkInfo.AppName); appName != "" {
 		fmt.Printf("%s\n", i18n.T("cmd.info.local.appName", map[string]interface{}{
 			"name": appName,
 		}))
+		printLocalizedNames(apkInfo.AppName)
 	}
 	fmt.Printf("%s\n", i18n.T("cmd.info.local.version", map[string]interface{}{
 		"version": apkInfo.Version, "code": apkInfo.VersionCode,
@@ -226,6 +252,10 @@ func showLocalAPKInfo(apkPath string) error {
 	fmt.Printf("%s\n", i18n.T("cmd.info.local.sdk", map[string]interface{}{
 		"min": apkInfo.MinSDK, "target": apkInfo.TargetSDK,
 	}))
+	riskScore := apk.PermissionRiskScore(apkInfo.Permissions)
+	fmt.Printf("%s\n", i18n.T("cmd.info.risk", map[string]interface{}{
+		"score": riskScore, "level": riskLevelName(riskScore),
+	}))
 
 	if len(apkInfo.ABIs) > 0 {
 		fmt.Printf("%s\n", i18n.T("cmd.info.local.abis", map[string]interface{}{
@@ -245,7 +275,7 @@ func showLocalAPKInfo(apkPath string) error {
 		for category, perms := range permGroups {
 			fmt.Printf("%s:\n", category)
 			for _, perm := range perms {
-				fmt.Printf("  • %s\n", perm)
+				fmt.Printf("  • %s%s\n", perm, permissionNotes(perm))
 			}
 			fmt.Println()
 		}
@@ -262,6 +292,18 @@ func showLocalAPKInfo(apkPath string) error {
 		fmt.Println()
 	}
 
+	// Components and application flags
+	if apkInfo.Manifest != nil {
+		printManifestInfo(apkInfo.Manifest)
+		fmt.Println()
+	}
+
+	// SDKs detected in the code
+	if len(apkInfo.Libraries) > 0 {
+		printLibraries(apkInfo.Libraries)
+		fmt.Println()
+	}
+
 	// File analysis
 	fmt.Printf("%s\n\n", i18n.T("cmd.info.local.fileAnalysis"))
 	fmt.Printf("%s\n", i18n.T("cmd.info.local.sha256", map[string]interface{}{
@@ -285,6 +327,125 @@ func showLocalAPKInfo(apkPath string) error {
 	return nil
 }
 
+// printLocalizedNames lists the names of an app in every language it is
+// translated to
+func printLocalizedNames(names map[string]string) {
+	if len(names) < 2 {
+		return
+	}
+
+	languages := make([]string, 0, len(names))
+	for language := range names {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	fmt.Printf("%s\n", i18n.T("cmd.info.localizedNames"))
+	for _, language := range languages {
+		fmt.Printf("  • %s: %s\n", language, names[language])
+	}
+}
+
+// printManifestInfo displays the components and application flags of a manifest
+func printManifestInfo(manifest *models.ManifestInfo) {
+	fmt.Printf("%s\n\n", i18n.T("cmd.info.manifest.componentsTitle"))
+	if manifest.LauncherActivity != "" {
+		fmt.Printf("%s\n", i18n.T("cmd.info.manifest.launcher", map[string]interface{}{
+			"name": manifest.LauncherActivity,
+		}))
+	}
+
+	groups := []struct {
+		key        string
+		components []models.Component
+	}{
+		{"cmd.info.manifest.activities", manifest.Activities},
+		{"cmd.info.manifest.services", manifest.Services},
+		{"cmd.info.manifest.receivers", manifest.Receivers},
+		{"cmd.info.manifest.providers", manifest.Providers},
+	}
+	for _, group := range groups {
+		exported := 0
+		for _, component := range group.components {
+			if component.Exported {
+				exported++
+			}
+		}
+		fmt.Printf("%s\n", i18n.T(group.key, map[string]interface{}{
+			"components": len(group.components), "exported": exported,
+		}))
+	}
+
+	if links := manifest.DeepLinks(); len(links) > 0 {
+		fmt.Printf("\n%s\n", i18n.T("cmd.info.manifest.deepLinks"))
+		for _, link := range links {
+			fmt.Printf("  • %s\n", link)
+		}
+	}
+
+	if exported := manifest.ExportedComponents(); len(exported) > 0 {
+		fmt.Printf("\n%s\n", i18n.T("cmd.info.manifest.exported"))
+		for _, name := range exported {
+			fmt.Printf("  • %s\n", name)
+		}
+	}
+
+	fmt.Printf("\n%s\n\n", i18n.T("cmd.info.manifest.flagsTitle"))
+	flags := []struct {
+		key   string
+		value bool
+		risky bool // The value that weakens security
+	}{
+		{"cmd.info.manifest.debuggable", manifest.Debuggable, true},
+		{"cmd.info.manifest.allowBackup", manifest.AllowBackup, true},
+		{"cmd.info.manifest.cleartext", manifest.UsesCleartextTraffic, true},
+		{"cmd.info.manifest.largeHeap", manifest.LargeHeap, false},
+	}
+	for _, flag := range flags {
+		value := i18n.T("cmd.info.manifest.no")
+		if flag.value {
+			value = i18n.T("cmd.info.manifest.yes")
+		}
+		if flag.risky && flag.value {
+			value += " ⚠️"
+		}
+		fmt.Printf("%s\n", i18n.T(flag.key, map[string]interface{}{"value": value}))
+	}
+	if manifest.NetworkSecurityConfig != "" {
+		fmt.Printf("%s\n", i18n.T("cmd.info.manifest.networkSecurityConfig", map[string]interface{}{
+			"path": manifest.NetworkSecurityConfig,
+		}))
+	}
+	if manifest.SharedUserID != "" {
+		fmt.Printf("%s\n", i18n.T("cmd.info.manifest.sharedUserId", map[string]interface{}{
+			"id": manifest.SharedUserID,
+		}))
+	}
+	if manifest.InstallLocation != "" {
+		fmt.Printf("%s\n", i18n.T("cmd.info.manifest.installLocation", map[string]interface{}{
+			"location": manifest.InstallLocation,
+		}))
+	}
+}
+
+// printLibraries lists the SDKs detected in an APK with their categories
+func printLibraries(libraries []models.Library) {
+	fmt.Printf("%s\n\n", i18n.T("cmd.info.libraries.title", map[string]interface{}{
+		"libraries": len(libraries),
+	}))
+	for _, library := range libraries {
+		categories := make([]string, len(library.Categories))
+		for i, category := range library.Categories {
+			categories[i] = i18n.T("cmd.info.libraries.category." + category)
+		}
+		if len(categories) > 0 {
+			fmt.Printf("  • %s (%s)\n", library.Name, strings.Join(categories, ", "))
+		} else {
+			fmt.Printf("  • %s\n", library.Name)
+		}
+	}
+}
+
 // groupPermissions groups permissions by category for better display
 func groupPermissions(permissions []string) map[string][]string {
 	groups := make(map[string][]string)
@@ -297,8 +458,13 @@ func groupPermissions(permissions []string) map[string][]string {
 	return groups
 }
 
-// categorizePermission categorizes a permission for display
+// categorizePermission categorizes a permission for display: platform
+// permissions by the group of the permission table, others by their name
 func categorizePermission(permission string) string {
+	if info, ok := apk.LookupPermission(permission); ok {
+		return i18n.T("cmd.info.perm." + info.Group)
+	}
+
 	perm := strings.ToLower(permission)
 
 	if strings.Contains(perm, "camera") {
@@ -326,6 +492,32 @@ func categorizePermission(permission string) string {
 	}
 }
 
+// permissionNotes describes the protection level of a platform permission
+// above normal and its deprecation
+func permissionNotes(permission string) string {
+	info, ok := apk.LookupPermission(permission)
+	if !ok {
+		return ""
+	}
+
+	var notes []string
+	if info.ProtectionLevel != apk.ProtectionNormal {
+		notes = append(notes, i18n.T("cmd.info.protection."+string(info.ProtectionLevel)))
+	}
+	if info.Deprecated > 0 {
+		notes = append(notes, i18n.T("cmd.info.permDeprecated", map[string]interface{}{"api": info.Deprecated}))
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(notes, ", ") + "]"
+}
+
+// riskLevelName returns the localized risk level of a permission risk score
+func riskLevelName(score int) string {
+	return i18n.T("cmd.info.riskLevel." + apk.RiskLevel(score))
+}
+
 // min returns the minimum of two integers
 func min(a, b int) int {
 	if a < b {
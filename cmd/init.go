@@ -198,7 +198,7 @@ func interactiveInit(configPath string, exists bool) error {
 		Scanning: models.ScanningConfig{
 			Recursive:      recursive,
 			FollowSymlinks: false,
-			IncludePattern: []string{"*.apk", "*.xapk", "*.apkm"},
+			IncludePattern: []string{"*.apk", "*.xapk", "*.apkm", "*.aab", "*.apks"},
 			ExcludePattern: []string{},
 			ParseAPKInfo:   parseInfo,
 		},
@@ -325,7 +325,7 @@ func validateAndFixConfig(cfg *models.Config) error {
 
 	// Validate scanning settings
 	if len(cfg.Scanning.IncludePattern) == 0 {
-		cfg.Scanning.IncludePattern = []string{"*.apk", "*.xapk", "*.apkm"}
+		cfg.Scanning.IncludePattern = []string{"*.apk", "*.xapk", "*.apkm", "*.aab", "*.apks"}
 	}
 
 	return nil
@@ -368,6 +368,29 @@ repository:
   # - "reject": Reject APKs with different signatures
   signature_handling: "mark"
 
+  # Ed25519 private key (PEM) used to sign the manifest (optional)
+  # When set, apkhub_manifest.json.sig is published next to the manifest
+  # Manage keys with "apkhub repo key"; $APKHUB_SIGNING_KEY overrides the file
+  signing_key: ""
+
+  # Days after which clients refuse the published manifest (0 = never expires)
+  manifest_expiry_days: 0
+
+  # Compressed manifest copies to publish ("gzip", "zstd")
+  manifest_compression: []
+
+  # Manifest generations to keep deltas for (0 = none)
+  manifest_deltas: 0
+
+  # Publish a root index plus one file per package under index/
+  sharded_index: false
+
+  # Shape of adaptive icons ("circle", "squircle", "rounded-square", "none")
+  icon_mask: "circle"
+
+  # Extra icon sizes written to infos/<package>_<size>.png
+  icon_sizes: [48, 96, 192, 512]
+
 scanning:
   # Scan directories recursively
   recursive: true
@@ -380,6 +403,8 @@ scanning:
     - "*.apk"
     - "*.xapk"
     - "*.apkm"
+    - "*.aab"
+    - "*.apks"
 
   # Exclude patterns (glob)
   exclude_pattern:
@@ -413,6 +438,7 @@ repository:
   base_url: ""
   keep_versions: 3
   signature_handling: "mark"
+  signing_key: ""
 
 scanning:
   recursive: true
@@ -421,6 +447,8 @@ scanning:
     - "*.apk"
     - "*.xapk"
     - "*.apkm"
+    - "*.aab"
+    - "*.apks"
   exclude_pattern:
     - "*.tmp"
     - "*.bak"
@@ -452,6 +480,8 @@ scanning:
     - "*.apk"
     - "*.xapk"
     - "*.apkm"
+    - "*.aab"
+    - "*.apks"
   exclude_pattern:
     - "*.tmp"
     - "*.debug"
@@ -482,6 +512,8 @@ scanning:
     - "*.apk"
     - "*.xapk"
     - "*.apkm"
+    - "*.aab"
+    - "*.apks"
   exclude_pattern:
     - "*.tmp"
     - "*.bak"
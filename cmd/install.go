@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/huanfeng/apkhub/internal/device"
 	"github.com/huanfeng/apkhub/internal/errors"
@@ -18,15 +19,18 @@ import (
 )
 
 var (
-	installDevice     string
-	installDeviceIDs  []string
-	installAllDevices bool
-	installReplace    bool
-	installDowngrade  bool
-	installGrant      bool
-	installLocalPath  string
-	installCheckDeps  bool
-	installWorkers    int
+	installDevice      string
+	installDeviceIDs   []string
+	installAllDevices  bool
+	installReplace     bool
+	installDowngrade   bool
+	installGrant       bool
+	installLocalPath   string
+	installCheckDeps   bool
+	installCheckSigner bool
+	installWorkers     int
+	installTimeout     time.Duration
+	installDryRun      bool
 )
 
 var installCmd = &cobra.Command{
@@ -266,7 +270,7 @@ func showLocalAPKDetails(apkPath string) error {
 		"target": apkInfo.TargetSDK,
 	}))
 
-	if appName := getDefaultName(apkInfo.AppName); appName != "" {
+	if appName := localizedName(apkInfo.AppName); appName != "" {
 		fmt.Printf("%s\n", i18n.T("cmd.install.local.appName", map[string]interface{}{
 			"name": appName,
 		}))
@@ -319,14 +323,18 @@ func performUnifiedInstall(config *client.Config, apkPath, target string, isLoca
 		}))
 	}
 
-	if err := performMultiDeviceInstall(adbMgr, deviceIDs, apkPath, target, isLocalFile); err != nil {
+	// The installed app's signer is checked unless signature checks are turned off;
+	// --check-signer forces it on regardless
+	checkSigner := config.Security.VerifySignature || installCheckSigner
+
+	if err := performMultiDeviceInstall(adbMgr, deviceIDs, apkPath, target, isLocalFile, config.Security.SignaturePolicy, checkSigner); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func performMultiDeviceInstall(adbMgr *client.ADBManager, deviceIDs []string, apkPath, target string, isLocalFile bool) error {
+func performMultiDeviceInstall(adbMgr *client.ADBManager, deviceIDs []string, apkPath, target string, isLocalFile bool, signaturePolicy string, checkSigner bool) error {
 	fmt.Printf("%s\n", i18n.T("cmd.install.targetDevices", map[string]interface{}{
 		"count": len(deviceIDs),
 	}))
@@ -336,6 +344,13 @@ func performMultiDeviceInstall(adbMgr *client.ADBManager, deviceIDs []string, ap
 		options = append(options, device.WithWorkerLimit[*client.InstallResult](installWorkers))
 	}
 
+	// Check the file, and every split of a bundle, once before anything is pushed
+	if err := validateAPKIntegrity(apkPath); err != nil {
+		return fmt.Errorf(i18n.T("cmd.install.preChecks.integrity", map[string]interface{}{
+			"error": err,
+		}))
+	}
+
 	manager := device.NewManager[*client.InstallResult](options...)
 	results := manager.Run(context.Background(), deviceIDs, func(ctx context.Context, deviceID string) (*client.InstallResult, error) {
 		fmt.Printf("\n%s\n", i18n.T("cmd.install.prepareDevice", map[string]interface{}{
@@ -345,16 +360,21 @@ func performMultiDeviceInstall(adbMgr *client.ADBManager, deviceIDs []string, ap
 			return nil, err
 		}
 
-		if err := performPreInstallChecks(adbMgr, apkPath, deviceID); err != nil {
+		if err := performPreInstallChecks(adbMgr, apkPath, deviceID, signaturePolicy, checkSigner); err != nil {
 			return nil, fmt.Errorf(i18n.T("cmd.install.errPreChecks", map[string]interface{}{
 				"error": err,
 			}))
 		}
 
+		if installDryRun {
+			return planInstallation(adbMgr, apkPath, deviceID)
+		}
+
 		installOptions := client.InstallOptions{
 			Replace:          installReplace,
 			Downgrade:        installDowngrade,
 			GrantPermissions: installGrant,
+			Timeout:          installTimeout,
 		}
 
 		fmt.Printf("%s\n", i18n.T("cmd.install.installing", map[string]interface{}{
@@ -448,6 +468,73 @@ func summarizeInstallResults(results []device.Result[*client.InstallResult]) err
 	return nil
 }
 
+// planInstallation shows what installing on a device would push, without installing
+func planInstallation(adbMgr *client.ADBManager, apkPath, deviceID string) (*client.InstallResult, error) {
+	plan, planErr := adbMgr.PlanInstall(apkPath, deviceID)
+	if plan == nil {
+		return nil, planErr
+	}
+
+	unknown := i18n.T("cmd.install.dryRun.unknown")
+	abis, density, locales := unknown, unknown, unknown
+	if len(plan.Device.ABIs) > 0 {
+		abis = strings.Join(plan.Device.ABIs, ", ")
+	}
+	if plan.Device.Density > 0 {
+		density = fmt.Sprintf("%ddpi", plan.Device.Density)
+	}
+	if len(plan.Device.Locales) > 0 {
+		locales = strings.Join(plan.Device.Locales, ", ")
+	}
+
+	fmt.Printf("%s\n", i18n.T("cmd.install.dryRun.title", map[string]interface{}{
+		"id": deviceID,
+	}))
+	fmt.Printf("%s\n", i18n.T("cmd.install.dryRun.device", map[string]interface{}{
+		"abis":    abis,
+		"density": density,
+		"locales": locales,
+	}))
+	if plan.PackageID != "" {
+		fmt.Printf("%s\n", i18n.T("cmd.install.dryRun.package", map[string]interface{}{
+			"id": plan.PackageID,
+		}))
+	}
+
+	selected := 0
+	for _, split := range plan.Splits {
+		key := "cmd.install.dryRun.skip"
+		if split.Selected {
+			key = "cmd.install.dryRun.push"
+			selected++
+		}
+		fmt.Printf("%s\n", i18n.T(key, map[string]interface{}{
+			"file":   filepath.Base(split.Path),
+			"reason": split.Reason,
+		}))
+	}
+	for _, obb := range plan.OBBFiles {
+		fmt.Printf("%s\n", i18n.T("cmd.install.dryRun.obb", map[string]interface{}{
+			"path": obb,
+		}))
+	}
+	fmt.Printf("%s\n", i18n.T("cmd.install.dryRun.summary", map[string]interface{}{
+		"selected": selected,
+		"total":    len(plan.Splits),
+	}))
+
+	result := &client.InstallResult{
+		DeviceID:  deviceID,
+		PackageID: plan.PackageID,
+		Success:   planErr == nil,
+	}
+	if planErr != nil {
+		result.ErrorMessage = planErr.Error()
+	}
+
+	return result, nil
+}
+
 // validateSpecifiedDevice validates that the specified device is available and online
 func validateSpecifiedDevice(adbMgr *client.ADBManager, deviceID string) error {
 	fmt.Printf("%s\n", i18n.T("cmd.install.validateDevice", map[string]interface{}{
@@ -475,16 +562,9 @@ func validateSpecifiedDevice(adbMgr *client.ADBManager, deviceID string) error {
 }
 
 // performPreInstallChecks performs various checks before installation
-func performPreInstallChecks(adbMgr *client.ADBManager, apkPath, deviceID string) error {
+func performPreInstallChecks(adbMgr *client.ADBManager, apkPath, deviceID, signaturePolicy string, checkSigner bool) error {
 	fmt.Println(i18n.T("cmd.install.preChecks.start"))
 
-	// Check APK file integrity
-	if err := validateAPKIntegrity(apkPath); err != nil {
-		return fmt.Errorf(i18n.T("cmd.install.preChecks.integrity", map[string]interface{}{
-			"error": err,
-		}))
-	}
-
 	// Check device storage space (if possible)
 	if err := checkDeviceStorage(adbMgr, apkPath, deviceID); err != nil {
 		fmt.Printf("%s\n", i18n.T("cmd.install.preChecks.storageWarn", map[string]interface{}{
@@ -494,13 +574,22 @@ func performPreInstallChecks(adbMgr *client.ADBManager, apkPath, deviceID string
 	}
 
 	// Check for existing installation
-	if err := checkExistingInstallation(adbMgr, apkPath, deviceID); err != nil {
+	apkInfo, installed, err := checkExistingInstallation(adbMgr, apkPath, deviceID)
+	if err != nil {
 		fmt.Printf("%s\n", i18n.T("cmd.install.preChecks.existingInfo", map[string]interface{}{
 			"error": err,
 		}))
 		// Don't fail, just inform
 	}
 
+	// Make sure an upgrade keeps the installed app's signer: a change is refused
+	// under the strict policy and reported under the lenient one
+	if installed && checkSigner {
+		if err := checkInstalledSigner(adbMgr, apkInfo, deviceID, signaturePolicy); err != nil {
+			return err
+		}
+	}
+
 	fmt.Println(i18n.T("cmd.install.preChecks.done"))
 	return nil
 }
@@ -529,6 +618,74 @@ func validateAPKIntegrity(apkPath string) error {
 		return fmt.Errorf(i18n.T("cmd.install.errInvalidAPK"))
 	}
 
+	// App bundles are rejected when installing
+	if apk.IsAppBundleFile(apkPath) {
+		return nil
+	}
+
+	// Bundles are not signed themselves: every APK inside is verified
+	if apk.IsXAPKFile(apkPath) || apk.IsAPKSetFile(apkPath) {
+		return validateBundleSignatures(apkPath)
+	}
+
+	// Verify APK signatures so tampered files never reach a device
+	result, err := apk.VerifySignatures(apkPath)
+	if err != nil {
+		return fmt.Errorf(i18n.T("cmd.install.errSignatureCheck", map[string]interface{}{
+			"error": err,
+		}))
+	}
+
+	if len(result.Schemes) == 0 {
+		return fmt.Errorf(i18n.T("cmd.install.errUnsigned"))
+	}
+
+	if !result.Verified {
+		return fmt.Errorf(i18n.T("cmd.install.errSignatureInvalid", map[string]interface{}{
+			"details": describeSchemeFailures(result),
+		}))
+	}
+
+	return nil
+}
+
+// validateBundleSignatures verifies every split of an XAPK/APKM bundle or APK set
+// and makes sure they all share the signer of the base APK, as the device would
+// refuse a session mixing signers only after the splits were pushed
+func validateBundleSignatures(bundlePath string) error {
+	splits, err := apk.VerifyBundleSignatures(bundlePath)
+	if err != nil {
+		return fmt.Errorf(i18n.T("cmd.install.errSignatureCheck", map[string]interface{}{
+			"error": err,
+		}))
+	}
+
+	base := splits[0]
+	for _, split := range splits {
+		if len(split.Verification.Schemes) == 0 {
+			return fmt.Errorf(i18n.T("cmd.install.errSplitUnsigned", map[string]interface{}{
+				"name": split.Name,
+			}))
+		}
+
+		if !split.Verification.Verified {
+			return fmt.Errorf(i18n.T("cmd.install.errSplitSignatureInvalid", map[string]interface{}{
+				"name":    split.Name,
+				"details": describeSchemeFailures(split.Verification),
+			}))
+		}
+
+		if split.Signer == nil || base.Signer == nil || !strings.EqualFold(split.Signer.SHA256, base.Signer.SHA256) {
+			return fmt.Errorf(i18n.T("cmd.install.errSplitSigner", map[string]interface{}{
+				"name": split.Name,
+				"base": base.Name,
+			}))
+		}
+	}
+
+	fmt.Printf("%s\n", i18n.T("cmd.install.splitsVerified", map[string]interface{}{
+		"splits": len(splits),
+	}))
 	return nil
 }
 
@@ -559,19 +716,13 @@ func checkDeviceStorage(adbMgr *client.ADBManager, apkPath, deviceID string) err
 	return nil
 }
 
-// checkExistingInstallation checks if the app is already installed
-func checkExistingInstallation(adbMgr *client.ADBManager, apkPath, deviceID string) error {
-	// For XAPK files, skip existing installation check to avoid duplicate parsing
-	if isXAPKFile(apkPath) {
-		fmt.Printf("%s\n", i18n.T("cmd.install.preChecks.xapkSkip"))
-		return nil
-	}
-
+// checkExistingInstallation checks if the app is already installed. It returns the
+// parsed APK and whether a version of it is installed on the device.
+func checkExistingInstallation(adbMgr *client.ADBManager, apkPath, deviceID string) (*apk.APKInfo, bool, error) {
 	// Try to extract package ID from APK
-	parser := apk.NewParser(".")
-	apkInfo, err := parser.ParseAPK(apkPath)
+	apkInfo, err := parseInstallTarget(apkPath)
 	if err != nil {
-		return fmt.Errorf(i18n.T("cmd.install.preChecks.packageInfo", map[string]interface{}{
+		return nil, false, fmt.Errorf(i18n.T("cmd.install.preChecks.packageInfo", map[string]interface{}{
 			"error": err,
 		}))
 	}
@@ -580,7 +731,7 @@ func checkExistingInstallation(adbMgr *client.ADBManager, apkPath, deviceID stri
 	versionName, versionCode, err := adbMgr.GetInstalledVersion(apkInfo.PackageID, deviceID)
 	if err != nil {
 		// Package not installed, which is fine
-		return nil
+		return apkInfo, false, nil
 	}
 
 	fmt.Printf("%s\n", i18n.T("cmd.install.preChecks.installed", map[string]interface{}{
@@ -603,6 +754,74 @@ func checkExistingInstallation(adbMgr *client.ADBManager, apkPath, deviceID stri
 		fmt.Printf("%s\n", i18n.T("cmd.install.preChecks.reinstall"))
 	}
 
+	return apkInfo, true, nil
+}
+
+// parseInstallTarget parses an APK, or the base APK of an XAPK/APKM bundle. A
+// bundle carries the signer of its base split, which the integrity check has
+// already matched against every other split.
+func parseInstallTarget(apkPath string) (*apk.APKInfo, error) {
+	if !isXAPKFile(apkPath) {
+		return apk.NewParser(".").ParseAPK(apkPath)
+	}
+
+	xapkInfo, err := apk.NewXAPKParser(".").ParseXAPKQuiet(apkPath)
+	if err != nil {
+		return nil, err
+	}
+	if xapkInfo.APKInfo == nil {
+		return nil, fmt.Errorf("no base APK found in %s", filepath.Base(apkPath))
+	}
+
+	apkInfo := xapkInfo.APKInfo
+	if splits, err := apk.VerifyBundleSignatures(apkPath); err == nil && len(splits) > 0 {
+		apkInfo.SignatureInfo = splits[0].Signer
+		apkInfo.SignatureVerification = splits[0].Verification
+	}
+
+	return apkInfo, nil
+}
+
+// checkInstalledSigner compares the APK's signer with the installed app's signer.
+// A change without a v3 rotation proof is refused under the strict policy.
+func checkInstalledSigner(adbMgr *client.ADBManager, apkInfo *apk.APKInfo, deviceID, signaturePolicy string) error {
+	if apkInfo == nil || apkInfo.SignatureInfo == nil || apkInfo.SignatureInfo.SHA256 == "" {
+		return nil
+	}
+
+	installedSigner, err := adbMgr.GetInstalledSigner(apkInfo.PackageID, deviceID)
+	if err != nil {
+		fmt.Printf("%s\n", i18n.T("cmd.install.preChecks.signerUnknown", map[string]interface{}{
+			"error": err,
+		}))
+		return nil
+	}
+
+	var lineage []string
+	if apkInfo.SignatureVerification != nil {
+		lineage = apkInfo.SignatureVerification.Lineage
+	}
+
+	if err := apk.CheckSignerContinuity(installedSigner, apkInfo.SignatureInfo.SHA256, lineage); err != nil {
+		message := i18n.T("cmd.install.preChecks.signerChanged", map[string]interface{}{
+			"installed": installedSigner,
+			"apk":       apkInfo.SignatureInfo.SHA256,
+		})
+		if strings.ToLower(signaturePolicy) == "strict" {
+			return fmt.Errorf("%s", message)
+		}
+		fmt.Printf("%s\n", i18n.T("cmd.install.preChecks.signerChangedWarn", map[string]interface{}{
+			"message": message,
+		}))
+		return nil
+	}
+
+	if !strings.EqualFold(installedSigner, apkInfo.SignatureInfo.SHA256) {
+		fmt.Printf("%s\n", i18n.T("cmd.install.preChecks.signerRotated"))
+	} else {
+		fmt.Printf("%s\n", i18n.T("cmd.install.preChecks.signerMatch"))
+	}
+
 	return nil
 }
 
@@ -714,6 +933,9 @@ func init() {
 	installCmd.Flags().StringVarP(&downloadVersion, "version", "v", "", i18n.T("cmd.install.flag.version"))
 	installCmd.Flags().StringVarP(&installLocalPath, "local", "l", "", i18n.T("cmd.install.flag.local"))
 	installCmd.Flags().BoolVar(&installCheckDeps, "check-deps", false, i18n.T("cmd.install.flag.checkDeps"))
+	installCmd.Flags().BoolVar(&installCheckSigner, "check-signer", false, i18n.T("cmd.install.flag.checkSigner"))
+	installCmd.Flags().BoolVar(&installDryRun, "dry-run", false, i18n.T("cmd.install.flag.dryRun"))
+	installCmd.Flags().DurationVar(&installTimeout, "timeout", client.DefaultInstallTimeout, i18n.T("cmd.install.flag.timeout"))
 }
 
 // isXAPKFile checks if the file is an XAPK or APKM file
@@ -0,0 +1,430 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/huanfeng/apkhub/internal/config"
+	"github.com/huanfeng/apkhub/internal/i18n"
+	"github.com/huanfeng/apkhub/pkg/models"
+	"github.com/huanfeng/apkhub/pkg/repo"
+	"github.com/huanfeng/apkhub/pkg/signing"
+	"github.com/spf13/cobra"
+)
+
+const defaultSigningKeyPath = "keys/manifest-signing.pem"
+
+var (
+	keyOutput       string
+	keyNoPassphrase bool
+	keyForce        bool
+	keyExportFormat string
+	keyRotateFinish bool
+)
+
+var keyCmd = &cobra.Command{
+	Use:   "key",
+	Short: i18n.T("cmd.repoKey.short"),
+	Long:  i18n.T("cmd.repoKey.long"),
+}
+
+var keyGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: i18n.T("cmd.repoKey.generate.short"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, repository, err := loadKeyRepository()
+		if err != nil {
+			return err
+		}
+
+		if cfg.Repository.SigningKey != "" && !keyForce {
+			if _, err := os.Stat(repository.ResolveKeyPath(cfg.Repository.SigningKey)); err == nil {
+				return fmt.Errorf(i18n.T("cmd.repoKey.generate.exists", map[string]interface{}{
+					"path": cfg.Repository.SigningKey,
+				}))
+			}
+		}
+
+		keyPath := keyOutput
+		if keyPath == "" {
+			keyPath = defaultSigningKeyPath
+		}
+
+		// Never replace a private key file without --force, configured or not
+		if _, err := os.Stat(repository.ResolveKeyPath(keyPath)); err == nil && !keyForce {
+			return fmt.Errorf(i18n.T("cmd.repoKey.generate.exists", map[string]interface{}{
+				"path": keyPath,
+			}))
+		}
+
+		pub, err := createSigningKey(repository.ResolveKeyPath(keyPath))
+		if err != nil {
+			return err
+		}
+
+		cfg.Repository.SigningKey = keyPath
+		cfg.Repository.SigningKeyFingerprint = signing.Fingerprint(pub)
+		fmt.Printf("%s\n", i18n.T("cmd.repoKey.generate.success", map[string]interface{}{
+			"path": keyPath,
+		}))
+		printPublicKey(pub)
+
+		if err := saveKeyConfig(cfg); err != nil {
+			return err
+		}
+
+		return resignManifest(cfg)
+	},
+}
+
+var keyShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: i18n.T("cmd.repoKey.show.short"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, repository, err := loadKeyRepository()
+		if err != nil {
+			return err
+		}
+
+		pub, err := loadSigningPublicKey(cfg, repository)
+		if err != nil {
+			return err
+		}
+
+		source := cfg.Repository.SigningKey
+		if os.Getenv(signing.EnvSigningKey) != "" {
+			source = "$" + signing.EnvSigningKey
+		}
+		fmt.Printf("%s\n", i18n.T("cmd.repoKey.show.path", map[string]interface{}{
+			"path": source,
+		}))
+		fmt.Printf("%s\n", i18n.T("cmd.repoKey.show.algorithm", map[string]interface{}{
+			"algorithm": signing.AlgorithmEd25519,
+		}))
+		printPublicKey(pub)
+
+		if len(cfg.Repository.PreviousSigningKeys) > 0 {
+			fmt.Printf("\n%s\n", i18n.T("cmd.repoKey.show.rotating"))
+			for _, previousPath := range cfg.Repository.PreviousSigningKeys {
+				line := previousPath
+				if previous, err := readPublicKeyFile(repository.ResolveKeyPath(previousPath)); err == nil {
+					line = fmt.Sprintf("%s (%s)", previousPath, signing.Fingerprint(previous))
+				}
+				fmt.Printf("  - %s\n", line)
+			}
+		}
+
+		return nil
+	},
+}
+
+var keyExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: i18n.T("cmd.repoKey.export.short"),
+	Long:  i18n.T("cmd.repoKey.export.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, repository, err := loadKeyRepository()
+		if err != nil {
+			return err
+		}
+
+		pub, err := loadSigningPublicKey(cfg, repository)
+		if err != nil {
+			return err
+		}
+
+		var output []byte
+		switch keyExportFormat {
+		case "base64":
+			output = []byte(signing.EncodePublicKey(pub) + "\n")
+		case "pem":
+			output, err = signing.MarshalPublicKeyPEM(pub)
+			if err != nil {
+				return err
+			}
+		case "yaml":
+			output = []byte(fmt.Sprintf("security:\n  verify_signature: true\n  trusted_keys:\n    - %q # %s\n",
+				signing.EncodePublicKey(pub), signing.Fingerprint(pub)))
+		default:
+			return fmt.Errorf(i18n.T("cmd.repoKey.export.errFormat", map[string]interface{}{
+				"format": keyExportFormat,
+			}))
+		}
+
+		if keyOutput == "" {
+			fmt.Print(string(output))
+			return nil
+		}
+
+		if err := os.WriteFile(keyOutput, output, 0644); err != nil {
+			return fmt.Errorf("%s: %w", i18n.T("cmd.repoKey.errWrite"), err)
+		}
+		fmt.Printf("%s\n", i18n.T("cmd.repoKey.export.saved", map[string]interface{}{
+			"path": keyOutput,
+		}))
+		return nil
+	},
+}
+
+var keyRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: i18n.T("cmd.repoKey.rotate.short"),
+	Long:  i18n.T("cmd.repoKey.rotate.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, repository, err := loadKeyRepository()
+		if err != nil {
+			return err
+		}
+
+		if cfg.Repository.SigningKey == "" {
+			return fmt.Errorf(i18n.T("cmd.repoKey.errNoKey"))
+		}
+
+		if keyRotateFinish {
+			if len(cfg.Repository.PreviousSigningKeys) == 0 {
+				fmt.Println(i18n.T("cmd.repoKey.rotate.nothingToFinish"))
+				return nil
+			}
+
+			cfg.Repository.PreviousSigningKeys = []string{}
+			if err := saveKeyConfig(cfg); err != nil {
+				return err
+			}
+
+			fmt.Println(i18n.T("cmd.repoKey.rotate.finished"))
+			return resignManifest(cfg)
+		}
+
+		// The environment key keeps signing whatever the configuration says, so
+		// a rotation would silently have no effect
+		if os.Getenv(signing.EnvSigningKey) != "" {
+			return fmt.Errorf(i18n.T("cmd.repoKey.rotate.errEnvKey", map[string]interface{}{
+				"env": signing.EnvSigningKey,
+			}))
+		}
+
+		keyPath := keyOutput
+		if keyPath == "" {
+			keyPath = filepath.Join(filepath.Dir(cfg.Repository.SigningKey),
+				fmt.Sprintf("manifest-signing-%s.pem", time.Now().Format("20060102150405")))
+		}
+
+		if _, err := os.Stat(repository.ResolveKeyPath(keyPath)); err == nil {
+			return fmt.Errorf(i18n.T("cmd.repoKey.generate.exists", map[string]interface{}{
+				"path": keyPath,
+			}))
+		}
+
+		pub, err := createSigningKey(repository.ResolveKeyPath(keyPath))
+		if err != nil {
+			return err
+		}
+
+		cfg.Repository.PreviousSigningKeys = append([]string{cfg.Repository.SigningKey}, cfg.Repository.PreviousSigningKeys...)
+		cfg.Repository.SigningKey = keyPath
+		cfg.Repository.SigningKeyFingerprint = signing.Fingerprint(pub)
+		fmt.Printf("%s\n", i18n.T("cmd.repoKey.rotate.success", map[string]interface{}{
+			"path": keyPath,
+		}))
+		printPublicKey(pub)
+
+		if err := saveKeyConfig(cfg); err != nil {
+			return err
+		}
+		fmt.Printf("\n%s\n", i18n.T("cmd.repoKey.rotate.transition", map[string]interface{}{
+			"cmd": "apkhub repo key export",
+		}))
+
+		return resignManifest(cfg)
+	},
+}
+
+// loadKeyRepository loads the repository configuration used by key commands
+func loadKeyRepository() (*models.Config, *repo.Repository, error) {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", i18n.T("cmd.repoKey.errLoadConfig"), err)
+	}
+
+	repository, err := repo.NewRepository(workDir, cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", i18n.T("cmd.repoKey.errCreateRepo"), err)
+	}
+
+	return cfg, repository, nil
+}
+
+// createSigningKey generates a key pair and writes the private key and <path>.pub
+func createSigningKey(keyPath string) (ed25519.PublicKey, error) {
+	passphrase, err := readNewPassphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	pub, priv, err := signing.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", i18n.T("cmd.repoKey.errGenerate"), err)
+	}
+
+	var privPEM []byte
+	if passphrase != nil {
+		privPEM, err = signing.EncryptPrivateKeyPEM(priv, passphrase)
+	} else {
+		privPEM, err = signing.MarshalPrivateKeyPEM(priv)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pubPEM, err := signing.MarshalPublicKeyPEM(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		return nil, fmt.Errorf("%s: %w", i18n.T("cmd.repoKey.errWrite"), err)
+	}
+	if err := os.WriteFile(keyPath, privPEM, 0600); err != nil {
+		return nil, fmt.Errorf("%s: %w", i18n.T("cmd.repoKey.errWrite"), err)
+	}
+	if err := os.WriteFile(keyPath+".pub", pubPEM, 0644); err != nil {
+		return nil, fmt.Errorf("%s: %w", i18n.T("cmd.repoKey.errWrite"), err)
+	}
+
+	if passphrase == nil {
+		fmt.Printf("%s\n", i18n.T("cmd.repoKey.unencryptedWarn"))
+	}
+
+	return pub, nil
+}
+
+// readNewPassphrase asks for a new passphrase, returning nil for an unencrypted key
+func readNewPassphrase() ([]byte, error) {
+	if keyNoPassphrase {
+		return nil, nil
+	}
+
+	if env := os.Getenv(signing.EnvSigningKeyPassphrase); env != "" {
+		return []byte(env), nil
+	}
+
+	passphrase, err := signing.PromptPassphrase(i18n.T("cmd.repoKey.promptPassphrase"))
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf(i18n.T("cmd.repoKey.errEmptyPassphrase"))
+	}
+
+	confirm, err := signing.PromptPassphrase(i18n.T("cmd.repoKey.promptConfirm"))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, confirm) {
+		return nil, fmt.Errorf(i18n.T("cmd.repoKey.errPassphraseMismatch"))
+	}
+
+	return passphrase, nil
+}
+
+// loadSigningPublicKey returns the public key of the current signing key,
+// preferring the .pub file so no passphrase is needed
+func loadSigningPublicKey(cfg *models.Config, repository *repo.Repository) (ed25519.PublicKey, error) {
+	if os.Getenv(signing.EnvSigningKey) == "" {
+		if cfg.Repository.SigningKey == "" {
+			return nil, fmt.Errorf(i18n.T("cmd.repoKey.errNoKey"))
+		}
+		if pub, err := readPublicKeyFile(repository.ResolveKeyPath(cfg.Repository.SigningKey)); err == nil {
+			return pub, nil
+		}
+	}
+
+	priv, err := signing.LoadSigningKey(repository.ResolveKeyPath(cfg.Repository.SigningKey), signing.PromptPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return priv.Public().(ed25519.PublicKey), nil
+}
+
+// readPublicKeyFile reads the <key>.pub file written next to a private key
+func readPublicKeyFile(keyPath string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(keyPath + ".pub")
+	if err != nil {
+		return nil, err
+	}
+	return signing.ParsePublicKey(string(data))
+}
+
+// printPublicKey prints the fingerprint and base64 public key
+func printPublicKey(pub ed25519.PublicKey) {
+	fmt.Printf("%s\n", i18n.T("cmd.repoKey.fingerprint", map[string]interface{}{
+		"fingerprint": signing.Fingerprint(pub),
+	}))
+	fmt.Printf("%s\n", i18n.T("cmd.repoKey.publicKey", map[string]interface{}{
+		"key": signing.EncodePublicKey(pub),
+	}))
+}
+
+// saveKeyConfig persists signing key settings to the repository configuration file
+func saveKeyConfig(cfg *models.Config) error {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.ConfigFileUsed()
+	}
+	if configPath == "" {
+		configPath = filepath.Join(workDir, "apkhub.yaml")
+	}
+
+	if err := config.SaveConfig(cfg, configPath); err != nil {
+		return fmt.Errorf("%s: %w", i18n.T("cmd.repoKey.errSaveConfig"), err)
+	}
+
+	fmt.Printf("%s\n", i18n.T("cmd.repoKey.configUpdated", map[string]interface{}{
+		"path": configPath,
+	}))
+	return nil
+}
+
+// resignManifest regenerates the manifest so its detached signature matches the new keys
+func resignManifest(cfg *models.Config) error {
+	repository, err := repo.NewRepository(workDir, cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", i18n.T("cmd.repoKey.errCreateRepo"), err)
+	}
+
+	manifestPath := filepath.Join(repository.GetRootDir(), "apkhub_manifest.json")
+	if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	if err := repository.UpdateManifest(); err != nil {
+		return fmt.Errorf("%s: %w", i18n.T("cmd.repoKey.errResign"), err)
+	}
+
+	fmt.Println(i18n.T("cmd.repoKey.resigned"))
+	return nil
+}
+
+func init() {
+	repoCmd.AddCommand(keyCmd)
+	keyCmd.AddCommand(keyGenerateCmd)
+	keyCmd.AddCommand(keyShowCmd)
+	keyCmd.AddCommand(keyExportCmd)
+	keyCmd.AddCommand(keyRotateCmd)
+
+	keyGenerateCmd.Flags().StringVarP(&keyOutput, "output", "o", "", i18n.T("cmd.repoKey.flag.output"))
+	keyGenerateCmd.Flags().BoolVar(&keyNoPassphrase, "no-passphrase", false, i18n.T("cmd.repoKey.flag.noPassphrase"))
+	keyGenerateCmd.Flags().BoolVarP(&keyForce, "force", "f", false, i18n.T("cmd.repoKey.flag.force"))
+
+	keyExportCmd.Flags().StringVar(&keyExportFormat, "format", "base64", i18n.T("cmd.repoKey.flag.format"))
+	keyExportCmd.Flags().StringVarP(&keyOutput, "output", "o", "", i18n.T("cmd.repoKey.flag.exportOutput"))
+
+	keyRotateCmd.Flags().StringVarP(&keyOutput, "output", "o", "", i18n.T("cmd.repoKey.flag.output"))
+	keyRotateCmd.Flags().BoolVar(&keyNoPassphrase, "no-passphrase", false, i18n.T("cmd.repoKey.flag.noPassphrase"))
+	keyRotateCmd.Flags().BoolVar(&keyRotateFinish, "finish", false, i18n.T("cmd.repoKey.flag.finish"))
+}
@@ -74,7 +74,7 @@ func listSinglePackage(manifest *models.ManifestIndex, packageID string) error {
 
 	fmt.Printf("%s\n", i18n.T("cmd.list.packageDetailsTitle"))
 	fmt.Printf("%s\n", i18n.T("cmd.list.packageID", map[string]interface{}{"id": packageID}))
-	fmt.Printf("%s\n", i18n.T("cmd.list.packageName", map[string]interface{}{"name": getDefaultName(pkg.Name)}))
+	fmt.Printf("%s\n", i18n.T("cmd.list.packageName", map[string]interface{}{"name": localizedName(pkg.Name)}))
 	fmt.Printf("%s\n", i18n.T("cmd.list.packageVersionCount", map[string]interface{}{"count": len(pkg.Versions)}))
 	fmt.Printf("%s\n\n", i18n.T("cmd.list.packageLatest", map[string]interface{}{"version": pkg.Latest}))
 
@@ -199,7 +199,7 @@ func listAllPackages(manifest *models.ManifestIndex) error {
 
 	// Display packages
 	for _, info := range packages {
-		name := getDefaultName(info.Package.Name)
+		name := localizedName(info.Package.Name)
 		if len(name) > 18 {
 			name = name[:15] + "..."
 		}
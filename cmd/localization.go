@@ -79,6 +79,8 @@ func applyCommandLocalization() {
 	importCmd.Long = i18n.T("cmd.import.long")
 	infoCmd.Short = i18n.T("cmd.info.short")
 	infoCmd.Long = i18n.T("cmd.info.long")
+	keyCmd.Short = i18n.T("cmd.repoKey.short")
+	keyCmd.Long = i18n.T("cmd.repoKey.long")
 	parseCmd.Short = i18n.T("cmd.parse.short")
 	parseCmd.Long = i18n.T("cmd.parse.long")
 	parserInfoCmd.Short = i18n.T("cmd.parserInfo.short")
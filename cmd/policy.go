@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/huanfeng/apkhub/internal/i18n"
+	"github.com/huanfeng/apkhub/pkg/models"
+	"github.com/huanfeng/apkhub/pkg/repo"
+)
+
+// loadAdmissionPolicy loads the policy configured for the repository, or nil
+// if there is none
+func loadAdmissionPolicy(repository *repo.Repository) (*models.Policy, error) {
+	policy, err := repository.LoadPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", i18n.T("cmd.policy.errLoad"), err)
+	}
+	return policy, nil
+}
+
+// policyViolationText describes a broken policy rule
+func policyViolationText(violation models.PolicyViolation) string {
+	if violation.Unknown {
+		if violation.Check == models.PolicyCheckAllowedSigners {
+			return i18n.T("cmd.policy.check.unsigned")
+		}
+		return i18n.T("cmd.policy.check.unknown")
+	}
+	return i18n.T("cmd.policy.check."+violation.Check, map[string]interface{}{
+		"found": violation.Found, "limit": violation.Limit,
+	})
+}
+
+// policyActionName returns the translated name of a policy action
+func policyActionName(action models.PolicyAction) string {
+	return i18n.T("cmd.policy.action." + string(action))
+}
+
+// printPolicyViolations lists the rules an APK breaks
+func printPolicyViolations(violations []models.PolicyViolation, indent string) {
+	if len(violations) == 0 {
+		return
+	}
+
+	fmt.Printf("%s%s\n", indent, i18n.T("cmd.policy.violations"))
+	for _, violation := range violations {
+		icon := "❌"
+		if violation.Action == models.PolicyWarn {
+			icon = "⚠️ "
+		}
+		fmt.Printf("%s  %s\n", indent, i18n.T("cmd.policy.item", map[string]interface{}{
+			"icon":   icon,
+			"rule":   violation.Rule,
+			"desc":   policyViolationText(violation),
+			"action": policyActionName(violation.Action),
+		}))
+	}
+}
+
+// quarantineAPK puts an APK into the quarantine directory instead of the
+// repository and records the rules it breaks. The APK is moved rather than
+// copied if move is set. Entries without a local file, such as imported ones,
+// only get the record. Returns the path of the quarantined file or record.
+func quarantineAPK(repository *repo.Repository, srcPath string, apkInfo *models.APKInfo, violations []models.PolicyViolation, move bool) (string, error) {
+	if srcPath != "" {
+		targetPath := repository.GetQuarantinePath(apkInfo.FileName)
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+			return "", fmt.Errorf("%s: %w", i18n.T("cmd.policy.errQuarantine"), err)
+		}
+
+		if move {
+			if err := os.Rename(srcPath, targetPath); err != nil {
+				// If rename fails (cross-device), fall back to copy
+				if err := copyAPKFile(srcPath, targetPath); err != nil {
+					return "", fmt.Errorf("%s: %w", i18n.T("cmd.policy.errQuarantine"), err)
+				}
+				os.Remove(srcPath)
+			}
+		} else if err := copyAPKFile(srcPath, targetPath); err != nil {
+			return "", fmt.Errorf("%s: %w", i18n.T("cmd.policy.errQuarantine"), err)
+		}
+		apkInfo.FilePath = filepath.Join("quarantine", apkInfo.FileName)
+	}
+
+	recordPath, err := repository.SaveQuarantineRecord(apkInfo, violations)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", i18n.T("cmd.policy.errQuarantine"), err)
+	}
+
+	if srcPath == "" {
+		return recordPath, nil
+	}
+	return apkInfo.FilePath, nil
+}
@@ -100,15 +100,28 @@ var scanCmd = &cobra.Command{
 			}
 		}
 
+		// Load the admission policy
+		policy, err := loadAdmissionPolicy(repository)
+		if err != nil {
+			return err
+		}
+		if policy != nil {
+			fmt.Printf("%s\n", i18n.T("cmd.scan.policy", map[string]interface{}{
+				"path": cfg.Repository.Policy, "rules": len(policy.Rules),
+			}))
+		}
+
 		// Initialize progress tracking
 		progress := utils.NewProgressTracker(i18n.T("cmd.scan.progress.label"), 0, false)
 
 		// Initialize counters
 		var (
-			scannedFiles  = 0
-			newAPKs       = 0
-			updatedAPKs   = 0
-			unchangedAPKs = 0
+			scannedFiles    = 0
+			newAPKs         = 0
+			updatedAPKs     = 0
+			unchangedAPKs   = 0
+			rejectedAPKs    = 0
+			quarantinedAPKs = 0
 		)
 
 		// First pass: count total files
@@ -212,23 +225,52 @@ var scanCmd = &cobra.Command{
 
 			// Create APK info
 			modelAPKInfo := &models.APKInfo{
-				PackageID:     apkInfo.PackageID,
-				AppName:       apkInfo.AppName,
-				Version:       apkInfo.Version,
-				VersionCode:   apkInfo.VersionCode,
-				MinSDK:        apkInfo.MinSDK,
-				TargetSDK:     apkInfo.TargetSDK,
-				Size:          apkInfo.Size,
-				SHA256:        apkInfo.SHA256,
-				SignatureInfo: apkInfo.SignatureInfo,
-				Permissions:   apkInfo.Permissions,
-				Features:      apkInfo.Features,
-				ABIs:          apkInfo.ABIs,
-				AddedAt:       time.Now(),
-				UpdatedAt:     info.ModTime(),
-				OriginalName:  filename,
-				FileName:      normalizedName,
-				FilePath:      filepath.Join("apks", normalizedName),
+				PackageID:             apkInfo.PackageID,
+				AppName:               apkInfo.AppName,
+				Version:               apkInfo.Version,
+				VersionCode:           apkInfo.VersionCode,
+				MinSDK:                apkInfo.MinSDK,
+				TargetSDK:             apkInfo.TargetSDK,
+				Size:                  apkInfo.Size,
+				SHA256:                apkInfo.SHA256,
+				SignatureInfo:         apkInfo.SignatureInfo,
+				SignatureVerification: apkInfo.SignatureVerification,
+				Permissions:           apkInfo.Permissions,
+				Features:              apkInfo.Features,
+				ABIs:                  apkInfo.ABIs,
+				Manifest:              apkInfo.Manifest,
+				Libraries:             apkInfo.Libraries,
+				AddedAt:               time.Now(),
+				UpdatedAt:             info.ModTime(),
+				OriginalName:          filename,
+				FileName:              normalizedName,
+				FilePath:              filepath.Join("apks", normalizedName),
+			}
+
+			// An APK held back on an earlier run stays in quarantine
+			if repository.HasQuarantineRecord(modelAPKInfo) {
+				unchangedAPKs++
+				fmt.Printf("%s\n", i18n.T("cmd.policy.alreadyQuarantined", map[string]interface{}{"name": filename}))
+				return nil
+			}
+
+			// Check the admission policy
+			violations := repo.EvaluatePolicy(policy, modelAPKInfo)
+			printPolicyViolations(violations, "  ")
+			switch repo.PolicyDecision(violations) {
+			case models.PolicyReject:
+				rejectedAPKs++
+				fmt.Printf("  %s\n", i18n.T("cmd.scan.policyRejected", map[string]interface{}{"name": filename}))
+				return nil
+			case models.PolicyQuarantine:
+				quarantinePath, err := quarantineAPK(repository, path, modelAPKInfo, violations, false)
+				if err != nil {
+					errors = append(errors, fmt.Errorf("%s: %w", filename, err))
+					return nil
+				}
+				quarantinedAPKs++
+				fmt.Printf("  %s\n", i18n.T("cmd.policy.quarantined", map[string]interface{}{"path": quarantinePath}))
+				return nil
 			}
 
 			// If existing, preserve original added time
@@ -285,7 +327,7 @@ var scanCmd = &cobra.Command{
 		for _, err := range errors {
 			errorMessages = append(errorMessages, err.Error())
 		}
-		showScanResults(scannedFiles, newAPKs, updatedAPKs, unchangedAPKs, len(errors), errorMessages, time.Since(scanStart))
+		showScanResults(scannedFiles, newAPKs, updatedAPKs, unchangedAPKs, rejectedAPKs, quarantinedAPKs, len(errors), errorMessages, time.Since(scanStart))
 
 		return nil
 	},
@@ -442,7 +484,7 @@ func countTotalAPKFiles(dir string, recursive bool) int {
 			return nil
 		}
 
-		if strings.HasSuffix(strings.ToLower(info.Name()), ".apk") {
+		if apk.IsAPKFile(info.Name()) {
 			count++
 		}
 
@@ -453,7 +495,7 @@ func countTotalAPKFiles(dir string, recursive bool) int {
 }
 
 // showScanResults displays detailed scan results
-func showScanResults(scanned, newAPKs, updated, unchanged, errors int, errorMessages []string, duration time.Duration) {
+func showScanResults(scanned, newAPKs, updated, unchanged, rejected, quarantined, errors int, errorMessages []string, duration time.Duration) {
 	fmt.Println("\n" + strings.Repeat("=", 50))
 	fmt.Println(i18n.T("cmd.scan.results.title"))
 	fmt.Println(strings.Repeat("=", 50))
@@ -464,6 +506,10 @@ func showScanResults(scanned, newAPKs, updated, unchanged, errors int, errorMess
 	fmt.Printf("%s\n", i18n.T("cmd.scan.results.newAPKs", map[string]interface{}{"count": newAPKs}))
 	fmt.Printf("%s\n", i18n.T("cmd.scan.results.updatedAPKs", map[string]interface{}{"count": updated}))
 	fmt.Printf("%s\n", i18n.T("cmd.scan.results.unchangedAPKs", map[string]interface{}{"count": unchanged}))
+	if rejected > 0 || quarantined > 0 {
+		fmt.Printf("%s\n", i18n.T("cmd.scan.results.rejectedAPKs", map[string]interface{}{"rejected": rejected}))
+		fmt.Printf("%s\n", i18n.T("cmd.scan.results.quarantinedAPKs", map[string]interface{}{"quarantined": quarantined}))
+	}
 	fmt.Printf("%s\n", i18n.T("cmd.scan.results.errors", map[string]interface{}{"count": errors}))
 
 	// Performance metrics
@@ -8,6 +8,7 @@ import (
 	"text/tabwriter"
 
 	"github.com/huanfeng/apkhub/internal/i18n"
+	"github.com/huanfeng/apkhub/pkg/apk"
 	"github.com/huanfeng/apkhub/pkg/client"
 	"github.com/spf13/cobra"
 )
@@ -22,6 +23,17 @@ var (
 	searchVerbose   bool
 	searchExact     bool
 	searchInstalled bool
+
+	searchDeepLink   string
+	searchComponent  string
+	searchDebuggable bool
+
+	searchLibrary                string
+	searchLibraryCategory        string
+	searchWithoutLibraryCategory string
+
+	searchMaxRisk            int
+	searchWithoutPermissions []string
 )
 
 var searchCmd = &cobra.Command{
@@ -52,6 +64,18 @@ var searchCmd = &cobra.Command{
 			Sort:          searchSort,
 			Exact:         searchExact,
 			ShowInstalled: searchInstalled,
+			DeepLink:      searchDeepLink,
+			Component:     searchComponent,
+			Debuggable:    searchDebuggable,
+
+			Library:                searchLibrary,
+			LibraryCategory:        searchLibraryCategory,
+			WithoutLibraryCategory: searchWithoutLibraryCategory,
+			WithoutPermissions:     searchWithoutPermissions,
+			Languages:              i18n.PreferredLanguages(),
+		}
+		if cmd.Flags().Changed("max-risk") {
+			options.MaxRisk = &searchMaxRisk
 		}
 
 		// Create managers
@@ -138,7 +162,7 @@ func displayResultsDefault(results []client.SearchResult, verbose bool) error {
 		}))
 		if result.BucketName != "" {
 			fmt.Printf(" %s", i18n.T("cmd.search.resultBucket", map[string]interface{}{
-				"bucket": result.BucketName,
+				"bucket": searchResultBucket(result),
 			}))
 		}
 		if verbose && result.Score > 0 {
@@ -164,6 +188,25 @@ func displayResultsDefault(results []client.SearchResult, verbose bool) error {
 			}))
 		}
 
+		if verbose && len(result.DeepLinks) > 0 {
+			fmt.Printf("%s\n", i18n.T("cmd.search.resultDeepLinks", map[string]interface{}{
+				"links": strings.Join(result.DeepLinks, ", "),
+			}))
+		}
+
+		if verbose && len(result.Libraries) > 0 {
+			fmt.Printf("%s\n", i18n.T("cmd.search.resultLibraries", map[string]interface{}{
+				"libraries": strings.Join(result.Libraries, ", "),
+			}))
+		}
+
+		if verbose {
+			fmt.Printf("%s\n", i18n.T("cmd.search.resultRisk", map[string]interface{}{
+				"score": result.RiskScore,
+				"level": riskLevelName(result.RiskScore),
+			}))
+		}
+
 		fmt.Println()
 	}
 
@@ -211,7 +254,7 @@ func displayResultsTable(results []client.SearchResult, verbose bool) error {
 				result.PackageID,
 				result.AppName,
 				result.Version,
-				result.BucketName,
+				searchResultBucket(result),
 				result.Category,
 				result.Score,
 				desc,
@@ -237,7 +280,7 @@ func displayResultsTable(results []client.SearchResult, verbose bool) error {
 				result.PackageID,
 				result.AppName,
 				result.Version,
-				result.BucketName,
+				searchResultBucket(result),
 				desc,
 			)
 		}
@@ -280,4 +323,20 @@ func init() {
 	searchCmd.Flags().BoolVarP(&searchVerbose, "verbose", "v", false, i18n.T("cmd.search.flag.verbose"))
 	searchCmd.Flags().BoolVar(&searchExact, "exact", false, i18n.T("cmd.search.flag.exact"))
 	searchCmd.Flags().BoolVar(&searchInstalled, "installed", false, i18n.T("cmd.search.flag.installed"))
+	searchCmd.Flags().StringVar(&searchDeepLink, "deep-link", "", i18n.T("cmd.search.flag.deepLink"))
+	searchCmd.Flags().StringVar(&searchComponent, "component", "", i18n.T("cmd.search.flag.component"))
+	searchCmd.Flags().BoolVar(&searchDebuggable, "debuggable", false, i18n.T("cmd.search.flag.debuggable"))
+	searchCmd.Flags().StringVar(&searchLibrary, "library", "", i18n.T("cmd.search.flag.library"))
+	searchCmd.Flags().StringVar(&searchLibraryCategory, "library-category", "", i18n.T("cmd.search.flag.libraryCategory"))
+	searchCmd.Flags().StringVar(&searchWithoutLibraryCategory, "without-library-category", "", i18n.T("cmd.search.flag.withoutLibraryCategory"))
+	searchCmd.Flags().IntVar(&searchMaxRisk, "max-risk", apk.MaxRiskScore, i18n.T("cmd.search.flag.maxRisk"))
+	searchCmd.Flags().StringSliceVar(&searchWithoutPermissions, "without-permission", nil, i18n.T("cmd.search.flag.withoutPermission"))
+}
+
+// searchResultBucket returns the bucket column, marking pinned packages
+func searchResultBucket(result client.SearchResult) string {
+	if result.Pinned {
+		return result.BucketName + " 📌"
+	}
+	return result.BucketName
 }
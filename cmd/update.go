@@ -27,10 +27,15 @@ var updateCmd = &cobra.Command{
 			// Update specific bucket
 			name := args[0]
 			fmt.Printf("%s\n", i18n.T("cmd.update.single", map[string]interface{}{"name": name}))
-			if _, err := bucketMgr.FetchManifest(name); err != nil {
+			status, err := bucketMgr.UpdateManifest(name)
+			if err != nil {
 				return fmt.Errorf("%s: %w", i18n.T("cmd.update.errUpdate"), err)
 			}
-			fmt.Printf("%s\n", i18n.T("cmd.update.singleSuccess", map[string]interface{}{"name": name}))
+			if status == client.ManifestUnchanged {
+				fmt.Printf("%s\n", i18n.T("cmd.update.singleUnchanged", map[string]interface{}{"name": name}))
+			} else {
+				fmt.Printf("%s\n", i18n.T("cmd.update.singleSuccess", map[string]interface{}{"name": name}))
+			}
 		} else {
 			// Update all buckets
 			if updateAll {
@@ -39,11 +44,14 @@ var updateCmd = &cobra.Command{
 
 				for name := range config.Buckets {
 					fmt.Printf("%s\n", i18n.T("cmd.update.fetching", map[string]interface{}{"name": name}))
-					if _, err := bucketMgr.FetchManifest(name); err != nil {
+					status, err := bucketMgr.UpdateManifest(name)
+					if err != nil {
 						fmt.Printf("%s\n", i18n.T("cmd.update.updateFail", map[string]interface{}{
 							"name":  name,
 							"error": err,
 						}))
+					} else if status == client.ManifestUnchanged {
+						fmt.Printf("%s\n", i18n.T("cmd.update.updateUnchanged", map[string]interface{}{"name": name}))
 					} else {
 						fmt.Printf("%s\n", i18n.T("cmd.update.updateSuccess", map[string]interface{}{"name": name}))
 					}
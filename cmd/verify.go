@@ -13,7 +13,10 @@ import (
 
 	"github.com/huanfeng/apkhub/internal/config"
 	"github.com/huanfeng/apkhub/internal/i18n"
+	"github.com/huanfeng/apkhub/pkg/apk"
 	"github.com/huanfeng/apkhub/pkg/models"
+	"github.com/huanfeng/apkhub/pkg/repo"
+	"github.com/huanfeng/apkhub/pkg/signing"
 	"github.com/spf13/cobra"
 )
 
@@ -93,12 +96,13 @@ type VerificationResult struct {
 
 // VerificationStats contains verification statistics
 type VerificationStats struct {
-	MissingFiles    int `json:"missing_files"`
-	CorruptedFiles  int `json:"corrupted_files"`
-	OrphanedFiles   int `json:"orphaned_files"`
-	InvalidMetadata int `json:"invalid_metadata"`
-	MissingIcons    int `json:"missing_icons"`
-	MissingInfo     int `json:"missing_info"`
+	MissingFiles     int `json:"missing_files"`
+	CorruptedFiles   int `json:"corrupted_files"`
+	OrphanedFiles    int `json:"orphaned_files"`
+	InvalidMetadata  int `json:"invalid_metadata"`
+	MissingIcons     int `json:"missing_icons"`
+	MissingInfo      int `json:"missing_info"`
+	PolicyViolations int `json:"policy_violations"`
 }
 
 // FixResult contains the results of auto-fix attempts
@@ -195,6 +199,22 @@ func performRepositoryVerification(cfg *models.Config) (*VerificationResult, err
 			}
 		}
 
+		// Check 6: Admission policy
+		if cfg.Repository.Policy != "" {
+			if !verifyQuiet {
+				fmt.Print(i18n.T("cmd.verify.check.policy"))
+			}
+			policyIssues := checkPolicyCompliance(cfg, manifest)
+			result.Issues = append(result.Issues, policyIssues...)
+			if !verifyQuiet {
+				if len(policyIssues) == 0 {
+					fmt.Println("✅")
+				} else {
+					fmt.Printf(i18n.T("cmd.verify.check.failCount")+"\n", len(policyIssues))
+				}
+			}
+		}
+
 		// Deep verification if requested
 		if verifyDeep {
 			if !verifyQuiet {
@@ -319,11 +339,23 @@ func checkManifestIntegrity(cfg *models.Config) ([]VerificationIssue, *models.Ma
 	}
 
 	if verifySignatures {
-		if issue := validateManifestSignature(&manifest, cfg); issue != nil {
+		if issue := validateManifestSignature(&manifest, data, cfg); issue != nil {
 			issues = append(issues, *issue)
 		}
 	}
 
+	// A sharded manifest only lists latest versions: check every version from the package index
+	failures := repo.LoadPackageShards(".", &manifest)
+	for pkgID, err := range failures {
+		issues = append(issues, VerificationIssue{
+			Type:        "manifest",
+			Severity:    "error",
+			Description: i18n.T("cmd.verify.issue.shardInvalid", map[string]interface{}{"id": pkgID, "error": err}),
+			File:        manifest.Packages[pkgID].Shard.Path,
+			Fixable:     false,
+		})
+	}
+
 	return issues, &manifest
 }
 
@@ -367,7 +399,7 @@ func checkAPKFiles(cfg *models.Config, manifest *models.ManifestIndex) ([]Verifi
 			}
 
 			if verifySignatures {
-				if sigIssue := validateAPKSignature(pkgID, versionKey, version, cfg, strictPolicy); sigIssue != nil {
+				if sigIssue := validateAPKSignature(pkgID, versionKey, localPath, version, cfg, strictPolicy); sigIssue != nil {
 					issues = append(issues, *sigIssue)
 				}
 
@@ -442,7 +474,7 @@ func checkOrphanedFiles(cfg *models.Config, manifest *models.ManifestIndex) []Ve
 			}
 
 			filename := entry.Name()
-			if strings.HasSuffix(strings.ToLower(filename), ".apk") {
+			if apk.IsAPKFile(filename) {
 				apkCount++
 			}
 		}
@@ -465,6 +497,71 @@ func checkOrphanedFiles(cfg *models.Config, manifest *models.ManifestIndex) []Ve
 	return issues
 }
 
+// checkPolicyCompliance re-evaluates the admission policy against every version
+// in the repository, so rules added or tightened later catch APKs admitted before
+func checkPolicyCompliance(cfg *models.Config, manifest *models.ManifestIndex) []VerificationIssue {
+	var issues []VerificationIssue
+
+	// The policy path is relative to the repository, as for add, scan and import
+	var policy *models.Policy
+	repository, err := repo.NewRepository(workDir, cfg)
+	if err == nil {
+		policy, err = repository.LoadPolicy()
+	}
+	if err != nil {
+		return append(issues, VerificationIssue{
+			Type:        "config",
+			Severity:    "error",
+			Description: i18n.T("cmd.verify.issue.policyLoad", map[string]interface{}{"error": err}),
+			File:        cfg.Repository.Policy,
+			Fixable:     false,
+		})
+	}
+
+	for pkgID, pkg := range manifest.Packages {
+		if pkg == nil {
+			continue
+		}
+
+		for versionKey, version := range pkg.Versions {
+			if version == nil {
+				continue
+			}
+
+			violations := repo.EvaluatePolicy(policy, &models.APKInfo{
+				PackageID:     pkgID,
+				Version:       version.Version,
+				VersionCode:   version.VersionCode,
+				TargetSDK:     version.TargetSDK,
+				Size:          version.Size,
+				SignatureInfo: version.SignatureInfo,
+				Permissions:   version.Permissions,
+				Manifest:      version.Manifest,
+			})
+			localPath, _ := resolveLocalAPKPath(version.DownloadURL)
+
+			for _, violation := range violations {
+				severity := "error"
+				if violation.Action == models.PolicyWarn {
+					severity = "warning"
+				}
+				issues = append(issues, VerificationIssue{
+					Type:     "policy",
+					Severity: severity,
+					Description: i18n.T("cmd.verify.issue.policy", map[string]interface{}{
+						"id": pkgID, "version": versionKey, "rule": violation.Rule,
+						"desc": policyViolationText(violation), "action": policyActionName(violation.Action),
+					}),
+					File:    localPath,
+					Fixable: false,
+				})
+			}
+		}
+	}
+
+	return issues
+}
+
 // performDeepVerification performs additional deep checks
 func performDeepVerification(cfg *models.Config, manifest *models.ManifestIndex) []VerificationIssue {
 	var issues []VerificationIssue
@@ -520,7 +617,7 @@ func performDeepVerification(cfg *models.Config, manifest *models.ManifestIndex)
 	return issues
 }
 
-func validateManifestSignature(manifest *models.ManifestIndex, cfg *models.Config) *VerificationIssue {
+func validateManifestSignature(manifest *models.ManifestIndex, data []byte, cfg *models.Config) *VerificationIssue {
 	policyStrict := strings.ToLower(cfg.Repository.SignaturePolicy) == "strict"
 
 	if manifest.Signature == nil || manifest.Signature.PublicKeyFingerprint == "" {
@@ -569,10 +666,63 @@ func validateManifestSignature(manifest *models.ManifestIndex, cfg *models.Confi
 		}
 	}
 
+	return validateDetachedManifestSignature(data, cfg, policyStrict)
+}
+
+// validateDetachedManifestSignature verifies apkhub_manifest.json.sig against the manifest bytes
+func validateDetachedManifestSignature(data []byte, cfg *models.Config, policyStrict bool) *VerificationIssue {
+	sigPath := "apkhub_manifest.json" + models.ManifestSignatureSuffix
+
+	sigData, err := os.ReadFile(sigPath)
+	if os.IsNotExist(err) {
+		severity := "warning"
+		if policyStrict || cfg.Repository.SigningKey != "" {
+			severity = "error"
+		}
+
+		return &VerificationIssue{
+			Type:        "manifest",
+			Severity:    severity,
+			Description: i18n.T("cmd.verify.issue.manifestSigMissing"),
+			File:        sigPath,
+			Fixable:     cfg.Repository.SigningKey != "",
+		}
+	}
+
+	// The repository's own signing key is implicitly trusted
+	trustedKeys := append([]string{}, cfg.Repository.TrustedKeys...)
+	if cfg.Repository.SigningKeyFingerprint != "" {
+		trustedKeys = append(trustedKeys, cfg.Repository.SigningKeyFingerprint)
+	}
+
+	if err == nil {
+		var sigFile *models.ManifestSignatureFile
+		sigFile, err = signing.ParseSignatureFile(sigData)
+		if err == nil {
+			_, err = signing.Verify(data, sigFile, trustedKeys)
+		}
+	}
+
+	if err != nil {
+		return &VerificationIssue{
+			Type:     "manifest",
+			Severity: "error",
+			Description: i18n.T("cmd.verify.issue.manifestSigInvalid", map[string]interface{}{
+				"error": err,
+			}),
+			File:    sigPath,
+			Fixable: cfg.Repository.SigningKey != "",
+		}
+	}
+
 	return nil
 }
 
-func validateAPKSignature(pkgID, versionKey string, version *models.AppVersion, cfg *models.Config, strict bool) *VerificationIssue {
+func validateAPKSignature(pkgID, versionKey, localPath string, version *models.AppVersion, cfg *models.Config, strict bool) *VerificationIssue {
+	if issue := verifyAPKSignatureSchemes(pkgID, versionKey, localPath, version, strict); issue != nil {
+		return issue
+	}
+
 	if version.SignatureInfo == nil || version.SignatureInfo.SHA256 == "" {
 		severity := "warning"
 		if strict {
@@ -610,6 +760,79 @@ func validateAPKSignature(pkgID, versionKey string, version *models.AppVersion,
 	return nil
 }
 
+// verifyAPKSignatureSchemes cryptographically verifies the APK file against its signatures
+func verifyAPKSignatureSchemes(pkgID, versionKey, localPath string, version *models.AppVersion, strict bool) *VerificationIssue {
+	result, err := apk.VerifySignatures(localPath)
+	if err != nil {
+		return &VerificationIssue{
+			Type:     "signature",
+			Severity: "error",
+			Description: i18n.T("cmd.verify.issue.apkSignatureCheckFail", map[string]interface{}{
+				"id": pkgID, "version": versionKey, "error": err,
+			}),
+			File:    localPath,
+			Fixable: false,
+		}
+	}
+
+	if len(result.Schemes) == 0 {
+		severity := "warning"
+		if strict {
+			severity = "error"
+		}
+
+		return &VerificationIssue{
+			Type:     "signature",
+			Severity: severity,
+			Description: i18n.T("cmd.verify.issue.apkUnsigned", map[string]interface{}{
+				"id": pkgID, "version": versionKey,
+			}),
+			File:    localPath,
+			Fixable: false,
+		}
+	}
+
+	if !result.Verified {
+		return &VerificationIssue{
+			Type:     "signature",
+			Severity: "error",
+			Description: i18n.T("cmd.verify.issue.apkSignatureInvalid", map[string]interface{}{
+				"id": pkgID, "version": versionKey, "details": describeSchemeFailures(result),
+			}),
+			File:    localPath,
+			Fixable: false,
+		}
+	}
+
+	if version.SignatureInfo != nil && version.SignatureInfo.SHA256 != "" {
+		actual, err := apk.ExtractSignatureInfo(localPath)
+		if err == nil && !strings.EqualFold(actual.SHA256, version.SignatureInfo.SHA256) {
+			return &VerificationIssue{
+				Type:     "signature",
+				Severity: "error",
+				Description: i18n.T("cmd.verify.issue.apkSignerMismatch", map[string]interface{}{
+					"id": pkgID, "version": versionKey, "expected": version.SignatureInfo.SHA256, "actual": actual.SHA256,
+				}),
+				File:    localPath,
+				Fixable: false,
+			}
+		}
+	}
+
+	return nil
+}
+
+// describeSchemeFailures summarizes the schemes that failed verification
+func describeSchemeFailures(result *models.SignatureVerification) string {
+	var failures []string
+	for _, scheme := range result.Schemes {
+		if !scheme.Valid {
+			failures = append(failures, fmt.Sprintf("%s: %s", scheme.Scheme, scheme.Error))
+		}
+	}
+	return strings.Join(failures, "; ")
+}
+
 func resolveLocalAPKPath(downloadURL string) (string, bool) {
 	if downloadURL == "" {
 		return "", false
@@ -688,6 +911,8 @@ func calculateStatistics(result *VerificationResult) {
 			result.Statistics.InvalidMetadata++
 		case "icon":
 			result.Statistics.MissingIcons++
+		case "policy":
+			result.Statistics.PolicyViolations++
 		}
 	}
 }
@@ -715,6 +940,9 @@ func showVerificationResults(result *VerificationResult, duration time.Duration)
 		fmt.Printf("%s\n", i18n.T("cmd.verify.results.orphaned", map[string]interface{}{"count": result.Statistics.OrphanedFiles}))
 		fmt.Printf("%s\n", i18n.T("cmd.verify.results.invalid", map[string]interface{}{"count": result.Statistics.InvalidMetadata}))
 		fmt.Printf("%s\n", i18n.T("cmd.verify.results.icons", map[string]interface{}{"count": result.Statistics.MissingIcons}))
+		if result.Statistics.PolicyViolations > 0 {
+			fmt.Printf("%s\n", i18n.T("cmd.verify.results.policy", map[string]interface{}{"violations": result.Statistics.PolicyViolations}))
+		}
 
 		fmt.Println()
 		fmt.Println(i18n.T("cmd.verify.results.details"))
@@ -16,15 +16,24 @@ var defaultConfig = models.Config{
 		BaseURL:               "",
 		KeepVersions:          0,
 		SignatureHandling:     "mark",
+		SigningKey:            "",
 		SigningKeyFingerprint: "",
+		PreviousSigningKeys:   []string{},
 		Signer:                "",
 		TrustedKeys:           []string{},
 		SignaturePolicy:       "lenient",
+		ManifestExpiryDays:    0,
+		ManifestCompression:   []string{},
+		ManifestDeltas:        0,
+		ShardedIndex:          false,
+		IconMask:              "circle",
+		IconSizes:             []int{48, 96, 192, 512},
+		Policy:                "",
 	},
 	Scanning: models.ScanningConfig{
 		Recursive:      true,
 		FollowSymlinks: false,
-		IncludePattern: []string{"*.apk", "*.xapk", "*.apkm"},
+		IncludePattern: []string{"*.apk", "*.xapk", "*.apkm", "*.aab", "*.apks"},
 		ExcludePattern: []string{},
 		ParseAPKInfo:   true,
 	},
@@ -40,10 +49,19 @@ func Load(configPath string) (*models.Config, error) {
 	viper.SetDefault("repository.base_url", defaultConfig.Repository.BaseURL)
 	viper.SetDefault("repository.keep_versions", defaultConfig.Repository.KeepVersions)
 	viper.SetDefault("repository.signature_handling", defaultConfig.Repository.SignatureHandling)
+	viper.SetDefault("repository.signing_key", defaultConfig.Repository.SigningKey)
 	viper.SetDefault("repository.signing_key_fingerprint", defaultConfig.Repository.SigningKeyFingerprint)
+	viper.SetDefault("repository.previous_signing_keys", defaultConfig.Repository.PreviousSigningKeys)
 	viper.SetDefault("repository.signer", defaultConfig.Repository.Signer)
 	viper.SetDefault("repository.trusted_keys", defaultConfig.Repository.TrustedKeys)
 	viper.SetDefault("repository.signature_policy", defaultConfig.Repository.SignaturePolicy)
+	viper.SetDefault("repository.manifest_expiry_days", defaultConfig.Repository.ManifestExpiryDays)
+	viper.SetDefault("repository.manifest_compression", defaultConfig.Repository.ManifestCompression)
+	viper.SetDefault("repository.manifest_deltas", defaultConfig.Repository.ManifestDeltas)
+	viper.SetDefault("repository.sharded_index", defaultConfig.Repository.ShardedIndex)
+	viper.SetDefault("repository.icon_mask", defaultConfig.Repository.IconMask)
+	viper.SetDefault("repository.icon_sizes", defaultConfig.Repository.IconSizes)
+	viper.SetDefault("repository.policy", defaultConfig.Repository.Policy)
 	viper.SetDefault("scanning.recursive", defaultConfig.Scanning.Recursive)
 	viper.SetDefault("scanning.follow_symlinks", defaultConfig.Scanning.FollowSymlinks)
 	viper.SetDefault("scanning.include_pattern", defaultConfig.Scanning.IncludePattern)
@@ -85,6 +103,11 @@ func Load(configPath string) (*models.Config, error) {
 	return &config, nil
 }
 
+// ConfigFileUsed returns the path of the configuration file read by Load, if any
+func ConfigFileUsed() string {
+	return viper.ConfigFileUsed()
+}
+
 // SaveTemplate saves a configuration template
 func SaveTemplate(path string) error {
 	templateContent := `# ApkHub Configuration File
@@ -109,6 +132,14 @@ repository:
   # - "reject": Reject APKs with different signatures
   signature_handling: "mark"
 
+  # Ed25519 private key (PEM) used to sign the manifest (optional)
+  # When set, apkhub_manifest.json.sig is published next to the manifest
+  # Manage keys with "apkhub repo key"; $APKHUB_SIGNING_KEY overrides the file
+  signing_key: ""
+
+  # Old signing keys that keep co-signing the manifest during a key rotation
+  previous_signing_keys: []
+
   # Manifest signing metadata (optional)
   signing_key_fingerprint: ""
   signer: ""
@@ -121,6 +152,49 @@ repository:
   # Trusted signer fingerprints for verification
   trusted_keys: []
 
+  # Days after which clients refuse the published manifest (0 = never expires)
+  # Republish (e.g. "apkhub repo scan") before the deadline
+  manifest_expiry_days: 0
+
+  # Also publish compressed copies of the manifest for faster bucket updates
+  # Supported: "gzip" (apkhub_manifest.json.gz), "zstd" (apkhub_manifest.json.zst)
+  manifest_compression: []
+
+  # Number of manifest generations for which deltas are kept in deltas/ (0 = none)
+  # Clients a few generations behind download only the changed packages
+  manifest_deltas: 0
+
+  # Publish a small root index (latest version of each package) plus one file per
+  # package under index/, fetched by clients only when a package is used
+  sharded_index: false
+
+  # Shape adaptive icons are cut to: "circle", "squircle", "rounded-square" or
+  # "none" (full square). Legacy icons keep their own shape
+  icon_mask: "circle"
+
+  # Extra icon sizes in pixels, written to infos/<package>_<size>.png next to the
+  # standard 144px infos/<package>.png
+  icon_sizes: [48, 96, 192, 512]
+
+  # Admission policy (YAML) checked when "repo add", "repo scan" or "repo import"
+  # brings in an APK, and re-checked by "repo verify". Empty = admit everything.
+  # Each rule has a check, an action (reject, quarantine or warn) and a parameter:
+  #   rules:
+  #     - check: debuggable
+  #     - check: min_target_sdk
+  #       target_sdk: 30
+  #       action: warn
+  #     - check: forbidden_permissions
+  #       permissions: ["android.permission.SEND_SMS"]
+  #       action: quarantine
+  #     - check: allowed_signers
+  #       signers: ["<certificate SHA256>"]
+  #     - check: max_size
+  #       max_size_mb: 200
+  #     - check: cleartext_traffic
+  # Quarantined APKs are kept in quarantine/ with a record of the broken rules
+  policy: ""
+
 scanning:
   # Scan directories recursively
   recursive: true
@@ -133,6 +207,8 @@ scanning:
     - "*.apk"
     - "*.xapk"
     - "*.apkm"
+    - "*.aab"
+    - "*.apks"
 
   # Exclude patterns (glob)
   exclude_pattern: []
@@ -152,10 +228,19 @@ func SaveConfig(cfg *models.Config, path string) error {
 	viper.Set("repository.base_url", cfg.Repository.BaseURL)
 	viper.Set("repository.keep_versions", cfg.Repository.KeepVersions)
 	viper.Set("repository.signature_handling", cfg.Repository.SignatureHandling)
+	viper.Set("repository.signing_key", cfg.Repository.SigningKey)
 	viper.Set("repository.signing_key_fingerprint", cfg.Repository.SigningKeyFingerprint)
+	viper.Set("repository.previous_signing_keys", cfg.Repository.PreviousSigningKeys)
 	viper.Set("repository.signer", cfg.Repository.Signer)
 	viper.Set("repository.trusted_keys", cfg.Repository.TrustedKeys)
 	viper.Set("repository.signature_policy", cfg.Repository.SignaturePolicy)
+	viper.Set("repository.manifest_expiry_days", cfg.Repository.ManifestExpiryDays)
+	viper.Set("repository.manifest_compression", cfg.Repository.ManifestCompression)
+	viper.Set("repository.manifest_deltas", cfg.Repository.ManifestDeltas)
+	viper.Set("repository.sharded_index", cfg.Repository.ShardedIndex)
+	viper.Set("repository.icon_mask", cfg.Repository.IconMask)
+	viper.Set("repository.icon_sizes", cfg.Repository.IconSizes)
+	viper.Set("repository.policy", cfg.Repository.Policy)
 	viper.Set("scanning.recursive", cfg.Scanning.Recursive)
 	viper.Set("scanning.follow_symlinks", cfg.Scanning.FollowSymlinks)
 	viper.Set("scanning.include_pattern", cfg.Scanning.IncludePattern)
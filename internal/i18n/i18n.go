@@ -20,6 +20,10 @@ var (
 		language.SimplifiedChinese,
 		language.Chinese,
 	})
+
+	// preferredLanguages are the requested languages, before they are matched
+	// against the message files
+	preferredLanguages []string
 )
 
 //go:embed locales/*.toml
@@ -38,7 +42,8 @@ func Init(langOverride string) error {
 		return fmt.Errorf("load locales: %w", err)
 	}
 
-	chosen := selectLanguage(langOverride)
+	preferredLanguages = languageCandidates(langOverride)
+	chosen := selectLanguage(preferredLanguages)
 	localizer = goi18n.NewLocalizer(bundle, chosen.String(), language.English.String())
 	currentLanguage = chosen
 
@@ -82,7 +87,19 @@ func CurrentLanguage() language.Tag {
 	return currentLanguage
 }
 
-func selectLanguage(langOverride string) language.Tag {
+// PreferredLanguages returns the languages the user asked for, most preferred
+// first, as given by --lang and the locale environment. Unlike CurrentLanguage
+// they are not limited to the languages of the messages, so content such as
+// localized app names can follow them.
+func PreferredLanguages() []string {
+	if localizer == nil {
+		return languageCandidates("")
+	}
+	return preferredLanguages
+}
+
+// languageCandidates lists the requested languages in order of precedence
+func languageCandidates(langOverride string) []string {
 	var candidates []string
 	if langOverride != "" {
 		candidates = append(candidates, langOverride)
@@ -99,6 +116,10 @@ func selectLanguage(langOverride string) language.Tag {
 		candidates = append(candidates, getPlatformLocales()...)
 	}
 
+	return candidates
+}
+
+func selectLanguage(candidates []string) language.Tag {
 	if len(candidates) == 0 {
 		return language.English
 	}
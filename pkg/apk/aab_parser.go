@@ -0,0 +1,402 @@
+package apk
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// bundleManifestPath is the protobuf manifest of the base module of an app bundle
+	bundleManifestPath = "base/manifest/AndroidManifest.xml"
+
+	// bundleResourcesPath is the protobuf resource table of the base module
+	bundleResourcesPath = "base/resources.pb"
+
+	// androidNamespace is the namespace of android: attributes
+	androidNamespace = "http://schemas.android.com/apk/res/android"
+)
+
+// AABParser parses Android App Bundles (.aab). Unlike APKs, bundles store the
+// manifest and resource table in the aapt2 protobuf format.
+type AABParser struct {
+	workDir string
+}
+
+// NewAABParser creates a new app bundle parser
+func NewAABParser(workDir string) *AABParser {
+	return &AABParser{
+		workDir: workDir,
+	}
+}
+
+// ParseAPK parses an app bundle from the manifest of its base module
+func (p *AABParser) ParseAPK(aabPath string) (*APKInfo, error) {
+	fileInfo, err := os.Stat(aabPath)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := zip.OpenReader(aabPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open app bundle (not a valid zip): %w", err)
+	}
+	defer reader.Close()
+
+	entries := make(map[string]*zip.File, len(reader.File))
+	for _, file := range reader.File {
+		entries[file.Name] = file
+	}
+
+	manifestFile, exists := entries[bundleManifestPath]
+	if !exists {
+		return nil, fmt.Errorf("not an app bundle: %s not found", bundleManifestPath)
+	}
+	manifestData, err := readZipFile(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := parseBundleXML(manifestData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", bundleManifestPath, err)
+	}
+	if manifest.name != "manifest" {
+		return nil, fmt.Errorf("%s has no manifest element", bundleManifestPath)
+	}
+
+	// Labels and icons are resolved through the resource table when it can be read
+	var table *bundleResourceTable
+	if file, exists := entries[bundleResourcesPath]; exists {
+		if data, err := readZipFile(file); err == nil {
+			table, _ = parseBundleResourceTable(data)
+		}
+	}
+
+	hash, err := hashFile(aabPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &APKInfo{
+		PackageID:   manifest.attr("", "package").text(),
+		Version:     manifest.attr(androidNamespace, "versionName").text(),
+		VersionCode: manifest.attr(androidNamespace, "versionCode").int(),
+		MinSDK:      1,
+		Size:        fileInfo.Size(),
+		SHA256:      hash,
+		Features:    []string{"aab"},
+		ABIs:        bundleABIs(reader.File),
+		ReleaseDate: fileInfo.ModTime(),
+	}
+
+	if sdk := manifest.child("uses-sdk"); sdk != nil {
+		if minSDK := sdk.attr(androidNamespace, "minSdkVersion").int(); minSDK > 0 {
+			info.MinSDK = int(minSDK)
+		}
+		info.TargetSDK = int(sdk.attr(androidNamespace, "targetSdkVersion").int())
+	}
+
+	for _, child := range manifest.children {
+		if child.name == "uses-permission" || child.name == "uses-permission-sdk-23" {
+			if name := child.attr(androidNamespace, "name").text(); name != "" {
+				info.Permissions = append(info.Permissions, name)
+			}
+		}
+	}
+
+	info.AppName = map[string]string{}
+	if application := manifest.child("application"); application != nil {
+		label := application.attr(androidNamespace, "label")
+		if label != nil && label.refID != 0 {
+			info.AppName = table.localizedStrings(label.refID)
+		} else if label.text() != "" {
+			info.AppName["default"] = label.text()
+		}
+
+		if icon := application.attr(androidNamespace, "icon"); icon != nil && icon.refID != 0 {
+			if iconPath := table.bestDensityFile(icon.refID); iconPath != "" {
+				if file, exists := entries["base/"+iconPath]; exists {
+					if data, err := readZipFile(file); err == nil {
+						if bitmap, err := decodeBitmap(data, path.Ext(iconPath)); err == nil {
+							info.Icon = &Icon{image: bitmap}
+						}
+					}
+				}
+			}
+		}
+	}
+	if info.AppName["default"] == "" {
+		info.AppName["default"] = info.PackageID
+	}
+
+	info.Manifest = buildManifestInfo(manifest, func(attr *xmlAttr) string {
+		return table.defaultValue(attr.refID)
+	})
+
+	// The code of all modules is in <module>/dex/
+	info.Libraries, _ = DetectLibraries(aabPath)
+
+	// The bundle is signed with jarsigner, like a v1-signed APK
+	if signatureInfo, err := ExtractSignatureInfo(aabPath); err == nil {
+		info.SignatureInfo = signatureInfo
+	}
+	if verification, err := VerifySignatures(aabPath); err == nil {
+		info.SignatureVerification = verification
+	}
+
+	relPath, err := filepath.Rel(p.workDir, aabPath)
+	if err == nil && !strings.HasPrefix(relPath, "..") {
+		info.FilePath = relPath
+	} else {
+		info.FilePath = filepath.Base(aabPath)
+	}
+
+	return info, nil
+}
+
+// GetParserInfo returns information about this parser
+func (p *AABParser) GetParserInfo() ParserInfo {
+	return ParserInfo{
+		Name:         "AAB",
+		Version:      "1.0",
+		Capabilities: []string{"aab", "manifest", "resources", "icons"},
+		Available:    true, // Always available
+		Priority:     3,
+	}
+}
+
+// CanParse checks if this parser can handle the given file
+func (p *AABParser) CanParse(path string) bool {
+	return IsAppBundleFile(path)
+}
+
+// IsAppBundleFile checks if the file is an Android App Bundle
+func IsAppBundleFile(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".aab"
+}
+
+// bundleABIs lists the ABIs of the native libraries of all modules
+func bundleABIs(files []*zip.File) []string {
+	abiMap := make(map[string]bool)
+	for _, file := range files {
+		// "<module>/lib/<abi>/<library>"
+		parts := strings.Split(file.Name, "/")
+		if len(parts) >= 4 && parts[1] == "lib" && parts[2] != "" {
+			abiMap[parts[2]] = true
+		}
+	}
+
+	var abis []string
+	for abi := range abiMap {
+		abis = append(abis, abi)
+	}
+	sort.Strings(abis)
+
+	return abis
+}
+
+// hashFile returns the hex SHA256 of a file
+func hashFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// parseBundleXML decodes an aapt2 XmlNode message (Resources.proto): element = 1,
+// and in XmlElement namespace_uri = 2, name = 3, attribute = 4, child = 5
+func parseBundleXML(data []byte) (*xmlElement, error) {
+	node, err := parseProtoMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	if !node.has(1) {
+		return nil, fmt.Errorf("document has no root element")
+	}
+
+	return decodeBundleElement(node.message(1)), nil
+}
+
+// decodeBundleElement decodes an XmlElement message
+func decodeBundleElement(element protoMessage) *xmlElement {
+	decoded := &xmlElement{name: element.string(3)}
+
+	// XmlAttribute: namespace_uri = 1, name = 2, value = 3, compiled_item = 6
+	for _, attr := range element.messages(4) {
+		decodedAttr := &xmlAttr{
+			namespace: attr.string(1),
+			name:      attr.string(2),
+			value:     attr.string(3),
+		}
+
+		// Item: ref = 1 (Reference: id = 2), prim = 7 (Primitive:
+		// int_decimal_value = 6, int_hexadecimal_value = 7, boolean_value = 8)
+		item := attr.message(6)
+		if item.has(1) {
+			decodedAttr.refID = uint32(item.message(1).uint(2))
+		}
+		if item.has(7) {
+			prim := item.message(7)
+			for _, number := range []int{6, 7, 8} {
+				if prim.has(number) {
+					value := int64(int32(prim.uint(number)))
+					decodedAttr.number = &value
+				}
+			}
+		}
+
+		decoded.attrs = append(decoded.attrs, decodedAttr)
+	}
+
+	// XmlNode: element = 1, text = 2
+	for _, child := range element.messages(5) {
+		if child.has(1) {
+			decoded.children = append(decoded.children, decodeBundleElement(child.message(1)))
+		}
+	}
+
+	return decoded
+}
+
+// bundleResourceValue is one configuration of a resource entry
+type bundleResourceValue struct {
+	locale  string
+	density uint32
+	str     string // String value
+	file    string // File path, relative to the module
+}
+
+// bundleResourceTable maps resource IDs to their values in every configuration
+type bundleResourceTable struct {
+	entries map[uint32][]bundleResourceValue
+}
+
+// parseBundleResourceTable decodes the string and file values of an aapt2
+// ResourceTable message (Resources.proto)
+func parseBundleResourceTable(data []byte) (*bundleResourceTable, error) {
+	root, err := parseProtoMessage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &bundleResourceTable{entries: make(map[uint32][]bundleResourceValue)}
+
+	// ResourceTable.package = 2; Package: package_id = 1, type = 3;
+	// Type: type_id = 1, entry = 3; Entry: entry_id = 1, config_value = 6
+	for _, pkg := range root.messages(2) {
+		packageID := uint32(pkg.message(1).uint(1))
+		for _, resourceType := range pkg.messages(3) {
+			typeID := uint32(resourceType.message(1).uint(1))
+			for _, entry := range resourceType.messages(3) {
+				entryID := uint32(entry.message(1).uint(1))
+				resourceID := packageID<<24 | typeID<<16 | entryID
+
+				// ConfigValue: config = 1 (Configuration: locale = 3, density = 18),
+				// value = 2 (Value: item = 4; Item: str = 2, file = 5)
+				for _, configValue := range entry.messages(6) {
+					config := configValue.message(1)
+					item := configValue.message(2).message(4)
+
+					value := bundleResourceValue{
+						locale:  config.string(3),
+						density: uint32(config.uint(18)),
+					}
+					switch {
+					case item.has(2):
+						value.str = item.message(2).string(1)
+					case item.has(5):
+						value.file = item.message(5).string(1)
+					default:
+						continue
+					}
+					table.entries[resourceID] = append(table.entries[resourceID], value)
+				}
+			}
+		}
+	}
+
+	return table, nil
+}
+
+// localizedStrings returns the values of a string resource, keyed "default" for
+// the default configuration and by locale otherwise
+func (t *bundleResourceTable) localizedStrings(resourceID uint32) map[string]string {
+	names := make(map[string]string)
+	if t == nil {
+		return names
+	}
+
+	for _, value := range t.entries[resourceID] {
+		if value.str == "" {
+			continue
+		}
+		key := value.locale
+		if key == "" {
+			key = "default"
+		}
+		if _, exists := names[key]; !exists {
+			names[key] = value.str
+		}
+	}
+
+	return names
+}
+
+// defaultValue returns the string or file path of a resource in the default
+// configuration, or "" if it has none
+func (t *bundleResourceTable) defaultValue(resourceID uint32) string {
+	if t == nil {
+		return ""
+	}
+
+	for _, value := range t.entries[resourceID] {
+		if value.locale == "" && value.density == 0 {
+			if value.str != "" {
+				return value.str
+			}
+			return value.file
+		}
+	}
+	return ""
+}
+
+// bestDensityFile returns the bitmap of a drawable resource with the highest
+// density, or "" if it has none
+func (t *bundleResourceTable) bestDensityFile(resourceID uint32) string {
+	if t == nil {
+		return ""
+	}
+
+	best := ""
+	var bestDensity uint32
+	for _, value := range t.entries[resourceID] {
+		ext := strings.ToLower(path.Ext(value.file))
+		if ext != ".png" && ext != ".webp" {
+			continue
+		}
+		// 0xFFFF is "anydpi", which holds adaptive icon XML rather than bitmaps
+		if value.density == 0xFFFF {
+			continue
+		}
+		if best == "" || value.density > bestDensity {
+			best, bestDensity = value.file, value.density
+		}
+	}
+
+	return best
+}
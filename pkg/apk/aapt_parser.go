@@ -187,6 +187,7 @@ type APKBasicInfo struct {
 	MinSDK          int
 	TargetSDK       int
 	AppName         string
+	AppNames        map[string]string // Localized labels by BCP-47 tag
 	Permissions     []string
 	Features        []string
 	ABIs            []string
@@ -196,6 +197,7 @@ type APKBasicInfo struct {
 // parseBadgingOutput parses aapt dump badging output
 func (p *AAPTParser) parseBadgingOutput(output string) (*APKBasicInfo, error) {
 	info := &APKBasicInfo{
+		AppNames:        map[string]string{},
 		Permissions:     []string{},
 		Features:        []string{},
 		ABIs:            []string{},
@@ -246,6 +248,11 @@ func (p *AAPTParser) parseBadgingOutput(output string) (*APKBasicInfo, error) {
 			}
 		}
 
+		// Parse localized labels ("application-label-zh-CN:'...'")
+		if matches := regexp.MustCompile(`^application-label-([^:]+):'([^']+)'`).FindStringSubmatch(line); len(matches) > 2 {
+			info.AppNames[matches[1]] = matches[2]
+		}
+
 		// Parse permissions
 		if strings.HasPrefix(line, "uses-permission:") {
 			if matches := regexp.MustCompile(`name='([^']+)'`).FindStringSubmatch(line); len(matches) > 1 {
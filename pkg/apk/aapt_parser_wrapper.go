@@ -42,33 +42,40 @@ func (p *AAPTParserWrapper) ParseAPK(apkPath string) (*APKInfo, error) {
 		return nil, err
 	}
 
-	// Extract icon
-	iconExtractor := NewIconExtractor()
-	iconData, iconExt, iconErr := iconExtractor.ExtractIcon(apkPath)
-	// Icon extraction is non-fatal
+	// Signature extraction does not need aapt
+	signatureInfo, err := ExtractSignatureInfo(apkPath)
+	if err != nil {
+		signatureInfo = &models.SignatureInfo{}
+	}
+	signatureVerification, _ := VerifySignatures(apkPath)
+
+	// The binary manifest can often be read even when androidbinary fails on
+	// the resources
+	manifestInfo, _ := ReadManifestInfo(apkPath)
+	libraries, _ := DetectLibraries(apkPath)
 
 	// Build APK info from aapt data
 	info := &APKInfo{
-		PackageID:     basicInfo.PackageID,
-		AppName:       map[string]string{"default": basicInfo.AppName},
-		Version:       basicInfo.VersionName,
-		VersionCode:   basicInfo.VersionCode,
-		MinSDK:        basicInfo.MinSDK,
-		TargetSDK:     basicInfo.TargetSDK,
-		Size:          fileInfo.Size(),
-		SHA256:        hashes["sha256"],
-		SignatureInfo: &models.SignatureInfo{}, // Empty signature for aapt
-		Permissions:   basicInfo.Permissions,
-		Features:      basicInfo.Features,
-		ABIs:          basicInfo.ABIs,
-		ReleaseDate:   fileInfo.ModTime(),
+		PackageID:             basicInfo.PackageID,
+		AppName:               p.appNames(basicInfo),
+		Version:               basicInfo.VersionName,
+		VersionCode:           basicInfo.VersionCode,
+		MinSDK:                basicInfo.MinSDK,
+		TargetSDK:             basicInfo.TargetSDK,
+		Size:                  fileInfo.Size(),
+		SHA256:                hashes["sha256"],
+		SignatureInfo:         signatureInfo,
+		SignatureVerification: signatureVerification,
+		Permissions:           basicInfo.Permissions,
+		Features:              basicInfo.Features,
+		ABIs:                  basicInfo.ABIs,
+		Manifest:              manifestInfo,
+		Libraries:             libraries,
+		ReleaseDate:           fileInfo.ModTime(),
 	}
 
-	// Add icon data if extraction was successful
-	if iconErr == nil && iconData != nil {
-		info.IconData = iconData
-		info.IconExt = iconExt
-	}
+	// The icon is only read and rendered when it is used
+	info.Icon = lazyIcon(apkPath)
 
 	// Calculate relative path if within work directory
 	relPath, err := filepath.Rel(p.workDir, apkPath)
@@ -112,3 +119,12 @@ func (p *AAPTParserWrapper) calculateHashes(filePath string) (map[string]string,
 	parser := NewAndroidBinaryParser(p.workDir)
 	return parser.calculateHashes(filePath)
 }
+
+// appNames returns the default and localized application labels aapt reported
+func (p *AAPTParserWrapper) appNames(basicInfo *APKBasicInfo) map[string]string {
+	names := map[string]string{"default": basicInfo.AppName}
+	for locale, name := range basicInfo.AppNames {
+		names[locale] = name
+	}
+	return names
+}
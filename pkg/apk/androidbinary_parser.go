@@ -52,39 +52,50 @@ func (p *AndroidBinaryParser) ParseAPK(apkPath string) (*APKInfo, error) {
 	}
 
 	// Extract signature info
-	signatureInfo, err := p.extractSignatureInfo(pkg)
+	signatureInfo, err := p.extractSignatureInfo(apkPath)
 	if err != nil {
 		// Non-fatal error, continue without signature info
 		signatureInfo = nil
 	}
 
-	// Extract icon
-	iconExtractor := NewIconExtractor()
-	iconData, iconExt, iconErr := iconExtractor.ExtractIcon(apkPath)
-	// Icon extraction is non-fatal
+	// Verify signature schemes
+	signatureVerification, err := VerifySignatures(apkPath)
+	if err != nil {
+		// Non-fatal error, continue without verification result
+		signatureVerification = nil
+	}
+
+	// Components and application flags are non-fatal
+	manifestInfo, err := ReadManifestInfo(apkPath)
+	if err != nil {
+		manifestInfo = nil
+	}
+
+	// SDK detection is non-fatal, the APK may hold no code
+	libraries, _ := DetectLibraries(apkPath)
 
 	// Build APK info
 	info := &APKInfo{
-		PackageID:     manifest.Package.MustString(),
-		AppName:       p.extractAppName(&manifest),
-		Version:       manifest.VersionName.MustString(),
-		VersionCode:   int64(manifest.VersionCode.MustInt32()),
-		MinSDK:        p.extractMinSDK(&manifest),
-		TargetSDK:     p.extractTargetSDK(&manifest),
-		Size:          fileInfo.Size(),
-		SHA256:        hashes["sha256"],
-		SignatureInfo: signatureInfo,
-		Permissions:   p.extractPermissions(&manifest),
-		Features:      p.extractFeatures(&manifest),
-		ABIs:          p.extractABIs(apkPath),
-		ReleaseDate:   fileInfo.ModTime(),
-	}
-
-	// Add icon data if extraction was successful
-	if iconErr == nil && iconData != nil {
-		info.IconData = iconData
-		info.IconExt = iconExt
-	}
+		PackageID:             manifest.Package.MustString(),
+		AppName:               p.extractAppName(&manifest, apkPath),
+		Version:               manifest.VersionName.MustString(),
+		VersionCode:           int64(manifest.VersionCode.MustInt32()),
+		MinSDK:                p.extractMinSDK(&manifest),
+		TargetSDK:             p.extractTargetSDK(&manifest),
+		Size:                  fileInfo.Size(),
+		SHA256:                hashes["sha256"],
+		SignatureInfo:         signatureInfo,
+		SignatureVerification: signatureVerification,
+		Permissions:           p.extractPermissions(&manifest),
+		Features:              p.extractFeatures(&manifest),
+		ABIs:                  p.extractABIs(apkPath),
+		Manifest:              manifestInfo,
+		Libraries:             libraries,
+		ReleaseDate:           fileInfo.ModTime(),
+	}
+
+	// The icon is only read and rendered when it is used
+	info.Icon = lazyIcon(apkPath)
 
 	// Calculate relative path if within work directory
 	relPath, err := filepath.Rel(p.workDir, apkPath)
@@ -141,12 +152,22 @@ func (p *AndroidBinaryParser) calculateHashes(filePath string) (map[string]strin
 	}, nil
 }
 
-func (p *AndroidBinaryParser) extractAppName(manifest *apk.Manifest) map[string]string {
-	names := make(map[string]string)
+// extractAppName returns the application label in the default configuration
+// and in every locale resources.arsc translates it to
+func (p *AndroidBinaryParser) extractAppName(manifest *apk.Manifest, apkPath string) map[string]string {
+	// The manifest resolves a label reference in the most specific configuration,
+	// which is a translation whenever there are any, so the labels are read from
+	// every configuration of the resource table instead
+	names, err := ReadAppLabels(apkPath)
+	if err != nil {
+		names = make(map[string]string)
+	}
 
 	// Try to get the default name
-	if labelStr, err := manifest.App.Label.String(); err == nil && labelStr != "" {
-		names["default"] = labelStr
+	if names["default"] == "" {
+		if labelStr, err := manifest.App.Label.String(); err == nil && labelStr != "" {
+			names["default"] = labelStr
+		}
 	}
 
 	// If no label found, use package name as fallback
@@ -217,8 +238,6 @@ func (p *AndroidBinaryParser) extractABIs(apkPath string) []string {
 	return abis
 }
 
-func (p *AndroidBinaryParser) extractSignatureInfo(pkg *apk.Apk) (*models.SignatureInfo, error) {
-	// For now, return empty signature info
-	// TODO: Implement proper signature extraction
-	return &models.SignatureInfo{}, nil
+func (p *AndroidBinaryParser) extractSignatureInfo(apkPath string) (*models.SignatureInfo, error) {
+	return ExtractSignatureInfo(apkPath)
 }
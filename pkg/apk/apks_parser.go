@@ -0,0 +1,468 @@
+package apk
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// apkSetTOCPath is the table of contents bundletool writes into an APK set
+const apkSetTOCPath = "toc.pb"
+
+// abiAliases maps the Abi.AbiAlias values of bundletool targeting to ABI names
+var abiAliases = map[uint64]string{
+	1: "armeabi",
+	2: "armeabi-v7a",
+	3: "arm64-v8a",
+	4: "x86",
+	5: "x86_64",
+	6: "mips",
+	7: "mips64",
+	8: "riscv64",
+}
+
+// APKSParser parses APK sets (.apks), the archives `bundletool build-apks`
+// generates from an app bundle
+type APKSParser struct {
+	workDir string
+}
+
+// NewAPKSParser creates a new APK set parser
+func NewAPKSParser(workDir string) *APKSParser {
+	return &APKSParser{
+		workDir: workDir,
+	}
+}
+
+// ParseAPK parses an APK set from its base APK
+func (p *APKSParser) ParseAPK(apksPath string) (*APKInfo, error) {
+	fileInfo, err := os.Stat(apksPath)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := zip.OpenReader(apksPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open APK set (not a valid zip): %w", err)
+	}
+	defer reader.Close()
+
+	toc, err := readAPKSetTOC(&reader.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	basePath := toc.baseAPK()
+	if basePath == "" {
+		return nil, fmt.Errorf("no base APK found in APK set")
+	}
+	baseFile := findZipFile(&reader.Reader, basePath)
+	if baseFile == nil {
+		return nil, fmt.Errorf("APK set is missing %s", basePath)
+	}
+
+	tempFile, err := os.CreateTemp("", "apks_base_*.apk")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	if err := extractZipFile(baseFile, tempFile); err != nil {
+		return nil, fmt.Errorf("failed to extract base APK: %w", err)
+	}
+	tempFile.Close()
+
+	info, err := NewParser(p.workDir).ParseAPK(tempFile.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base APK: %w", err)
+	}
+	info.LoadIcon()
+
+	hash, err := hashFile(apksPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Describe the container rather than the extracted base APK
+	info.Size = fileInfo.Size()
+	info.SHA256 = hash
+	info.ReleaseDate = fileInfo.ModTime()
+	info.Features = append(info.Features, "apks")
+	if toc.hasSplits() {
+		info.Features = append(info.Features, "split_apk")
+	}
+	if abis := toc.abis(); len(abis) > 0 {
+		info.ABIs = abis
+	}
+
+	relPath, err := filepath.Rel(p.workDir, apksPath)
+	if err == nil && !strings.HasPrefix(relPath, "..") {
+		info.FilePath = relPath
+	} else {
+		info.FilePath = filepath.Base(apksPath)
+	}
+
+	return info, nil
+}
+
+// GetParserInfo returns information about this parser
+func (p *APKSParser) GetParserInfo() ParserInfo {
+	return ParserInfo{
+		Name:         "APKS",
+		Version:      "1.0",
+		Capabilities: []string{"apks", "split_apk", "manifest"},
+		Available:    true, // Always available
+		Priority:     3,
+	}
+}
+
+// CanParse checks if this parser can handle the given file
+func (p *APKSParser) CanParse(path string) bool {
+	return IsAPKSetFile(path)
+}
+
+// IsAPKSetFile checks if the file is an APK set generated by bundletool
+func IsAPKSetFile(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".apks"
+}
+
+// APKSetSelection lists the APKs of an APK set to install on a device
+type APKSetSelection struct {
+	PackageID  string
+	APKs       []string // Extracted APK paths
+	Standalone bool     // The APK is a standalone APK rather than splits
+}
+
+// ExtractAPKSet extracts the APKs of the variant of an APK set that matches a
+// device: the one with the highest minimum SDK the device runs, preferring the
+// device's primary ABI. Splits of on-demand modules are left out. An sdk of 0
+// or empty abis accept every variant.
+func ExtractAPKSet(apksPath, destDir string, sdk int, abis []string) (*APKSetSelection, error) {
+	reader, err := zip.OpenReader(apksPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open APK set: %w", err)
+	}
+	defer reader.Close()
+
+	toc, err := readAPKSetTOC(&reader.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := toc.selectVariant(sdk, abis)
+	if variant == nil {
+		deviceABIs := strings.Join(abis, ", ")
+		if deviceABIs == "" {
+			deviceABIs = "unknown"
+		}
+		return nil, fmt.Errorf("no variant in APK set supports this device (SDK %d, ABIs %s)", sdk, deviceABIs)
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	selection := &APKSetSelection{PackageID: toc.packageName}
+	for _, apkPath := range variant.installPaths() {
+		file := findZipFile(&reader.Reader, apkPath)
+		if file == nil {
+			return nil, fmt.Errorf("APK set is missing %s", apkPath)
+		}
+
+		// Entries are named by the toc, which must not point outside destDir.
+		// Zip names use forward slashes, so a backslash is refused on every OS.
+		if strings.Contains(apkPath, `\`) || !filepath.IsLocal(filepath.FromSlash(apkPath)) {
+			return nil, fmt.Errorf("invalid APK path in APK set: %s", apkPath)
+		}
+		destPath := filepath.Join(destDir, filepath.FromSlash(apkPath))
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return nil, err
+		}
+
+		outFile, err := os.Create(destPath)
+		if err != nil {
+			return nil, err
+		}
+		err = extractZipFile(file, outFile)
+		outFile.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", apkPath, err)
+		}
+
+		selection.APKs = append(selection.APKs, destPath)
+	}
+	selection.Standalone = variant.standalone
+
+	// Older bundletool versions do not record the package name in the toc
+	if selection.PackageID == "" {
+		for _, apkPath := range selection.APKs {
+			if info, err := ReadSplitInfo(apkPath); err == nil && info.PackageID != "" {
+				selection.PackageID = info.PackageID
+				break
+			}
+		}
+	}
+
+	return selection, nil
+}
+
+// apkSetTOC is the part of bundletool's BuildApksResult message needed to pick APKs
+type apkSetTOC struct {
+	packageName string
+	variants    []*apkSetVariant
+}
+
+// apkSetVariant is a set of APKs targeting one range of devices
+type apkSetVariant struct {
+	minSDK     int
+	abis       []string
+	standalone bool
+	modules    []*apkSetModule
+}
+
+// apkSetModule holds the APKs of one module of a variant
+type apkSetModule struct {
+	name     string
+	onDemand bool
+	apks     []apkSetAPK
+}
+
+// apkSetAPK describes one APK of a module
+type apkSetAPK struct {
+	path       string
+	master     bool
+	standalone bool
+	abis       []string
+}
+
+// readAPKSetTOC reads and decodes the toc.pb of an APK set (bundletool
+// commands.proto): BuildApksResult.variant = 1, package_name = 4
+func readAPKSetTOC(reader *zip.Reader) (*apkSetTOC, error) {
+	file := findZipFile(reader, apkSetTOCPath)
+	if file == nil {
+		return nil, fmt.Errorf("not an APK set: %s not found", apkSetTOCPath)
+	}
+	data, err := readZipFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parseProtoMessage(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", apkSetTOCPath, err)
+	}
+
+	toc := &apkSetTOC{packageName: result.string(4)}
+	for _, variant := range result.messages(1) {
+		toc.variants = append(toc.variants, decodeAPKSetVariant(variant))
+	}
+
+	return toc, nil
+}
+
+// decodeAPKSetVariant decodes a Variant message: targeting = 1, apk_set = 2
+func decodeAPKSetVariant(variant protoMessage) *apkSetVariant {
+	// VariantTargeting: sdk_version_targeting = 1, abi_targeting = 2
+	targeting := variant.message(1)
+	decoded := &apkSetVariant{
+		minSDK: targetedMinSDK(targeting.message(1)),
+		abis:   targetedABIs(targeting.message(2)),
+	}
+
+	// ApkSet: module_metadata = 1, apk_description = 2; ModuleMetadata: name = 1,
+	// on_demand_deprecated = 2, delivery_type = 6 (1 install-time, 2 on-demand,
+	// 3 fast-follow)
+	for _, apkSet := range variant.messages(2) {
+		metadata := apkSet.message(1)
+		deliveryType := metadata.uint(6)
+		module := &apkSetModule{
+			name:     metadata.string(1),
+			onDemand: metadata.bool(2) || deliveryType > 1,
+		}
+
+		// ApkDescription: targeting = 1 (ApkTargeting: abi_targeting = 1), path = 2,
+		// split_apk_metadata = 3 (is_master_split = 2),
+		// standalone_apk_metadata = 4
+		for _, description := range apkSet.messages(2) {
+			apk := apkSetAPK{
+				path:       description.string(2),
+				standalone: description.has(4),
+				abis:       targetedABIs(description.message(1).message(1)),
+			}
+			if description.has(3) {
+				apk.master = description.message(3).bool(2)
+			} else if !apk.standalone {
+				// Instant and system APKs are not installed from an APK set
+				continue
+			}
+			if apk.standalone {
+				decoded.standalone = true
+			}
+			module.apks = append(module.apks, apk)
+		}
+
+		decoded.modules = append(decoded.modules, module)
+	}
+
+	return decoded
+}
+
+// targetedMinSDK returns the minimum SDK of an SdkVersionTargeting message:
+// value = 1, SdkVersion.min = 1 (Int32Value.value = 1)
+func targetedMinSDK(targeting protoMessage) int {
+	minSDK := 0
+	for _, version := range targeting.messages(1) {
+		if value := int(version.message(1).uint(1)); value > minSDK {
+			minSDK = value
+		}
+	}
+	return minSDK
+}
+
+// targetedABIs returns the ABIs of an AbiTargeting message: value = 1, Abi.alias = 1
+func targetedABIs(targeting protoMessage) []string {
+	var abis []string
+	for _, abi := range targeting.messages(1) {
+		if name := abiAliases[abi.uint(1)]; name != "" {
+			abis = append(abis, name)
+		}
+	}
+	return abis
+}
+
+// baseAPK returns the path of the APK that describes the app: the master split
+// of the base module, or else a standalone APK
+func (t *apkSetTOC) baseAPK() string {
+	standalone := ""
+	for _, variant := range t.variants {
+		for _, module := range variant.modules {
+			for _, apk := range module.apks {
+				if apk.master && (module.name == "base" || module.name == "") {
+					return apk.path
+				}
+				if apk.standalone && standalone == "" {
+					standalone = apk.path
+				}
+			}
+		}
+	}
+	return standalone
+}
+
+// hasSplits reports whether any variant installs split APKs
+func (t *apkSetTOC) hasSplits() bool {
+	for _, variant := range t.variants {
+		if !variant.standalone && len(variant.modules) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// abis returns every ABI targeted by the variants and APKs of the set
+func (t *apkSetTOC) abis() []string {
+	abiMap := make(map[string]bool)
+	for _, variant := range t.variants {
+		for _, abi := range variant.abis {
+			abiMap[abi] = true
+		}
+		for _, module := range variant.modules {
+			for _, apk := range module.apks {
+				for _, abi := range apk.abis {
+					abiMap[abi] = true
+				}
+			}
+		}
+	}
+
+	var abis []string
+	for abi := range abiMap {
+		abis = append(abis, abi)
+	}
+	sort.Strings(abis)
+
+	return abis
+}
+
+// selectVariant returns the variant to install on a device, or nil if none
+// supports it
+func (t *apkSetTOC) selectVariant(sdk int, abis []string) *apkSetVariant {
+	var best *apkSetVariant
+	bestRank := 0
+
+	for _, variant := range t.variants {
+		if sdk > 0 && variant.minSDK > sdk {
+			continue
+		}
+		rank := abiRank(variant.abis, abis)
+		if rank < 0 {
+			continue
+		}
+
+		if best == nil || variant.minSDK > best.minSDK ||
+			(variant.minSDK == best.minSDK && rank < bestRank) {
+			best, bestRank = variant, rank
+		}
+	}
+
+	return best
+}
+
+// abiRank returns the position in the device ABIs of the best ABI a variant
+// targets, 0 for variants without ABI targeting, or -1 if the device supports
+// none of them
+func abiRank(targeted, deviceABIs []string) int {
+	if len(targeted) == 0 || len(deviceABIs) == 0 {
+		return 0
+	}
+
+	for rank, deviceABI := range deviceABIs {
+		for _, abi := range targeted {
+			if abi == deviceABI {
+				return rank
+			}
+		}
+	}
+	return -1
+}
+
+// installPaths returns the APKs of the variant installed with the app
+func (v *apkSetVariant) installPaths() []string {
+	var paths []string
+	for _, module := range v.modules {
+		if module.onDemand && module.name != "base" {
+			continue
+		}
+		for _, apk := range module.apks {
+			paths = append(paths, apk.path)
+		}
+	}
+	return paths
+}
+
+// findZipFile returns the entry of a zip archive with the given name, or nil
+func findZipFile(reader *zip.Reader, name string) *zip.File {
+	for _, file := range reader.File {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+// extractZipFile copies the content of a zip entry to w
+func extractZipFile(file *zip.File, w io.Writer) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	_, err = io.Copy(w, rc)
+	return err
+}
@@ -0,0 +1,245 @@
+package apk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Binary XML chunk types (ResourceTypes.h)
+const (
+	resXMLType           = 0x0003
+	resXMLStartElement   = 0x0102
+	resXMLEndElement     = 0x0103
+	resXMLNullStringPool = 0xFFFFFFFF
+)
+
+// Res_value data types
+const (
+	resValueNull       = 0x00
+	resValueReference  = 0x01
+	resValueString     = 0x03
+	resValueFloat      = 0x04
+	resValueDimension  = 0x05
+	resValueFraction   = 0x06
+	resValueDynamicRef = 0x07
+	resValueIntDec     = 0x10
+	resValueIntHex     = 0x11
+	resValueIntBoolean = 0x12
+	resValueFirstColor = 0x1C
+	resValueLastColor  = 0x1F
+)
+
+// xmlElement is an element of a manifest or drawable, decoded from binary XML
+// (APKs) or from the aapt2 protobuf format (app bundles)
+type xmlElement struct {
+	name     string
+	attrs    []*xmlAttr
+	children []*xmlElement
+}
+
+// xmlAttr is an attribute of an element
+type xmlAttr struct {
+	namespace string
+	name      string
+	value     string // Source text, "@0x..." for resource references in APKs
+	refID     uint32 // Resource the value references, if any
+	number    *int64 // Compiled integer or boolean value, if any
+	dataType  uint8  // Type of the compiled value, resValueNull if there is none
+	data      uint32 // Compiled value: color, float, dimension or fraction bits
+}
+
+// attr returns an attribute of the element, or nil
+func (e *xmlElement) attr(namespace, name string) *xmlAttr {
+	for _, attr := range e.attrs {
+		if attr.namespace == namespace && attr.name == name {
+			return attr
+		}
+	}
+	return nil
+}
+
+// child returns the first child element with the name, or nil
+func (e *xmlElement) child(name string) *xmlElement {
+	for _, child := range e.children {
+		if child.name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// text returns the attribute value as written in the source manifest
+func (a *xmlAttr) text() string {
+	if a == nil {
+		return ""
+	}
+	if a.value == "" && a.number != nil {
+		return strconv.FormatInt(*a.number, 10)
+	}
+	return a.value
+}
+
+// int returns the attribute value as a number, or 0
+func (a *xmlAttr) int() int64 {
+	if a == nil {
+		return 0
+	}
+	if a.number != nil {
+		return *a.number
+	}
+	value, _ := strconv.ParseInt(a.value, 10, 64)
+	return value
+}
+
+// float returns a float, dimension or fraction attribute as a number in its
+// own unit, or def if the attribute is missing or has another type
+func (a *xmlAttr) float(def float64) float64 {
+	if a == nil {
+		return def
+	}
+	switch a.dataType {
+	case resValueFloat:
+		return float64(math.Float32frombits(a.data))
+	case resValueDimension, resValueFraction:
+		return complexValue(a.data)
+	case resValueIntDec, resValueIntHex:
+		return float64(int32(a.data))
+	}
+	if value, err := strconv.ParseFloat(a.value, 64); err == nil {
+		return value
+	}
+	return def
+}
+
+// complexValue decodes the number of a dimension or fraction: a signed 24-bit
+// mantissa with a radix selecting where its binary point is
+func complexValue(data uint32) float64 {
+	radixShifts := [4]uint{0, 7, 15, 23}
+	return float64(int32(data&0xFFFFFF00)) / 256 / float64(uint32(1)<<radixShifts[(data>>4)&3])
+}
+
+// parseBinaryXML decodes a binary XML document, such as the AndroidManifest.xml
+// or a drawable of an APK. Typed values are kept, unlike in androidbinary,
+// which prints colors and dimensions like references.
+func parseBinaryXML(data []byte) (*xmlElement, error) {
+	chunkType, headerSize, size, ok := readChunkHeader(data, 0)
+	if !ok || chunkType != resXMLType {
+		return nil, fmt.Errorf("not a binary XML document")
+	}
+
+	var pool []string
+	var root *xmlElement
+	var stack []*xmlElement
+	for offset := int(headerSize); offset < int(size); {
+		chunkType, chunkHeaderSize, chunkSize, ok := readChunkHeader(data, offset)
+		if !ok || chunkSize == 0 {
+			return nil, fmt.Errorf("invalid chunk at offset %d", offset)
+		}
+		chunk := data[offset : offset+int(chunkSize)]
+
+		switch chunkType {
+		case resStringPoolType:
+			var err error
+			if pool, err = parseStringPool(chunk); err != nil {
+				return nil, err
+			}
+		case resXMLStartElement:
+			element, err := decodeXMLStartElement(chunk, int(chunkHeaderSize), pool)
+			if err != nil {
+				return nil, err
+			}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, fmt.Errorf("document has several root elements")
+				}
+				root = element
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, element)
+			}
+			stack = append(stack, element)
+		case resXMLEndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+
+		offset += int(chunkSize)
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("document has no root element")
+	}
+	return root, nil
+}
+
+// decodeXMLStartElement decodes a ResXMLTree_attrExt chunk: ns (4), name (4),
+// attributeStart (2), attributeSize (2), attributeCount (2), then the
+// attributes: ns (4), name (4), rawValue (4) and a Res_value
+func decodeXMLStartElement(chunk []byte, headerSize int, pool []string) (*xmlElement, error) {
+	if headerSize+20 > len(chunk) {
+		return nil, fmt.Errorf("truncated start element")
+	}
+	ext := chunk[headerSize:]
+	element := &xmlElement{name: poolString(pool, binary.LittleEndian.Uint32(ext[4:]))}
+
+	attrStart := int(binary.LittleEndian.Uint16(ext[8:]))
+	attrSize := int(binary.LittleEndian.Uint16(ext[10:]))
+	attrCount := int(binary.LittleEndian.Uint16(ext[12:]))
+	if attrSize < 20 {
+		attrSize = 20
+	}
+
+	for i := 0; i < attrCount; i++ {
+		offset := attrStart + i*attrSize
+		if offset+20 > len(ext) {
+			return nil, fmt.Errorf("truncated attribute of <%s>", element.name)
+		}
+		raw := ext[offset:]
+
+		attr := &xmlAttr{
+			namespace: poolString(pool, binary.LittleEndian.Uint32(raw)),
+			name:      poolString(pool, binary.LittleEndian.Uint32(raw[4:])),
+			dataType:  raw[15],
+			data:      binary.LittleEndian.Uint32(raw[16:]),
+		}
+		if rawValue := binary.LittleEndian.Uint32(raw[8:]); rawValue != resXMLNullStringPool {
+			attr.value = poolString(pool, rawValue)
+		}
+
+		switch attr.dataType {
+		case resValueReference, resValueDynamicRef:
+			attr.refID = attr.data
+			if attr.value == "" {
+				attr.value = fmt.Sprintf("@0x%08X", attr.data)
+			}
+		case resValueString:
+			if attr.value == "" {
+				attr.value = poolString(pool, attr.data)
+			}
+		case resValueIntDec, resValueIntHex:
+			number := int64(int32(attr.data))
+			attr.number = &number
+		case resValueIntBoolean:
+			if attr.data != 0 {
+				attr.value = "true"
+			} else {
+				attr.value = "false"
+			}
+		}
+
+		element.attrs = append(element.attrs, attr)
+	}
+
+	return element, nil
+}
+
+// poolString returns a string of the pool, or "" for a missing index
+func poolString(pool []string, index uint32) string {
+	if int64(index) >= int64(len(pool)) {
+		return ""
+	}
+	return pool[index]
+}
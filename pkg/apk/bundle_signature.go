@@ -0,0 +1,102 @@
+package apk
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+// SplitVerification is the signature check of one APK inside a bundle
+type SplitVerification struct {
+	Name         string // Entry name inside the bundle
+	Base         bool
+	Signer       *models.SignatureInfo // nil if the signer cannot be read
+	Verification *models.SignatureVerification
+}
+
+// VerifyBundleSignatures verifies the signatures of every APK inside an
+// XAPK/APKM bundle or APK set. Base APKs come first. The bundle itself is not
+// signed, so each APK is extracted and checked on its own.
+func VerifyBundleSignatures(bundlePath string) ([]SplitVerification, error) {
+	reader, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer reader.Close()
+
+	tempDir, err := os.MkdirTemp("", "apkhub-verify-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var splits []SplitVerification
+	for i, file := range reader.File {
+		if file.FileInfo().IsDir() || strings.ToLower(path.Ext(file.Name)) != ".apk" {
+			continue
+		}
+
+		localPath := filepath.Join(tempDir, fmt.Sprintf("%d.apk", i))
+		if err := extractZipEntry(file, localPath); err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", file.Name, err)
+		}
+
+		verification, err := VerifySignatures(localPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Name, err)
+		}
+
+		split := SplitVerification{Name: file.Name, Verification: verification}
+		if signer, err := ExtractSignatureInfo(localPath); err == nil {
+			split.Signer = signer
+		}
+		if info, err := ReadSplitInfo(localPath); err == nil {
+			split.Base = info.IsBase()
+		} else {
+			split.Base = SplitInfoFromFileName(file.Name).IsBase()
+		}
+		splits = append(splits, split)
+
+		os.Remove(localPath)
+	}
+
+	if len(splits) == 0 {
+		return nil, fmt.Errorf("no APKs found in %s", filepath.Base(bundlePath))
+	}
+
+	sort.SliceStable(splits, func(i, j int) bool {
+		return splits[i].Base && !splits[j].Base
+	})
+	if !splits[0].Base {
+		return nil, fmt.Errorf("no base APK found in %s", filepath.Base(bundlePath))
+	}
+
+	return splits, nil
+}
+
+// extractZipEntry writes a ZIP entry to a file
+func extractZipEntry(file *zip.File, destPath string) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,132 @@
+package apk
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// dexHeaderSize is the size of the header of a DEX file
+const dexHeaderSize = 0x70
+
+// ReadDexClasses lists the classes defined in the DEX files of an APK
+// (classes.dex, classes2.dex, ...) or of the modules of an app bundle
+// (<module>/dex/classes.dex), as dotted names such as "com.example.Main"
+func ReadDexClasses(apkPath string) ([]string, error) {
+	reader, err := zip.OpenReader(apkPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var classes []string
+	found := false
+	for _, file := range reader.File {
+		if !isDexEntry(file.Name) {
+			continue
+		}
+		found = true
+
+		data, err := readZipFile(file)
+		if err != nil {
+			return nil, err
+		}
+		names, err := dexClassNames(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		classes = append(classes, names...)
+	}
+	if !found {
+		return nil, fmt.Errorf("no DEX files found")
+	}
+
+	sort.Strings(classes)
+	return classes, nil
+}
+
+// isDexEntry reports whether an archive entry is a DEX file of the app
+func isDexEntry(name string) bool {
+	dir, file := path.Split(name)
+	if !strings.HasPrefix(file, "classes") || !strings.HasSuffix(file, ".dex") {
+		return false
+	}
+	// The index between "classes" and ".dex" is empty or a number
+	for _, c := range strings.TrimSuffix(strings.TrimPrefix(file, "classes"), ".dex") {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return dir == "" || strings.HasSuffix(dir, "/dex/")
+}
+
+// dexClassNames reads the names of the classes a DEX file defines: each
+// class_def_item (32 bytes) starts with the index of its type_id, which holds
+// the index of the string_id of the type descriptor ("Lcom/example/Main;")
+func dexClassNames(data []byte) ([]string, error) {
+	if len(data) < dexHeaderSize || !bytes.HasPrefix(data, []byte("dex\n")) {
+		return nil, fmt.Errorf("not a DEX file")
+	}
+	u32 := func(offset int) int {
+		return int(binary.LittleEndian.Uint32(data[offset:]))
+	}
+	stringIDsSize, stringIDsOff := u32(0x38), u32(0x3C)
+	typeIDsSize, typeIDsOff := u32(0x40), u32(0x44)
+	classDefsSize, classDefsOff := u32(0x60), u32(0x64)
+
+	inBounds := func(offset, count, size int) bool {
+		return offset >= 0 && count >= 0 && count <= len(data)/size && offset <= len(data)-count*size
+	}
+	if !inBounds(stringIDsOff, stringIDsSize, 4) || !inBounds(typeIDsOff, typeIDsSize, 4) || !inBounds(classDefsOff, classDefsSize, 32) {
+		return nil, fmt.Errorf("truncated DEX file")
+	}
+
+	names := make([]string, 0, classDefsSize)
+	for i := 0; i < classDefsSize; i++ {
+		typeIndex := u32(classDefsOff + 32*i)
+		if typeIndex >= typeIDsSize {
+			continue
+		}
+		stringIndex := u32(typeIDsOff + 4*typeIndex)
+		if stringIndex >= stringIDsSize {
+			continue
+		}
+		descriptor, ok := dexString(data, u32(stringIDsOff+4*stringIndex))
+		if !ok {
+			continue
+		}
+		if name, ok := strings.CutPrefix(descriptor, "L"); ok {
+			names = append(names, strings.ReplaceAll(strings.TrimSuffix(name, ";"), "/", "."))
+		}
+	}
+
+	return names, nil
+}
+
+// dexString reads a string_data_item: the length in UTF-16 code units as a
+// ULEB128, then MUTF-8 bytes up to a NUL. Type descriptors are ASCII in
+// practice, so the bytes are taken as they are.
+func dexString(data []byte, offset int) (string, bool) {
+	if offset < 0 || offset >= len(data) {
+		return "", false
+	}
+	for i := 0; i < 5; i++ {
+		if offset >= len(data) {
+			return "", false
+		}
+		more := data[offset]&0x80 != 0
+		offset++
+		if !more {
+			break
+		}
+	}
+	end := bytes.IndexByte(data[offset:], 0)
+	if end < 0 {
+		return "", false
+	}
+	return string(data[offset : offset+end]), true
+}
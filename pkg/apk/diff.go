@@ -0,0 +1,377 @@
+package apk
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+// APKDiff lists the changes from an old APK to a new one
+type APKDiff struct {
+	Old                DiffTarget      `json:"old"`
+	New                DiffTarget      `json:"new"`
+	Size               SizeChange      `json:"size"` // Size of the files
+	Permissions        ListChange      `json:"permissions"`
+	MinSDK             *IntChange      `json:"min_sdk,omitempty"` // nil when unchanged
+	TargetSDK          *IntChange      `json:"target_sdk,omitempty"`
+	ABIs               ListChange      `json:"abis"`
+	Components         ComponentChange `json:"components"`
+	ExportedComponents ComponentChange `json:"exported_components"`
+	Libraries          ListChange      `json:"libraries"`        // SDKs detected in the code
+	Signer             *SignerChange   `json:"signer,omitempty"` // nil when unchanged
+	NativeLibraries    SizeChange      `json:"native_libraries"` // Uncompressed size of the .so files
+	Dex                SizeChange      `json:"dex"`              // Uncompressed size of the DEX files
+	Files              []FileChange    `json:"files,omitempty"`  // Largest changes first
+}
+
+// DiffTarget identifies one side of a diff
+type DiffTarget struct {
+	Path        string `json:"path"`
+	PackageID   string `json:"package_id"`
+	Version     string `json:"version"`
+	VersionCode int64  `json:"version_code"`
+	SHA256      string `json:"sha256"`
+}
+
+// ListChange lists the values added and removed, sorted
+type ListChange struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Empty reports whether nothing was added or removed
+func (c ListChange) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
+}
+
+// ComponentRef names a component of a given kind: activity, service,
+// receiver or provider
+type ComponentRef struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// ComponentChange lists the components added and removed, sorted by name
+type ComponentChange struct {
+	Added   []ComponentRef `json:"added,omitempty"`
+	Removed []ComponentRef `json:"removed,omitempty"`
+}
+
+// Empty reports whether nothing was added or removed
+func (c ComponentChange) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
+}
+
+// IntChange is a number that changed
+type IntChange struct {
+	Old int `json:"old"`
+	New int `json:"new"`
+}
+
+// SignerChange is a change of the signing certificate
+type SignerChange struct {
+	Old     string `json:"old"` // SHA256 of the certificate
+	New     string `json:"new"`
+	Rotated bool   `json:"rotated"` // The v3 lineage of the new APK includes the old certificate
+}
+
+// SizeChange compares two sizes in bytes
+type SizeChange struct {
+	Old   int64 `json:"old"`
+	New   int64 `json:"new"`
+	Delta int64 `json:"delta"`
+}
+
+// FileChange is an entry of the archive that was added, removed or changed
+type FileChange struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"` // added, removed or changed
+	OldSize int64  `json:"old_size"`
+	NewSize int64  `json:"new_size"`
+	Delta   int64  `json:"delta"`
+}
+
+// File change statuses
+const (
+	FileAdded   = "added"
+	FileRemoved = "removed"
+	FileChanged = "changed"
+)
+
+// Changed reports whether the diff found any difference
+func (d *APKDiff) Changed() bool {
+	return d.Old.SHA256 != d.New.SHA256 || d.Size.Delta != 0 || len(d.Files) > 0 ||
+		!d.Permissions.Empty() || d.MinSDK != nil || d.TargetSDK != nil || !d.ABIs.Empty() ||
+		!d.Components.Empty() || !d.ExportedComponents.Empty() || !d.Libraries.Empty() || d.Signer != nil
+}
+
+// DiffAPKs compares two parsed APKs. The files at oldPath and newPath are read
+// for the sizes of their entries; topFiles limits the changed entries reported
+// (0 reports all of them).
+func DiffAPKs(oldInfo *APKInfo, oldPath string, newInfo *APKInfo, newPath string, topFiles int) (*APKDiff, error) {
+	oldEntries, err := readZipEntries(oldPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", oldPath, err)
+	}
+	newEntries, err := readZipEntries(newPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", newPath, err)
+	}
+
+	diff := &APKDiff{
+		Old:                diffTarget(oldInfo, oldPath),
+		New:                diffTarget(newInfo, newPath),
+		Size:               sizeChange(oldInfo.Size, newInfo.Size),
+		Permissions:        diffLists(oldInfo.Permissions, newInfo.Permissions),
+		ABIs:               diffLists(oldInfo.ABIs, newInfo.ABIs),
+		Components:         diffComponents(manifestComponents(oldInfo.Manifest, false), manifestComponents(newInfo.Manifest, false)),
+		ExportedComponents: diffComponents(manifestComponents(oldInfo.Manifest, true), manifestComponents(newInfo.Manifest, true)),
+		Libraries:          diffLists(libraryNames(oldInfo.Libraries), libraryNames(newInfo.Libraries)),
+		Signer:             diffSigners(oldInfo, newInfo),
+		NativeLibraries:    sizeChange(oldEntries.total(isNativeLibrary), newEntries.total(isNativeLibrary)),
+		Dex:                sizeChange(oldEntries.total(isDexEntry), newEntries.total(isDexEntry)),
+		Files:              diffEntries(oldEntries, newEntries),
+	}
+	if oldInfo.MinSDK != newInfo.MinSDK {
+		diff.MinSDK = &IntChange{Old: oldInfo.MinSDK, New: newInfo.MinSDK}
+	}
+	if oldInfo.TargetSDK != newInfo.TargetSDK {
+		diff.TargetSDK = &IntChange{Old: oldInfo.TargetSDK, New: newInfo.TargetSDK}
+	}
+	if topFiles > 0 && len(diff.Files) > topFiles {
+		diff.Files = diff.Files[:topFiles]
+	}
+
+	return diff, nil
+}
+
+// diffTarget describes a parsed APK
+func diffTarget(info *APKInfo, apkPath string) DiffTarget {
+	return DiffTarget{
+		Path:        apkPath,
+		PackageID:   info.PackageID,
+		Version:     info.Version,
+		VersionCode: info.VersionCode,
+		SHA256:      info.SHA256,
+	}
+}
+
+// sizeChange compares two sizes
+func sizeChange(oldSize, newSize int64) SizeChange {
+	return SizeChange{Old: oldSize, New: newSize, Delta: newSize - oldSize}
+}
+
+// diffLists returns the values only in newValues as added and those only in
+// oldValues as removed
+func diffLists(oldValues, newValues []string) ListChange {
+	oldSet := make(map[string]bool, len(oldValues))
+	for _, value := range oldValues {
+		oldSet[value] = true
+	}
+	newSet := make(map[string]bool, len(newValues))
+	for _, value := range newValues {
+		newSet[value] = true
+	}
+
+	var change ListChange
+	for value := range newSet {
+		if !oldSet[value] {
+			change.Added = append(change.Added, value)
+		}
+	}
+	for value := range oldSet {
+		if !newSet[value] {
+			change.Removed = append(change.Removed, value)
+		}
+	}
+	sort.Strings(change.Added)
+	sort.Strings(change.Removed)
+	return change
+}
+
+// manifestComponents lists the components of a manifest, or only the exported
+// ones
+func manifestComponents(manifest *models.ManifestInfo, exportedOnly bool) []ComponentRef {
+	if manifest == nil {
+		return nil
+	}
+
+	var refs []ComponentRef
+	groups := []struct {
+		kind       string
+		components []models.Component
+	}{
+		{"activity", manifest.Activities},
+		{"service", manifest.Services},
+		{"receiver", manifest.Receivers},
+		{"provider", manifest.Providers},
+	}
+	for _, group := range groups {
+		for _, component := range group.components {
+			if exportedOnly && !component.Exported {
+				continue
+			}
+			refs = append(refs, ComponentRef{Kind: group.kind, Name: component.Name})
+		}
+	}
+	return refs
+}
+
+// diffComponents compares two lists of components
+func diffComponents(oldRefs, newRefs []ComponentRef) ComponentChange {
+	oldSet := make(map[ComponentRef]bool, len(oldRefs))
+	for _, ref := range oldRefs {
+		oldSet[ref] = true
+	}
+	newSet := make(map[ComponentRef]bool, len(newRefs))
+	for _, ref := range newRefs {
+		newSet[ref] = true
+	}
+
+	var change ComponentChange
+	for ref := range newSet {
+		if !oldSet[ref] {
+			change.Added = append(change.Added, ref)
+		}
+	}
+	for ref := range oldSet {
+		if !newSet[ref] {
+			change.Removed = append(change.Removed, ref)
+		}
+	}
+	sortComponentRefs(change.Added)
+	sortComponentRefs(change.Removed)
+	return change
+}
+
+// sortComponentRefs sorts components by name, then kind
+func sortComponentRefs(refs []ComponentRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Name != refs[j].Name {
+			return refs[i].Name < refs[j].Name
+		}
+		return refs[i].Kind < refs[j].Kind
+	})
+}
+
+// libraryNames returns the names of detected SDKs
+func libraryNames(libraries []models.Library) []string {
+	names := make([]string, len(libraries))
+	for i, library := range libraries {
+		names[i] = library.Name
+	}
+	return names
+}
+
+// diffSigners compares the signing certificates; APKs of which either signer
+// is unknown are not compared
+func diffSigners(oldInfo, newInfo *APKInfo) *SignerChange {
+	if oldInfo.SignatureInfo == nil || newInfo.SignatureInfo == nil {
+		return nil
+	}
+	oldSigner, newSigner := oldInfo.SignatureInfo.SHA256, newInfo.SignatureInfo.SHA256
+	if oldSigner == "" || newSigner == "" || strings.EqualFold(oldSigner, newSigner) {
+		return nil
+	}
+
+	change := &SignerChange{Old: oldSigner, New: newSigner}
+	if verification := newInfo.SignatureVerification; verification != nil {
+		for _, signer := range verification.Lineage {
+			if strings.EqualFold(signer, oldSigner) {
+				change.Rotated = true
+			}
+		}
+	}
+	return change
+}
+
+// zipEntry is the size and checksum of an archive entry
+type zipEntry struct {
+	size  int64
+	crc32 uint32
+}
+
+// zipEntries maps the names of the entries of an archive to their details
+type zipEntries map[string]zipEntry
+
+// readZipEntries lists the entries of an archive
+func readZipEntries(archivePath string) (zipEntries, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	entries := make(zipEntries, len(reader.File))
+	for _, file := range reader.File {
+		if strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+		entries[file.Name] = zipEntry{size: int64(file.UncompressedSize64), crc32: file.CRC32}
+	}
+	return entries, nil
+}
+
+// total sums the sizes of the entries whose name matches
+func (e zipEntries) total(matches func(name string) bool) int64 {
+	var total int64
+	for name, entry := range e {
+		if matches(name) {
+			total += entry.size
+		}
+	}
+	return total
+}
+
+// isNativeLibrary reports whether an archive entry is a native library, in the
+// lib/ directory of an APK or of a module of an app bundle
+func isNativeLibrary(name string) bool {
+	return path.Ext(name) == ".so" && (strings.HasPrefix(name, "lib/") || strings.Contains(name, "/lib/"))
+}
+
+// diffEntries compares the entries of two archives, the largest size changes
+// first
+func diffEntries(oldEntries, newEntries zipEntries) []FileChange {
+	var changes []FileChange
+	for name, newEntry := range newEntries {
+		oldEntry, exists := oldEntries[name]
+		switch {
+		case !exists:
+			changes = append(changes, FileChange{Name: name, Status: FileAdded, NewSize: newEntry.size, Delta: newEntry.size})
+		case oldEntry != newEntry:
+			changes = append(changes, FileChange{
+				Name:    name,
+				Status:  FileChanged,
+				OldSize: oldEntry.size,
+				NewSize: newEntry.size,
+				Delta:   newEntry.size - oldEntry.size,
+			})
+		}
+	}
+	for name, oldEntry := range oldEntries {
+		if _, exists := newEntries[name]; !exists {
+			changes = append(changes, FileChange{Name: name, Status: FileRemoved, OldSize: oldEntry.size, Delta: -oldEntry.size})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := abs(changes[i].Delta), abs(changes[j].Delta)
+		if a != b {
+			return a > b
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// abs returns the absolute value of n
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
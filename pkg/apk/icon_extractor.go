@@ -5,21 +5,142 @@ import (
 	"bytes"
 	"fmt"
 	"image"
+	"image/draw"
 	"image/png"
-	"io"
+	"math"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/nfnt/resize"
-	"github.com/shogo82148/androidbinary/apk"
-	"golang.org/x/image/webp"
 )
 
 const (
 	// Standard icon size for ApkHub
 	StandardIconSize = 144
+
+	// iconRenderSize is the size vector and adaptive icons are rendered at
+	// before they are scaled down
+	iconRenderSize = 512
 )
 
+// IconMask is the shape adaptive icons are cut to. Legacy icons bring their
+// own shape and are never masked.
+type IconMask string
+
+const (
+	IconMaskCircle        IconMask = "circle"
+	IconMaskSquircle      IconMask = "squircle"
+	IconMaskRoundedSquare IconMask = "rounded-square"
+	IconMaskNone          IconMask = "none" // The full square
+
+	// DefaultIconMask is the mask used when none is configured
+	DefaultIconMask = IconMaskCircle
+)
+
+// IconMasks lists the supported masks
+var IconMasks = []IconMask{IconMaskCircle, IconMaskSquircle, IconMaskRoundedSquare, IconMaskNone}
+
+// ParseIconMask parses a mask name; "" selects the default mask
+func ParseIconMask(name string) (IconMask, error) {
+	name = strings.NewReplacer("_", "-", " ", "-").Replace(strings.ToLower(strings.TrimSpace(name)))
+	if name == "" {
+		return DefaultIconMask, nil
+	}
+	for _, mask := range IconMasks {
+		if IconMask(name) == mask {
+			return mask, nil
+		}
+	}
+	return "", fmt.Errorf("unknown icon mask %q (supported: circle, squircle, rounded-square, none)", name)
+}
+
+// Icon is a rendered launcher icon, not yet scaled or masked. The icon of an
+// APK file is only read and rendered when first used, so the file must stay in
+// place until then or the icon be loaded before.
+type Icon struct {
+	apkPath  string // APK the icon is read from, "" if it was rendered already
+	once     sync.Once
+	err      error
+	image    image.Image
+	adaptive bool
+}
+
+// lazyIcon returns the launcher icon of an APK file, read when first used
+func lazyIcon(apkPath string) *Icon {
+	return &Icon{apkPath: apkPath}
+}
+
+// Load reads and renders the icon unless that was done already. It fails when
+// the APK has no launcher icon.
+func (i *Icon) Load() error {
+	i.once.Do(func() {
+		if i.apkPath == "" {
+			return
+		}
+		icon, err := NewIconExtractor().ReadIcon(i.apkPath)
+		if err != nil {
+			i.err = err
+			return
+		}
+		i.image, i.adaptive = icon.image, icon.adaptive
+	})
+	return i.err
+}
+
+// Adaptive reports whether the icon is an adaptive icon, which is full-bleed
+// and takes the shape of the mask
+func (i *Icon) Adaptive() bool {
+	return i.Load() == nil && i.adaptive
+}
+
+// PNG scales the icon to size x size pixels, applies the mask to adaptive
+// icons and encodes the result
+func (i *Icon) PNG(size int, mask IconMask) ([]byte, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid icon size %d", size)
+	}
+	if err := i.Load(); err != nil {
+		return nil, err
+	}
+
+	resized := resize.Resize(uint(size), uint(size), i.image, resize.Lanczos3)
+	canvas := image.NewRGBA(image.Rect(0, 0, size, size))
+	if shape, ok := maskPolygon(mask, float64(size)); ok && i.adaptive {
+		coverage := rasterize(size, size, []polyline{shape}, false)
+		draw.DrawMask(canvas, canvas.Bounds(), resized, resized.Bounds().Min, coverage, image.Point{}, draw.Src)
+	} else {
+		draw.Draw(canvas, canvas.Bounds(), resized, resized.Bounds().Min, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// maskPolygon returns the outline of a mask at a size, or false for no mask
+func maskPolygon(mask IconMask, size float64) (polyline, bool) {
+	switch mask {
+	case IconMaskCircle:
+		return circlePolygon(point{size / 2, size / 2}, size/2), true
+	case IconMaskRoundedSquare:
+		return roundedRectPolygon(0, 0, size, size, size*0.22), true
+	case IconMaskSquircle:
+		// Superellipse |x|^4 + |y|^4 = r^4
+		points := make([]point, 256)
+		for i := range points {
+			sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(len(points)))
+			x := math.Copysign(math.Sqrt(math.Abs(cos)), cos)
+			y := math.Copysign(math.Sqrt(math.Abs(sin)), sin)
+			points[i] = point{size/2 + x*size/2, size/2 + y*size/2}
+		}
+		return polyline{points: points, closed: true}, true
+	}
+	return polyline{}, false
+}
+
 // IconExtractor handles icon extraction from APK files
 type IconExtractor struct {
 	targetSize uint
@@ -32,33 +153,122 @@ func NewIconExtractor() *IconExtractor {
 	}
 }
 
-// ExtractIcon extracts the app icon from an APK file
+// ExtractIcon extracts the app icon from an APK file as a PNG of the standard
+// size, with the default mask
 func (e *IconExtractor) ExtractIcon(apkPath string) ([]byte, string, error) {
-	// Try androidbinary library first
-	pkg, err := apk.OpenFile(apkPath)
-	if err == nil {
-		defer pkg.Close()
-		return e.extractIconFromPackage(pkg)
+	icon, err := e.ReadIcon(apkPath)
+	if err != nil {
+		return nil, "", err
 	}
 
-	// Fallback to direct zip extraction
-	return e.extractIconFromZip(apkPath)
-}
-
-// extractIconFromPackage extracts icon using androidbinary library
-func (e *IconExtractor) extractIconFromPackage(pkg *apk.Apk) ([]byte, string, error) {
-	// androidbinary library has limited API, fallback to zip extraction
-	return nil, "", fmt.Errorf("androidbinary icon extraction not implemented")
+	data, err := icon.PNG(int(e.targetSize), DefaultIconMask)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, ".png", nil
 }
 
-// extractIconFromZip extracts icon directly from APK zip
-func (e *IconExtractor) extractIconFromZip(apkPath string) ([]byte, string, error) {
+// ReadIcon reads the launcher icon of an APK. The android:icon of the
+// application is resolved through resources.arsc, rendering adaptive icons,
+// vector drawables and the drawables wrapping them; well-known ic_launcher
+// bitmaps are the fallback.
+func (e *IconExtractor) ReadIcon(apkPath string) (*Icon, error) {
 	reader, err := zip.OpenReader(apkPath)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to open APK: %w", err)
+		return nil, fmt.Errorf("failed to open APK: %w", err)
 	}
 	defer reader.Close()
 
+	icon, err := e.readResourceIcon(reader.File)
+	if err == nil {
+		return icon, nil
+	}
+
+	if icon, fallbackErr := e.readLauncherBitmap(reader.File); fallbackErr == nil {
+		return icon, nil
+	}
+	return nil, fmt.Errorf("no launcher icon found in APK: %w", err)
+}
+
+// readResourceIcon renders the resource android:icon refers to
+func (e *IconExtractor) readResourceIcon(files []*zip.File) (*Icon, error) {
+	var manifestData, tableData []byte
+	for _, file := range files {
+		var err error
+		switch file.Name {
+		case "AndroidManifest.xml":
+			manifestData, err = readZipFile(file)
+		case "resources.arsc":
+			tableData, err = readZipFile(file)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if manifestData == nil || tableData == nil {
+		return nil, fmt.Errorf("AndroidManifest.xml or resources.arsc not found")
+	}
+
+	manifest, err := parseBinaryXML(manifestData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AndroidManifest.xml: %w", err)
+	}
+	application := manifest.child("application")
+	if application == nil {
+		return nil, fmt.Errorf("manifest has no application")
+	}
+	iconAttr := application.attr(androidNamespace, "icon")
+	if iconAttr == nil || iconAttr.refID == 0 {
+		iconAttr = application.attr(androidNamespace, "roundIcon")
+	}
+	if iconAttr == nil || iconAttr.refID == 0 {
+		return nil, fmt.Errorf("application has no android:icon")
+	}
+
+	table, err := parseResourceTable(tableData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse resources.arsc: %w", err)
+	}
+	renderer := newDrawableRenderer(files, table)
+
+	value := renderer.resolveResource(iconAttr.refID)
+	switch ext := strings.ToLower(filepath.Ext(value.file)); {
+	case value.file != "" && ext != ".xml":
+		// Bitmaps are kept at their own resolution
+		bitmap, err := renderer.loadBitmap(value.file)
+		if err != nil {
+			return nil, err
+		}
+		return &Icon{image: bitmap}, nil
+
+	case ext == ".xml":
+		element, err := renderer.loadXML(value.file)
+		if err != nil {
+			return nil, err
+		}
+		if element.name == "adaptive-icon" {
+			rendered, err := renderer.renderAdaptive(element, iconRenderSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render %s: %w", value.file, err)
+			}
+			return &Icon{image: rendered, adaptive: true}, nil
+		}
+
+		// Legacy icons are 48dp
+		renderer.pixelsPerDp = iconRenderSize / 48.0
+		rendered, err := renderer.renderElement(element, iconRenderSize, iconRenderSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", value.file, err)
+		}
+		return &Icon{image: rendered}, nil
+	}
+
+	return nil, fmt.Errorf("icon resource 0x%08X has no drawable", iconAttr.refID)
+}
+
+// readLauncherBitmap looks for the ic_launcher bitmap of the highest density
+// at its usual paths
+func (e *IconExtractor) readLauncherBitmap(files []*zip.File) (*Icon, error) {
 	// Priority order for icon selection
 	iconPriorities := []string{
 		"res/mipmap-xxxhdpi/ic_launcher.png",
@@ -75,76 +285,44 @@ func (e *IconExtractor) extractIconFromZip(apkPath string) ([]byte, string, erro
 		"res/mipmap-hdpi/ic_launcher.webp",
 	}
 
+	entries := make(map[string]*zip.File, len(files))
+	for _, file := range files {
+		entries[file.Name] = file
+	}
+
 	// Try to find icon by priority
 	for _, iconPath := range iconPriorities {
-		for _, file := range reader.File {
-			if file.Name == iconPath {
-				rc, err := file.Open()
-				if err != nil {
-					continue
-				}
-				defer rc.Close()
-
-				iconData, err := io.ReadAll(rc)
-				if err != nil {
-					continue
-				}
-
-				return e.processIcon(iconData, filepath.Ext(iconPath))
+		if file, exists := entries[iconPath]; exists {
+			if icon, err := decodeIconFile(file); err == nil {
+				return icon, nil
 			}
 		}
 	}
 
 	// If no standard icon found, search for any launcher icon
-	for _, file := range reader.File {
+	for _, file := range files {
 		if strings.Contains(file.Name, "ic_launcher") &&
 			(strings.HasSuffix(file.Name, ".png") || strings.HasSuffix(file.Name, ".webp")) &&
 			!strings.Contains(file.Name, "_foreground") &&
 			!strings.Contains(file.Name, "_background") {
-			rc, err := file.Open()
-			if err != nil {
-				continue
-			}
-			defer rc.Close()
-
-			iconData, err := io.ReadAll(rc)
-			if err != nil {
-				continue
+			if icon, err := decodeIconFile(file); err == nil {
+				return icon, nil
 			}
-
-			return e.processIcon(iconData, filepath.Ext(file.Name))
 		}
 	}
 
-	return nil, "", fmt.Errorf("no launcher icon found in APK")
+	return nil, fmt.Errorf("no launcher icon found in APK")
 }
 
-// processIcon processes and resizes the icon
-func (e *IconExtractor) processIcon(iconData []byte, ext string) ([]byte, string, error) {
-	// Decode image
-	var img image.Image
-	var err error
-
-	if ext == ".webp" {
-		img, err = webp.Decode(bytes.NewReader(iconData))
-		if err != nil {
-			return nil, "", fmt.Errorf("failed to decode webp: %w", err)
-		}
-	} else {
-		img, _, err = image.Decode(bytes.NewReader(iconData))
-		if err != nil {
-			return nil, "", fmt.Errorf("failed to decode image: %w", err)
-		}
+// decodeIconFile decodes a bitmap icon of an archive
+func decodeIconFile(file *zip.File) (*Icon, error) {
+	data, err := readZipFile(file)
+	if err != nil {
+		return nil, err
 	}
-
-	// Resize to standard size
-	resized := resize.Resize(e.targetSize, e.targetSize, img, resize.Lanczos3)
-
-	// Encode as PNG (standardize to PNG format)
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, resized); err != nil {
-		return nil, "", fmt.Errorf("failed to encode PNG: %w", err)
+	bitmap, err := decodeBitmap(data, filepath.Ext(file.Name))
+	if err != nil {
+		return nil, err
 	}
-
-	return buf.Bytes(), ".png", nil
+	return &Icon{image: bitmap}, nil
 }
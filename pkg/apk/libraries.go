@@ -0,0 +1,98 @@
+package apk
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+// libraryRulesData is the built-in library signature database
+//
+//go:embed data/libraries.json
+var libraryRulesData []byte
+
+// LibraryRule detects an SDK from the classes of an APK
+type LibraryRule struct {
+	Name          string   `json:"name"`
+	Categories    []string `json:"categories"`
+	Website       string   `json:"website,omitempty"`
+	CodeSignature string   `json:"code_signature"` // Regular expression searched in class names, as in Exodus
+
+	pattern *regexp.Regexp
+}
+
+var (
+	libraryRules     []*LibraryRule
+	libraryRulesErr  error
+	libraryRulesOnce sync.Once
+)
+
+// LibraryRules returns the built-in library signatures
+func LibraryRules() ([]*LibraryRule, error) {
+	libraryRulesOnce.Do(func() {
+		libraryRules, libraryRulesErr = parseLibraryRules(libraryRulesData)
+	})
+	return libraryRules, libraryRulesErr
+}
+
+// parseLibraryRules decodes and compiles a library signature database
+func parseLibraryRules(data []byte) ([]*LibraryRule, error) {
+	var database struct {
+		Libraries []*LibraryRule `json:"libraries"`
+	}
+	if err := json.Unmarshal(data, &database); err != nil {
+		return nil, fmt.Errorf("invalid library rules: %w", err)
+	}
+
+	for _, rule := range database.Libraries {
+		if rule.CodeSignature == "" {
+			return nil, fmt.Errorf("library rule %q has no code signature", rule.Name)
+		}
+		// Classes are matched one per line, so anchors apply to a class name
+		pattern, err := regexp.Compile("(?m)" + rule.CodeSignature)
+		if err != nil {
+			return nil, fmt.Errorf("invalid code signature of %q: %w", rule.Name, err)
+		}
+		rule.pattern = pattern
+	}
+
+	return database.Libraries, nil
+}
+
+// DetectLibraries reads the classes of an APK or app bundle and matches them
+// against the built-in library signatures
+func DetectLibraries(apkPath string) ([]models.Library, error) {
+	classes, err := ReadDexClasses(apkPath)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := LibraryRules()
+	if err != nil {
+		return nil, err
+	}
+	return MatchLibraries(classes, rules), nil
+}
+
+// MatchLibraries returns the libraries whose signature matches any of the
+// class names, in the order of the rules
+func MatchLibraries(classes []string, rules []*LibraryRule) []models.Library {
+	// Searching all names at once is much faster than one search per class
+	corpus := strings.Join(classes, "\n")
+
+	var libraries []models.Library
+	for _, rule := range rules {
+		if rule.pattern != nil && rule.pattern.MatchString(corpus) {
+			libraries = append(libraries, models.Library{
+				Name:       rule.Name,
+				Categories: rule.Categories,
+				Website:    rule.Website,
+			})
+		}
+	}
+	return libraries
+}
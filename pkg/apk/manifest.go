@@ -0,0 +1,240 @@
+package apk
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+	"github.com/shogo82148/androidbinary"
+)
+
+// ReadManifestInfo reads the components and application flags from the
+// AndroidManifest.xml of an APK. References to resources are resolved through
+// resources.arsc when it can be read.
+func ReadManifestInfo(apkPath string) (*models.ManifestInfo, error) {
+	reader, err := zip.OpenReader(apkPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var manifestData, tableData []byte
+	for _, file := range reader.File {
+		switch file.Name {
+		case "AndroidManifest.xml":
+			if manifestData, err = readZipFile(file); err != nil {
+				return nil, err
+			}
+		case "resources.arsc":
+			tableData, _ = readZipFile(file)
+		}
+	}
+	if manifestData == nil {
+		return nil, fmt.Errorf("AndroidManifest.xml not found")
+	}
+
+	manifest, err := parseBinaryXML(manifestData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AndroidManifest.xml: %w", err)
+	}
+
+	var table *androidbinary.TableFile
+	if tableData != nil {
+		table, _ = androidbinary.NewTableFile(bytes.NewReader(tableData))
+	}
+
+	return buildManifestInfo(manifest, func(attr *xmlAttr) string {
+		if table == nil {
+			return ""
+		}
+		value, err := table.GetResource(androidbinary.ResID(attr.refID), &androidbinary.ResTableConfig{})
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}), nil
+}
+
+// manifestResolver returns the value of a resource an attribute references, or
+// "" if it cannot be resolved
+type manifestResolver func(attr *xmlAttr) string
+
+// manifestReader reads attribute values of a manifest, resolving references
+type manifestReader struct {
+	packageID string
+	resolve   manifestResolver
+}
+
+// value returns the value of an android: attribute
+func (r *manifestReader) value(element *xmlElement, name string) string {
+	attr := element.attr(androidNamespace, name)
+	if attr == nil {
+		return ""
+	}
+	if attr.refID != 0 {
+		return r.resolve(attr)
+	}
+	return attr.text()
+}
+
+// flag returns the value of a boolean android: attribute, or def when it is
+// not set
+func (r *manifestReader) flag(element *xmlElement, name string, def bool) bool {
+	switch strings.ToLower(r.value(element, name)) {
+	case "true", "1":
+		return true
+	case "false", "0":
+		return false
+	default:
+		return def
+	}
+}
+
+// className qualifies a component class name relative to the package
+func (r *manifestReader) className(name string) string {
+	if strings.HasPrefix(name, ".") {
+		return r.packageID + name
+	}
+	if name != "" && !strings.Contains(name, ".") {
+		return r.packageID + "." + name
+	}
+	return name
+}
+
+// installLocations maps the installLocation enum values to their names
+var installLocations = map[string]string{
+	"0": "auto",
+	"1": "internalOnly",
+	"2": "preferExternal",
+}
+
+// buildManifestInfo builds the component model of a decoded manifest
+func buildManifestInfo(manifest *xmlElement, resolve manifestResolver) *models.ManifestInfo {
+	r := &manifestReader{
+		packageID: manifest.attr("", "package").text(),
+		resolve:   resolve,
+	}
+
+	targetSDK := 0
+	if sdk := manifest.child("uses-sdk"); sdk != nil {
+		targetSDK, _ = strconv.Atoi(r.value(sdk, "targetSdkVersion"))
+		if targetSDK == 0 {
+			targetSDK, _ = strconv.Atoi(r.value(sdk, "minSdkVersion"))
+		}
+	}
+
+	info := &models.ManifestInfo{
+		SharedUserID:    r.value(manifest, "sharedUserId"),
+		InstallLocation: r.value(manifest, "installLocation"),
+		AllowBackup:     true,
+		// Cleartext traffic is blocked by default from Android 9 (API 28)
+		UsesCleartextTraffic: targetSDK < 28,
+	}
+	if name, exists := installLocations[info.InstallLocation]; exists {
+		info.InstallLocation = name
+	}
+
+	application := manifest.child("application")
+	if application == nil {
+		return info
+	}
+
+	info.Debuggable = r.flag(application, "debuggable", false)
+	info.AllowBackup = r.flag(application, "allowBackup", true)
+	info.UsesCleartextTraffic = r.flag(application, "usesCleartextTraffic", info.UsesCleartextTraffic)
+	info.NetworkSecurityConfig = r.value(application, "networkSecurityConfig")
+	info.LargeHeap = r.flag(application, "largeHeap", false)
+
+	for _, element := range application.children {
+		switch element.name {
+		case "activity", "activity-alias":
+			component := r.component(element, false)
+			if element.name == "activity-alias" {
+				component.TargetActivity = r.className(r.value(element, "targetActivity"))
+			}
+			info.Activities = append(info.Activities, component)
+
+			if info.LauncherActivity == "" && r.flag(element, "enabled", true) && isLauncher(component) {
+				info.LauncherActivity = component.Name
+			}
+		case "service":
+			info.Services = append(info.Services, r.component(element, false))
+		case "receiver":
+			info.Receivers = append(info.Receivers, r.component(element, false))
+		case "provider":
+			// Providers were exported by default before Android 4.2 (API 17)
+			component := r.component(element, targetSDK > 0 && targetSDK < 17)
+			for _, authority := range strings.Split(r.value(element, "authorities"), ";") {
+				if authority = strings.TrimSpace(authority); authority != "" {
+					component.Authorities = append(component.Authorities, authority)
+				}
+			}
+			info.Providers = append(info.Providers, component)
+		}
+	}
+
+	return info
+}
+
+// component reads an application component. Components without an exported
+// attribute are exported when they have intent filters, or else when
+// exportedDefault is set.
+func (r *manifestReader) component(element *xmlElement, exportedDefault bool) models.Component {
+	component := models.Component{
+		Name:       r.className(r.value(element, "name")),
+		Permission: r.value(element, "permission"),
+	}
+
+	for _, filterElement := range element.children {
+		if filterElement.name != "intent-filter" {
+			continue
+		}
+
+		filter := models.IntentFilter{
+			AutoVerify: r.flag(filterElement, "autoVerify", false),
+		}
+		for _, child := range filterElement.children {
+			switch child.name {
+			case "action":
+				filter.Actions = append(filter.Actions, r.value(child, "name"))
+			case "category":
+				filter.Categories = append(filter.Categories, r.value(child, "name"))
+			case "data":
+				filter.Data = append(filter.Data, models.IntentData{
+					Scheme:      r.value(child, "scheme"),
+					Host:        r.value(child, "host"),
+					Port:        r.value(child, "port"),
+					Path:        r.value(child, "path"),
+					PathPrefix:  r.value(child, "pathPrefix"),
+					PathPattern: r.value(child, "pathPattern"),
+					MimeType:    r.value(child, "mimeType"),
+				})
+			}
+		}
+		component.IntentFilters = append(component.IntentFilters, filter)
+	}
+
+	component.Exported = r.flag(element, "exported", exportedDefault || len(component.IntentFilters) > 0)
+
+	return component
+}
+
+// isLauncher reports whether an activity appears in the launcher
+func isLauncher(activity models.Component) bool {
+	for _, filter := range activity.IntentFilters {
+		hasMain, hasLauncher := false, false
+		for _, action := range filter.Actions {
+			hasMain = hasMain || action == "android.intent.action.MAIN"
+		}
+		for _, category := range filter.Categories {
+			hasLauncher = hasLauncher || category == "android.intent.category.LAUNCHER"
+		}
+		if hasMain && hasLauncher {
+			return true
+		}
+	}
+	return false
+}
@@ -21,6 +21,7 @@ import (
 type Parser struct {
 	workDir     string
 	parserChain *ParserChain
+	output      io.Writer // Progress messages
 }
 
 // NewParser creates a new APK parser with parser chain
@@ -33,27 +34,35 @@ func NewParser(workDir string) *Parser {
 	chain.AddParser(NewAndroidBinaryParser(workDir))
 	chain.AddParser(NewAAPTParserWrapper(workDir))
 	chain.AddParser(NewXAPKParserWrapper(workDir))
+	chain.AddParser(NewAABParser(workDir))
+	chain.AddParser(NewAPKSParser(workDir))
 
 	return &Parser{
 		workDir:     workDir,
 		parserChain: chain,
+		output:      os.Stdout,
 	}
 }
 
+// SetOutput sets where progress messages are written, stdout by default
+func (p *Parser) SetOutput(w io.Writer) {
+	p.output = w
+}
+
 // ParseAPK parses an APK file and extracts its information using parser chain
 func (p *Parser) ParseAPK(apkPath string) (*APKInfo, error) {
-	// Use parser chain to parse APK (handles APK, XAPK, APKM)
+	// Use parser chain to parse APK (handles APK, XAPK, APKM, AAB, APKS)
 	result, err := p.parserChain.ParseAPK(apkPath)
 	if err != nil {
 		return nil, err
 	}
 
 	// Log parsing result
-	fmt.Printf("File parsed successfully using %s parser (took %v)\n", result.Parser, result.Duration)
+	fmt.Fprintf(p.output, "File parsed successfully using %s parser (took %v)\n", result.Parser, result.Duration)
 
 	// Show warnings if any
 	for _, warning := range result.Warnings {
-		fmt.Printf("Warning: %s\n", warning)
+		fmt.Fprintf(p.output, "Warning: %s\n", warning)
 	}
 
 	return result.APKInfo, nil
@@ -66,22 +75,32 @@ func (p *Parser) GetParserInfo() []ParserInfo {
 
 // APKInfo contains parsed APK information
 type APKInfo struct {
-	PackageID     string
-	AppName       map[string]string
-	Version       string
-	VersionCode   int64
-	MinSDK        int
-	TargetSDK     int
-	Size          int64
-	SHA256        string
-	SignatureInfo *models.SignatureInfo
-	Permissions   []string
-	Features      []string
-	ABIs          []string
-	ReleaseDate   time.Time
-	FilePath      string
-	IconData      []byte // Icon data in PNG format
-	IconExt       string // Icon file extension (.png)
+	PackageID             string
+	AppName               map[string]string
+	Version               string
+	VersionCode           int64
+	MinSDK                int
+	TargetSDK             int
+	Size                  int64
+	SHA256                string
+	SignatureInfo         *models.SignatureInfo
+	SignatureVerification *models.SignatureVerification
+	Permissions           []string
+	Features              []string
+	ABIs                  []string
+	Manifest              *models.ManifestInfo // Components and application flags
+	Libraries             []models.Library     // SDKs detected from the class names of the code
+	ReleaseDate           time.Time
+	FilePath              string
+	Icon                  *Icon // Launcher icon, read when first used; nil if the format has none
+}
+
+// LoadIcon reads the launcher icon now, for an APK file that is about to be
+// moved or removed, and drops it if the APK has none
+func (info *APKInfo) LoadIcon() {
+	if info.Icon != nil && info.Icon.Load() != nil {
+		info.Icon = nil
+	}
 }
 
 // calculateHashes calculates various hashes of the APK file
@@ -193,17 +212,33 @@ func (p *Parser) extractABIs(apkPath string) []string {
 }
 
 // extractSignatureInfo extracts APK signature information
-func (p *Parser) extractSignatureInfo(pkg *apk.Apk) (*models.SignatureInfo, error) {
-	// For now, return empty signature info
-	// TODO: Implement proper signature extraction
-	return &models.SignatureInfo{}, nil
+func (p *Parser) extractSignatureInfo(apkPath string) (*models.SignatureInfo, error) {
+	return ExtractSignatureInfo(apkPath)
 }
 
-// IsAPKFile checks if the file is an APK, XAPK, or APKM file
+// signatureInfoOrEmpty extracts signature info, falling back to an empty value
+func (p *Parser) signatureInfoOrEmpty(apkPath string) *models.SignatureInfo {
+	info, err := p.extractSignatureInfo(apkPath)
+	if err != nil {
+		return &models.SignatureInfo{}
+	}
+	return info
+}
+
+// verifySignaturesOrNil verifies signatures, returning nil if the APK cannot be read
+func (p *Parser) verifySignaturesOrNil(apkPath string) *models.SignatureVerification {
+	result, err := VerifySignatures(apkPath)
+	if err != nil {
+		return nil
+	}
+	return result
+}
+
+// IsAPKFile checks if the file is an APK, XAPK, APKM, app bundle or APK set
 func IsAPKFile(path string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
 	switch ext {
-	case ".apk", ".xapk", ".apkm":
+	case ".apk", ".xapk", ".apkm", ".aab", ".apks":
 		return true
 	default:
 		return false
@@ -243,33 +278,26 @@ func (p *Parser) parseWithAAPTFallback(apkPath string, originalErr error) (*APKI
 		return nil, fmt.Errorf("failed to calculate hashes: %w", err)
 	}
 
-	// Extract icon
-	iconExtractor := NewIconExtractor()
-	iconData, iconExt, iconErr := iconExtractor.ExtractIcon(apkPath)
-	// Icon extraction is non-fatal
-
 	// Build APK info from aapt data
 	info := &APKInfo{
-		PackageID:     basicInfo.PackageID,
-		AppName:       map[string]string{"default": basicInfo.AppName},
-		Version:       basicInfo.VersionName,
-		VersionCode:   basicInfo.VersionCode,
-		MinSDK:        basicInfo.MinSDK,
-		TargetSDK:     basicInfo.TargetSDK,
-		Size:          fileInfo.Size(),
-		SHA256:        hashes["sha256"],
-		SignatureInfo: &models.SignatureInfo{}, // Empty signature for aapt fallback
-		Permissions:   basicInfo.Permissions,
-		Features:      basicInfo.Features,
-		ABIs:          basicInfo.ABIs,
-		ReleaseDate:   fileInfo.ModTime(),
+		PackageID:             basicInfo.PackageID,
+		AppName:               map[string]string{"default": basicInfo.AppName},
+		Version:               basicInfo.VersionName,
+		VersionCode:           basicInfo.VersionCode,
+		MinSDK:                basicInfo.MinSDK,
+		TargetSDK:             basicInfo.TargetSDK,
+		Size:                  fileInfo.Size(),
+		SHA256:                hashes["sha256"],
+		SignatureInfo:         p.signatureInfoOrEmpty(apkPath),
+		SignatureVerification: p.verifySignaturesOrNil(apkPath),
+		Permissions:           basicInfo.Permissions,
+		Features:              basicInfo.Features,
+		ABIs:                  basicInfo.ABIs,
+		ReleaseDate:           fileInfo.ModTime(),
 	}
 
-	// Add icon data if extraction was successful
-	if iconErr == nil && iconData != nil {
-		info.IconData = iconData
-		info.IconExt = iconExt
-	}
+	// The icon is only read and rendered when it is used
+	info.Icon = lazyIcon(apkPath)
 
 	// Calculate relative path if within work directory
 	relPath, err := filepath.Rel(p.workDir, apkPath)
@@ -305,19 +333,20 @@ func (p *Parser) createBasicAPKInfo(apkPath string, originalErr error) (*APKInfo
 
 	// Build minimal APK info
 	info := &APKInfo{
-		PackageID:     packageID, // Use filename as fallback
-		AppName:       map[string]string{"default": packageID},
-		Version:       "unknown",
-		VersionCode:   0,
-		MinSDK:        1,
-		TargetSDK:     0,
-		Size:          fileInfo.Size(),
-		SHA256:        hashes["sha256"],
-		SignatureInfo: &models.SignatureInfo{}, // Empty signature info
-		Permissions:   []string{},
-		Features:      []string{"parsing_limited"}, // Mark as limited parsing
-		ABIs:          abis,
-		ReleaseDate:   fileInfo.ModTime(),
+		PackageID:             packageID, // Use filename as fallback
+		AppName:               map[string]string{"default": packageID},
+		Version:               "unknown",
+		VersionCode:           0,
+		MinSDK:                1,
+		TargetSDK:             0,
+		Size:                  fileInfo.Size(),
+		SHA256:                hashes["sha256"],
+		SignatureInfo:         p.signatureInfoOrEmpty(apkPath),
+		SignatureVerification: p.verifySignaturesOrNil(apkPath),
+		Permissions:           []string{},
+		Features:              []string{"parsing_limited"}, // Mark as limited parsing
+		ABIs:                  abis,
+		ReleaseDate:           fileInfo.ModTime(),
 	}
 
 	// Calculate relative path if within work directory
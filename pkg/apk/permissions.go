@@ -0,0 +1,152 @@
+package apk
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// permissionTableData is the built-in table of platform permissions
+//
+//go:embed data/permissions.json
+var permissionTableData []byte
+
+// ProtectionLevel is the protection level of a platform permission
+type ProtectionLevel string
+
+const (
+	ProtectionNormal     ProtectionLevel = "normal"
+	ProtectionDangerous  ProtectionLevel = "dangerous"  // Runtime permissions the user grants
+	ProtectionSignature  ProtectionLevel = "signature"  // Including special app accesses granted in settings
+	ProtectionPrivileged ProtectionLevel = "privileged" // Only granted to system apps
+)
+
+// Permission describes a platform permission
+type Permission struct {
+	Name            string          `json:"name"`
+	ProtectionLevel ProtectionLevel `json:"protection_level"`
+	Group           string          `json:"group"`                // Display group: camera, location, storage, ...
+	Added           int             `json:"added"`                // API level
+	Deprecated      int             `json:"deprecated,omitempty"` // API level, 0 if not deprecated
+}
+
+// permissionRiskWeights are the points a permission of each protection level
+// adds to the risk score. Runtime permissions weigh most. Signature permissions
+// weigh less, as only the special app accesses among them can be granted to an
+// installed app. Privileged permissions are never granted to it and add nothing.
+var permissionRiskWeights = map[ProtectionLevel]int{
+	ProtectionNormal:     0,
+	ProtectionDangerous:  10,
+	ProtectionSignature:  5,
+	ProtectionPrivileged: 0,
+}
+
+// MaxRiskScore is the highest permission risk score
+const MaxRiskScore = 100
+
+// Risk levels of a score
+const (
+	RiskLow    = "low"    // Below 20
+	RiskMedium = "medium" // Below 50
+	RiskHigh   = "high"
+)
+
+var (
+	permissionTable     map[string]*Permission
+	permissionTableErr  error
+	permissionTableOnce sync.Once
+)
+
+// PermissionTable returns the built-in platform permissions by name
+func PermissionTable() (map[string]*Permission, error) {
+	permissionTableOnce.Do(func() {
+		permissionTable, permissionTableErr = parsePermissionTable(permissionTableData)
+	})
+	return permissionTable, permissionTableErr
+}
+
+// parsePermissionTable decodes a permission table
+func parsePermissionTable(data []byte) (map[string]*Permission, error) {
+	var database struct {
+		Permissions []*Permission `json:"permissions"`
+	}
+	if err := json.Unmarshal(data, &database); err != nil {
+		return nil, fmt.Errorf("invalid permission table: %w", err)
+	}
+
+	table := make(map[string]*Permission, len(database.Permissions))
+	for _, permission := range database.Permissions {
+		if _, ok := permissionRiskWeights[permission.ProtectionLevel]; !ok {
+			return nil, fmt.Errorf("permission %s has unknown protection level %q", permission.Name, permission.ProtectionLevel)
+		}
+		table[permission.Name] = permission
+	}
+	return table, nil
+}
+
+// LookupPermission returns a platform permission, or false for permissions
+// apps and vendors define
+func LookupPermission(name string) (*Permission, bool) {
+	table, err := PermissionTable()
+	if err != nil {
+		return nil, false
+	}
+	permission, ok := table[name]
+	return permission, ok
+}
+
+// IsDangerousPermission reports whether a permission is a platform permission
+// of the dangerous protection level, i.e. a runtime permission
+func IsDangerousPermission(name string) bool {
+	permission, ok := LookupPermission(name)
+	return ok && permission.ProtectionLevel == ProtectionDangerous
+}
+
+// DangerousPermissions returns the dangerous permissions of a list, sorted
+func DangerousPermissions(permissions []string) []string {
+	var dangerous []string
+	seen := make(map[string]bool)
+	for _, name := range permissions {
+		if !seen[name] && IsDangerousPermission(name) {
+			dangerous = append(dangerous, name)
+		}
+		seen[name] = true
+	}
+	sort.Strings(dangerous)
+	return dangerous
+}
+
+// PermissionRiskScore scores the permissions an app requests from 0 to
+// MaxRiskScore: each platform permission adds the weight of its protection
+// level. Permissions outside the table add nothing.
+func PermissionRiskScore(permissions []string) int {
+	score := 0
+	seen := make(map[string]bool)
+	for _, name := range permissions {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if permission, ok := LookupPermission(name); ok {
+			score += permissionRiskWeights[permission.ProtectionLevel]
+		}
+	}
+	if score > MaxRiskScore {
+		score = MaxRiskScore
+	}
+	return score
+}
+
+// RiskLevel returns the risk level of a score
+func RiskLevel(score int) string {
+	switch {
+	case score < 20:
+		return RiskLow
+	case score < 50:
+		return RiskMedium
+	default:
+		return RiskHigh
+	}
+}
@@ -0,0 +1,240 @@
+package apk
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+)
+
+// PKCS#7 and digest algorithm object identifiers
+var (
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidDigestMD5     = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 5}
+	oidDigestSHA1    = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidDigestSHA256  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidDigestSHA384  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidDigestSHA512  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+// pkcs7ContentInfo is the outer PKCS#7 wrapper
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+// pkcs7SignedData is the SignedData structure used by JAR (v1) signatures
+type pkcs7SignedData struct {
+	Version                    int                        `asn1:"default:1"`
+	DigestAlgorithmIdentifiers []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo                pkcs7ContentInfo
+	Certificates               pkcs7RawCertificates `asn1:"optional,tag:0"`
+	CRLs                       asn1.RawValue        `asn1:"optional,tag:1"`
+	SignerInfos                []pkcs7SignerInfo    `asn1:"set"`
+}
+
+// pkcs7RawCertificates keeps the certificate set undecoded
+type pkcs7RawCertificates struct {
+	Raw asn1.RawContent
+}
+
+// pkcs7Attribute is a signed or unsigned signer attribute
+type pkcs7Attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+// pkcs7IssuerAndSerial identifies the certificate of a signer
+type pkcs7IssuerAndSerial struct {
+	IssuerName   asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// pkcs7SignerInfo describes one signer of the SignedData
+type pkcs7SignerInfo struct {
+	Version                   int `asn1:"default:1"`
+	IssuerAndSerialNumber     pkcs7IssuerAndSerial
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+	UnauthenticatedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// pkcs7 is a decoded PKCS#7 SignedData blob
+type pkcs7 struct {
+	Certificates []*x509.Certificate
+	Signers      []pkcs7SignerInfo
+	raw          pkcs7SignedData
+}
+
+// parsePKCS7 decodes a DER encoded PKCS#7 SignedData blob
+func parsePKCS7(data []byte) (*pkcs7, error) {
+	var info pkcs7ContentInfo
+	rest, err := asn1.Unmarshal(data, &info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PKCS#7 content info: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data after PKCS#7 content info")
+	}
+	if !info.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("unsupported PKCS#7 content type %s", info.ContentType)
+	}
+
+	var sd pkcs7SignedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("failed to parse PKCS#7 signed data: %w", err)
+	}
+
+	certs, err := sd.Certificates.parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PKCS#7 certificates: %w", err)
+	}
+
+	return &pkcs7{
+		Certificates: certs,
+		Signers:      sd.SignerInfos,
+		raw:          sd,
+	}, nil
+}
+
+// parse decodes the raw certificate set
+func (raw pkcs7RawCertificates) parse() ([]*x509.Certificate, error) {
+	if len(raw.Raw) == 0 {
+		return nil, nil
+	}
+
+	var val asn1.RawValue
+	if _, err := asn1.Unmarshal(raw.Raw, &val); err != nil {
+		return nil, err
+	}
+
+	return x509.ParseCertificates(val.Bytes)
+}
+
+// signerCertificate returns the certificate referenced by a signer info
+func (p *pkcs7) signerCertificate(signer pkcs7SignerInfo) *x509.Certificate {
+	for _, cert := range p.Certificates {
+		if cert.SerialNumber.Cmp(signer.IssuerAndSerialNumber.SerialNumber) == 0 &&
+			bytes.Equal(cert.RawIssuer, signer.IssuerAndSerialNumber.IssuerName.FullBytes) {
+			return cert
+		}
+	}
+	return nil
+}
+
+// verifySigner checks a signer's signature over the detached content
+func (p *pkcs7) verifySigner(signer pkcs7SignerInfo, content []byte) (*x509.Certificate, error) {
+	cert := p.signerCertificate(signer)
+	if cert == nil {
+		return nil, fmt.Errorf("signer certificate not found")
+	}
+
+	hash, err := pkcs7DigestHash(signer.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := content
+	if len(signer.AuthenticatedAttributes.FullBytes) > 0 {
+		digest, err := signer.messageDigest()
+		if err != nil {
+			return nil, err
+		}
+
+		h := hash.New()
+		h.Write(content)
+		if !bytes.Equal(h.Sum(nil), digest) {
+			return nil, fmt.Errorf("message digest does not match signed content")
+		}
+
+		// Authenticated attributes are signed as a SET OF, not with their implicit tag
+		signed = append([]byte(nil), signer.AuthenticatedAttributes.FullBytes...)
+		signed[0] = 0x31
+	}
+
+	h := hash.New()
+	h.Write(signed)
+	if err := verifyDigestSignature(cert.PublicKey, hash, h.Sum(nil), signer.EncryptedDigest); err != nil {
+		return nil, err
+	}
+
+	return cert, nil
+}
+
+// messageDigest returns the messageDigest authenticated attribute
+func (s pkcs7SignerInfo) messageDigest() ([]byte, error) {
+	rest := s.AuthenticatedAttributes.Bytes
+	for len(rest) > 0 {
+		var attr pkcs7Attribute
+		var err error
+		rest, err = asn1.Unmarshal(rest, &attr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse authenticated attribute: %w", err)
+		}
+		if !attr.Type.Equal(oidMessageDigest) {
+			continue
+		}
+
+		var digest []byte
+		if _, err := asn1.Unmarshal(attr.Value.Bytes, &digest); err != nil {
+			return nil, fmt.Errorf("failed to parse message digest: %w", err)
+		}
+		return digest, nil
+	}
+
+	return nil, fmt.Errorf("message digest attribute not found")
+}
+
+// pkcs7DigestHash maps a digest algorithm OID to a hash function
+func pkcs7DigestHash(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case oid.Equal(oidDigestSHA1):
+		return crypto.SHA1, nil
+	case oid.Equal(oidDigestSHA256):
+		return crypto.SHA256, nil
+	case oid.Equal(oidDigestSHA384):
+		return crypto.SHA384, nil
+	case oid.Equal(oidDigestSHA512):
+		return crypto.SHA512, nil
+	case oid.Equal(oidDigestMD5):
+		return crypto.MD5, nil
+	default:
+		return 0, fmt.Errorf("unsupported digest algorithm %s", oid)
+	}
+}
+
+// verifyDigestSignature verifies a PKCS#1 v1.5, ECDSA or DSA signature over a digest
+func verifyDigestSignature(pub crypto.PublicKey, hash crypto.Hash, digest, sig []byte) error {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, hash, digest, sig)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest, sig) {
+			return fmt.Errorf("ECDSA signature verification failed")
+		}
+		return nil
+	case *dsa.PublicKey:
+		var rs struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(sig, &rs); err != nil {
+			return fmt.Errorf("failed to parse DSA signature: %w", err)
+		}
+		if len(digest) > (key.Q.BitLen()+7)/8 {
+			digest = digest[:(key.Q.BitLen()+7)/8]
+		}
+		if !dsa.Verify(key, digest, rs.R, rs.S) {
+			return fmt.Errorf("DSA signature verification failed")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
@@ -0,0 +1,150 @@
+package apk
+
+import (
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"testing"
+)
+
+// Object identifiers only the test PKCS#7 builder needs
+var (
+	oidTestData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidTestContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidTestRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+)
+
+// buildTestPKCS7 returns a detached PKCS#7 SignedData over content, as found
+// in META-INF/*.RSA. withAttributes signs authenticated attributes carrying the
+// content digest instead of the content itself.
+func buildTestPKCS7(t *testing.T, signer *testSigner, content []byte, withAttributes bool) []byte {
+	t.Helper()
+
+	mustMarshal := func(v any) []byte {
+		data, err := asn1.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	info := pkcs7SignerInfo{
+		Version: 1,
+		IssuerAndSerialNumber: pkcs7IssuerAndSerial{
+			IssuerName:   asn1.RawValue{FullBytes: signer.cert.RawIssuer},
+			SerialNumber: signer.cert.SerialNumber,
+		},
+		DigestAlgorithm:           pkix.AlgorithmIdentifier{Algorithm: oidDigestSHA256},
+		DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidTestRSAEncryption},
+	}
+
+	signed := content
+	if withAttributes {
+		digest := sha256.Sum256(content)
+		attrs := append(
+			mustMarshal(pkcs7Attribute{
+				Type:  oidTestContentType,
+				Value: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: mustMarshal(oidTestData)},
+			}),
+			mustMarshal(pkcs7Attribute{
+				Type:  oidMessageDigest,
+				Value: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: mustMarshal(digest[:])},
+			})...,
+		)
+		info.AuthenticatedAttributes = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attrs}
+		// The signature covers the attributes encoded as a SET OF
+		signed = mustMarshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: attrs})
+	}
+	info.EncryptedDigest = signer.sign(t, signed)
+
+	sd := pkcs7SignedData{
+		Version:                    1,
+		DigestAlgorithmIdentifiers: []pkix.AlgorithmIdentifier{{Algorithm: oidDigestSHA256}},
+		ContentInfo:                pkcs7ContentInfo{ContentType: oidTestData},
+		Certificates: pkcs7RawCertificates{
+			Raw: mustMarshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signer.cert.Raw}),
+		},
+		SignerInfos: []pkcs7SignerInfo{info},
+	}
+
+	return mustMarshal(pkcs7ContentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: mustMarshal(sd)},
+	})
+}
+
+func TestParsePKCS7(t *testing.T) {
+	signer := newTestSigner(t, "v1")
+	content := []byte("Signature-Version: 1.0\r\n\r\n")
+
+	for _, withAttributes := range []bool{false, true} {
+		p7, err := parsePKCS7(buildTestPKCS7(t, signer, content, withAttributes))
+		if err != nil {
+			t.Fatalf("parsePKCS7 (attributes %v): %v", withAttributes, err)
+		}
+		if len(p7.Certificates) != 1 || len(p7.Signers) != 1 {
+			t.Fatalf("parsed %d certificates and %d signers, want 1 each", len(p7.Certificates), len(p7.Signers))
+		}
+
+		cert, err := p7.verifySigner(p7.Signers[0], content)
+		if err != nil {
+			t.Errorf("verifySigner (attributes %v): %v", withAttributes, err)
+		} else if !cert.Equal(signer.cert) {
+			t.Errorf("verifySigner (attributes %v) returned another certificate", withAttributes)
+		}
+
+		if _, err := p7.verifySigner(p7.Signers[0], []byte("other content")); err == nil {
+			t.Errorf("verifySigner (attributes %v) accepted other content", withAttributes)
+		}
+	}
+}
+
+func TestParsePKCS7RejectsCorruptData(t *testing.T) {
+	signer := newTestSigner(t, "v1")
+	valid := buildTestPKCS7(t, signer, []byte("content"), true)
+
+	wrongType, err := asn1.Marshal(pkcs7ContentInfo{ContentType: oidTestData})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"garbage", []byte("not a PKCS#7 block")},
+		{"trailing data", append(append([]byte(nil), valid...), 0x00)},
+		{"not signed data", wrongType},
+	}
+	for n := 1; n < len(valid); n++ {
+		tests = append(tests, struct {
+			name string
+			data []byte
+		}{"truncated", valid[:n]})
+	}
+
+	for _, tt := range tests {
+		if _, err := parsePKCS7(tt.data); err == nil {
+			t.Errorf("%s (%d bytes): parsePKCS7 succeeded", tt.name, len(tt.data))
+		}
+	}
+}
+
+func TestPKCS7VerifySignerRejectsUnknownSigner(t *testing.T) {
+	content := []byte("content")
+	p7, err := parsePKCS7(buildTestPKCS7(t, newTestSigner(t, "v1"), content, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A signer info referencing a certificate missing from the block
+	info := p7.Signers[0]
+	info.IssuerAndSerialNumber.SerialNumber = newTestSigner(t, "rotated").cert.SerialNumber
+	if info.IssuerAndSerialNumber.SerialNumber.Cmp(p7.Certificates[0].SerialNumber) == 0 {
+		t.Fatal("test signers share a serial number")
+	}
+	if _, err := p7.verifySigner(info, content); err == nil {
+		t.Error("verifySigner accepted a signer without its certificate")
+	}
+}
@@ -0,0 +1,138 @@
+package apk
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Protobuf wire types
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// protoField is one field of an encoded protobuf message
+type protoField struct {
+	number   int
+	wireType int
+	value    uint64 // Varint and fixed-size fields
+	data     []byte // Length-delimited fields
+}
+
+// protoMessage is a decoded protobuf message. App bundles and APK sets store their
+// metadata as protobuf (aapt2 resources, bundletool commands); only the few fields
+// needed here are read, without the generated code.
+type protoMessage []protoField
+
+// parseProtoMessage splits an encoded message into its fields
+func parseProtoMessage(data []byte) (protoMessage, error) {
+	var message protoMessage
+
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid protobuf field key")
+		}
+		data = data[n:]
+
+		field := protoField{number: int(key >> 3), wireType: int(key & 7)}
+		switch field.wireType {
+		case protoVarint:
+			field.value, n = binary.Uvarint(data)
+			if n <= 0 {
+				return nil, fmt.Errorf("invalid varint in field %d", field.number)
+			}
+			data = data[n:]
+		case protoFixed64:
+			if len(data) < 8 {
+				return nil, fmt.Errorf("truncated field %d", field.number)
+			}
+			field.value = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case protoFixed32:
+			if len(data) < 4 {
+				return nil, fmt.Errorf("truncated field %d", field.number)
+			}
+			field.value = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case protoBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return nil, fmt.Errorf("truncated field %d", field.number)
+			}
+			field.data = data[n : n+int(length)]
+			data = data[n+int(length):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d in field %d", field.wireType, field.number)
+		}
+
+		message = append(message, field)
+	}
+
+	return message, nil
+}
+
+// uint returns the last value of a scalar field, or 0 if it is not set
+func (m protoMessage) uint(number int) uint64 {
+	var value uint64
+	for _, field := range m {
+		if field.number == number && field.wireType != protoBytes {
+			value = field.value
+		}
+	}
+	return value
+}
+
+// bool returns the last value of a bool field
+func (m protoMessage) bool(number int) bool {
+	return m.uint(number) != 0
+}
+
+// string returns the last value of a string field, or "" if it is not set
+func (m protoMessage) string(number int) string {
+	var value string
+	for _, field := range m {
+		if field.number == number && field.wireType == protoBytes {
+			value = string(field.data)
+		}
+	}
+	return value
+}
+
+// message returns the last value of a message field. A missing or malformed
+// message is returned empty.
+func (m protoMessage) message(number int) protoMessage {
+	var value protoMessage
+	for _, field := range m {
+		if field.number == number && field.wireType == protoBytes {
+			value, _ = parseProtoMessage(field.data)
+		}
+	}
+	return value
+}
+
+// messages returns every value of a repeated message field, skipping malformed ones
+func (m protoMessage) messages(number int) []protoMessage {
+	var values []protoMessage
+	for _, field := range m {
+		if field.number != number || field.wireType != protoBytes {
+			continue
+		}
+		if value, err := parseProtoMessage(field.data); err == nil {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// has reports whether a field is set
+func (m protoMessage) has(number int) bool {
+	for _, field := range m {
+		if field.number == number {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+package apk
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// rasterSubsamples is the number of scanlines sampled per pixel row; coverage
+// across a row is computed exactly
+const rasterSubsamples = 4
+
+// point is a point of a path
+type point struct {
+	x, y float64
+}
+
+// affine is a 2D affine transform: x' = a*x + c*y + e, y' = b*x + d*y + f
+type affine struct {
+	a, b, c, d, e, f float64
+}
+
+// apply transforms a point
+func (m affine) apply(p point) point {
+	return point{m.a*p.x + m.c*p.y + m.e, m.b*p.x + m.d*p.y + m.f}
+}
+
+// then returns the transform applying m and then n
+func (m affine) then(n affine) affine {
+	return affine{
+		a: n.a*m.a + n.c*m.b,
+		b: n.b*m.a + n.d*m.b,
+		c: n.a*m.c + n.c*m.d,
+		d: n.b*m.c + n.d*m.d,
+		e: n.a*m.e + n.c*m.f + n.e,
+		f: n.b*m.e + n.d*m.f + n.f,
+	}
+}
+
+// scale returns the average factor the transform scales lengths by
+func (m affine) scale() float64 {
+	return math.Sqrt(math.Abs(m.a*m.d - m.b*m.c))
+}
+
+// translateAffine, scaleAffine and rotateAffine build elementary transforms
+func translateAffine(x, y float64) affine { return affine{a: 1, d: 1, e: x, f: y} }
+func scaleAffine(x, y float64) affine     { return affine{a: x, d: y} }
+func rotateAffine(degrees float64) affine {
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	return affine{a: cos, b: sin, c: -sin, d: cos}
+}
+
+// polyline is a flattened subpath
+type polyline struct {
+	points []point
+	closed bool
+}
+
+// transform applies a transform to every point
+func (p polyline) transform(m affine) polyline {
+	points := make([]point, len(p.points))
+	for i, pt := range p.points {
+		points[i] = m.apply(pt)
+	}
+	return polyline{points: points, closed: p.closed}
+}
+
+// rasterize computes the coverage of polygons in a width x height mask. With
+// evenOdd set, areas covered an even number of times are outside; otherwise
+// any non-zero winding is inside.
+func rasterize(width, height int, polygons []polyline, evenOdd bool) *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
+
+	type edge struct {
+		x0, y0, x1, y1 float64
+		dir            int
+	}
+	var edges []edge
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, polygon := range polygons {
+		n := len(polygon.points)
+		for i := 0; i < n; i++ {
+			p, q := polygon.points[i], polygon.points[(i+1)%n]
+			if p.y == q.y {
+				continue
+			}
+			e := edge{p.x, p.y, q.x, q.y, 1}
+			if p.y > q.y {
+				e = edge{q.x, q.y, p.x, p.y, -1}
+			}
+			edges = append(edges, e)
+			minY, maxY = math.Min(minY, e.y0), math.Max(maxY, e.y1)
+		}
+	}
+	if len(edges) == 0 {
+		return mask
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].y0 < edges[j].y0 })
+
+	type crossing struct {
+		x   float64
+		dir int
+	}
+	coverage := make([]float64, width+1)
+	firstRow := int(math.Max(0, math.Floor(minY)))
+	lastRow := int(math.Min(float64(height-1), math.Ceil(maxY)))
+	var crossings []crossing
+
+	for row := firstRow; row <= lastRow; row++ {
+		for i := range coverage {
+			coverage[i] = 0
+		}
+		for sample := 0; sample < rasterSubsamples; sample++ {
+			y := float64(row) + (float64(sample)+0.5)/rasterSubsamples
+			crossings = crossings[:0]
+			for _, e := range edges {
+				if e.y0 > y {
+					break
+				}
+				if y >= e.y1 {
+					continue
+				}
+				x := e.x0 + (y-e.y0)*(e.x1-e.x0)/(e.y1-e.y0)
+				crossings = append(crossings, crossing{x, e.dir})
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+			winding := 0
+			for i := 0; i+1 < len(crossings); i++ {
+				winding += crossings[i].dir
+				inside := winding != 0
+				if evenOdd {
+					inside = (i+1)%2 == 1
+				}
+				if inside {
+					addSpan(coverage, crossings[i].x, crossings[i+1].x, 1.0/rasterSubsamples)
+				}
+			}
+		}
+
+		for x := 0; x < width; x++ {
+			if value := coverage[x]; value > 0 {
+				mask.Pix[row*mask.Stride+x] = uint8(math.Min(1, value)*255 + 0.5)
+			}
+		}
+	}
+
+	return mask
+}
+
+// addSpan adds weight to the coverage of the pixels between x0 and x1, with
+// partial pixels at the ends
+func addSpan(coverage []float64, x0, x1, weight float64) {
+	width := float64(len(coverage) - 1)
+	x0, x1 = math.Max(0, x0), math.Min(width, x1)
+	if x1 <= x0 {
+		return
+	}
+
+	first, last := int(x0), int(x1)
+	if first == last {
+		coverage[first] += (x1 - x0) * weight
+		return
+	}
+	coverage[first] += (float64(first+1) - x0) * weight
+	for x := first + 1; x < last; x++ {
+		coverage[x] += weight
+	}
+	coverage[last] += (x1 - float64(last)) * weight
+}
+
+// strokePolygons outlines polylines with the given width as polygons to fill
+// with the non-zero rule: a quad per segment and round joins and caps
+func strokePolygons(lines []polyline, width float64) []polyline {
+	var polygons []polyline
+	radius := width / 2
+	if radius <= 0 {
+		return nil
+	}
+
+	for _, line := range lines {
+		points := line.points
+		if line.closed && len(points) > 1 {
+			points = append(append([]point{}, points...), points[0])
+		}
+		for i := 0; i+1 < len(points); i++ {
+			p, q := points[i], points[i+1]
+			dx, dy := q.x-p.x, q.y-p.y
+			length := math.Hypot(dx, dy)
+			if length == 0 {
+				continue
+			}
+			nx, ny := -dy/length*radius, dx/length*radius
+			polygons = append(polygons, polyline{points: []point{
+				{p.x + nx, p.y + ny}, {q.x + nx, q.y + ny}, {q.x - nx, q.y - ny}, {p.x - nx, p.y - ny},
+			}, closed: true})
+		}
+		for _, p := range points {
+			polygons = append(polygons, circlePolygon(p, radius))
+		}
+	}
+
+	// Overlapping outlines only add up under the non-zero rule when they wind
+	// the same way
+	for i, polygon := range polygons {
+		if signedArea(polygon.points) < 0 {
+			reversed := make([]point, len(polygon.points))
+			for j, p := range polygon.points {
+				reversed[len(reversed)-1-j] = p
+			}
+			polygons[i].points = reversed
+		}
+	}
+
+	return polygons
+}
+
+// circlePolygon approximates a circle
+func circlePolygon(center point, radius float64) polyline {
+	return ellipsePolygon(center, radius, radius)
+}
+
+// ellipsePolygon approximates an axis-aligned ellipse
+func ellipsePolygon(center point, rx, ry float64) polyline {
+	segments := int(math.Max(8, math.Min(256, math.Max(rx, ry)*4)))
+	points := make([]point, segments)
+	for i := range points {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(segments))
+		points[i] = point{center.x + rx*cos, center.y + ry*sin}
+	}
+	return polyline{points: points, closed: true}
+}
+
+// roundedRectPolygon approximates a rectangle with corners of the given radius
+func roundedRectPolygon(x0, y0, x1, y1, radius float64) polyline {
+	radius = math.Max(0, math.Min(radius, math.Min(x1-x0, y1-y0)/2))
+	if radius == 0 {
+		return polyline{points: []point{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}, closed: true}
+	}
+
+	steps := int(math.Max(2, math.Min(64, radius)))
+	corners := []struct {
+		center point
+		angle  float64
+	}{
+		{point{x1 - radius, y0 + radius}, -math.Pi / 2},
+		{point{x1 - radius, y1 - radius}, 0},
+		{point{x0 + radius, y1 - radius}, math.Pi / 2},
+		{point{x0 + radius, y0 + radius}, math.Pi},
+	}
+	var points []point
+	for _, corner := range corners {
+		for i := 0; i <= steps; i++ {
+			sin, cos := math.Sincos(corner.angle + math.Pi/2*float64(i)/float64(steps))
+			points = append(points, point{corner.center.x + radius*cos, corner.center.y + radius*sin})
+		}
+	}
+	return polyline{points: points, closed: true}
+}
+
+// signedArea returns the signed area of a polygon, positive when it winds
+// clockwise in image coordinates
+func signedArea(points []point) float64 {
+	area := 0.0
+	for i := range points {
+		p, q := points[i], points[(i+1)%len(points)]
+		area += p.x*q.y - q.x*p.y
+	}
+	return area / 2
+}
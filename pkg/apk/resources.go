@@ -0,0 +1,485 @@
+package apk
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Resource table chunk types (ResourceTypes.h)
+const (
+	resStringPoolType = 0x0001
+	resTableType      = 0x0002
+	resTablePackage   = 0x0200
+	resTableTypeType  = 0x0201
+)
+
+// ReadAppLabels reads the android:label of the application in every locale
+// configuration of resources.arsc, keyed "default" for the default
+// configuration and by BCP-47 tag otherwise. androidbinary only resolves a
+// reference for a single configuration, so the table is walked here.
+func ReadAppLabels(apkPath string) (map[string]string, error) {
+	reader, err := zip.OpenReader(apkPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var manifestData, tableData []byte
+	for _, file := range reader.File {
+		switch file.Name {
+		case "AndroidManifest.xml":
+			if manifestData, err = readZipFile(file); err != nil {
+				return nil, err
+			}
+		case "resources.arsc":
+			if tableData, err = readZipFile(file); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if manifestData == nil {
+		return nil, fmt.Errorf("AndroidManifest.xml not found")
+	}
+
+	manifest, err := parseBinaryXML(manifestData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AndroidManifest.xml: %w", err)
+	}
+
+	labels := make(map[string]string)
+	application := manifest.child("application")
+	if application == nil {
+		return labels, nil
+	}
+	label := application.attr(androidNamespace, "label")
+	if label == nil {
+		return labels, nil
+	}
+	if label.refID == 0 {
+		if label.value != "" {
+			labels["default"] = label.value
+		}
+		return labels, nil
+	}
+	if tableData == nil {
+		return nil, fmt.Errorf("resources.arsc not found")
+	}
+
+	table, err := parseResourceTable(tableData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse resources.arsc: %w", err)
+	}
+
+	return table.localizedStrings(label.refID), nil
+}
+
+// arscValue is the value of a resource entry in one configuration
+type arscValue struct {
+	resourceConfig
+	dataType uint8
+	data     uint32
+}
+
+// resourceConfig is the part of a ResTable_config resources are chosen by here
+type resourceConfig struct {
+	locale  string // BCP-47 tag, "" for any locale
+	density uint16 // 0 for any density, 0xFFFF for anydpi
+	sdk     uint16 // Minimum platform version, 0 for any
+	other   bool   // Qualified by anything else, such as night mode or screen size
+}
+
+// localeOnly reports whether the configuration is qualified by nothing but the locale
+func (c resourceConfig) localeOnly() bool {
+	return c.density == 0 && c.sdk == 0 && !c.other
+}
+
+// Densities of ResTable_config
+const (
+	densityAny = 0xFFFF
+)
+
+// resourceTable is the part of resources.arsc needed to resolve strings in
+// every locale: the global string pool and the raw table
+type resourceTable struct {
+	data    []byte
+	strings []string
+}
+
+// parseResourceTable reads the global string pool of a resource table
+func parseResourceTable(data []byte) (*resourceTable, error) {
+	chunkType, headerSize, size, ok := readChunkHeader(data, 0)
+	if !ok || chunkType != resTableType || int(size) > len(data) {
+		return nil, fmt.Errorf("invalid resource table header")
+	}
+
+	table := &resourceTable{data: data[:size]}
+	for offset := int(headerSize); offset < int(size); {
+		chunkType, _, chunkSize, ok := readChunkHeader(data, offset)
+		if !ok || chunkSize == 0 {
+			return nil, fmt.Errorf("invalid chunk at offset %d", offset)
+		}
+		if chunkType == resStringPoolType {
+			pool, err := parseStringPool(data[offset : offset+int(chunkSize)])
+			if err != nil {
+				return nil, err
+			}
+			table.strings = pool
+			break
+		}
+		offset += int(chunkSize)
+	}
+
+	return table, nil
+}
+
+// localizedStrings resolves a string resource in every locale configuration,
+// following references to other resources
+func (t *resourceTable) localizedStrings(resourceID uint32) map[string]string {
+	names := make(map[string]string)
+	for _, value := range t.values(resourceID) {
+		if !value.localeOnly() {
+			continue
+		}
+		if str := t.resolveString(value, value.locale, 0); str != "" {
+			key := value.locale
+			if key == "" {
+				key = "default"
+			}
+			names[key] = str
+		}
+	}
+	return names
+}
+
+// resolveString returns the string a value holds, looking references up in the
+// configuration of the locale
+func (t *resourceTable) resolveString(value arscValue, locale string, depth int) string {
+	switch value.dataType {
+	case resValueString:
+		if int(value.data) < len(t.strings) {
+			return t.strings[value.data]
+		}
+	case resValueReference:
+		if depth >= 4 {
+			return ""
+		}
+		var fallback string
+		for _, target := range t.values(value.data) {
+			if !target.localeOnly() {
+				continue
+			}
+			if target.locale == locale {
+				return t.resolveString(target, locale, depth+1)
+			}
+			if target.locale == "" {
+				fallback = t.resolveString(target, locale, depth+1)
+			}
+		}
+		return fallback
+	}
+	return ""
+}
+
+// drawableValue picks the configuration of a drawable or mipmap resource to
+// render: XML for anydpi (adaptive icons and vectors) on the newest platform,
+// else the bitmap of the highest density, else any other value in the default
+// locale, such as a color
+func (t *resourceTable) drawableValue(resourceID uint32) (arscValue, bool) {
+	var xmlValue, bitmapValue, otherValue *arscValue
+	for _, value := range t.values(resourceID) {
+		if value.locale != "" || value.other {
+			continue
+		}
+		value := value
+
+		file := ""
+		if value.dataType == resValueString && int(value.data) < len(t.strings) {
+			file = strings.ToLower(t.strings[value.data])
+		}
+		switch {
+		case strings.HasSuffix(file, ".xml") && value.density == densityAny:
+			if xmlValue == nil || value.sdk > xmlValue.sdk {
+				xmlValue = &value
+			}
+		case strings.HasSuffix(file, ".png") || strings.HasSuffix(file, ".webp") || strings.HasSuffix(file, ".jpg"):
+			if bitmapValue == nil || value.density > bitmapValue.density && value.density != densityAny {
+				bitmapValue = &value
+			}
+		default:
+			if otherValue == nil || value.sdk > otherValue.sdk {
+				otherValue = &value
+			}
+		}
+	}
+
+	for _, value := range []*arscValue{xmlValue, bitmapValue, otherValue} {
+		if value != nil {
+			return *value, true
+		}
+	}
+	return arscValue{}, false
+}
+
+// values returns the values of a resource entry in every configuration
+func (t *resourceTable) values(resourceID uint32) []arscValue {
+	var values []arscValue
+
+	packageID := resourceID >> 24
+	typeID := uint8(resourceID >> 16)
+	entryIndex := int(resourceID & 0xFFFF)
+
+	_, tableHeaderSize, _, _ := readChunkHeader(t.data, 0)
+	for offset := int(tableHeaderSize); offset < len(t.data); {
+		chunkType, headerSize, size, ok := readChunkHeader(t.data, offset)
+		if !ok || size == 0 {
+			break
+		}
+		if chunkType == resTablePackage && offset+12 <= len(t.data) &&
+			binary.LittleEndian.Uint32(t.data[offset+8:]) == packageID {
+			chunk := t.data[offset : offset+int(size)]
+			for inner := int(headerSize); inner < len(chunk); {
+				innerType, _, innerSize, ok := readChunkHeader(chunk, inner)
+				if !ok || innerSize == 0 {
+					break
+				}
+				if innerType == resTableTypeType {
+					typeChunk := chunk[inner : inner+int(innerSize)]
+					if value, ok := readTypeEntry(typeChunk, typeID, entryIndex); ok {
+						values = append(values, value)
+					}
+				}
+				inner += int(innerSize)
+			}
+		}
+		offset += int(size)
+	}
+
+	return values
+}
+
+// readTypeEntry reads an entry from a ResTable_type chunk of the given type
+func readTypeEntry(chunk []byte, typeID uint8, entryIndex int) (arscValue, bool) {
+	// ResTable_type: header (8), id (1), flags (1), reserved (2), entryCount (4),
+	// entriesStart (4), config
+	if len(chunk) < 28 || chunk[8] != typeID {
+		return arscValue{}, false
+	}
+	headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+	flags := chunk[9]
+	entryCount := int(binary.LittleEndian.Uint32(chunk[12:]))
+	entriesStart := int(binary.LittleEndian.Uint32(chunk[16:]))
+
+	rawConfig := chunk[20:]
+	configSize := int(binary.LittleEndian.Uint32(rawConfig))
+	if configSize > len(rawConfig) || configSize < 12 {
+		return arscValue{}, false
+	}
+	config := parseResourceConfig(rawConfig[:configSize])
+
+	offsets := chunk[headerSize:]
+	entryOffset := -1
+	switch {
+	case flags&0x01 != 0: // FLAG_SPARSE: (index, offset / 4) pairs
+		for i := 0; i < entryCount && 4*i+4 <= len(offsets); i++ {
+			if int(binary.LittleEndian.Uint16(offsets[4*i:])) == entryIndex {
+				entryOffset = int(binary.LittleEndian.Uint16(offsets[4*i+2:])) * 4
+				break
+			}
+		}
+	case flags&0x02 != 0: // FLAG_OFFSET16: offset / 4, 0xFFFF for no entry
+		if entryIndex < entryCount && 2*entryIndex+2 <= len(offsets) {
+			if offset := binary.LittleEndian.Uint16(offsets[2*entryIndex:]); offset != 0xFFFF {
+				entryOffset = int(offset) * 4
+			}
+		}
+	default:
+		if entryIndex < entryCount && 4*entryIndex+4 <= len(offsets) {
+			if offset := binary.LittleEndian.Uint32(offsets[4*entryIndex:]); offset != 0xFFFFFFFF {
+				entryOffset = int(offset)
+			}
+		}
+	}
+	if entryOffset < 0 {
+		return arscValue{}, false
+	}
+
+	// ResTable_entry: size (2), flags (2), key (4), then a Res_value: size (2),
+	// res0 (1), dataType (1), data (4). Compact entries hold the value inline.
+	entry := entriesStart + entryOffset
+	if entry+8 > len(chunk) {
+		return arscValue{}, false
+	}
+	entrySize := int(binary.LittleEndian.Uint16(chunk[entry:]))
+	entryFlags := binary.LittleEndian.Uint16(chunk[entry+2:])
+	switch {
+	case entryFlags&0x08 != 0: // FLAG_COMPACT
+		return arscValue{resourceConfig: config, dataType: uint8(entryFlags >> 8), data: binary.LittleEndian.Uint32(chunk[entry+4:])}, true
+	case entryFlags&0x01 != 0: // FLAG_COMPLEX: a map, not a single value
+		return arscValue{}, false
+	}
+	value := entry + entrySize
+	if value+8 > len(chunk) {
+		return arscValue{}, false
+	}
+	return arscValue{resourceConfig: config, dataType: chunk[value+3], data: binary.LittleEndian.Uint32(chunk[value+4:])}, true
+}
+
+// parseResourceConfig reads a ResTable_config: size (4), imsi (4), language (2),
+// country (2), orientation (1), touchscreen (1), density (2), input (4),
+// screen size (4), sdkVersion (2), minorVersion (2), screen config (4),
+// screen size dp (4), localeScript (4), localeVariant (8), screen config 2 (4),
+// localeScriptWasComputed (1) and localeNumberingSystem (8)
+func parseResourceConfig(raw []byte) resourceConfig {
+	field := func(offset, size int) []byte {
+		if offset+size > len(raw) {
+			return make([]byte, size)
+		}
+		return raw[offset : offset+size]
+	}
+
+	config := resourceConfig{
+		density: binary.LittleEndian.Uint16(field(14, 2)),
+		sdk:     binary.LittleEndian.Uint16(field(24, 2)),
+	}
+
+	for i := 4; i < len(raw); i++ {
+		switch {
+		case i >= 8 && i < 12, i >= 36 && i < 48, i >= 52 && i < 61: // Locale
+		case i == 14 || i == 15 || i == 24 || i == 25: // Density and version
+		default:
+			config.other = config.other || raw[i] != 0
+		}
+	}
+
+	language := unpackLocaleCode(field(8, 2), 'a')
+	if language == "" {
+		return config
+	}
+
+	parts := []string{language}
+	// A script computed from the language is not part of the tag
+	if script := strings.TrimRight(string(field(36, 4)), "\x00"); script != "" && field(52, 1)[0] == 0 {
+		parts = append(parts, script)
+	}
+	if region := unpackLocaleCode(field(10, 2), '0'); region != "" {
+		parts = append(parts, region)
+	}
+	if variant := strings.TrimRight(string(field(40, 8)), "\x00"); variant != "" {
+		parts = append(parts, variant)
+	}
+	config.locale = strings.Join(parts, "-")
+
+	return config
+}
+
+// unpackLocaleCode decodes a language or region code, which holds either two
+// ASCII characters or three 5-bit characters relative to base when the high
+// bit is set
+func unpackLocaleCode(code []byte, base byte) string {
+	if code[0] == 0 {
+		return ""
+	}
+	if code[0]&0x80 == 0 {
+		return string(code)
+	}
+
+	first := code[1] & 0x1F
+	second := ((code[1] & 0xE0) >> 5) | ((code[0] & 0x03) << 3)
+	third := (code[0] & 0x7C) >> 2
+	return string([]byte{base + first, base + second, base + third})
+}
+
+// readChunkHeader reads a ResChunk_header at an offset
+func readChunkHeader(data []byte, offset int) (chunkType, headerSize uint16, size uint32, ok bool) {
+	if offset < 0 || offset+8 > len(data) {
+		return 0, 0, 0, false
+	}
+	chunkType = binary.LittleEndian.Uint16(data[offset:])
+	headerSize = binary.LittleEndian.Uint16(data[offset+2:])
+	size = binary.LittleEndian.Uint32(data[offset+4:])
+	if int(size) > len(data)-offset || int(headerSize) > int(size) {
+		return 0, 0, 0, false
+	}
+	return chunkType, headerSize, size, true
+}
+
+// parseStringPool decodes the strings of a ResStringPool chunk
+func parseStringPool(chunk []byte) ([]string, error) {
+	// header (8), stringCount (4), styleCount (4), flags (4), stringsStart (4),
+	// stylesStart (4)
+	if len(chunk) < 28 {
+		return nil, fmt.Errorf("truncated string pool")
+	}
+	headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+	count := int(binary.LittleEndian.Uint32(chunk[8:]))
+	utf8Pool := binary.LittleEndian.Uint32(chunk[16:])&(1<<8) != 0
+	stringsStart := int(binary.LittleEndian.Uint32(chunk[20:]))
+	if headerSize+4*count > len(chunk) || stringsStart > len(chunk) {
+		return nil, fmt.Errorf("truncated string pool")
+	}
+
+	pool := make([]string, count)
+	for i := range pool {
+		start := stringsStart + int(binary.LittleEndian.Uint32(chunk[headerSize+4*i:]))
+		if start >= len(chunk) {
+			continue
+		}
+		if utf8Pool {
+			pool[i] = decodeUTF8PoolString(chunk[start:])
+		} else {
+			pool[i] = decodeUTF16PoolString(chunk[start:])
+		}
+	}
+
+	return pool, nil
+}
+
+// decodeUTF8PoolString decodes a string of a UTF-8 pool: the UTF-16 length and
+// the byte length, each one or two bytes, then the bytes
+func decodeUTF8PoolString(data []byte) string {
+	pos := 0
+	readLength := func() int {
+		if pos >= len(data) {
+			return 0
+		}
+		length := int(data[pos])
+		pos++
+		if length&0x80 != 0 && pos < len(data) {
+			length = (length&0x7F)<<8 | int(data[pos])
+			pos++
+		}
+		return length
+	}
+	readLength()
+	length := readLength()
+	if pos+length > len(data) {
+		return ""
+	}
+	return string(data[pos : pos+length])
+}
+
+// decodeUTF16PoolString decodes a string of a UTF-16 pool: the length in code
+// units, one or two uint16, then the code units
+func decodeUTF16PoolString(data []byte) string {
+	if len(data) < 2 {
+		return ""
+	}
+	pos := 2
+	length := int(binary.LittleEndian.Uint16(data))
+	if length&0x8000 != 0 && len(data) >= 4 {
+		length = (length&0x7FFF)<<16 | int(binary.LittleEndian.Uint16(data[2:]))
+		pos = 4
+	}
+	if pos+2*length > len(data) {
+		return ""
+	}
+
+	units := make([]uint16, length)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[pos+2*i:])
+	}
+	return string(utf16.Decode(units))
+}
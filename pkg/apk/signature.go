@@ -0,0 +1,186 @@
+package apk
+
+import (
+	"archive/zip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+// ExtractSignatureInfo reads the signing certificate of an APK.
+// APK Signature Scheme v3 is preferred, then v2, then the v1 JAR signature.
+func ExtractSignatureInfo(apkPath string) (*models.SignatureInfo, error) {
+	cert, err := extractSigningCertificate(apkPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return signatureInfoFromCertificate(cert), nil
+}
+
+// extractSigningCertificate returns the signer certificate using the strongest scheme present
+func extractSigningCertificate(apkPath string) (*x509.Certificate, error) {
+	file, err := os.Open(apkPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open APK: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat APK: %w", err)
+	}
+
+	sections, err := findZipSections(file, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := findAPKSigningBlock(file, sections)
+	if err != nil {
+		return nil, err
+	}
+
+	if block != nil {
+		for _, scheme := range []struct {
+			id uint32
+			v3 bool
+		}{
+			{apkSignatureSchemeV31BlockID, true},
+			{apkSignatureSchemeV3BlockID, true},
+			{apkSignatureSchemeV2BlockID, false},
+		} {
+			data, ok := block.pairs[scheme.id]
+			if !ok {
+				continue
+			}
+			signers, err := parseSchemeSigners(data, scheme.v3)
+			if err != nil {
+				return nil, err
+			}
+			if cert := signers[0].leafCertificate(); cert != nil {
+				return cert, nil
+			}
+		}
+	}
+
+	zr, err := zip.NewReader(file, stat.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open APK as ZIP: %w", err)
+	}
+
+	return extractV1Certificate(zr)
+}
+
+// extractV1Certificate reads the certificate from the JAR signature block in META-INF
+func extractV1Certificate(zr *zip.Reader) (*x509.Certificate, error) {
+	blocks := findV1SignatureBlocks(zr)
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("APK is not signed")
+	}
+
+	data, err := readZipFile(blocks[0])
+	if err != nil {
+		return nil, err
+	}
+
+	p7, err := parsePKCS7(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, signer := range p7.Signers {
+		if cert := p7.signerCertificate(signer); cert != nil {
+			return cert, nil
+		}
+	}
+	if len(p7.Certificates) > 0 {
+		return p7.Certificates[0], nil
+	}
+
+	return nil, fmt.Errorf("no certificate found in %s", blocks[0].Name)
+}
+
+// findV1SignatureBlocks returns the META-INF signature block files sorted by name
+func findV1SignatureBlocks(zr *zip.Reader) []*zip.File {
+	var blocks []*zip.File
+	for _, f := range zr.File {
+		dir, name := path.Split(f.Name)
+		if !strings.EqualFold(dir, "META-INF/") {
+			continue
+		}
+		switch strings.ToUpper(path.Ext(name)) {
+		case ".RSA", ".DSA", ".EC":
+			blocks = append(blocks, f)
+		}
+	}
+
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Name < blocks[j].Name
+	})
+
+	return blocks
+}
+
+// readZipFile reads the full contents of a ZIP entry
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+
+	return data, nil
+}
+
+// signatureInfoFromCertificate builds fingerprints and names from a certificate
+func signatureInfoFromCertificate(cert *x509.Certificate) *models.SignatureInfo {
+	sha256Sum := sha256.Sum256(cert.Raw)
+	sha1Sum := sha1.Sum(cert.Raw)
+	md5Sum := md5.Sum(cert.Raw)
+
+	return &models.SignatureInfo{
+		SHA256:  hex.EncodeToString(sha256Sum[:]),
+		SHA1:    hex.EncodeToString(sha1Sum[:]),
+		MD5:     hex.EncodeToString(md5Sum[:]),
+		Issuer:  cert.Issuer.String(),
+		Subject: cert.Subject.String(),
+	}
+}
+
+// ErrSignerChanged is returned when an APK would replace one signed by a different
+// certificate and no v3 proof-of-rotation links the two
+var ErrSignerChanged = errors.New("signing certificate changed without rotation proof")
+
+// CheckSignerContinuity checks that an APK signed by newSigner may replace one signed
+// by oldSigner (hex SHA256 certificate digests). The v3 lineage of the new APK counts
+// as proof of rotation when it contains oldSigner. Unknown signers are not checked.
+func CheckSignerContinuity(oldSigner, newSigner string, lineage []string) error {
+	if oldSigner == "" || newSigner == "" || strings.EqualFold(oldSigner, newSigner) {
+		return nil
+	}
+
+	for _, past := range lineage {
+		if strings.EqualFold(past, oldSigner) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s -> %s", ErrSignerChanged, oldSigner, newSigner)
+}
@@ -0,0 +1,658 @@
+package apk
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha512" // SHA-384/SHA-512 digests
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+// Signature algorithm IDs used by APK Signature Scheme v2/v3
+const (
+	sigRSAPSSSHA256         uint32 = 0x0101
+	sigRSAPSSSHA512         uint32 = 0x0102
+	sigRSAPKCS1SHA256       uint32 = 0x0103
+	sigRSAPKCS1SHA512       uint32 = 0x0104
+	sigECDSASHA256          uint32 = 0x0201
+	sigECDSASHA512          uint32 = 0x0202
+	sigDSASHA256            uint32 = 0x0301
+	sigVerityRSAPKCS1SHA256 uint32 = 0x0421
+	sigVerityECDSASHA256    uint32 = 0x0423
+	sigVerityDSASHA256      uint32 = 0x0425
+)
+
+// contentDigestChunkSize is the chunk size used by v2/v3 content digests
+const contentDigestChunkSize = 1 << 20
+
+// VerifySignatures cryptographically verifies every signature scheme present in an APK.
+// An unsigned APK yields a result with no schemes and Verified set to false.
+func VerifySignatures(apkPath string) (*models.SignatureVerification, error) {
+	file, err := os.Open(apkPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open APK: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat APK: %w", err)
+	}
+
+	sections, err := findZipSections(file, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := zip.NewReader(file, stat.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open APK as ZIP: %w", err)
+	}
+
+	result := &models.SignatureVerification{
+		VerifiedAt: time.Now(),
+	}
+
+	block, blockErr := findAPKSigningBlock(file, sections)
+	hasV2 := false
+
+	if blockErr != nil {
+		result.Schemes = append(result.Schemes, models.SchemeVerification{
+			Scheme: "v2",
+			Valid:  false,
+			Error:  blockErr.Error(),
+		})
+	} else if block != nil {
+		verifier := &schemeVerifier{
+			r:           file,
+			sections:    sections,
+			blockOffset: block.offset,
+			digests:     make(map[crypto.Hash][]byte),
+		}
+
+		for _, scheme := range []struct {
+			name string
+			id   uint32
+			v3   bool
+		}{
+			{"v2", apkSignatureSchemeV2BlockID, false},
+			{"v3", apkSignatureSchemeV3BlockID, true},
+			{"v3.1", apkSignatureSchemeV31BlockID, true},
+		} {
+			data, ok := block.pairs[scheme.id]
+			if !ok {
+				continue
+			}
+			if scheme.id == apkSignatureSchemeV2BlockID {
+				hasV2 = true
+			}
+
+			lineage, err := verifier.verifyBlock(data, scheme.v3)
+			check := models.SchemeVerification{Scheme: scheme.name, Valid: err == nil}
+			if err != nil {
+				check.Error = err.Error()
+			}
+			result.Schemes = append(result.Schemes, check)
+
+			if err == nil && len(lineage) > 0 {
+				// v3.1 is verified after v3 and takes precedence for the lineage
+				result.Lineage = nil
+				for _, cert := range lineage {
+					result.Lineage = append(result.Lineage, signatureInfoFromCertificate(cert).SHA256)
+				}
+			}
+		}
+	}
+
+	if len(findV1SignatureBlocks(zr)) > 0 {
+		check := models.SchemeVerification{Scheme: "v1", Valid: true}
+		if err := verifyV1Signature(zr, hasV2); err != nil {
+			check.Valid = false
+			check.Error = err.Error()
+		}
+		// Keep schemes in ascending order
+		result.Schemes = append([]models.SchemeVerification{check}, result.Schemes...)
+	}
+
+	result.Verified = len(result.Schemes) > 0
+	for _, check := range result.Schemes {
+		if !check.Valid {
+			result.Verified = false
+		}
+	}
+
+	return result, nil
+}
+
+// schemeVerifier verifies v2/v3 signature blocks, caching content digests per hash
+type schemeVerifier struct {
+	r           io.ReaderAt
+	sections    *zipSections
+	blockOffset int64
+	digests     map[crypto.Hash][]byte
+}
+
+// verifyBlock verifies all signers of a v2/v3 block and returns the v3 lineage, if any
+func (v *schemeVerifier) verifyBlock(data []byte, v3 bool) ([]*x509.Certificate, error) {
+	signers, err := parseSchemeSigners(data, v3)
+	if err != nil {
+		return nil, err
+	}
+
+	var lineage []*x509.Certificate
+	for i, signer := range signers {
+		if err := v.verifySigner(signer); err != nil {
+			return nil, fmt.Errorf("signer #%d: %w", i+1, err)
+		}
+
+		if !v3 {
+			continue
+		}
+		if por, ok := signer.attributes[proofOfRotationAttrID]; ok {
+			certs, err := parseProofOfRotation(por)
+			if err != nil {
+				return nil, fmt.Errorf("signer #%d: invalid proof-of-rotation: %w", i+1, err)
+			}
+			if len(certs) > 0 && !bytes.Equal(certs[len(certs)-1].Raw, signer.certificates[0].Raw) {
+				return nil, fmt.Errorf("signer #%d: proof-of-rotation does not end with the signing certificate", i+1)
+			}
+			lineage = certs
+		}
+	}
+
+	return lineage, nil
+}
+
+// verifySigner checks the signatures and content digests of one signer
+func (v *schemeVerifier) verifySigner(signer *apkSigner) error {
+	if len(signer.signatures) == 0 {
+		return fmt.Errorf("no signatures")
+	}
+	if len(signer.certificates) == 0 {
+		return fmt.Errorf("no certificates")
+	}
+	if !bytes.Equal(signer.certificates[0].RawSubjectPublicKeyInfo, signer.publicKey) {
+		return fmt.Errorf("public key does not match the first certificate")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(signer.publicKey)
+	if err != nil {
+		return fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	verified := 0
+	for _, sig := range signer.signatures {
+		if _, _, ok := contentDigestHash(sig.algorithm); !ok {
+			continue // Unknown algorithms are ignored, as on device
+		}
+		if err := verifySchemeSignature(pub, sig.algorithm, signer.signedData, sig.signature); err != nil {
+			return fmt.Errorf("signature 0x%04x: %w", sig.algorithm, err)
+		}
+		verified++
+	}
+	if verified == 0 {
+		return fmt.Errorf("no supported signature algorithms")
+	}
+
+	if len(signer.digests) != len(signer.signatures) {
+		return fmt.Errorf("signature and digest algorithm lists differ")
+	}
+	for i, digest := range signer.digests {
+		if digest.algorithm != signer.signatures[i].algorithm {
+			return fmt.Errorf("signature and digest algorithm lists differ")
+		}
+	}
+
+	checked := 0
+	for _, digest := range signer.digests {
+		hash, verity, ok := contentDigestHash(digest.algorithm)
+		if !ok || verity {
+			continue
+		}
+
+		actual, err := v.contentDigest(hash)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(actual, digest.digest) {
+			return fmt.Errorf("content digest 0x%04x does not match APK contents", digest.algorithm)
+		}
+		checked++
+	}
+	if checked == 0 {
+		return fmt.Errorf("no supported content digest algorithms")
+	}
+
+	return nil
+}
+
+// contentDigest returns the chunked digest over entries, central directory and EOCD
+func (v *schemeVerifier) contentDigest(hash crypto.Hash) ([]byte, error) {
+	if digest, ok := v.digests[hash]; ok {
+		return digest, nil
+	}
+
+	// The EOCD is digested as if the central directory started at the signing block
+	eocd := append([]byte(nil), v.sections.eocd...)
+	binary.LittleEndian.PutUint32(eocd[16:], uint32(v.blockOffset))
+
+	parts := []io.Reader{
+		io.NewSectionReader(v.r, 0, v.blockOffset),
+		io.NewSectionReader(v.r, v.sections.centralDirOffset, v.sections.centralDirSize),
+		bytes.NewReader(eocd),
+	}
+
+	var chunkDigests []byte
+	chunkCount := uint32(0)
+	buf := make([]byte, contentDigestChunkSize)
+	prefix := make([]byte, 5)
+
+	for _, part := range parts {
+		for {
+			n, err := io.ReadFull(part, buf)
+			if n > 0 {
+				prefix[0] = 0xa5
+				binary.LittleEndian.PutUint32(prefix[1:], uint32(n))
+				h := hash.New()
+				h.Write(prefix)
+				h.Write(buf[:n])
+				chunkDigests = h.Sum(chunkDigests)
+				chunkCount++
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read APK contents: %w", err)
+			}
+		}
+	}
+
+	prefix[0] = 0x5a
+	binary.LittleEndian.PutUint32(prefix[1:], chunkCount)
+	h := hash.New()
+	h.Write(prefix)
+	h.Write(chunkDigests)
+
+	digest := h.Sum(nil)
+	v.digests[hash] = digest
+	return digest, nil
+}
+
+// contentDigestHash returns the hash behind a signature algorithm and whether it is a verity digest
+func contentDigestHash(algorithm uint32) (crypto.Hash, bool, bool) {
+	switch algorithm {
+	case sigRSAPSSSHA256, sigRSAPKCS1SHA256, sigECDSASHA256, sigDSASHA256:
+		return crypto.SHA256, false, true
+	case sigRSAPSSSHA512, sigRSAPKCS1SHA512, sigECDSASHA512:
+		return crypto.SHA512, false, true
+	case sigVerityRSAPKCS1SHA256, sigVerityECDSASHA256, sigVerityDSASHA256:
+		return crypto.SHA256, true, true
+	default:
+		return 0, false, false
+	}
+}
+
+// verifySchemeSignature verifies a v2/v3 signature over signed data
+func verifySchemeSignature(pub crypto.PublicKey, algorithm uint32, data, sig []byte) error {
+	hash, _, ok := contentDigestHash(algorithm)
+	if !ok {
+		return fmt.Errorf("unsupported signature algorithm 0x%04x", algorithm)
+	}
+
+	h := hash.New()
+	h.Write(data)
+	digest := h.Sum(nil)
+
+	switch algorithm {
+	case sigRSAPSSSHA256, sigRSAPSSSHA512:
+		key, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm requires an RSA key, got %T", pub)
+		}
+		return rsa.VerifyPSS(key, hash, digest, sig, &rsa.PSSOptions{SaltLength: hash.Size(), Hash: hash})
+	case sigRSAPKCS1SHA256, sigRSAPKCS1SHA512, sigVerityRSAPKCS1SHA256:
+		if _, ok := pub.(*rsa.PublicKey); !ok {
+			return fmt.Errorf("algorithm requires an RSA key, got %T", pub)
+		}
+	case sigECDSASHA256, sigECDSASHA512, sigVerityECDSASHA256:
+		if _, ok := pub.(*ecdsa.PublicKey); !ok {
+			return fmt.Errorf("algorithm requires an EC key, got %T", pub)
+		}
+	}
+
+	return verifyDigestSignature(pub, hash, digest, sig)
+}
+
+// parseProofOfRotation decodes and verifies a v3 signing certificate lineage
+func parseProofOfRotation(data []byte) ([]*x509.Certificate, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("truncated lineage")
+	}
+	data = data[4:] // Lineage format version
+
+	var certs []*x509.Certificate
+	var parent *x509.Certificate
+	var parentAlgorithm uint32
+
+	for len(data) > 0 {
+		var node []byte
+		var err error
+		node, data, err = readLengthPrefixed(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lineage node: %w", err)
+		}
+
+		signedData, rest, err := readLengthPrefixed(node)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lineage signed data: %w", err)
+		}
+		if len(rest) < 8 {
+			return nil, fmt.Errorf("truncated lineage node")
+		}
+		signatureAlgorithm := binary.LittleEndian.Uint32(rest[4:8])
+		signature, _, err := readLengthPrefixed(rest[8:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lineage signature: %w", err)
+		}
+
+		der, signedRest, err := readLengthPrefixed(signedData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lineage certificate: %w", err)
+		}
+		if len(signedRest) < 4 {
+			return nil, fmt.Errorf("truncated lineage signed data")
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse lineage certificate: %w", err)
+		}
+
+		if parent != nil {
+			algorithm := binary.LittleEndian.Uint32(signedRest[:4])
+			if algorithm != parentAlgorithm {
+				return nil, fmt.Errorf("lineage signature algorithm mismatch")
+			}
+			if err := verifySchemeSignature(parent.PublicKey, algorithm, signedData, signature); err != nil {
+				return nil, fmt.Errorf("lineage link %d: %w", len(certs), err)
+			}
+		}
+
+		certs = append(certs, cert)
+		parent = cert
+		parentAlgorithm = signatureAlgorithm
+	}
+
+	return certs, nil
+}
+
+// verifyV1Signature verifies the JAR signature: signature blocks, .SF files and MANIFEST.MF digests
+func verifyV1Signature(zr *zip.Reader, hasV2 bool) error {
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	mfFile, ok := files["META-INF/MANIFEST.MF"]
+	if !ok {
+		return fmt.Errorf("META-INF/MANIFEST.MF not found")
+	}
+	mfData, err := readZipFile(mfFile)
+	if err != nil {
+		return err
+	}
+	manifest, err := parseJarManifest(mfData)
+	if err != nil {
+		return fmt.Errorf("invalid MANIFEST.MF: %w", err)
+	}
+
+	for _, blockFile := range findV1SignatureBlocks(zr) {
+		sfName := strings.TrimSuffix(blockFile.Name, path.Ext(blockFile.Name)) + ".SF"
+		sfFile, ok := files[sfName]
+		if !ok {
+			return fmt.Errorf("%s not found for %s", sfName, blockFile.Name)
+		}
+
+		blockData, err := readZipFile(blockFile)
+		if err != nil {
+			return err
+		}
+		sfData, err := readZipFile(sfFile)
+		if err != nil {
+			return err
+		}
+
+		p7, err := parsePKCS7(blockData)
+		if err != nil {
+			return fmt.Errorf("%s: %w", blockFile.Name, err)
+		}
+		if len(p7.Signers) == 0 {
+			return fmt.Errorf("%s: no signers", blockFile.Name)
+		}
+		for _, signer := range p7.Signers {
+			if _, err := p7.verifySigner(signer, sfData); err != nil {
+				return fmt.Errorf("%s: %w", blockFile.Name, err)
+			}
+		}
+
+		sf, err := parseJarManifest(sfData)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", sfName, err)
+		}
+		if err := verifySignatureFile(sf, manifest, mfData, hasV2); err != nil {
+			return fmt.Errorf("%s: %w", sfName, err)
+		}
+	}
+
+	// Every entry outside of the signature files must be covered by MANIFEST.MF
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, "/") || isV1SignatureFile(f.Name) {
+			continue
+		}
+
+		section, ok := manifest.sections[f.Name]
+		if !ok {
+			return fmt.Errorf("entry %s is not covered by the signature", f.Name)
+		}
+
+		data, err := readZipFile(f)
+		if err != nil {
+			return err
+		}
+		if err := verifyJarDigests(section.attrs, "-Digest", data); err != nil {
+			return fmt.Errorf("entry %s: %w", f.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// verifySignatureFile checks a .SF file against MANIFEST.MF
+func verifySignatureFile(sf, manifest *jarManifest, mfData []byte, hasV2 bool) error {
+	if signedWith, ok := sf.main.attrs["X-Android-APK-Signed"]; ok && !hasV2 {
+		for _, id := range strings.Split(signedWith, ",") {
+			if strings.TrimSpace(id) == "2" {
+				return fmt.Errorf("APK was signed with scheme v2 but the v2 signature was stripped")
+			}
+		}
+	}
+
+	// A digest over the whole manifest makes per-section checks unnecessary
+	if err := verifyJarDigests(sf.main.attrs, "-Digest-Manifest", mfData); err == nil {
+		return nil
+	}
+
+	if err := verifyJarDigests(sf.main.attrs, "-Digest-Manifest-Main-Attributes", manifest.main.raw); err != nil && err != errNoJarDigest {
+		return fmt.Errorf("main attributes: %w", err)
+	}
+
+	for name, section := range sf.sections {
+		mfSection, ok := manifest.sections[name]
+		if !ok {
+			return fmt.Errorf("entry %s is not in MANIFEST.MF", name)
+		}
+		if err := verifyJarDigests(section.attrs, "-Digest", mfSection.raw); err != nil {
+			return fmt.Errorf("entry %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// errNoJarDigest is returned when no supported digest attribute is present
+var errNoJarDigest = fmt.Errorf("no supported digest attribute")
+
+// jarDigestAlgorithms maps JAR digest attribute prefixes to hash functions
+var jarDigestAlgorithms = map[string]crypto.Hash{
+	"SHA-512": crypto.SHA512,
+	"SHA-384": crypto.SHA384,
+	"SHA-256": crypto.SHA256,
+	"SHA1":    crypto.SHA1,
+	"SHA-1":   crypto.SHA1,
+	"MD5":     crypto.MD5,
+}
+
+// verifyJarDigests checks every supported "<alg><suffix>" attribute against data
+func verifyJarDigests(attrs map[string]string, suffix string, data []byte) error {
+	checked := 0
+	for name, hash := range jarDigestAlgorithms {
+		value, ok := attrs[name+suffix]
+		if !ok {
+			continue
+		}
+
+		expected, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid %s%s value", name, suffix)
+		}
+
+		h := hash.New()
+		h.Write(data)
+		if !bytes.Equal(h.Sum(nil), expected) {
+			return fmt.Errorf("%s%s mismatch", name, suffix)
+		}
+		checked++
+	}
+
+	if checked == 0 {
+		return errNoJarDigest
+	}
+
+	return nil
+}
+
+// isV1SignatureFile reports whether a ZIP entry is part of the JAR signature itself
+func isV1SignatureFile(name string) bool {
+	dir, base := path.Split(name)
+	if !strings.EqualFold(dir, "META-INF/") {
+		return false
+	}
+	if strings.EqualFold(base, "MANIFEST.MF") {
+		return true
+	}
+	switch strings.ToUpper(path.Ext(base)) {
+	case ".SF", ".RSA", ".DSA", ".EC":
+		return true
+	}
+	return false
+}
+
+// jarSection is one section of a JAR manifest or signature file
+type jarSection struct {
+	raw   []byte
+	attrs map[string]string
+}
+
+// jarManifest is a parsed JAR manifest or signature file
+type jarManifest struct {
+	main     *jarSection
+	sections map[string]*jarSection
+}
+
+// parseJarManifest parses a MANIFEST.MF or .SF file, keeping the raw bytes of each section
+func parseJarManifest(data []byte) (*jarManifest, error) {
+	m := &jarManifest{sections: make(map[string]*jarSection)}
+
+	start, pos := 0, 0
+	attrs := make(map[string]string)
+	lastKey := ""
+
+	flush := func(end int) error {
+		defer func() {
+			start = end
+			attrs = make(map[string]string)
+			lastKey = ""
+		}()
+
+		if len(attrs) == 0 {
+			return nil
+		}
+
+		section := &jarSection{raw: data[start:end], attrs: attrs}
+		if m.main == nil {
+			m.main = section
+			return nil
+		}
+
+		name, ok := attrs["Name"]
+		if !ok {
+			return fmt.Errorf("section without Name attribute")
+		}
+		m.sections[name] = section
+		return nil
+	}
+
+	for pos < len(data) {
+		next := len(data)
+		line := data[pos:]
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+			next = pos + i + 1
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		pos = next
+
+		if len(line) == 0 {
+			if err := flush(pos); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if line[0] == ' ' {
+			if lastKey == "" {
+				return nil, fmt.Errorf("continuation line without attribute")
+			}
+			attrs[lastKey] += string(line[1:])
+			continue
+		}
+
+		key, value, ok := strings.Cut(string(line), ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed line %q", string(line))
+		}
+		lastKey = key
+		attrs[key] = strings.TrimPrefix(value, " ")
+	}
+
+	if err := flush(len(data)); err != nil {
+		return nil, err
+	}
+	if m.main == nil {
+		m.main = &jarSection{attrs: make(map[string]string)}
+	}
+
+	return m, nil
+}
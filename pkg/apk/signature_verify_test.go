@@ -0,0 +1,560 @@
+package apk
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSigner is an RSA key with a self-signed certificate
+type testSigner struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+}
+
+// testSigners caches keys across tests, as generating them is slow
+var testSigners = map[string]*testSigner{}
+
+// newTestSigner returns the key and certificate for a common name
+func newTestSigner(t *testing.T, name string) *testSigner {
+	t.Helper()
+
+	if signer, ok := testSigners[name]; ok {
+		return signer
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(len(testSigners) + 1)),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer := &testSigner{key: key, cert: cert}
+	testSigners[name] = signer
+	return signer
+}
+
+// sha256 returns the certificate fingerprint as reported in SignatureInfo
+func (s *testSigner) sha256() string {
+	sum := sha256.Sum256(s.cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// sign returns an RSA PKCS#1 v1.5 SHA-256 signature over data
+func (s *testSigner) sign(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	digest := sha256.Sum256(data)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+// testEntry is a file stored in a test APK
+type testEntry struct {
+	name string
+	data []byte
+}
+
+// testAPKEntries returns the contents of a minimal unsigned APK
+func testAPKEntries() []testEntry {
+	return []testEntry{
+		{"AndroidManifest.xml", []byte("<manifest package=\"com.example.app\"/>")},
+		{"classes.dex", bytes.Repeat([]byte("dex\n035\x00"), 512)},
+		{"res/raw/data.bin", bytes.Repeat([]byte{0x01, 0x02, 0x03}, 1000)},
+	}
+}
+
+// buildTestZip writes the entries into a ZIP archive
+func buildTestZip(t *testing.T, entries []testEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		w, err := zw.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(entry.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// signTestV1 adds a JAR signature over the entries. apkSigned sets the
+// X-Android-APK-Signed attribute that announces a v2 signature.
+func signTestV1(t *testing.T, entries []testEntry, signer *testSigner, apkSigned bool) []testEntry {
+	t.Helper()
+
+	digest := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		return base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	var mf strings.Builder
+	mf.WriteString("Manifest-Version: 1.0\r\nCreated-By: test\r\n\r\n")
+	for _, entry := range entries {
+		fmt.Fprintf(&mf, "Name: %s\r\nSHA-256-Digest: %s\r\n\r\n", entry.name, digest(entry.data))
+	}
+
+	var sf strings.Builder
+	sf.WriteString("Signature-Version: 1.0\r\nCreated-By: test\r\n")
+	if apkSigned {
+		sf.WriteString("X-Android-APK-Signed: 2\r\n")
+	}
+	fmt.Fprintf(&sf, "SHA-256-Digest-Manifest: %s\r\n\r\n", digest([]byte(mf.String())))
+
+	return append(entries,
+		testEntry{"META-INF/MANIFEST.MF", []byte(mf.String())},
+		testEntry{"META-INF/CERT.SF", []byte(sf.String())},
+		testEntry{"META-INF/CERT.RSA", buildTestPKCS7(t, signer, []byte(sf.String()), false)},
+	)
+}
+
+// testLengthPrefixed concatenates the parts behind a uint32 length prefix
+func testLengthPrefixed(parts ...[]byte) []byte {
+	data := bytes.Join(parts, nil)
+	return append(binary.LittleEndian.AppendUint32(nil, uint32(len(data))), data...)
+}
+
+// testUint32 encodes a little-endian uint32
+func testUint32(v uint32) []byte {
+	return binary.LittleEndian.AppendUint32(nil, v)
+}
+
+// testContentDigest computes the v2/v3 SHA-256 content digest of an unsigned ZIP
+func testContentDigest(apk []byte, sections *zipSections) []byte {
+	var chunks []byte
+	count := 0
+	// Entries, central directory and EOCD are chunked separately
+	for _, section := range [][]byte{
+		apk[:sections.centralDirOffset],
+		apk[sections.centralDirOffset:sections.eocdOffset],
+		apk[sections.eocdOffset:],
+	} {
+		for len(section) > 0 {
+			n := min(len(section), contentDigestChunkSize)
+			h := sha256.New()
+			h.Write([]byte{0xa5})
+			h.Write(testUint32(uint32(n)))
+			h.Write(section[:n])
+			chunks = h.Sum(chunks)
+			section = section[n:]
+			count++
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte{0x5a})
+	h.Write(testUint32(uint32(count)))
+	h.Write(chunks)
+	return h.Sum(nil)
+}
+
+// testSchemeBlock describes a v2 or v3 block to add to a test APK
+type testSchemeBlock struct {
+	id      uint32
+	signer  *testSigner
+	lineage []byte // Encoded v3 proof-of-rotation, if any
+}
+
+// buildTestSchemeSigner encodes a block value with one signer over digest
+func buildTestSchemeSigner(t *testing.T, block testSchemeBlock, digest []byte) []byte {
+	t.Helper()
+
+	v3 := block.id != apkSignatureSchemeV2BlockID
+	sdkRange := bytes.Join([][]byte{testUint32(24), testUint32(0x7fffffff)}, nil)
+
+	signedData := bytes.Join([][]byte{
+		testLengthPrefixed(testLengthPrefixed(testUint32(sigRSAPKCS1SHA256), testLengthPrefixed(digest))),
+		testLengthPrefixed(testLengthPrefixed(block.signer.cert.Raw)),
+	}, nil)
+	if v3 {
+		var attrs []byte
+		if len(block.lineage) > 0 {
+			attrs = testLengthPrefixed(testUint32(proofOfRotationAttrID), block.lineage)
+		}
+		signedData = bytes.Join([][]byte{signedData, sdkRange, testLengthPrefixed(attrs)}, nil)
+	}
+
+	signer := [][]byte{testLengthPrefixed(signedData)}
+	if v3 {
+		signer = append(signer, sdkRange)
+	}
+	signer = append(signer,
+		testLengthPrefixed(testLengthPrefixed(testUint32(sigRSAPKCS1SHA256), testLengthPrefixed(block.signer.sign(t, signedData)))),
+		testLengthPrefixed(block.signer.cert.RawSubjectPublicKeyInfo),
+	)
+
+	return testLengthPrefixed(testLengthPrefixed(signer...))
+}
+
+// buildTestLineage encodes a proof-of-rotation lineage where each certificate
+// is signed by the one before it
+func buildTestLineage(t *testing.T, signers []*testSigner) []byte {
+	t.Helper()
+
+	lineage := testUint32(1)
+	for i, signer := range signers {
+		parentAlgorithm, algorithm := uint32(0), uint32(0)
+		if i > 0 {
+			parentAlgorithm = sigRSAPKCS1SHA256
+		}
+		if i < len(signers)-1 {
+			algorithm = sigRSAPKCS1SHA256
+		}
+
+		signedData := append(testLengthPrefixed(signer.cert.Raw), testUint32(parentAlgorithm)...)
+		var sig []byte
+		if i > 0 {
+			sig = signers[i-1].sign(t, signedData)
+		}
+
+		lineage = append(lineage, testLengthPrefixed(
+			testLengthPrefixed(signedData),
+			testUint32(3), // Flags
+			testUint32(algorithm),
+			testLengthPrefixed(sig),
+		)...)
+	}
+	return lineage
+}
+
+// signTestAPK inserts an APK Signing Block with the given blocks before the
+// central directory of an unsigned ZIP
+func signTestAPK(t *testing.T, unsigned []byte, blocks ...testSchemeBlock) []byte {
+	t.Helper()
+
+	sections, err := findZipSections(bytes.NewReader(unsigned), int64(len(unsigned)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cdOffset := int(sections.centralDirOffset)
+	digest := testContentDigest(unsigned, sections)
+
+	var pairs []byte
+	for _, block := range blocks {
+		value := buildTestSchemeSigner(t, block, digest)
+		pairs = binary.LittleEndian.AppendUint64(pairs, uint64(4+len(value)))
+		pairs = append(pairs, testUint32(block.id)...)
+		pairs = append(pairs, value...)
+	}
+	size := uint64(len(pairs) + 8 + 16)
+
+	var apk []byte
+	apk = append(apk, unsigned[:cdOffset]...)
+	apk = binary.LittleEndian.AppendUint64(apk, size)
+	apk = append(apk, pairs...)
+	apk = binary.LittleEndian.AppendUint64(apk, size)
+	apk = append(apk, apkSigBlockMagic...)
+	apk = append(apk, unsigned[cdOffset:]...)
+
+	// Point the EOCD at the central directory, which moved behind the block
+	eocdOffset := len(apk) - len(unsigned) + int(sections.eocdOffset)
+	binary.LittleEndian.PutUint32(apk[eocdOffset+16:], uint32(len(apk)-len(unsigned)+cdOffset))
+	return apk
+}
+
+// writeTestAPK stores APK bytes in a temporary file
+func writeTestAPK(t *testing.T, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "app.apk")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// verifyTestAPK runs VerifySignatures and returns the scheme results by name
+func verifyTestAPK(t *testing.T, data []byte) (map[string]string, []string, bool) {
+	t.Helper()
+
+	result, err := VerifySignatures(writeTestAPK(t, data))
+	if err != nil {
+		t.Fatalf("VerifySignatures: %v", err)
+	}
+
+	// Scheme name to its error, empty when valid
+	schemes := make(map[string]string)
+	for _, check := range result.Schemes {
+		if check.Valid {
+			schemes[check.Scheme] = ""
+		} else {
+			schemes[check.Scheme] = check.Error
+		}
+	}
+	return schemes, result.Lineage, result.Verified
+}
+
+// assertValidSchemes checks that exactly the wanted schemes were found and verified
+func assertValidSchemes(t *testing.T, schemes map[string]string, verified bool, want ...string) {
+	t.Helper()
+
+	if len(schemes) != len(want) {
+		t.Errorf("schemes = %v, want %v", schemes, want)
+	}
+	for _, name := range want {
+		if err, ok := schemes[name]; !ok || err != "" {
+			t.Errorf("scheme %s: present %v, error %q", name, ok, err)
+		}
+	}
+	if !verified {
+		t.Error("APK was not reported as verified")
+	}
+}
+
+func TestVerifySignaturesV1Only(t *testing.T) {
+	signer := newTestSigner(t, "v1")
+	apk := buildTestZip(t, signTestV1(t, testAPKEntries(), signer, false))
+
+	schemes, _, verified := verifyTestAPK(t, apk)
+	assertValidSchemes(t, schemes, verified, "v1")
+
+	info, err := ExtractSignatureInfo(writeTestAPK(t, apk))
+	if err != nil {
+		t.Fatalf("ExtractSignatureInfo: %v", err)
+	}
+	if info.SHA256 != signer.sha256() {
+		t.Errorf("signer = %s, want %s", info.SHA256, signer.sha256())
+	}
+}
+
+func TestVerifySignaturesV2(t *testing.T) {
+	signer := newTestSigner(t, "v2")
+	unsigned := buildTestZip(t, signTestV1(t, testAPKEntries(), signer, true))
+	apk := signTestAPK(t, unsigned, testSchemeBlock{id: apkSignatureSchemeV2BlockID, signer: signer})
+
+	schemes, lineage, verified := verifyTestAPK(t, apk)
+	assertValidSchemes(t, schemes, verified, "v1", "v2")
+	if len(lineage) != 0 {
+		t.Errorf("lineage = %v, want none", lineage)
+	}
+
+	info, err := ExtractSignatureInfo(writeTestAPK(t, apk))
+	if err != nil {
+		t.Fatalf("ExtractSignatureInfo: %v", err)
+	}
+	if info.SHA256 != signer.sha256() {
+		t.Errorf("signer = %s, want %s", info.SHA256, signer.sha256())
+	}
+}
+
+func TestVerifySignaturesV3(t *testing.T) {
+	signer := newTestSigner(t, "v2")
+	apk := signTestAPK(t, buildTestZip(t, testAPKEntries()),
+		testSchemeBlock{id: apkSignatureSchemeV2BlockID, signer: signer},
+		testSchemeBlock{id: apkSignatureSchemeV3BlockID, signer: signer},
+	)
+
+	schemes, _, verified := verifyTestAPK(t, apk)
+	assertValidSchemes(t, schemes, verified, "v2", "v3")
+}
+
+func TestVerifySignaturesRotatedLineage(t *testing.T) {
+	oldSigner := newTestSigner(t, "v2")
+	newSigner := newTestSigner(t, "rotated")
+	apk := signTestAPK(t, buildTestZip(t, testAPKEntries()),
+		testSchemeBlock{id: apkSignatureSchemeV2BlockID, signer: oldSigner},
+		testSchemeBlock{
+			id:      apkSignatureSchemeV3BlockID,
+			signer:  newSigner,
+			lineage: buildTestLineage(t, []*testSigner{oldSigner, newSigner}),
+		},
+	)
+
+	schemes, lineage, verified := verifyTestAPK(t, apk)
+	assertValidSchemes(t, schemes, verified, "v2", "v3")
+	want := []string{oldSigner.sha256(), newSigner.sha256()}
+	if len(lineage) != 2 || lineage[0] != want[0] || lineage[1] != want[1] {
+		t.Fatalf("lineage = %v, want %v", lineage, want)
+	}
+
+	// v3 takes precedence over v2 for the reported signer
+	info, err := ExtractSignatureInfo(writeTestAPK(t, apk))
+	if err != nil {
+		t.Fatalf("ExtractSignatureInfo: %v", err)
+	}
+	if info.SHA256 != newSigner.sha256() {
+		t.Errorf("signer = %s, want the rotated %s", info.SHA256, newSigner.sha256())
+	}
+	if err := CheckSignerContinuity(oldSigner.sha256(), info.SHA256, lineage); err != nil {
+		t.Errorf("CheckSignerContinuity: %v", err)
+	}
+}
+
+func TestVerifySignaturesUnsigned(t *testing.T) {
+	schemes, _, verified := verifyTestAPK(t, buildTestZip(t, testAPKEntries()))
+	if len(schemes) != 0 || verified {
+		t.Errorf("unsigned APK: schemes %v, verified %v", schemes, verified)
+	}
+}
+
+func TestVerifySignaturesDetectsTampering(t *testing.T) {
+	signer := newTestSigner(t, "v2")
+	other := newTestSigner(t, "rotated")
+
+	tests := []struct {
+		name   string
+		build  func() []byte
+		scheme string
+	}{
+		{
+			name: "v1 entry modified",
+			build: func() []byte {
+				entries := signTestV1(t, testAPKEntries(), signer, false)
+				entries[1].data = append([]byte(nil), entries[1].data...)
+				entries[1].data[0] ^= 0xff
+				return buildTestZip(t, entries)
+			},
+			scheme: "v1",
+		},
+		{
+			name: "v1 entry added",
+			build: func() []byte {
+				entries := signTestV1(t, testAPKEntries(), signer, false)
+				return buildTestZip(t, append(entries, testEntry{"assets/extra.txt", []byte("extra")}))
+			},
+			scheme: "v1",
+		},
+		{
+			name: "v2 signature stripped",
+			build: func() []byte {
+				return buildTestZip(t, signTestV1(t, testAPKEntries(), signer, true))
+			},
+			scheme: "v1",
+		},
+		{
+			name: "v2 content modified",
+			build: func() []byte {
+				apk := signTestAPK(t, buildTestZip(t, testAPKEntries()), testSchemeBlock{id: apkSignatureSchemeV2BlockID, signer: signer})
+				apk[100] ^= 0xff
+				return apk
+			},
+			scheme: "v2",
+		},
+		{
+			name: "v3 lineage link with a bad signature",
+			build: func() []byte {
+				lineage := buildTestLineage(t, []*testSigner{signer, other})
+				// The lineage ends with the signature over the rotated certificate
+				lineage[len(lineage)-1] ^= 0xff
+				return signTestAPK(t, buildTestZip(t, testAPKEntries()),
+					testSchemeBlock{id: apkSignatureSchemeV3BlockID, signer: other, lineage: lineage},
+				)
+			},
+			scheme: "v3",
+		},
+		{
+			name: "v3 lineage not ending with the signer",
+			build: func() []byte {
+				return signTestAPK(t, buildTestZip(t, testAPKEntries()),
+					testSchemeBlock{
+						id:      apkSignatureSchemeV3BlockID,
+						signer:  other,
+						lineage: buildTestLineage(t, []*testSigner{other, signer}),
+					},
+				)
+			},
+			scheme: "v3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schemes, _, verified := verifyTestAPK(t, tt.build())
+			if verified {
+				t.Error("tampered APK was reported as verified")
+			}
+			if err, ok := schemes[tt.scheme]; !ok || err == "" {
+				t.Errorf("scheme %s: present %v, error %q, want a failure", tt.scheme, ok, err)
+			}
+		})
+	}
+}
+
+func TestVerifySignaturesTruncatedAPK(t *testing.T) {
+	signer := newTestSigner(t, "v2")
+	unsigned := buildTestZip(t, signTestV1(t, testAPKEntries(), signer, true))
+	apk := signTestAPK(t, unsigned,
+		testSchemeBlock{id: apkSignatureSchemeV2BlockID, signer: signer},
+		testSchemeBlock{id: apkSignatureSchemeV3BlockID, signer: signer},
+	)
+
+	path := filepath.Join(t.TempDir(), "app.apk")
+	for n := 0; n < len(apk); n++ {
+		if err := os.WriteFile(path, apk[:n], 0644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := VerifySignatures(path)
+		if err == nil && result.Verified {
+			t.Fatalf("APK truncated to %d of %d bytes was reported as verified", n, len(apk))
+		}
+		if _, err := ExtractSignatureInfo(path); err == nil {
+			t.Fatalf("read a signer from an APK truncated to %d of %d bytes", n, len(apk))
+		}
+	}
+}
+
+func TestParseProofOfRotationTruncated(t *testing.T) {
+	lineage := buildTestLineage(t, []*testSigner{newTestSigner(t, "v2"), newTestSigner(t, "rotated")})
+
+	certs, err := parseProofOfRotation(lineage)
+	if err != nil || len(certs) != 2 {
+		t.Fatalf("parseProofOfRotation = %d certificates, %v, want 2", len(certs), err)
+	}
+
+	// Cutting the lineage after its first node leaves a valid, shorter lineage
+	first, _, err := readLengthPrefixed(lineage[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	boundary := 4 + 4 + len(first)
+
+	for n := 5; n < len(lineage); n++ {
+		if n == boundary {
+			continue
+		}
+		if _, err := parseProofOfRotation(lineage[:n]); err == nil {
+			t.Errorf("lineage truncated to %d of %d bytes was accepted", n, len(lineage))
+		}
+	}
+	if _, err := parseProofOfRotation(lineage[:3]); err == nil {
+		t.Error("lineage without a version was accepted")
+	}
+}
@@ -0,0 +1,352 @@
+package apk
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// apkSigBlockMagic terminates the APK Signing Block
+	apkSigBlockMagic = "APK Sig Block 42"
+
+	// Block IDs inside the APK Signing Block
+	apkSignatureSchemeV2BlockID  uint32 = 0x7109871a
+	apkSignatureSchemeV3BlockID  uint32 = 0xf05368c0
+	apkSignatureSchemeV31BlockID uint32 = 0x1b93ad61
+
+	// Additional attribute carrying the v3 proof-of-rotation lineage
+	proofOfRotationAttrID uint32 = 0x3ba06f8c
+
+	eocdSignature    uint32 = 0x06054b50
+	eocdMinSize             = 22
+	eocdMaxComment          = 0xffff
+	apkSigBlockMinSz        = 32
+)
+
+// zipSections describes the ZIP regions that APK signature schemes operate on
+type zipSections struct {
+	centralDirOffset int64
+	centralDirSize   int64
+	eocdOffset       int64
+	eocd             []byte
+	fileSize         int64
+}
+
+// apkSigningBlock holds the ID-value pairs found in the APK Signing Block
+type apkSigningBlock struct {
+	offset int64 // Offset of the block within the APK
+	pairs  map[uint32][]byte
+}
+
+// apkDigest is a content digest listed in a v2/v3 signer's signed data
+type apkDigest struct {
+	algorithm uint32
+	digest    []byte
+}
+
+// apkSignature is a signature over a v2/v3 signer's signed data
+type apkSignature struct {
+	algorithm uint32
+	signature []byte
+}
+
+// apkSigner is a single signer entry from a v2 or v3 signature block
+type apkSigner struct {
+	signedData   []byte
+	digests      []apkDigest
+	certificates []*x509.Certificate
+	attributes   map[uint32][]byte
+	minSDK       uint32 // v3 only
+	maxSDK       uint32 // v3 only
+	signatures   []apkSignature
+	publicKey    []byte
+}
+
+// findZipSections locates the central directory and end of central directory record
+func findZipSections(r io.ReaderAt, size int64) (*zipSections, error) {
+	if size < eocdMinSize {
+		return nil, fmt.Errorf("file too small to be a ZIP archive")
+	}
+
+	searchLen := int64(eocdMinSize + eocdMaxComment)
+	if searchLen > size {
+		searchLen = size
+	}
+
+	tail := make([]byte, searchLen)
+	if _, err := r.ReadAt(tail, size-searchLen); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read ZIP trailer: %w", err)
+	}
+
+	// Scan backwards for an EOCD record whose comment length reaches the end of file
+	for i := len(tail) - eocdMinSize; i >= 0; i-- {
+		if binary.LittleEndian.Uint32(tail[i:]) != eocdSignature {
+			continue
+		}
+
+		commentLen := int(binary.LittleEndian.Uint16(tail[i+20:]))
+		if i+eocdMinSize+commentLen != len(tail) {
+			continue
+		}
+
+		eocd := tail[i:]
+		cdSize := int64(binary.LittleEndian.Uint32(eocd[12:]))
+		cdOffset := int64(binary.LittleEndian.Uint32(eocd[16:]))
+		eocdOffset := size - searchLen + int64(i)
+
+		if cdOffset == 0xffffffff || cdSize == 0xffffffff {
+			return nil, fmt.Errorf("ZIP64 archives are not supported")
+		}
+		if cdOffset+cdSize > eocdOffset {
+			return nil, fmt.Errorf("central directory overlaps end of central directory record")
+		}
+
+		return &zipSections{
+			centralDirOffset: cdOffset,
+			centralDirSize:   cdSize,
+			eocdOffset:       eocdOffset,
+			eocd:             append([]byte(nil), eocd...),
+			fileSize:         size,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("end of central directory record not found")
+}
+
+// findAPKSigningBlock reads the APK Signing Block preceding the central directory
+func findAPKSigningBlock(r io.ReaderAt, sections *zipSections) (*apkSigningBlock, error) {
+	cdOffset := sections.centralDirOffset
+	if cdOffset < apkSigBlockMinSz {
+		return nil, nil
+	}
+
+	footer := make([]byte, 24)
+	if _, err := r.ReadAt(footer, cdOffset-24); err != nil {
+		return nil, fmt.Errorf("failed to read APK Signing Block footer: %w", err)
+	}
+
+	if string(footer[8:]) != apkSigBlockMagic {
+		// No signing block: the APK is only v1 signed or unsigned
+		return nil, nil
+	}
+
+	sizeInFooter := binary.LittleEndian.Uint64(footer[:8])
+	if sizeInFooter < 24 || sizeInFooter > uint64(cdOffset-8) {
+		return nil, fmt.Errorf("APK Signing Block size out of range: %d", sizeInFooter)
+	}
+
+	blockOffset := cdOffset - int64(sizeInFooter) - 8
+	block := make([]byte, sizeInFooter+8)
+	if _, err := r.ReadAt(block, blockOffset); err != nil {
+		return nil, fmt.Errorf("failed to read APK Signing Block: %w", err)
+	}
+
+	if sizeInHeader := binary.LittleEndian.Uint64(block[:8]); sizeInHeader != sizeInFooter {
+		return nil, fmt.Errorf("APK Signing Block sizes in header and footer do not match")
+	}
+
+	pairs := make(map[uint32][]byte)
+	buf := block[8 : len(block)-24]
+	for len(buf) > 0 {
+		if len(buf) < 8 {
+			return nil, fmt.Errorf("truncated APK Signing Block entry")
+		}
+		pairLen := binary.LittleEndian.Uint64(buf[:8])
+		buf = buf[8:]
+		if pairLen < 4 || pairLen > uint64(len(buf)) {
+			return nil, fmt.Errorf("APK Signing Block entry length out of range: %d", pairLen)
+		}
+		id := binary.LittleEndian.Uint32(buf[:4])
+		pairs[id] = buf[4:pairLen]
+		buf = buf[pairLen:]
+	}
+
+	return &apkSigningBlock{
+		offset: blockOffset,
+		pairs:  pairs,
+	}, nil
+}
+
+// parseSchemeSigners parses the signer list of a v2 (v3=false) or v3 (v3=true) block
+func parseSchemeSigners(block []byte, v3 bool) ([]*apkSigner, error) {
+	signersSeq, _, err := readLengthPrefixed(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signers: %w", err)
+	}
+
+	var signers []*apkSigner
+	for len(signersSeq) > 0 {
+		var signerData []byte
+		signerData, signersSeq, err = readLengthPrefixed(signersSeq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signer: %w", err)
+		}
+
+		signer, err := parseSchemeSigner(signerData, v3)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("no signers found")
+	}
+
+	return signers, nil
+}
+
+// parseSchemeSigner parses one signer record
+func parseSchemeSigner(data []byte, v3 bool) (*apkSigner, error) {
+	signer := &apkSigner{attributes: make(map[uint32][]byte)}
+
+	var err error
+	signer.signedData, data, err = readLengthPrefixed(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signed data: %w", err)
+	}
+
+	if v3 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("truncated v3 signer SDK range")
+		}
+		signer.minSDK = binary.LittleEndian.Uint32(data[0:4])
+		signer.maxSDK = binary.LittleEndian.Uint32(data[4:8])
+		data = data[8:]
+	}
+
+	var signaturesSeq []byte
+	signaturesSeq, data, err = readLengthPrefixed(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signatures: %w", err)
+	}
+	for len(signaturesSeq) > 0 {
+		var record []byte
+		record, signaturesSeq, err = readLengthPrefixed(signaturesSeq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signature record: %w", err)
+		}
+		if len(record) < 4 {
+			return nil, fmt.Errorf("truncated signature record")
+		}
+		sig, _, err := readLengthPrefixed(record[4:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signature: %w", err)
+		}
+		signer.signatures = append(signer.signatures, apkSignature{
+			algorithm: binary.LittleEndian.Uint32(record[:4]),
+			signature: sig,
+		})
+	}
+
+	signer.publicKey, _, err = readLengthPrefixed(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	if err := signer.parseSignedData(v3); err != nil {
+		return nil, err
+	}
+
+	return signer, nil
+}
+
+// parseSignedData decodes the digests, certificates and attributes of a signer
+func (s *apkSigner) parseSignedData(v3 bool) error {
+	data := s.signedData
+
+	digestsSeq, data, err := readLengthPrefixed(data)
+	if err != nil {
+		return fmt.Errorf("failed to read digests: %w", err)
+	}
+	for len(digestsSeq) > 0 {
+		var record []byte
+		record, digestsSeq, err = readLengthPrefixed(digestsSeq)
+		if err != nil {
+			return fmt.Errorf("failed to read digest record: %w", err)
+		}
+		if len(record) < 4 {
+			return fmt.Errorf("truncated digest record")
+		}
+		digest, _, err := readLengthPrefixed(record[4:])
+		if err != nil {
+			return fmt.Errorf("failed to read digest: %w", err)
+		}
+		s.digests = append(s.digests, apkDigest{
+			algorithm: binary.LittleEndian.Uint32(record[:4]),
+			digest:    digest,
+		})
+	}
+
+	certsSeq, data, err := readLengthPrefixed(data)
+	if err != nil {
+		return fmt.Errorf("failed to read certificates: %w", err)
+	}
+	for len(certsSeq) > 0 {
+		var der []byte
+		der, certsSeq, err = readLengthPrefixed(certsSeq)
+		if err != nil {
+			return fmt.Errorf("failed to read certificate: %w", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		s.certificates = append(s.certificates, cert)
+	}
+
+	if v3 {
+		if len(data) < 8 {
+			return fmt.Errorf("truncated v3 signed data SDK range")
+		}
+		data = data[8:]
+	}
+
+	attrsSeq, _, err := readLengthPrefixed(data)
+	if err != nil {
+		// Attributes are optional in practice; treat a missing sequence as empty
+		return nil
+	}
+	for len(attrsSeq) > 0 {
+		var record []byte
+		record, attrsSeq, err = readLengthPrefixed(attrsSeq)
+		if err != nil {
+			return fmt.Errorf("failed to read attribute: %w", err)
+		}
+		if len(record) < 4 {
+			return fmt.Errorf("truncated attribute record")
+		}
+		s.attributes[binary.LittleEndian.Uint32(record[:4])] = record[4:]
+	}
+
+	return nil
+}
+
+// readLengthPrefixed reads a uint32 little-endian length-prefixed slice
+func readLengthPrefixed(buf []byte) ([]byte, []byte, error) {
+	if len(buf) < 4 {
+		return nil, nil, fmt.Errorf("remaining buffer too short for length prefix")
+	}
+	n := binary.LittleEndian.Uint32(buf[:4])
+	buf = buf[4:]
+	if uint64(n) > uint64(len(buf)) {
+		return nil, nil, fmt.Errorf("length prefix %d exceeds remaining %d bytes", n, len(buf))
+	}
+	return buf[:n], buf[n:], nil
+}
+
+// leafCertificate returns the signer's certificate matching its public key
+func (s *apkSigner) leafCertificate() *x509.Certificate {
+	for _, cert := range s.certificates {
+		if bytes.Equal(cert.RawSubjectPublicKeyInfo, s.publicKey) {
+			return cert
+		}
+	}
+	if len(s.certificates) > 0 {
+		return s.certificates[0]
+	}
+	return nil
+}
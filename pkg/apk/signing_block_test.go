@@ -0,0 +1,191 @@
+package apk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// testSignedAPK returns an APK with v2 and v3 blocks, the v3 one with a lineage
+func testSignedAPK(t *testing.T) []byte {
+	t.Helper()
+
+	oldSigner := newTestSigner(t, "v2")
+	newSigner := newTestSigner(t, "rotated")
+	return signTestAPK(t, buildTestZip(t, testAPKEntries()),
+		testSchemeBlock{id: apkSignatureSchemeV2BlockID, signer: oldSigner},
+		testSchemeBlock{
+			id:      apkSignatureSchemeV3BlockID,
+			signer:  newSigner,
+			lineage: buildTestLineage(t, []*testSigner{oldSigner, newSigner}),
+		},
+	)
+}
+
+// readTestSigningBlock locates the APK Signing Block of an in-memory APK
+func readTestSigningBlock(data []byte) (*apkSigningBlock, error) {
+	r := bytes.NewReader(data)
+	sections, err := findZipSections(r, int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return findAPKSigningBlock(r, sections)
+}
+
+func TestFindAPKSigningBlock(t *testing.T) {
+	unsigned := buildTestZip(t, testAPKEntries())
+	apk := testSignedAPK(t)
+
+	block, err := readTestSigningBlock(apk)
+	if err != nil {
+		t.Fatalf("findAPKSigningBlock: %v", err)
+	}
+	if block == nil {
+		t.Fatal("signing block not found")
+	}
+	for _, id := range []uint32{apkSignatureSchemeV2BlockID, apkSignatureSchemeV3BlockID} {
+		if _, ok := block.pairs[id]; !ok {
+			t.Errorf("block 0x%08x missing", id)
+		}
+	}
+
+	// The block starts where the unsigned central directory did
+	sections, err := findZipSections(bytes.NewReader(unsigned), int64(len(unsigned)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.offset != sections.centralDirOffset {
+		t.Errorf("block offset = %d, want %d", block.offset, sections.centralDirOffset)
+	}
+
+	block, err = readTestSigningBlock(unsigned)
+	if err != nil || block != nil {
+		t.Errorf("unsigned APK: block %v, error %v, want neither", block, err)
+	}
+}
+
+func TestFindZipSectionsRejectsCorruptArchives(t *testing.T) {
+	apk := buildTestZip(t, testAPKEntries())
+	eocdOffset := len(apk) - eocdMinSize
+
+	withEOCD := func(offset int, value uint32) []byte {
+		data := append([]byte(nil), apk...)
+		binary.LittleEndian.PutUint32(data[eocdOffset+offset:], value)
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"too small", apk[len(apk)-eocdMinSize+1:]},
+		{"no end of central directory", bytes.Repeat([]byte{0x50}, 1000)},
+		{"central directory past the end", withEOCD(16, uint32(len(apk)))},
+		{"central directory too large", withEOCD(12, uint32(len(apk)))},
+		{"zip64", withEOCD(16, 0xffffffff)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := findZipSections(bytes.NewReader(tt.data), int64(len(tt.data))); err == nil {
+				t.Error("findZipSections succeeded")
+			}
+		})
+	}
+}
+
+func TestFindAPKSigningBlockRejectsCorruptBlocks(t *testing.T) {
+	apk := testSignedAPK(t)
+	block, err := readTestSigningBlock(apk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := int(block.offset)
+	sections, err := findZipSections(bytes.NewReader(apk), int64(len(apk)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	footer := int(sections.centralDirOffset) - 24
+
+	withUint64 := func(offset int, value uint64) []byte {
+		data := append([]byte(nil), apk...)
+		binary.LittleEndian.PutUint64(data[offset:], value)
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"size beyond the start of file", withUint64(footer, uint64(footer)+100)},
+		{"size below the minimum", withUint64(footer, 8)},
+		{"header and footer sizes differ", withUint64(start, 12345)},
+		{"pair longer than the block", withUint64(start+8, 1<<20)},
+		{"pair shorter than its ID", withUint64(start+8, 2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readTestSigningBlock(tt.data); err == nil {
+				t.Error("findAPKSigningBlock succeeded")
+			}
+		})
+	}
+}
+
+func TestParseSchemeSignersRejectsTruncatedBlocks(t *testing.T) {
+	block, err := readTestSigningBlock(testSignedAPK(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, scheme := range []struct {
+		name string
+		id   uint32
+		v3   bool
+	}{
+		{"v2", apkSignatureSchemeV2BlockID, false},
+		{"v3", apkSignatureSchemeV3BlockID, true},
+	} {
+		data := block.pairs[scheme.id]
+		signers, err := parseSchemeSigners(data, scheme.v3)
+		if err != nil {
+			t.Fatalf("%s: parseSchemeSigners: %v", scheme.name, err)
+		}
+		if len(signers) != 1 || len(signers[0].certificates) != 1 || len(signers[0].digests) != 1 {
+			t.Fatalf("%s: parsed %+v", scheme.name, signers)
+		}
+
+		for n := 0; n < len(data); n++ {
+			if _, err := parseSchemeSigners(data[:n], scheme.v3); err == nil {
+				t.Errorf("%s: block truncated to %d of %d bytes was accepted", scheme.name, n, len(data))
+			}
+		}
+	}
+}
+
+func TestParseSchemeSignersSurvivesCorruptLengths(t *testing.T) {
+	block, err := readTestSigningBlock(testSignedAPK(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Overwrite every position with length prefixes that overrun or cut short
+	// the data that follows; parsing may fail but must not panic
+	data := block.pairs[apkSignatureSchemeV3BlockID]
+	for i := 0; i+4 <= len(data); i++ {
+		for _, value := range []uint32{0, 1, 3, 0x7fffffff, 0xffffffff} {
+			corrupt := append([]byte(nil), data...)
+			binary.LittleEndian.PutUint32(corrupt[i:], value)
+			for _, v3 := range []bool{false, true} {
+				signers, err := parseSchemeSigners(corrupt, v3)
+				if err == nil {
+					for _, signer := range signers {
+						signer.leafCertificate()
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package apk
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/shogo82148/androidbinary"
+)
+
+// SplitInfo identifies one APK of a split application
+type SplitInfo struct {
+	PackageID      string
+	Name           string // Split name, empty for the base APK
+	ConfigForSplit string // Split the config split applies to, empty for the base APK
+	IsFeature      bool   // Dynamic feature module rather than a config split
+}
+
+// IsBase reports whether the APK is the base APK
+func (s *SplitInfo) IsBase() bool {
+	return s.Name == ""
+}
+
+// IsConfig reports whether the APK is a configuration split
+// (ABI, screen density, language...)
+func (s *SplitInfo) IsConfig() bool {
+	return s.Name != "" && !s.IsFeature
+}
+
+// ConfigQualifier returns the qualifier of a config split, e.g. "arm64_v8a"
+// for "config.arm64_v8a" or "feature.config.xxhdpi"
+func (s *SplitInfo) ConfigQualifier() string {
+	if !s.IsConfig() {
+		return ""
+	}
+	if i := strings.LastIndex(s.Name, "config."); i >= 0 {
+		return s.Name[i+len("config."):]
+	}
+	return s.Name
+}
+
+// splitManifest holds the split attributes of AndroidManifest.xml
+type splitManifest struct {
+	Package        string `xml:"package,attr"`
+	Split          string `xml:"split,attr"`
+	ConfigForSplit string `xml:"configForSplit,attr"`
+	IsFeatureSplit string `xml:"http://schemas.android.com/apk/res/android isFeatureSplit,attr"`
+}
+
+// ReadSplitInfo reads the split attributes from the manifest of an APK
+func ReadSplitInfo(apkPath string) (*SplitInfo, error) {
+	reader, err := zip.OpenReader(apkPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.Name != "AndroidManifest.xml" {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		xmlFile, err := androidbinary.NewXMLFile(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse AndroidManifest.xml: %w", err)
+		}
+
+		var manifest splitManifest
+		if err := xmlFile.Decode(&manifest, nil, nil); err != nil {
+			return nil, fmt.Errorf("failed to decode AndroidManifest.xml: %w", err)
+		}
+
+		info := &SplitInfo{
+			PackageID:      manifest.Package,
+			Name:           manifest.Split,
+			ConfigForSplit: manifest.ConfigForSplit,
+		}
+		// Older bundletool versions do not set isFeatureSplit
+		info.IsFeature = manifest.IsFeatureSplit == "true" ||
+			(info.Name != "" && info.ConfigForSplit == "" && !strings.HasPrefix(info.Name, "config."))
+
+		return info, nil
+	}
+
+	return nil, fmt.Errorf("AndroidManifest.xml not found in %s", filepath.Base(apkPath))
+}
+
+// SplitInfoFromFileName guesses the split from the file name used by bundletool
+// and XAPK packagers ("base.apk", "split_config.arm64_v8a.apk", "config.en.apk"),
+// for APKs whose manifest cannot be read
+func SplitInfoFromFileName(apkPath string) *SplitInfo {
+	name := strings.TrimSuffix(filepath.Base(apkPath), filepath.Ext(apkPath))
+	name = strings.TrimPrefix(name, "split_")
+
+	// Anything else is taken as the base APK, which XAPK files often name after
+	// the package
+	info := &SplitInfo{}
+	if strings.HasPrefix(name, "config.") {
+		info.Name = name
+	} else if feature, _, found := strings.Cut(name, ".config."); found {
+		info.Name = name
+		info.ConfigForSplit = feature
+	}
+
+	return info
+}
@@ -0,0 +1,552 @@
+package apk
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// renderVector renders a <vector> drawable: its viewport is scaled to the
+// size, then groups, clip paths and paths are drawn in document order
+func (r *drawableRenderer) renderVector(vector *xmlElement, width, height int) (*image.RGBA, error) {
+	viewportWidth := r.float(vector.attr(androidNamespace, "viewportWidth"), 0)
+	viewportHeight := r.float(vector.attr(androidNamespace, "viewportHeight"), 0)
+	if viewportWidth <= 0 || viewportHeight <= 0 {
+		return nil, fmt.Errorf("vector has no viewport")
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	transform := scaleAffine(float64(width)/viewportWidth, float64(height)/viewportHeight)
+	if err := r.renderVectorGroup(canvas, vector, transform, nil); err != nil {
+		return nil, err
+	}
+
+	if tint, ok := r.colorOf(vector.attr(androidNamespace, "tint")); ok {
+		tintImage(canvas, tint)
+	}
+	if alpha := r.float(vector.attr(androidNamespace, "alpha"), 1); alpha < 1 {
+		fadeImage(canvas, alpha)
+	}
+
+	return canvas, nil
+}
+
+// renderVectorGroup draws the children of a <vector> or <group>. A clip path
+// applies to the siblings after it.
+func (r *drawableRenderer) renderVectorGroup(canvas *image.RGBA, group *xmlElement, transform affine, clip *image.Alpha) error {
+	size := canvas.Bounds().Size()
+	attr := func(element *xmlElement, name string, def float64) float64 {
+		return r.float(element.attr(androidNamespace, name), def)
+	}
+
+	for _, child := range group.children {
+		switch child.name {
+		case "group":
+			pivotX, pivotY := attr(child, "pivotX", 0), attr(child, "pivotY", 0)
+			local := translateAffine(-pivotX, -pivotY).
+				then(scaleAffine(attr(child, "scaleX", 1), attr(child, "scaleY", 1))).
+				then(rotateAffine(attr(child, "rotation", 0))).
+				then(translateAffine(attr(child, "translateX", 0)+pivotX, attr(child, "translateY", 0)+pivotY))
+			if err := r.renderVectorGroup(canvas, child, local.then(transform), clip); err != nil {
+				return err
+			}
+
+		case "clip-path":
+			lines, err := parsePathData(r.string(child.attr(androidNamespace, "pathData")), transform)
+			if err != nil {
+				return err
+			}
+			clip = intersectMasks(clip, rasterize(size.X, size.Y, lines, false))
+
+		case "path":
+			lines, err := parsePathData(r.string(child.attr(androidNamespace, "pathData")), transform)
+			if err != nil {
+				return err
+			}
+
+			fillType := child.attr(androidNamespace, "fillType")
+			evenOdd := fillType.text() == "evenOdd" || fillType.int() == 1
+			if paint := r.vectorPaint(child.attr(androidNamespace, "fillColor"), transform); paint != nil {
+				mask := rasterize(size.X, size.Y, lines, evenOdd)
+				drawPaint(canvas, paint, mask, clip, attr(child, "fillAlpha", 1))
+			}
+
+			strokeWidth := attr(child, "strokeWidth", 0) * transform.scale()
+			if paint := r.vectorPaint(child.attr(androidNamespace, "strokeColor"), transform); paint != nil && strokeWidth > 0 {
+				mask := rasterize(size.X, size.Y, strokePolygons(lines, strokeWidth), false)
+				drawPaint(canvas, paint, mask, clip, attr(child, "strokeAlpha", 1))
+			}
+		}
+	}
+
+	return nil
+}
+
+// vectorPaint returns the paint of a fill or stroke color: a color, or a
+// gradient in the coordinates of the path. It returns nil for no paint.
+func (r *drawableRenderer) vectorPaint(attr *xmlAttr, transform affine) image.Image {
+	if attr == nil {
+		return nil
+	}
+	if c, ok := r.colorOf(attr); ok {
+		if c.A == 0 {
+			return nil
+		}
+		return image.NewUniform(c)
+	}
+
+	element, err := r.loadXML(r.resolveAttr(attr).file)
+	if err != nil || element.name != "gradient" {
+		return nil
+	}
+	stops := r.gradientStops(element)
+	if len(stops) == 0 {
+		return nil
+	}
+
+	value := func(name string) float64 {
+		return r.float(element.attr(androidNamespace, name), 0)
+	}
+	return &gradientPaint{
+		kind:     int(value("type")),
+		tileMode: int(value("tileMode")),
+		start:    transform.apply(point{value("startX"), value("startY")}),
+		end:      transform.apply(point{value("endX"), value("endY")}),
+		center:   transform.apply(point{value("centerX"), value("centerY")}),
+		radius:   value("gradientRadius") * transform.scale(),
+		stops:    stops,
+	}
+}
+
+// drawPaint draws a paint through a coverage mask, a clip and an opacity
+func drawPaint(canvas *image.RGBA, paint image.Image, mask, clip *image.Alpha, alpha float64) {
+	mask = intersectMasks(mask, clip)
+	if alpha < 1 {
+		scale := clamp(alpha, 0, 1)
+		for i, value := range mask.Pix {
+			mask.Pix[i] = uint8(float64(value)*scale + 0.5)
+		}
+	}
+	draw.DrawMask(canvas, canvas.Bounds(), paint, image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// intersectMasks multiplies two masks of the same size; a nil mask covers
+// everything
+func intersectMasks(a, b *image.Alpha) *image.Alpha {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	}
+	result := image.NewAlpha(a.Rect)
+	for i := range result.Pix {
+		result.Pix[i] = uint8((int(a.Pix[i])*int(b.Pix[i]) + 127) / 255)
+	}
+	return result
+}
+
+// tintImage replaces the colors of an image by a tint, keeping their coverage
+// (the default src_in tint mode)
+func tintImage(img *image.RGBA, tint color.NRGBA) {
+	for i := 0; i < len(img.Pix); i += 4 {
+		alpha := int(img.Pix[i+3]) * int(tint.A) / 255
+		img.Pix[i] = uint8(int(tint.R) * alpha / 255)
+		img.Pix[i+1] = uint8(int(tint.G) * alpha / 255)
+		img.Pix[i+2] = uint8(int(tint.B) * alpha / 255)
+		img.Pix[i+3] = uint8(alpha)
+	}
+}
+
+// fadeImage multiplies the opacity of an image
+func fadeImage(img *image.RGBA, alpha float64) {
+	scale := clamp(alpha, 0, 1)
+	for i, value := range img.Pix {
+		img.Pix[i] = uint8(float64(value)*scale + 0.5)
+	}
+}
+
+// parsePathData parses SVG path data, as used by android:pathData, into
+// polylines in the coordinates of the transform
+func parsePathData(data string, transform affine) ([]polyline, error) {
+	builder := &pathBuilder{transform: transform}
+	tokens := &pathTokens{data: data}
+
+	var command byte
+	for {
+		tokens.skipSeparators()
+		if tokens.done() {
+			break
+		}
+		if c := tokens.data[tokens.pos]; isPathCommand(c) {
+			command = c
+			tokens.pos++
+		} else if command == 0 {
+			return nil, fmt.Errorf("path data must start with a command: %q", truncate(data, 32))
+		}
+
+		relative := command >= 'a'
+		base := builder.current
+		if !relative {
+			base = point{}
+		}
+		at := func(x, y float64) point { return point{base.x + x, base.y + y} }
+
+		var err error
+		switch command | 0x20 {
+		case 'm':
+			var p []float64
+			if p, err = tokens.numbers(2); err == nil {
+				builder.moveTo(at(p[0], p[1]))
+				// Further coordinate pairs are implicit line commands
+				command = 'L' | (command & 0x20)
+			}
+		case 'l':
+			var p []float64
+			if p, err = tokens.numbers(2); err == nil {
+				builder.lineTo(at(p[0], p[1]))
+			}
+		case 'h':
+			var p []float64
+			if p, err = tokens.numbers(1); err == nil {
+				x := p[0]
+				if relative {
+					x += builder.current.x
+				}
+				builder.lineTo(point{x, builder.current.y})
+			}
+		case 'v':
+			var p []float64
+			if p, err = tokens.numbers(1); err == nil {
+				y := p[0]
+				if relative {
+					y += builder.current.y
+				}
+				builder.lineTo(point{builder.current.x, y})
+			}
+		case 'c':
+			var p []float64
+			if p, err = tokens.numbers(6); err == nil {
+				builder.cubicTo(at(p[0], p[1]), at(p[2], p[3]), at(p[4], p[5]))
+			}
+		case 's':
+			var p []float64
+			if p, err = tokens.numbers(4); err == nil {
+				builder.cubicTo(builder.reflectedControl('c'), at(p[0], p[1]), at(p[2], p[3]))
+			}
+		case 'q':
+			var p []float64
+			if p, err = tokens.numbers(4); err == nil {
+				builder.quadTo(at(p[0], p[1]), at(p[2], p[3]))
+			}
+		case 't':
+			var p []float64
+			if p, err = tokens.numbers(2); err == nil {
+				builder.quadTo(builder.reflectedControl('q'), at(p[0], p[1]))
+			}
+		case 'a':
+			var p []float64
+			if p, err = tokens.arc(); err == nil {
+				builder.arcTo(p[0], p[1], p[2], p[3] != 0, p[4] != 0, at(p[5], p[6]))
+			}
+		case 'z':
+			builder.close()
+			// Z takes no arguments; a number after it is an error
+			tokens.skipSeparators()
+			if !tokens.done() && !isPathCommand(tokens.data[tokens.pos]) {
+				err = fmt.Errorf("unexpected number after Z")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid path data %q: %w", truncate(data, 32), err)
+		}
+	}
+
+	builder.finish()
+	return builder.lines, nil
+}
+
+// isPathCommand reports whether a character is a path command letter
+func isPathCommand(c byte) bool {
+	return strings.IndexByte("MmLlHhVvCcSsQqTtAaZz", c) >= 0
+}
+
+// truncate shortens a string for error messages
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	return s[:length] + "..."
+}
+
+// pathTokens reads the numbers of path data
+type pathTokens struct {
+	data string
+	pos  int
+}
+
+// done reports whether all data was read
+func (t *pathTokens) done() bool {
+	return t.pos >= len(t.data)
+}
+
+// skipSeparators skips whitespace and commas
+func (t *pathTokens) skipSeparators() {
+	for !t.done() && strings.IndexByte(" \t\r\n,", t.data[t.pos]) >= 0 {
+		t.pos++
+	}
+}
+
+// number reads a number; signs and a second decimal point start a new one, as
+// in "1-2" and "0.5.5"
+func (t *pathTokens) number() (float64, error) {
+	t.skipSeparators()
+	start := t.pos
+	if !t.done() && (t.data[t.pos] == '-' || t.data[t.pos] == '+') {
+		t.pos++
+	}
+	digits, dot := false, false
+	for !t.done() {
+		c := t.data[t.pos]
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c == '.' && !dot:
+			dot = true
+		case (c == 'e' || c == 'E') && digits:
+			t.pos++
+			if !t.done() && (t.data[t.pos] == '-' || t.data[t.pos] == '+') {
+				t.pos++
+			}
+			continue
+		default:
+			return t.parse(start, digits)
+		}
+		t.pos++
+	}
+	return t.parse(start, digits)
+}
+
+// parse converts the number read since start
+func (t *pathTokens) parse(start int, digits bool) (float64, error) {
+	if !digits {
+		return 0, fmt.Errorf("expected a number at offset %d", start)
+	}
+	return strconv.ParseFloat(t.data[start:t.pos], 64)
+}
+
+// numbers reads count numbers
+func (t *pathTokens) numbers(count int) ([]float64, error) {
+	values := make([]float64, count)
+	for i := range values {
+		value, err := t.number()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// arc reads the arguments of an arc: radii, rotation, two flags and the end
+// point. Flags are single digits that need no separator, as in "a1 1 0 011 1".
+func (t *pathTokens) arc() ([]float64, error) {
+	values, err := t.numbers(3)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < 2; i++ {
+		t.skipSeparators()
+		if t.done() || (t.data[t.pos] != '0' && t.data[t.pos] != '1') {
+			return nil, fmt.Errorf("expected an arc flag at offset %d", t.pos)
+		}
+		values = append(values, float64(t.data[t.pos]-'0'))
+		t.pos++
+	}
+	end, err := t.numbers(2)
+	if err != nil {
+		return nil, err
+	}
+	return append(values, end...), nil
+}
+
+// pathBuilder flattens path commands into polylines. Coordinates are in the
+// path's space; curves are flattened after the transform, so their precision
+// follows the size they are drawn at.
+type pathBuilder struct {
+	transform affine
+	lines     []polyline
+	points    []point // Transformed points of the current subpath
+	start     point   // Start of the current subpath
+	current   point
+	control   point // Last control point, for smooth curves
+	last      byte  // Kind of the last segment: 'c', 'q' or 0
+}
+
+// moveTo starts a new subpath
+func (b *pathBuilder) moveTo(p point) {
+	b.finish()
+	b.points = []point{b.transform.apply(p)}
+	b.start, b.current, b.last = p, p, 0
+}
+
+// lineTo adds a straight segment
+func (b *pathBuilder) lineTo(p point) {
+	b.ensureStarted()
+	b.points = append(b.points, b.transform.apply(p))
+	b.current, b.last = p, 0
+}
+
+// cubicTo adds a cubic Bézier segment
+func (b *pathBuilder) cubicTo(c1, c2, p point) {
+	b.ensureStarted()
+	p0 := b.transform.apply(b.current)
+	t1, t2, t3 := b.transform.apply(c1), b.transform.apply(c2), b.transform.apply(p)
+	steps := curveSteps(p0, t1, t2, t3)
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		u := 1 - t
+		b.points = append(b.points, point{
+			u*u*u*p0.x + 3*u*u*t*t1.x + 3*u*t*t*t2.x + t*t*t*t3.x,
+			u*u*u*p0.y + 3*u*u*t*t1.y + 3*u*t*t*t2.y + t*t*t*t3.y,
+		})
+	}
+	b.current, b.control, b.last = p, c2, 'c'
+}
+
+// quadTo adds a quadratic Bézier segment
+func (b *pathBuilder) quadTo(c, p point) {
+	b.ensureStarted()
+	p0 := b.transform.apply(b.current)
+	t1, t2 := b.transform.apply(c), b.transform.apply(p)
+	steps := curveSteps(p0, t1, t2)
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		u := 1 - t
+		b.points = append(b.points, point{
+			u*u*p0.x + 2*u*t*t1.x + t*t*t2.x,
+			u*u*p0.y + 2*u*t*t1.y + t*t*t2.y,
+		})
+	}
+	b.current, b.control, b.last = p, c, 'q'
+}
+
+// reflectedControl returns the first control point of a smooth curve: the
+// previous control point mirrored, if the previous segment was of the same kind
+func (b *pathBuilder) reflectedControl(kind byte) point {
+	if b.last != kind {
+		return b.current
+	}
+	return point{2*b.current.x - b.control.x, 2*b.current.y - b.control.y}
+}
+
+// arcTo adds an elliptical arc, converted to cubic segments of at most 90°
+// (SVG implementation notes, F.6.5)
+func (b *pathBuilder) arcTo(rx, ry, rotation float64, largeArc, sweep bool, p point) {
+	p0 := b.current
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 || p0 == p {
+		b.lineTo(p)
+		return
+	}
+
+	sinPhi, cosPhi := math.Sincos(rotation * math.Pi / 180)
+	dx, dy := (p0.x-p.x)/2, (p0.y-p.y)/2
+	x1 := cosPhi*dx + sinPhi*dy
+	y1 := -sinPhi*dx + cosPhi*dy
+
+	// Scale radii up that are too small to reach the end point
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		rx, ry = rx*math.Sqrt(lambda), ry*math.Sqrt(lambda)
+	}
+
+	numerator := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	denominator := rx*rx*y1*y1 + ry*ry*x1*x1
+	coefficient := math.Sqrt(math.Max(0, numerator/denominator))
+	if largeArc == sweep {
+		coefficient = -coefficient
+	}
+	cx1, cy1 := coefficient*rx*y1/ry, -coefficient*ry*x1/rx
+	center := point{
+		cosPhi*cx1 - sinPhi*cy1 + (p0.x+p.x)/2,
+		sinPhi*cx1 + cosPhi*cy1 + (p0.y+p.y)/2,
+	}
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	theta := angle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := angle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	onEllipse := func(angle float64) (point, point) {
+		sin, cos := math.Sincos(angle)
+		position := point{
+			center.x + rx*cos*cosPhi - ry*sin*sinPhi,
+			center.y + rx*cos*sinPhi + ry*sin*cosPhi,
+		}
+		derivative := point{-rx*sin*cosPhi - ry*cos*sinPhi, -rx*sin*sinPhi + ry*cos*cosPhi}
+		return position, derivative
+	}
+
+	segments := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(segments)
+	k := 4.0 / 3.0 * math.Tan(step/4)
+	for i := 0; i < segments; i++ {
+		from, fromDerivative := onEllipse(theta + float64(i)*step)
+		to, toDerivative := onEllipse(theta + float64(i+1)*step)
+		if i == segments-1 {
+			to = p
+		}
+		b.cubicTo(
+			point{from.x + k*fromDerivative.x, from.y + k*fromDerivative.y},
+			point{to.x - k*toDerivative.x, to.y - k*toDerivative.y},
+			to,
+		)
+	}
+	b.last = 0
+}
+
+// close closes the current subpath
+func (b *pathBuilder) close() {
+	if len(b.points) > 0 {
+		b.lines = append(b.lines, polyline{points: b.points, closed: true})
+		b.points = nil
+	}
+	// A command after Z starts at the start of the closed subpath
+	b.current, b.last = b.start, 0
+}
+
+// finish ends the current subpath without closing it
+func (b *pathBuilder) finish() {
+	if len(b.points) > 1 {
+		b.lines = append(b.lines, polyline{points: b.points})
+	}
+	b.points = nil
+}
+
+// ensureStarted starts a subpath at the current point after Z
+func (b *pathBuilder) ensureStarted() {
+	if len(b.points) == 0 {
+		b.points = []point{b.transform.apply(b.current)}
+		b.start = b.current
+	}
+}
+
+// curveSteps picks the number of segments to flatten a curve with from the
+// length of its control polygon in pixels
+func curveSteps(points ...point) int {
+	length := 0.0
+	for i := 1; i < len(points); i++ {
+		length += math.Hypot(points[i].x-points[i-1].x, points[i].y-points[i-1].y)
+	}
+	return int(clamp(math.Ceil(math.Sqrt(length)*2), 1, 100))
+}
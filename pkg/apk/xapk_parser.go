@@ -190,6 +190,7 @@ func (p *XAPKParser) ParseXAPK(xapkPath string) (*XAPKInfo, error) {
 			}
 		} else {
 			fmt.Printf("Base APK parsed successfully\n")
+			apkInfo.LoadIcon()
 		}
 
 		xapkInfo.APKInfo = apkInfo
@@ -200,7 +201,11 @@ func (p *XAPKParser) ParseXAPK(xapkPath string) (*XAPKInfo, error) {
 				xapkInfo.PackageID = manifest.PackageName
 			}
 			if manifest.Name != "" {
-				xapkInfo.AppName = map[string]string{"default": manifest.Name}
+				// Keep the labels the base APK translates
+				if xapkInfo.AppName == nil {
+					xapkInfo.AppName = make(map[string]string)
+				}
+				xapkInfo.AppName["default"] = manifest.Name
 			}
 			if manifest.VersionName != "" {
 				xapkInfo.Version = manifest.VersionName
@@ -453,6 +458,8 @@ func (p *XAPKParser) ParseXAPKQuiet(xapkPath string) (*XAPKInfo, error) {
 			} else {
 				return nil, fmt.Errorf("failed to parse base APK and no manifest available: %w", err)
 			}
+		} else {
+			apkInfo.LoadIcon()
 		}
 
 		xapkInfo.APKInfo = apkInfo
@@ -463,7 +470,11 @@ func (p *XAPKParser) ParseXAPKQuiet(xapkPath string) (*XAPKInfo, error) {
 				xapkInfo.PackageID = manifest.PackageName
 			}
 			if manifest.Name != "" {
-				xapkInfo.AppName = map[string]string{"default": manifest.Name}
+				// Keep the labels the base APK translates
+				if xapkInfo.AppName == nil {
+					xapkInfo.AppName = make(map[string]string)
+				}
+				xapkInfo.AppName["default"] = manifest.Name
 			}
 			if manifest.VersionName != "" {
 				xapkInfo.Version = manifest.VersionName
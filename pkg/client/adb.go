@@ -2,10 +2,9 @@ package client
 
 import (
 	"bufio"
-	"bytes"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -17,13 +16,20 @@ import (
 
 // ADBManager handles ADB operations
 type ADBManager struct {
-	config *Config
+	config  *Config
+	backend ADBBackend
 }
 
-// NewADBManager creates a new ADB manager
+// NewADBManager creates a new ADB manager talking to the local adb server
 func NewADBManager(config *Config) *ADBManager {
+	return NewADBManagerWithBackend(config, NewADBClient(ADBServerAddress(config.ADB.ServerAddress), config.ADB.Path))
+}
+
+// NewADBManagerWithBackend creates an ADB manager using the given backend
+func NewADBManagerWithBackend(config *Config, backend ADBBackend) *ADBManager {
 	return &ADBManager{
-		config: config,
+		config:  config,
+		backend: backend,
 	}
 }
 
@@ -64,55 +70,16 @@ type InstallResult struct {
 
 // GetDevices returns list of connected devices with detailed information
 func (a *ADBManager) GetDevices() ([]Device, error) {
-	cmd := exec.Command(a.config.ADB.Path, "devices", "-l")
-	output, err := cmd.Output()
+	devices, err := a.backend.ListDevices()
 	if err != nil {
-		return nil, fmt.Errorf("failed to run adb devices: %w", err)
+		return nil, fmt.Errorf("failed to list devices: %w", err)
 	}
 
-	var devices []Device
-	lines := strings.Split(string(output), "\n")
-
-	for _, line := range lines[1:] { // Skip header
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		parts := strings.Fields(line)
-		if len(parts) < 2 {
-			continue
-		}
-
-		device := Device{
-			ID:       parts[0],
-			Status:   parts[1],
-			LastSeen: time.Now(),
-		}
-
-		// Check if it's an emulator
-		device.IsEmulator = strings.Contains(device.ID, "emulator-") ||
-			strings.Contains(strings.ToLower(device.ID), "emulator")
-
-		// Extract additional info from device list
-		for _, part := range parts[2:] {
-			if strings.HasPrefix(part, "model:") {
-				device.Model = strings.TrimPrefix(part, "model:")
-			} else if strings.HasPrefix(part, "product:") {
-				device.Product = strings.TrimPrefix(part, "product:")
-			} else if strings.HasPrefix(part, "device:") {
-				device.Device = strings.TrimPrefix(part, "device:")
-			} else if strings.HasPrefix(part, "transport_id:") {
-				device.Transport = strings.TrimPrefix(part, "transport_id:")
-			}
-		}
-
-		// Get additional device info if device is online
-		if device.Status == "device" {
-			a.enrichDeviceInfo(&device)
+	// Get additional device info if device is online
+	for i := range devices {
+		if devices[i].Status == "device" {
+			a.enrichDeviceInfo(&devices[i])
 		}
-
-		devices = append(devices, device)
 	}
 
 	return devices, nil
@@ -150,12 +117,7 @@ func (a *ADBManager) enrichDeviceInfo(device *Device) {
 
 // getDeviceProperty gets a system property from a device
 func (a *ADBManager) getDeviceProperty(deviceID, property string) (string, error) {
-	cmd := exec.Command(a.config.ADB.Path, "-s", deviceID, "shell", "getprop", property)
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	return string(output), nil
+	return a.backend.Shell(deviceID, shellCommand("getprop", property))
 }
 
 // GetDeviceStatus returns categorized device status
@@ -224,15 +186,25 @@ func (a *ADBManager) InstallWithResult(apkPath string, deviceID string, options
 		return a.installXAPK(apkPath, deviceID, options, startTime)
 	}
 
+	if apk.IsAPKSetFile(apkPath) {
+		return a.installAPKSet(apkPath, deviceID, options, startTime)
+	}
+
+	// App bundles are a publishing format, devices only install the APKs built from them
+	if apk.IsAppBundleFile(apkPath) {
+		result.ErrorMessage = "app bundles (.aab) cannot be installed directly"
+		result.Suggestions = []string{
+			"Build an APK set from the bundle: bundletool build-apks --bundle=app.aab --output=app.apks",
+			"Then install the .apks file",
+		}
+		return result, nil
+	}
+
 	// Validate device is online
 	if deviceID != "" {
 		if err := a.validateDeviceOnline(deviceID); err != nil {
 			result.ErrorMessage = err.Error()
-			result.Suggestions = []string{
-				"Check device connection with 'adb devices'",
-				"Enable USB debugging on the device",
-				"Try reconnecting the device",
-			}
+			result.Suggestions = adbFailureSuggestions(err)
 			return result, nil
 		}
 	}
@@ -242,53 +214,17 @@ func (a *ADBManager) InstallWithResult(apkPath string, deviceID string, options
 		return a.installXAPK(apkPath, deviceID, options, startTime)
 	}
 
-	// Build command arguments
-	args := []string{}
-
-	// Add device selection if specified
-	if deviceID != "" {
-		args = append(args, "-s", deviceID)
-	}
-
-	args = append(args, "install")
-
-	// Add install options
-	if options.Replace {
-		args = append(args, "-r")
-	}
-	if options.Downgrade {
-		args = append(args, "-d")
-	}
-	if options.GrantPermissions {
-		args = append(args, "-g")
-	}
-
-	args = append(args, apkPath)
+	fmt.Printf("🔧 Installing: %s\n", filepath.Base(apkPath))
 
-	// Run adb install
-	cmd := exec.Command(a.config.ADB.Path, args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	fmt.Printf("🔧 Running: %s %s\n", a.config.ADB.Path, strings.Join(args, " "))
-
-	err := cmd.Run()
+	output, err := a.installAPK(apkPath, deviceID, options)
 	result.Duration = time.Since(startTime)
 
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("command execution failed: %v", err)
-		result.Suggestions = []string{
-			"Check if ADB is properly installed",
-			"Verify device is connected and authorized",
-			"Try running 'adb kill-server && adb start-server'",
-		}
+		result.ErrorMessage = fmt.Sprintf("installation could not run: %v", err)
+		result.Suggestions = adbFailureSuggestions(err)
 		return result, nil
 	}
 
-	// Parse output
-	output := stdout.String() + stderr.String()
-
 	if strings.Contains(output, "Success") {
 		result.Success = true
 		return result, nil
@@ -302,7 +238,7 @@ func (a *ADBManager) InstallWithResult(apkPath string, deviceID string, options
 
 // validateDeviceOnline checks if a device is online and accessible
 func (a *ADBManager) validateDeviceOnline(deviceID string) error {
-	devices, err := a.GetDevices()
+	devices, err := a.backend.ListDevices()
 	if err != nil {
 		return fmt.Errorf("failed to get device list: %w", err)
 	}
@@ -313,16 +249,52 @@ func (a *ADBManager) validateDeviceOnline(deviceID string) error {
 			case "device":
 				return nil
 			case "offline":
-				return fmt.Errorf("device %s is offline", deviceID)
+				return fmt.Errorf("%w: %s", ErrDeviceOffline, deviceID)
 			case "unauthorized":
-				return fmt.Errorf("device %s is unauthorized - please allow USB debugging", deviceID)
+				return fmt.Errorf("%w: %s - please allow USB debugging", ErrDeviceUnauthorized, deviceID)
 			default:
 				return fmt.Errorf("device %s has status: %s", deviceID, device.Status)
 			}
 		}
 	}
 
-	return fmt.Errorf("device %s not found", deviceID)
+	return fmt.Errorf("%w: %s", ErrDeviceNotFound, deviceID)
+}
+
+// adbFailureSuggestions returns suggestions for an error talking to adb
+func adbFailureSuggestions(err error) []string {
+	switch {
+	case errors.Is(err, ErrADBServerUnavailable):
+		return []string{
+			"Check if ADB is properly installed",
+			"Start the server with 'adb start-server'",
+			"Check adb.server_address in the client configuration",
+		}
+	case errors.Is(err, ErrDeviceUnauthorized):
+		return []string{
+			"Unlock the device and allow USB debugging for this computer",
+		}
+	case errors.Is(err, ErrDeviceOffline):
+		return []string{
+			"Reconnect the device",
+			"Try running 'adb reconnect'",
+		}
+	case errors.Is(err, ErrDeviceNotFound):
+		return []string{
+			"Check device connection with 'apkhub devices'",
+			"Enable USB debugging on the device",
+		}
+	case errors.Is(err, ErrMultipleDevices):
+		return []string{
+			"Select a device with --device",
+			"Set adb.default_device in the client configuration",
+		}
+	default:
+		return []string{
+			"Verify device is connected and authorized",
+			"Try running 'adb kill-server && adb start-server'",
+		}
+	}
 }
 
 // parseInstallError parses ADB install error output and provides suggestions
@@ -394,6 +366,14 @@ func (a *ADBManager) parseInstallError(output string) (string, string, []string)
 				"Use universal APK if available",
 			},
 		},
+		"INSTALL_FAILED_UPDATE_INCOMPATIBLE": {
+			"UPDATE_INCOMPATIBLE",
+			"Installed app is signed with a different certificate",
+			[]string{
+				"Verify the APK comes from the same publisher as the installed app",
+				"Uninstall the existing app first (app data will be lost)",
+			},
+		},
 		"INSTALL_FAILED_PERMISSION_MODEL": {
 			"PERMISSION_MODEL",
 			"Permission model incompatibility",
@@ -437,50 +417,29 @@ func (a *ADBManager) parseInstallError(output string) (string, string, []string)
 
 // InstallOptions contains install options
 type InstallOptions struct {
-	Replace          bool // Replace existing app
-	Downgrade        bool // Allow version downgrade
-	GrantPermissions bool // Grant all runtime permissions
+	Replace          bool          // Replace existing app
+	Downgrade        bool          // Allow version downgrade
+	GrantPermissions bool          // Grant all runtime permissions
+	Timeout          time.Duration // Abandon split installations taking longer; DefaultInstallTimeout if zero
 }
 
 // Uninstall uninstalls an app from device
 func (a *ADBManager) Uninstall(packageID string, deviceID string) error {
-	args := []string{}
-
-	// Add device selection if specified
-	if deviceID != "" {
-		args = append(args, "-s", deviceID)
-	}
-
-	args = append(args, "uninstall", packageID)
-
-	// Run adb uninstall
-	cmd := exec.Command(a.config.ADB.Path, args...)
-	output, err := cmd.CombinedOutput()
-
+	output, err := a.backend.Shell(deviceID, shellCommand("pm", "uninstall", packageID))
 	if err != nil {
-		return fmt.Errorf("adb uninstall failed: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("adb uninstall failed: %w", err)
 	}
 
-	if strings.Contains(string(output), "Success") {
+	if strings.Contains(output, "Success") {
 		return nil
 	}
 
-	return fmt.Errorf("uninstall failed: %s", string(output))
+	return fmt.Errorf("uninstall failed: %s", strings.TrimSpace(output))
 }
 
 // GetInstalledVersion gets the installed version of an app
 func (a *ADBManager) GetInstalledVersion(packageID string, deviceID string) (string, int64, error) {
-	args := []string{}
-
-	// Add device selection if specified
-	if deviceID != "" {
-		args = append(args, "-s", deviceID)
-	}
-
-	args = append(args, "shell", "dumpsys", "package", packageID)
-
-	cmd := exec.Command(a.config.ADB.Path, args...)
-	output, err := cmd.Output()
+	output, err := a.backend.Shell(deviceID, shellCommand("dumpsys", "package", packageID))
 	if err != nil {
 		return "", 0, fmt.Errorf("failed to get package info: %w", err)
 	}
@@ -489,7 +448,7 @@ func (a *ADBManager) GetInstalledVersion(packageID string, deviceID string) (str
 	var versionName string
 	var versionCode int64
 
-	lines := strings.Split(string(output), "\n")
+	lines := strings.Split(output, "\n")
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 		if strings.HasPrefix(line, "versionName=") {
@@ -511,6 +470,51 @@ func (a *ADBManager) GetInstalledVersion(packageID string, deviceID string) (str
 	return versionName, versionCode, nil
 }
 
+// GetInstalledSigner returns the SHA256 digest of the signing certificate of an
+// installed app. dumpsys only exposes signature hash codes, so the base APK is
+// located with "pm path" and pulled to read its certificate.
+func (a *ADBManager) GetInstalledSigner(packageID string, deviceID string) (string, error) {
+	output, err := a.backend.Shell(deviceID, shellCommand("pm", "path", packageID))
+	if err != nil {
+		return "", fmt.Errorf("failed to locate installed package: %w", err)
+	}
+
+	// Split installs list several APKs; the signer is the same for all of them
+	var remotePath string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "package:") {
+			continue
+		}
+		path := strings.TrimPrefix(line, "package:")
+		if remotePath == "" || strings.HasSuffix(path, "/base.apk") {
+			remotePath = path
+		}
+	}
+
+	if remotePath == "" {
+		return "", fmt.Errorf("package not found or not installed")
+	}
+
+	tempDir, err := os.MkdirTemp("", "apkhub-installed-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	localPath := filepath.Join(tempDir, "base.apk")
+	if err := a.backend.Pull(deviceID, remotePath, localPath); err != nil {
+		return "", fmt.Errorf("adb pull failed: %w", err)
+	}
+
+	sigInfo, err := apk.ExtractSignatureInfo(localPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read installed signer: %w", err)
+	}
+
+	return sigInfo.SHA256, nil
+}
+
 // SelectDevice prompts user to select a device with enhanced interface
 func (a *ADBManager) SelectDevice() (string, error) {
 	// Use default device if configured
@@ -740,6 +744,11 @@ func (a *ADBManager) installXAPK(xapkPath string, deviceID string, options Insta
 	apkPaths, err := a.prepareAPKsForInstallation(xapkInfo, tempDir, deviceID)
 	if err != nil {
 		result.ErrorMessage = fmt.Sprintf("failed to prepare APKs: %v", err)
+		result.Suggestions = []string{
+			"Device architecture may not be supported",
+			"Try with a different XAPK variant",
+			"Run with --dry-run to see how the APKs were selected",
+		}
 		return result, nil
 	}
 
@@ -752,10 +761,18 @@ func (a *ADBManager) installXAPK(xapkPath string, deviceID string, options Insta
 		return result, nil
 	}
 
+	// OBB files are copied within the install session, so they are only kept
+	// when the APKs install
+	obbs, err := xapkOBBTransfers(xapkInfo, tempDir)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result, nil
+	}
+
 	// Install APKs with optimized output
 	fmt.Printf("🚀 Installing to device...\n")
 
-	if len(apkPaths) == 1 {
+	if len(apkPaths) == 1 && len(obbs) == 0 {
 		// Single APK installation
 		if err := a.installSingleAPKQuietly(apkPaths[0], deviceID, options); err != nil {
 			result.ErrorMessage = a.formatInstallError(err)
@@ -763,24 +780,14 @@ func (a *ADBManager) installXAPK(xapkPath string, deviceID string, options Insta
 			return result, nil
 		}
 	} else {
-		// Multiple APK installation (split APKs)
-		if err := a.installMultipleAPKsQuietly(apkPaths, deviceID, options); err != nil {
+		// Split APKs and OBB files in one install session
+		if err := a.installSessionQuietly(apkPaths, obbs, deviceID, options); err != nil {
 			result.ErrorMessage = a.formatInstallError(err)
 			result.Suggestions = a.getInstallSuggestions(err)
 			return result, nil
 		}
 	}
 
-	// Install OBB files if present
-	if len(xapkInfo.OBBFiles) > 0 {
-		fmt.Printf("📦 Installing OBB files...\n")
-
-		if err := a.installOBBFiles(xapkInfo, tempDir, deviceID); err != nil {
-			fmt.Printf("⚠️  OBB installation failed, but APK was installed successfully\n")
-			// Don't fail the entire installation for OBB issues
-		}
-	}
-
 	result.Success = true
 	result.Duration = time.Since(startTime)
 	result.PackageID = xapkInfo.PackageID
@@ -789,71 +796,111 @@ func (a *ADBManager) installXAPK(xapkPath string, deviceID string, options Insta
 	return result, nil
 }
 
-// installSingleAPK installs a single APK file
-func (a *ADBManager) installSingleAPK(apkPath string, deviceID string, options InstallOptions) error {
-	args := []string{}
+// installAPKSet installs the APKs of the APK set variant that fits the device
+func (a *ADBManager) installAPKSet(apksPath string, deviceID string, options InstallOptions, startTime time.Time) (*InstallResult, error) {
+	result := &InstallResult{
+		DeviceID: deviceID,
+		Duration: 0,
+		Success:  false,
+	}
 
-	if deviceID != "" {
-		args = append(args, "-s", deviceID)
+	fmt.Printf("📦 Installing APK set: %s\n", filepath.Base(apksPath))
+
+	tempDir, err := os.MkdirTemp("", "apks_install_*")
+	if err != nil {
+		result.ErrorMessage = fmt.Sprintf("failed to create temp directory: %v", err)
+		result.Suggestions = []string{
+			"Check disk space and permissions",
+		}
+		return result, nil
 	}
+	defer os.RemoveAll(tempDir)
+
+	fmt.Printf("📂 Extracting and analyzing package...\n")
 
-	args = append(args, "install")
+	packageID, choices, err := selectAPKSetSplits(apksPath, tempDir, a.GetDeviceSpec(deviceID))
+	if err != nil {
+		result.ErrorMessage = fmt.Sprintf("failed to prepare APKs: %v", err)
+		result.Suggestions = []string{
+			"Device SDK version or architecture may not be supported",
+			"Build an APK set for this device: bundletool build-apks --connected-device",
+			"Run with --dry-run to see how the APKs were selected",
+		}
+		return result, nil
+	}
 
-	if options.Replace {
-		args = append(args, "-r")
+	apkPaths := selectedSplitPaths(choices)
+	if len(choices) > 1 {
+		fmt.Printf("🧩 Selected %d of %d APKs for this device\n", len(apkPaths), len(choices))
 	}
-	if options.Downgrade {
-		args = append(args, "-d")
+
+	fmt.Printf("🚀 Installing to device...\n")
+
+	if len(apkPaths) == 1 {
+		err = a.installSingleAPKQuietly(apkPaths[0], deviceID, options)
+	} else {
+		err = a.installSessionQuietly(apkPaths, nil, deviceID, options)
 	}
-	if options.GrantPermissions {
-		args = append(args, "-g")
+	if err != nil {
+		result.ErrorMessage = a.formatInstallError(err)
+		result.Suggestions = a.getInstallSuggestions(err)
+		return result, nil
 	}
 
-	args = append(args, apkPath)
+	result.Success = true
+	result.Duration = time.Since(startTime)
+	result.PackageID = packageID
 
-	fmt.Printf("   🔧 Installing: %s\n", filepath.Base(apkPath))
+	fmt.Printf("✅ Installation completed successfully!\n")
+	return result, nil
+}
 
-	cmd := exec.Command(a.config.ADB.Path, args...)
-	output, err := cmd.CombinedOutput()
+// installSingleAPK installs a single APK file
+func (a *ADBManager) installSingleAPK(apkPath string, deviceID string, options InstallOptions) error {
+	fmt.Printf("   🔧 Installing: %s\n", filepath.Base(apkPath))
 
+	output, err := a.installAPK(apkPath, deviceID, options)
 	if err != nil {
-		return fmt.Errorf("adb install failed: %v, output: %s", err, string(output))
+		return fmt.Errorf("adb install failed: %w", err)
 	}
 
-	if !strings.Contains(string(output), "Success") {
-		return fmt.Errorf("installation failed: %s", string(output))
+	if !strings.Contains(output, "Success") {
+		return fmt.Errorf("installation failed: %s", output)
 	}
 
 	return nil
 }
 
-// installMultipleAPKs installs multiple APK files using install-multiple
+// installMultipleAPKs installs multiple APK files in one install session
 func (a *ADBManager) installMultipleAPKs(apkPaths []string, deviceID string, options InstallOptions) error {
-	args := []string{}
+	sortedPaths := baseAPKFirst(apkPaths)
 
-	if deviceID != "" {
-		args = append(args, "-s", deviceID)
+	fmt.Printf("   🔧 Installing split APKs: %d files\n", len(sortedPaths))
+	for _, path := range sortedPaths {
+		fmt.Printf("      - %s\n", filepath.Base(path))
 	}
 
-	args = append(args, "install-multiple")
-
-	if options.Replace {
-		args = append(args, "-r")
-	}
-	if options.Downgrade {
-		args = append(args, "-d")
+	output, err := a.installSession(sortedPaths, nil, deviceID, options)
+	if err != nil {
+		return fmt.Errorf("adb install-multiple failed: %w", err)
 	}
-	if options.GrantPermissions {
-		args = append(args, "-g")
+
+	if !strings.Contains(output, "Success") {
+		return fmt.Errorf("installation failed: %s", output)
 	}
 
-	// Sort APK paths to ensure base.apk is installed first
+	return nil
+}
+
+// baseAPKFirst returns the APK paths with base.apk (base-master.apk in APK sets)
+// moved to the front
+func baseAPKFirst(apkPaths []string) []string {
 	sortedPaths := make([]string, len(apkPaths))
 	copy(sortedPaths, apkPaths)
 
-	// Move base.apk to front if present
 	for i, path := range sortedPaths {
-		if strings.Contains(strings.ToLower(filepath.Base(path)), "base.apk") {
+		name := strings.ToLower(filepath.Base(path))
+		if strings.Contains(name, "base.apk") || name == "base-master.apk" {
 			if i != 0 {
 				sortedPaths[0], sortedPaths[i] = sortedPaths[i], sortedPaths[0]
 			}
@@ -861,95 +908,60 @@ func (a *ADBManager) installMultipleAPKs(apkPaths []string, deviceID string, opt
 		}
 	}
 
-	args = append(args, sortedPaths...)
-
-	fmt.Printf("   🔧 Installing split APKs: %d files\n", len(sortedPaths))
-	for _, path := range sortedPaths {
-		fmt.Printf("      - %s\n", filepath.Base(path))
-	}
-
-	cmd := exec.Command(a.config.ADB.Path, args...)
-	output, err := cmd.CombinedOutput()
+	return sortedPaths
+}
 
-	if err != nil {
-		return fmt.Errorf("adb install-multiple failed: %v, output: %s", err, string(output))
-	}
+// remoteInstallDir is where APKs are pushed before the package manager installs them
+const remoteInstallDir = "/data/local/tmp"
 
-	if !strings.Contains(string(output), "Success") {
-		return fmt.Errorf("installation failed: %s", string(output))
+// installAPK pushes an APK to the device and installs it with "pm install". It
+// returns the pm output, which reports "Success" or the failure reason; err is
+// only set when pm could not be run.
+func (a *ADBManager) installAPK(apkPath string, deviceID string, options InstallOptions) (string, error) {
+	remotePath := fmt.Sprintf("%s/apkhub-%s", remoteInstallDir, filepath.Base(apkPath))
+	if err := a.backend.Push(deviceID, apkPath, remotePath, 0644); err != nil {
+		return "", fmt.Errorf("failed to push %s: %w", filepath.Base(apkPath), err)
 	}
+	defer a.backend.Shell(deviceID, shellCommand("rm", "-f", remotePath))
 
-	return nil
+	args := append(append([]string{"pm", "install"}, installFlags(options)...), remotePath)
+	return a.backend.Shell(deviceID, shellCommand(args...))
 }
 
-// installOBBFiles installs OBB files to the device
-func (a *ADBManager) installOBBFiles(xapkInfo *apk.XAPKInfo, tempDir string, deviceID string) error {
+// xapkOBBTransfers lists where the OBB files of an XAPK go on the device:
+// /sdcard/Android/obb/<package_name>/
+func xapkOBBTransfers(xapkInfo *apk.XAPKInfo, tempDir string) ([]obbTransfer, error) {
 	if len(xapkInfo.OBBFiles) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	packageID := xapkInfo.PackageID
 	if packageID == "" {
-		return fmt.Errorf("package ID not found, cannot install OBB files")
+		return nil, fmt.Errorf("package ID not found, cannot install OBB files")
 	}
 
-	// OBB files should be placed in /sdcard/Android/obb/<package_name>/
 	obbDir := fmt.Sprintf("/sdcard/Android/obb/%s/", packageID)
 
-	// Create OBB directory on device
-	fmt.Printf("   📁 Creating OBB directory: %s\n", obbDir)
-	if err := a.createDeviceDirectory(obbDir, deviceID); err != nil {
-		return fmt.Errorf("failed to create OBB directory: %v", err)
-	}
-
-	// Copy each OBB file
+	var obbs []obbTransfer
 	for _, obbFile := range xapkInfo.OBBFiles {
-		localOBBPath := filepath.Join(tempDir, obbFile)
-		remoteOBBPath := obbDir + filepath.Base(obbFile)
-
-		fmt.Printf("   📦 Copying OBB: %s\n", filepath.Base(obbFile))
-
-		if err := a.pushFile(localOBBPath, remoteOBBPath, deviceID); err != nil {
-			return fmt.Errorf("failed to copy OBB file %s: %v", obbFile, err)
-		}
+		obbs = append(obbs, obbTransfer{
+			localPath:  filepath.Join(tempDir, obbFile),
+			remotePath: obbDir + filepath.Base(obbFile),
+		})
 	}
 
-	return nil
+	return obbs, nil
 }
 
 // createDeviceDirectory creates a directory on the device
 func (a *ADBManager) createDeviceDirectory(dirPath string, deviceID string) error {
-	args := []string{}
-
-	if deviceID != "" {
-		args = append(args, "-s", deviceID)
-	}
-
-	args = append(args, "shell", "mkdir", "-p", dirPath)
-
-	cmd := exec.Command(a.config.ADB.Path, args...)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("mkdir command failed: %v", err)
-	}
-
-	return nil
-}
-
-// pushFile copies a file from local to device
-func (a *ADBManager) pushFile(localPath string, remotePath string, deviceID string) error {
-	args := []string{}
-
-	if deviceID != "" {
-		args = append(args, "-s", deviceID)
-	}
-
-	args = append(args, "push", localPath, remotePath)
-
-	cmd := exec.Command(a.config.ADB.Path, args...)
-	output, err := cmd.CombinedOutput()
-
+	// The shell service has no exit status: mkdir -p only prints on failure
+	output, err := a.backend.Shell(deviceID, shellCommand("mkdir", "-p", dirPath))
 	if err != nil {
-		return fmt.Errorf("adb push failed: %v, output: %s", err, string(output))
+		return fmt.Errorf("mkdir command failed: %w", err)
+	}
+	if output = strings.TrimSpace(output); output != "" {
+		return fmt.Errorf("mkdir command failed: %s", output)
 	}
 
 	return nil
@@ -961,29 +973,17 @@ func (a *ADBManager) parseXAPKQuietly(parser *apk.XAPKParser, xapkPath string) (
 	return parser.ParseXAPKQuiet(xapkPath)
 }
 
-// prepareAPKsForInstallation filters APKs based on device compatibility
+// prepareAPKsForInstallation selects the APKs that match the device and reports
+// the selection
 func (a *ADBManager) prepareAPKsForInstallation(xapkInfo *apk.XAPKInfo, tempDir string, deviceID string) ([]string, error) {
-	var apkPaths []string
-
-	// Get device ABI to filter compatible APKs
-	deviceABI, err := a.getDeviceABI(deviceID)
+	choices, err := selectXAPKSplits(xapkInfo, tempDir, a.GetDeviceSpec(deviceID))
 	if err != nil {
-		// If we can't get device ABI, include all APKs and let ADB handle it
-		deviceABI = ""
+		return nil, err
 	}
 
-	for _, apkFile := range xapkInfo.APKFiles {
-		apkPath := filepath.Join(tempDir, apkFile)
-		if _, err := os.Stat(apkPath); err != nil {
-			continue
-		}
-
-		// Filter out incompatible architecture APKs
-		if deviceABI != "" && a.isArchitectureAPK(apkFile) && !a.isCompatibleArchitecture(apkFile, deviceABI) {
-			continue
-		}
-
-		apkPaths = append(apkPaths, apkPath)
+	apkPaths := selectedSplitPaths(choices)
+	if len(choices) > 1 {
+		fmt.Printf("🧩 Selected %d of %d APKs for this device\n", len(apkPaths), len(choices))
 	}
 
 	return apkPaths, nil
@@ -991,137 +991,37 @@ func (a *ADBManager) prepareAPKsForInstallation(xapkInfo *apk.XAPKInfo, tempDir
 
 // getDeviceABI gets the primary ABI of the device
 func (a *ADBManager) getDeviceABI(deviceID string) (string, error) {
-	args := []string{}
-	if deviceID != "" {
-		args = append(args, "-s", deviceID)
-	}
-	args = append(args, "shell", "getprop", "ro.product.cpu.abi")
-
-	cmd := exec.Command(a.config.ADB.Path, args...)
-	output, err := cmd.Output()
+	output, err := a.getDeviceProperty(deviceID, "ro.product.cpu.abi")
 	if err != nil {
 		return "", err
 	}
 
-	return strings.TrimSpace(string(output)), nil
-}
-
-// isArchitectureAPK checks if the APK is architecture-specific
-func (a *ADBManager) isArchitectureAPK(filename string) bool {
-	archPatterns := []string{"x86", "x86_64", "arm64", "armeabi", "mips"}
-	lowerName := strings.ToLower(filename)
-
-	for _, arch := range archPatterns {
-		if strings.Contains(lowerName, arch) {
-			return true
-		}
-	}
-	return false
-}
-
-// isCompatibleArchitecture checks if the APK architecture is compatible with device
-func (a *ADBManager) isCompatibleArchitecture(filename string, deviceABI string) bool {
-	lowerName := strings.ToLower(filename)
-	lowerABI := strings.ToLower(deviceABI)
-
-	// Simple compatibility mapping
-	if strings.Contains(lowerABI, "arm64") {
-		return strings.Contains(lowerName, "arm64") || strings.Contains(lowerName, "armeabi")
-	}
-	if strings.Contains(lowerABI, "arm") {
-		return strings.Contains(lowerName, "arm") && !strings.Contains(lowerName, "arm64")
-	}
-	if strings.Contains(lowerABI, "x86_64") {
-		return strings.Contains(lowerName, "x86")
-	}
-	if strings.Contains(lowerABI, "x86") {
-		return strings.Contains(lowerName, "x86") && !strings.Contains(lowerName, "x86_64")
-	}
-
-	return true // Default to compatible if unsure
+	return strings.TrimSpace(output), nil
 }
 
 // installSingleAPKQuietly installs a single APK with minimal output
 func (a *ADBManager) installSingleAPKQuietly(apkPath string, deviceID string, options InstallOptions) error {
-	args := []string{}
-
-	if deviceID != "" {
-		args = append(args, "-s", deviceID)
-	}
-
-	args = append(args, "install")
-
-	if options.Replace {
-		args = append(args, "-r")
-	}
-	if options.Downgrade {
-		args = append(args, "-d")
-	}
-	if options.GrantPermissions {
-		args = append(args, "-g")
-	}
-
-	args = append(args, apkPath)
-
-	cmd := exec.Command(a.config.ADB.Path, args...)
-	output, err := cmd.CombinedOutput()
-
+	output, err := a.installAPK(apkPath, deviceID, options)
 	if err != nil {
-		return fmt.Errorf("installation failed: %s", strings.TrimSpace(string(output)))
+		return fmt.Errorf("installation failed: %w", err)
 	}
 
-	if !strings.Contains(string(output), "Success") {
-		return fmt.Errorf("installation failed: %s", strings.TrimSpace(string(output)))
+	if !strings.Contains(output, "Success") {
+		return fmt.Errorf("installation failed: %s", strings.TrimSpace(output))
 	}
 
 	return nil
 }
 
-// installMultipleAPKsQuietly installs multiple APKs with minimal output
-func (a *ADBManager) installMultipleAPKsQuietly(apkPaths []string, deviceID string, options InstallOptions) error {
-	args := []string{}
-
-	if deviceID != "" {
-		args = append(args, "-s", deviceID)
-	}
-
-	args = append(args, "install-multiple")
-
-	if options.Replace {
-		args = append(args, "-r")
-	}
-	if options.Downgrade {
-		args = append(args, "-d")
-	}
-	if options.GrantPermissions {
-		args = append(args, "-g")
-	}
-
-	// Sort APK paths to ensure base.apk is installed first
-	sortedPaths := make([]string, len(apkPaths))
-	copy(sortedPaths, apkPaths)
-
-	// Move base.apk to front if present
-	for i, path := range sortedPaths {
-		if strings.Contains(strings.ToLower(filepath.Base(path)), "base.apk") {
-			if i != 0 {
-				sortedPaths[0], sortedPaths[i] = sortedPaths[i], sortedPaths[0]
-			}
-			break
-		}
-	}
-
-	args = append(args, sortedPaths...)
-
-	cmd := exec.Command(a.config.ADB.Path, args...)
-	output, err := cmd.CombinedOutput()
-
+// installSessionQuietly installs split APKs and OBB files with minimal output
+func (a *ADBManager) installSessionQuietly(apkPaths []string, obbs []obbTransfer, deviceID string, options InstallOptions) error {
+	output, err := a.installSession(baseAPKFirst(apkPaths), obbs, deviceID, options)
 	if err != nil {
-		return fmt.Errorf("installation failed: %s", strings.TrimSpace(string(output)))
+		return fmt.Errorf("installation failed: %w", err)
 	}
 
-	if !strings.Contains(string(output), "Success") {
-		return fmt.Errorf("installation failed: %s", strings.TrimSpace(string(output)))
+	if !strings.Contains(output, "Success") {
+		return fmt.Errorf("installation failed: %s", strings.TrimSpace(output))
 	}
 
 	return nil
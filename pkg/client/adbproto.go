@@ -0,0 +1,626 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultADBServerPort is the port the local adb server listens on
+const defaultADBServerPort = "5037"
+
+// syncMaxChunk is the largest DATA chunk of the sync protocol
+const syncMaxChunk = 64 * 1024
+
+var (
+	// ErrADBServerUnavailable means no adb server accepted the connection
+	ErrADBServerUnavailable = errors.New("adb server is not running")
+
+	// ErrDeviceNotFound means the adb server does not know the requested device
+	ErrDeviceNotFound = errors.New("device not found")
+
+	// ErrDeviceUnauthorized means the device has not authorized this computer
+	ErrDeviceUnauthorized = errors.New("device unauthorized")
+
+	// ErrDeviceOffline means the device is connected but not responding
+	ErrDeviceOffline = errors.New("device offline")
+
+	// ErrMultipleDevices means a device must be chosen because several are connected
+	ErrMultipleDevices = errors.New("more than one device connected")
+)
+
+// ADBBackend performs the device operations ADBManager is built on. An empty
+// serial selects the only connected device.
+type ADBBackend interface {
+	// ListDevices returns the devices known to adb, without properties
+	ListDevices() ([]Device, error)
+
+	// Shell runs a command on the device and returns its combined output
+	Shell(serial, command string) (string, error)
+
+	// Exec runs a command on the device without a terminal, streaming stdin (if
+	// not nil) to it, and returns its output. Cancelling ctx aborts the command.
+	Exec(ctx context.Context, serial, command string, stdin io.Reader) (string, error)
+
+	// Push copies a local file to the device
+	Push(serial, localPath, remotePath string, mode os.FileMode) error
+
+	// PushReader copies everything read from r to a device file. Cancelling ctx
+	// aborts the transfer.
+	PushReader(ctx context.Context, serial string, r io.Reader, remotePath string, mode os.FileMode, modTime time.Time) error
+
+	// Pull copies a file from the device
+	Pull(serial, remotePath, localPath string) error
+
+	// Stat returns information on a device file, or nil if it does not exist
+	Stat(serial, remotePath string) (*RemoteFileInfo, error)
+}
+
+// RemoteFileInfo describes a file on a device
+type RemoteFileInfo struct {
+	Mode    os.FileMode
+	Size    int64
+	ModTime time.Time
+}
+
+// ADBError is a failure reported by the adb server or the sync service
+type ADBError struct {
+	Op      string // Request that failed, e.g. "host:devices-l"
+	Serial  string
+	Message string // Message sent by adb
+	Err     error  // Classified cause, if recognized
+}
+
+// Error implements the error interface
+func (e *ADBError) Error() string {
+	if e.Serial != "" {
+		return fmt.Sprintf("adb %s on %s: %s", e.Op, e.Serial, e.Message)
+	}
+	return fmt.Sprintf("adb %s: %s", e.Op, e.Message)
+}
+
+// Unwrap returns the classified cause
+func (e *ADBError) Unwrap() error {
+	return e.Err
+}
+
+// classifyADBFailure maps an adb server failure message to a sentinel error
+func classifyADBFailure(message string) error {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "not found") && strings.Contains(lower, "device"),
+		strings.Contains(lower, "no devices"):
+		return ErrDeviceNotFound
+	case strings.Contains(lower, "unauthorized"):
+		return ErrDeviceUnauthorized
+	case strings.Contains(lower, "offline"):
+		return ErrDeviceOffline
+	case strings.Contains(lower, "more than one"):
+		return ErrMultipleDevices
+	default:
+		return nil
+	}
+}
+
+// ADBClient talks the adb host protocol to the local adb server over TCP
+type ADBClient struct {
+	addr        string
+	adbPath     string // Used only to start the server when it is not running
+	dialTimeout time.Duration
+
+	startOnce sync.Once
+}
+
+// NewADBClient creates a client for the adb server at addr. If adbPath is set,
+// the server is started with it the first time it cannot be reached.
+func NewADBClient(addr, adbPath string) *ADBClient {
+	return &ADBClient{
+		addr:        addr,
+		adbPath:     adbPath,
+		dialTimeout: 5 * time.Second,
+	}
+}
+
+// ADBServerAddress returns the address of the adb server: the configured one,
+// else the port from ANDROID_ADB_SERVER_PORT, else 127.0.0.1:5037
+func ADBServerAddress(configured string) string {
+	if configured != "" {
+		return configured
+	}
+
+	port := os.Getenv("ANDROID_ADB_SERVER_PORT")
+	if _, err := strconv.Atoi(port); err != nil {
+		port = defaultADBServerPort
+	}
+
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// dial connects to the adb server, starting it once if it is not running
+func (c *ADBClient) dial() (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", c.addr, c.dialTimeout)
+	if err == nil {
+		return conn, nil
+	}
+
+	started := false
+	c.startOnce.Do(func() {
+		started = c.startServer() == nil
+	})
+	if started {
+		if conn, err := net.DialTimeout("tcp", c.addr, c.dialTimeout); err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w at %s: %v", ErrADBServerUnavailable, c.addr, err)
+}
+
+// startServer runs "adb start-server", which returns once the server listens
+func (c *ADBClient) startServer() error {
+	if c.adbPath == "" {
+		return fmt.Errorf("no adb binary configured")
+	}
+
+	host, port, err := net.SplitHostPort(c.addr)
+	if err != nil || (host != "127.0.0.1" && host != "localhost" && host != "::1") {
+		// A remote server cannot be started from here
+		return fmt.Errorf("adb server %s is not local", c.addr)
+	}
+
+	cmd := exec.Command(c.adbPath, "-P", port, "start-server")
+	return cmd.Run()
+}
+
+// ListDevices implements ADBBackend using host:devices-l
+func (c *ADBClient) ListDevices() ([]Device, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	const op = "host:devices-l"
+	if err := sendADBRequest(conn, op); err != nil {
+		return nil, err
+	}
+	if err := readADBStatus(conn, op, ""); err != nil {
+		return nil, err
+	}
+
+	list, err := readADBHexString(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device list: %w", err)
+	}
+
+	return parseDeviceList(list), nil
+}
+
+// parseDeviceList parses the long device list format:
+// "<serial> <state> product:<p> model:<m> device:<d> transport_id:<n>"
+func parseDeviceList(list string) []Device {
+	var devices []Device
+
+	for _, line := range strings.Split(list, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			continue
+		}
+
+		device := Device{
+			ID:       parts[0],
+			Status:   parts[1],
+			LastSeen: time.Now(),
+		}
+
+		// Check if it's an emulator
+		device.IsEmulator = strings.Contains(device.ID, "emulator-") ||
+			strings.Contains(strings.ToLower(device.ID), "emulator")
+
+		for _, part := range parts[2:] {
+			key, value, found := strings.Cut(part, ":")
+			if !found {
+				continue
+			}
+			switch key {
+			case "model":
+				device.Model = value
+			case "product":
+				device.Product = value
+			case "device":
+				device.Device = value
+			case "transport_id":
+				device.Transport = value
+			}
+		}
+
+		devices = append(devices, device)
+	}
+
+	return devices
+}
+
+// openService connects to a device and opens a service on it
+func (c *ADBClient) openService(serial, service string) (net.Conn, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := "host:transport-any"
+	if serial != "" {
+		transport = "host:transport:" + serial
+	}
+
+	if err := sendADBRequest(conn, transport); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := readADBStatus(conn, transport, serial); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := sendADBRequest(conn, service); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := readADBStatus(conn, service, serial); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// Shell implements ADBBackend using the shell: service
+func (c *ADBClient) Shell(serial, command string) (string, error) {
+	conn, err := c.openService(serial, "shell:"+command)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	output, err := io.ReadAll(conn)
+	if err != nil {
+		return string(output), fmt.Errorf("failed to read shell output: %w", err)
+	}
+
+	return string(output), nil
+}
+
+// Exec implements ADBBackend using the exec: service, which passes stdin and
+// output through unmodified
+func (c *ADBClient) Exec(ctx context.Context, serial, command string, stdin io.Reader) (string, error) {
+	conn, err := c.openService(serial, "exec:"+command)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	// Closing the connection makes adbd terminate the command
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if stdin != nil {
+		if _, err := io.Copy(conn, stdin); err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "", fmt.Errorf("failed to stream input: %w", err)
+		}
+	}
+
+	output, err := io.ReadAll(conn)
+	if ctx.Err() != nil {
+		return string(output), ctx.Err()
+	}
+	if err != nil {
+		return string(output), fmt.Errorf("failed to read command output: %w", err)
+	}
+
+	return string(output), nil
+}
+
+// Push implements ADBBackend using the sync SEND request
+func (c *ADBClient) Push(serial, localPath, remotePath string, mode os.FileMode) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return c.PushReader(context.Background(), serial, file, remotePath, mode, info.ModTime())
+}
+
+// PushReader implements ADBBackend using the sync SEND request
+func (c *ADBClient) PushReader(ctx context.Context, serial string, r io.Reader, remotePath string, mode os.FileMode, modTime time.Time) (err error) {
+	conn, err := c.openService(serial, "sync:")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+	}()
+
+	// The SEND target is "<path>,<mode>" with the permission bits of a regular file
+	target := fmt.Sprintf("%s,%d", remotePath, 0100000|uint32(mode.Perm()))
+	if err := writeSyncRequest(conn, "SEND", []byte(target)); err != nil {
+		return err
+	}
+
+	buf := make([]byte, syncMaxChunk)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			if err := writeSyncRequest(conn, "DATA", buf[:n]); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	if err := writeSyncHeader(conn, "DONE", uint32(modTime.Unix())); err != nil {
+		return err
+	}
+
+	id, length, err := readSyncHeader(conn)
+	if err != nil {
+		return err
+	}
+	switch id {
+	case "OKAY":
+		writeSyncHeader(conn, "QUIT", 0)
+		return nil
+	case "FAIL":
+		message, _ := readSyncData(conn, length)
+		return &ADBError{Op: "push " + remotePath, Serial: serial, Message: message}
+	default:
+		return fmt.Errorf("unexpected sync response %q", id)
+	}
+}
+
+// Pull implements ADBBackend using the sync RECV request
+func (c *ADBClient) Pull(serial, remotePath, localPath string) error {
+	conn, err := c.openService(serial, "sync:")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := writeSyncRequest(conn, "RECV", []byte(remotePath)); err != nil {
+		return err
+	}
+
+	out, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+
+	if err := receiveSyncData(conn, out, serial, remotePath); err != nil {
+		out.Close()
+		os.Remove(localPath)
+		return err
+	}
+
+	writeSyncHeader(conn, "QUIT", 0)
+	return out.Close()
+}
+
+// receiveSyncData copies DATA chunks to w until DONE
+func receiveSyncData(conn net.Conn, w io.Writer, serial, remotePath string) error {
+	for {
+		id, length, err := readSyncHeader(conn)
+		if err != nil {
+			return err
+		}
+
+		switch id {
+		case "DATA":
+			if length > syncMaxChunk {
+				return fmt.Errorf("sync chunk of %d bytes exceeds the protocol limit", length)
+			}
+			if _, err := io.CopyN(w, conn, int64(length)); err != nil {
+				return err
+			}
+		case "DONE":
+			return nil
+		case "FAIL":
+			message, _ := readSyncData(conn, length)
+			return &ADBError{Op: "pull " + remotePath, Serial: serial, Message: message}
+		default:
+			return fmt.Errorf("unexpected sync response %q", id)
+		}
+	}
+}
+
+// Stat implements ADBBackend using the sync STAT request
+func (c *ADBClient) Stat(serial, remotePath string) (*RemoteFileInfo, error) {
+	conn, err := c.openService(serial, "sync:")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := writeSyncRequest(conn, "STAT", []byte(remotePath)); err != nil {
+		return nil, err
+	}
+
+	var reply struct {
+		ID    [4]byte
+		Mode  uint32
+		Size  uint32
+		MTime uint32
+	}
+	if err := binary.Read(conn, binary.LittleEndian, &reply); err != nil {
+		return nil, fmt.Errorf("failed to read stat response: %w", err)
+	}
+	if string(reply.ID[:]) != "STAT" {
+		return nil, fmt.Errorf("unexpected sync response %q", string(reply.ID[:]))
+	}
+
+	writeSyncHeader(conn, "QUIT", 0)
+
+	// A zero mode means the file does not exist
+	if reply.Mode == 0 {
+		return nil, nil
+	}
+
+	return &RemoteFileInfo{
+		Mode:    unixModeToFileMode(reply.Mode),
+		Size:    int64(reply.Size),
+		ModTime: time.Unix(int64(reply.MTime), 0),
+	}, nil
+}
+
+// unixModeToFileMode converts a st_mode value to an os.FileMode
+func unixModeToFileMode(mode uint32) os.FileMode {
+	fileMode := os.FileMode(mode & 0777)
+	switch mode & 0170000 {
+	case 0040000:
+		fileMode |= os.ModeDir
+	case 0120000:
+		fileMode |= os.ModeSymlink
+	}
+	return fileMode
+}
+
+// sendADBRequest sends a host protocol request: 4 hex digits of length, then the payload
+func sendADBRequest(conn net.Conn, payload string) error {
+	if len(payload) > 0xffff {
+		return fmt.Errorf("adb request too long (%d bytes)", len(payload))
+	}
+	_, err := fmt.Fprintf(conn, "%04x%s", len(payload), payload)
+	return err
+}
+
+// readADBStatus reads the OKAY/FAIL reply to a host protocol request
+func readADBStatus(conn net.Conn, op, serial string) error {
+	status := make([]byte, 4)
+	if _, err := io.ReadFull(conn, status); err != nil {
+		return fmt.Errorf("failed to read adb response to %s: %w", op, err)
+	}
+
+	switch string(status) {
+	case "OKAY":
+		return nil
+	case "FAIL":
+		message, err := readADBHexString(conn)
+		if err != nil {
+			message = "unknown failure"
+		}
+		return &ADBError{Op: op, Serial: serial, Message: message, Err: classifyADBFailure(message)}
+	default:
+		return fmt.Errorf("unexpected adb response %q to %s", status, op)
+	}
+}
+
+// readADBHexString reads a string prefixed with its length in 4 hex digits
+func readADBHexString(conn net.Conn) (string, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", err
+	}
+
+	length, err := strconv.ParseUint(string(header), 16, 16)
+	if err != nil {
+		return "", fmt.Errorf("invalid adb length %q", header)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// writeSyncRequest writes a sync request with its payload
+func writeSyncRequest(conn net.Conn, id string, data []byte) error {
+	var buf bytes.Buffer
+	buf.WriteString(id)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+
+	_, err := conn.Write(buf.Bytes())
+	return err
+}
+
+// writeSyncHeader writes a sync request without payload
+func writeSyncHeader(conn net.Conn, id string, value uint32) error {
+	var buf bytes.Buffer
+	buf.WriteString(id)
+	binary.Write(&buf, binary.LittleEndian, value)
+
+	_, err := conn.Write(buf.Bytes())
+	return err
+}
+
+// readSyncHeader reads a sync response id and its length field
+func readSyncHeader(conn net.Conn) (string, uint32, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", 0, fmt.Errorf("failed to read sync response: %w", err)
+	}
+
+	return string(header[:4]), binary.LittleEndian.Uint32(header[4:]), nil
+}
+
+// readSyncData reads a sync payload of length bytes as a string
+func readSyncData(conn net.Conn, length uint32) (string, error) {
+	if length > syncMaxChunk {
+		return "", fmt.Errorf("sync message of %d bytes exceeds the protocol limit", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// shellQuote quotes an argument for the device shell
+func shellQuote(arg string) string {
+	if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./-_", r))
+	}) < 0 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// shellCommand joins a command and its arguments for the device shell
+func shellCommand(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,375 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeADBServer accepts adb client connections on a local port and hands each
+// one to handle
+func fakeADBServer(t *testing.T, handle func(conn net.Conn)) *ADBClient {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+				handle(conn)
+			}()
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		wg.Wait()
+	})
+
+	return NewADBClient(listener.Addr().String(), "")
+}
+
+// readTestRequest reads a host protocol request as the server does
+func readTestRequest(t *testing.T, conn net.Conn) string {
+	t.Helper()
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		t.Errorf("reading request length: %v", err)
+		return ""
+	}
+	length, err := strconv.ParseUint(string(header), 16, 16)
+	if err != nil {
+		t.Errorf("invalid request length %q", header)
+		return ""
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		t.Errorf("reading request: %v", err)
+		return ""
+	}
+	return string(payload)
+}
+
+// expectTestRequest reads a request and replies OKAY if it is the wanted one
+func expectTestRequest(t *testing.T, conn net.Conn, want string) bool {
+	t.Helper()
+
+	if got := readTestRequest(t, conn); got != want {
+		t.Errorf("request = %q, want %q", got, want)
+		writeTestFailure(conn, "unexpected request")
+		return false
+	}
+	conn.Write([]byte("OKAY"))
+	return true
+}
+
+// writeTestFailure replies FAIL with a message
+func writeTestFailure(conn net.Conn, message string) {
+	fmt.Fprintf(conn, "FAIL%04x%s", len(message), message)
+}
+
+// readTestSyncPacket reads a sync request id and its length field
+func readTestSyncPacket(t *testing.T, conn net.Conn) (string, uint32) {
+	t.Helper()
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		t.Errorf("reading sync header: %v", err)
+		return "", 0
+	}
+	return string(header[:4]), binary.LittleEndian.Uint32(header[4:])
+}
+
+// writeTestSyncPacket writes a sync response id, length field and payload
+func writeTestSyncPacket(conn net.Conn, id string, value uint32, data []byte) {
+	var buf bytes.Buffer
+	buf.WriteString(id)
+	binary.Write(&buf, binary.LittleEndian, value)
+	buf.Write(data)
+	conn.Write(buf.Bytes())
+}
+
+func TestADBClientListDevices(t *testing.T) {
+	list := "emulator-5554 device product:sdk_gphone64 model:sdk_gphone64_arm64 device:emu64a transport_id:1\n" +
+		"R58M12345 unauthorized usb:1-1 transport_id:2\n" +
+		"\n"
+
+	client := fakeADBServer(t, func(conn net.Conn) {
+		if expectTestRequest(t, conn, "host:devices-l") {
+			fmt.Fprintf(conn, "%04x%s", len(list), list)
+		}
+	})
+
+	devices, err := client.ListDevices()
+	if err != nil {
+		t.Fatalf("ListDevices: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("got %d devices, want 2: %+v", len(devices), devices)
+	}
+
+	emulator := devices[0]
+	if emulator.ID != "emulator-5554" || emulator.Status != "device" || !emulator.IsEmulator ||
+		emulator.Product != "sdk_gphone64" || emulator.Model != "sdk_gphone64_arm64" ||
+		emulator.Device != "emu64a" || emulator.Transport != "1" {
+		t.Errorf("emulator parsed as %+v", emulator)
+	}
+
+	phone := devices[1]
+	if phone.ID != "R58M12345" || phone.Status != "unauthorized" || phone.IsEmulator ||
+		phone.Model != "" || phone.Transport != "2" {
+		t.Errorf("phone parsed as %+v", phone)
+	}
+}
+
+func TestADBClientStatusReplies(t *testing.T) {
+	tests := []struct {
+		name    string
+		serial  string
+		reply   func(conn net.Conn)
+		output  string
+		wantErr error
+	}{
+		{
+			name:   "okay",
+			serial: "dev1",
+			reply: func(conn net.Conn) {
+				conn.Write([]byte("OKAY"))
+				if expectTestRequest(t, conn, "shell:echo hello") {
+					conn.Write([]byte("hello\n"))
+				}
+			},
+			output: "hello\n",
+		},
+		{
+			name:    "device not found",
+			serial:  "dev1",
+			reply:   func(conn net.Conn) { writeTestFailure(conn, "device 'dev1' not found") },
+			wantErr: ErrDeviceNotFound,
+		},
+		{
+			name:   "unauthorized",
+			serial: "dev1",
+			reply: func(conn net.Conn) {
+				writeTestFailure(conn, "device unauthorized.\nThis adb server's $ADB_VENDOR_KEYS is not set")
+			},
+			wantErr: ErrDeviceUnauthorized,
+		},
+		{
+			name:    "several devices",
+			reply:   func(conn net.Conn) { writeTestFailure(conn, "more than one device/emulator") },
+			wantErr: ErrMultipleDevices,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := "host:transport-any"
+			if tt.serial != "" {
+				transport = "host:transport:" + tt.serial
+			}
+
+			client := fakeADBServer(t, func(conn net.Conn) {
+				if got := readTestRequest(t, conn); got != transport {
+					t.Errorf("request = %q, want %q", got, transport)
+				}
+				tt.reply(conn)
+			})
+
+			output, err := client.Shell(tt.serial, "echo hello")
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Shell: %v", err)
+				}
+				if output != tt.output {
+					t.Errorf("output = %q, want %q", output, tt.output)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			var adbErr *ADBError
+			if !errors.As(err, &adbErr) {
+				t.Fatalf("error %T is not an *ADBError", err)
+			}
+			if adbErr.Op != transport || adbErr.Serial != tt.serial {
+				t.Errorf("error op %q serial %q, want %q %q", adbErr.Op, adbErr.Serial, transport, tt.serial)
+			}
+		})
+	}
+}
+
+func TestADBClientRejectsUnknownStatus(t *testing.T) {
+	client := fakeADBServer(t, func(conn net.Conn) {
+		readTestRequest(t, conn)
+		conn.Write([]byte("WHAT"))
+	})
+
+	if _, err := client.ListDevices(); err == nil {
+		t.Fatal("ListDevices accepted an unknown status")
+	}
+}
+
+func TestADBClientPushFraming(t *testing.T) {
+	// More than two chunks, the last one partial
+	content := testAPKContent(2*syncMaxChunk + 100)
+	modTime := time.Unix(1700000000, 0)
+
+	var received bytes.Buffer
+	var target string
+	var chunks int
+	var doneTime uint32
+	quit := make(chan bool, 1)
+
+	client := fakeADBServer(t, func(conn net.Conn) {
+		if !expectTestRequest(t, conn, "host:transport:dev1") || !expectTestRequest(t, conn, "sync:") {
+			return
+		}
+
+		id, length := readTestSyncPacket(t, conn)
+		if id != "SEND" {
+			t.Errorf("sync request %q, want SEND", id)
+			return
+		}
+		path := make([]byte, length)
+		io.ReadFull(conn, path)
+		target = string(path)
+
+		for {
+			id, length := readTestSyncPacket(t, conn)
+			switch id {
+			case "DATA":
+				if length > syncMaxChunk {
+					t.Errorf("DATA chunk of %d bytes exceeds %d", length, syncMaxChunk)
+				}
+				chunks++
+				io.CopyN(&received, conn, int64(length))
+			case "DONE":
+				doneTime = length
+				writeTestSyncPacket(conn, "OKAY", 0, nil)
+				id, _ := readTestSyncPacket(t, conn)
+				quit <- id == "QUIT"
+				return
+			default:
+				t.Errorf("unexpected sync request %q", id)
+				return
+			}
+		}
+	})
+
+	err := client.PushReader(context.Background(), "dev1", bytes.NewReader(content), "/sdcard/Download/app.obb", 0644, modTime)
+	if err != nil {
+		t.Fatalf("PushReader: %v", err)
+	}
+
+	if !<-quit {
+		t.Error("client did not end the sync session with QUIT")
+	}
+	if want := "/sdcard/Download/app.obb," + strconv.Itoa(0100644); target != want {
+		t.Errorf("SEND target = %q, want %q", target, want)
+	}
+	if chunks != 3 {
+		t.Errorf("sent %d DATA chunks, want 3", chunks)
+	}
+	if !bytes.Equal(received.Bytes(), content) {
+		t.Errorf("server received %d bytes, want the %d bytes pushed", received.Len(), len(content))
+	}
+	if doneTime != uint32(modTime.Unix()) {
+		t.Errorf("DONE carries mtime %d, want %d", doneTime, modTime.Unix())
+	}
+}
+
+func TestADBClientPushFailure(t *testing.T) {
+	client := fakeADBServer(t, func(conn net.Conn) {
+		if !expectTestRequest(t, conn, "host:transport:dev1") || !expectTestRequest(t, conn, "sync:") {
+			return
+		}
+		for {
+			id, length := readTestSyncPacket(t, conn)
+			if id == "" {
+				return
+			}
+			if id == "DONE" {
+				message := "couldn't create file: Permission denied"
+				writeTestSyncPacket(conn, "FAIL", uint32(len(message)), []byte(message))
+				return
+			}
+			io.CopyN(io.Discard, conn, int64(length))
+		}
+	})
+
+	err := client.PushReader(context.Background(), "dev1", bytes.NewReader([]byte("data")), "/system/app.obb", 0644, time.Now())
+	var adbErr *ADBError
+	if !errors.As(err, &adbErr) {
+		t.Fatalf("error = %v, want an *ADBError", err)
+	}
+	if adbErr.Op != "push /system/app.obb" || adbErr.Message != "couldn't create file: Permission denied" {
+		t.Errorf("error = %+v", adbErr)
+	}
+}
+
+func TestADBClientStat(t *testing.T) {
+	mtime := time.Unix(1700000000, 0)
+
+	client := fakeADBServer(t, func(conn net.Conn) {
+		if !expectTestRequest(t, conn, "host:transport:dev1") || !expectTestRequest(t, conn, "sync:") {
+			return
+		}
+
+		id, length := readTestSyncPacket(t, conn)
+		if id != "STAT" {
+			t.Errorf("sync request %q, want STAT", id)
+			return
+		}
+		path := make([]byte, length)
+		io.ReadFull(conn, path)
+
+		// STAT replies with mode, size and mtime; a zero mode means no such file
+		var reply bytes.Buffer
+		reply.WriteString("STAT")
+		if string(path) == "/sdcard/app.obb" {
+			binary.Write(&reply, binary.LittleEndian, []uint32{0100644, 4096, uint32(mtime.Unix())})
+		} else {
+			binary.Write(&reply, binary.LittleEndian, []uint32{0, 0, 0})
+		}
+		conn.Write(reply.Bytes())
+		readTestSyncPacket(t, conn) // QUIT
+	})
+
+	info, err := client.Stat("dev1", "/sdcard/app.obb")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info == nil || info.Mode != 0644 || info.Size != 4096 || !info.ModTime.Equal(mtime) {
+		t.Errorf("Stat = %+v, want mode 0644, 4096 bytes, mtime %v", info, mtime)
+	}
+
+	info, err = client.Stat("dev1", "/sdcard/missing.obb")
+	if err != nil || info != nil {
+		t.Errorf("Stat of a missing file = %+v, %v, want nil, nil", info, err)
+	}
+}
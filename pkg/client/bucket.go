@@ -2,17 +2,24 @@ package client
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/huanfeng/apkhub/pkg/models"
+	"github.com/huanfeng/apkhub/pkg/signing"
+	"github.com/huanfeng/apkhub/pkg/utils"
 )
 
 // BucketHealth represents the health status of a bucket
@@ -26,8 +33,61 @@ type BucketHealth struct {
 	ErrorCount       int       `json:"error_count"`
 	LastError        string    `json:"last_error,omitempty"`
 	ConsecutiveFails int       `json:"consecutive_fails"`
+	NotModified      int       `json:"not_modified"`  // Conditional requests answered with 304
+	BytesSaved       int64     `json:"bytes_saved"`   // Manifest bytes not transferred thanks to 304 or deltas
+	DeltaUpdates     int       `json:"delta_updates"` // Manifests brought up to date with deltas
 }
 
+// ManifestUpdateStatus describes how a bucket manifest was obtained
+type ManifestUpdateStatus string
+
+const (
+	// ManifestUpdated means a new manifest was downloaded
+	ManifestUpdated ManifestUpdateStatus = "updated"
+
+	// ManifestUnchanged means the bucket confirmed the cached manifest is current (HTTP 304)
+	ManifestUnchanged ManifestUpdateStatus = "unchanged"
+
+	// ManifestCached means a fresh cached manifest was used without contacting the bucket
+	ManifestCached ManifestUpdateStatus = "cached"
+
+	// ManifestStale means the bucket could not be used and a stale cached manifest was returned
+	ManifestStale ManifestUpdateStatus = "stale"
+)
+
+// maxManifestSize bounds a decompressed manifest
+const maxManifestSize = 512 * 1024 * 1024
+
+// fetchResult is the outcome of a (conditional) HTTP fetch
+type fetchResult struct {
+	data         []byte
+	size         int64 // Bytes transferred
+	notModified  bool
+	etag         string
+	lastModified string
+	variant      string // Compression of the manifest copy that was fetched, "" for plain JSON
+	deltas       int    // Number of deltas applied to the cached manifest
+	saved        int64  // Bytes not transferred thanks to deltas
+}
+
+// httpStatusError is returned for unexpected HTTP response codes
+type httpStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Status)
+}
+
+var (
+	// ErrManifestRollback is returned when a bucket serves a manifest older than one already accepted
+	ErrManifestRollback = errors.New("manifest rollback detected")
+
+	// ErrManifestExpired is returned when a bucket manifest is past its expiry
+	ErrManifestExpired = errors.New("manifest expired")
+)
+
 // RetryConfig defines retry behavior
 type RetryConfig struct {
 	MaxRetries    int
@@ -93,66 +153,131 @@ func (b *BucketManager) SetSignatureVerification(enabled bool) {
 
 // FetchManifest fetches and caches a bucket's manifest with retry and health monitoring
 func (b *BucketManager) FetchManifest(bucketName string) (*models.ManifestIndex, error) {
+	manifest, _, err := b.fetchManifest(bucketName, false)
+	return manifest, err
+}
+
+// UpdateManifest revalidates a bucket's manifest even if the cached copy is still
+// fresh, and reports whether it changed
+func (b *BucketManager) UpdateManifest(bucketName string) (ManifestUpdateStatus, error) {
+	_, status, err := b.fetchManifest(bucketName, true)
+	return status, err
+}
+
+// fetchManifest returns a bucket's manifest from cache or the bucket. Remote buckets
+// are asked with a conditional request when a cached copy exists, so an unchanged
+// manifest is not downloaded again. force skips the fresh cache shortcut.
+func (b *BucketManager) fetchManifest(bucketName string, force bool) (*models.ManifestIndex, ManifestUpdateStatus, error) {
 	bucket, exists := b.config.Buckets[bucketName]
 	if !exists {
-		return nil, fmt.Errorf("bucket %s not found", bucketName)
+		return nil, "", fmt.Errorf("bucket %s not found", bucketName)
 	}
 
 	if !bucket.Enabled {
-		return nil, fmt.Errorf("bucket %s is disabled", bucketName)
+		return nil, "", fmt.Errorf("bucket %s is disabled", bucketName)
 	}
 
 	// Initialize health tracking
 	health := b.getOrCreateHealth(bucketName, bucket.URL)
 
 	// Check cache first
-	if manifest, isStale, err := b.cacheManager.GetManifest(bucketName, false); err == nil && manifest != nil {
+	cached, isStale, err := b.cacheManager.GetManifest(bucketName, true)
+	if err == nil && cached != nil {
 		// Update health status for cache hit
 		health.LastCheck = time.Now()
 		if health.Status == "unknown" {
 			health.Status = "healthy"
 		}
 
-		if !isStale {
-			return manifest, nil
+		if !force && !isStale && !manifestExpired(cached, time.Now()) {
+			return cached, ManifestCached, nil
 		}
 		// Continue to fetch fresh data but keep stale as fallback
+	} else {
+		cached = nil
 	}
 
-	// Determine if this is a local or remote bucket
-	var data []byte
-	var err error
+	var result *fetchResult
 
-	if strings.HasPrefix(bucket.URL, "file://") {
+	// A cached manifest a few generations behind is brought up to date with the
+	// deltas the bucket publishes instead of being downloaded again
+	if cached != nil && cached.Deltas != "" && cached.Generation > 0 {
+		validators := b.cacheManager.GetManifestValidators(bucketName)
+
+		data, applied, size, err := b.fetchManifestDeltas(bucket, bucketName, cached)
+		switch {
+		case err != nil:
+			if !errors.Is(err, errDeltaUnavailable) {
+				fmt.Printf("⚠️  Delta update failed for bucket '%s': %v, downloading full manifest\n", bucketName, err)
+			}
+		case data == nil:
+			// The delta index is not signed, so it cannot prove the manifest is
+			// unchanged: the signed manifest is revalidated below
+		default:
+			result = &fetchResult{data: data, size: size, deltas: applied}
+			if validators != nil {
+				result.variant = validators.Variant
+				if validators.Size > size {
+					result.saved = validators.Size - size
+				}
+			}
+			if !strings.HasPrefix(bucket.URL, "file://") {
+				// Keep the next sync conditional: the rebuilt manifest is the
+				// published one, so its current validators apply
+				result.etag, result.lastModified = b.fetchManifestValidators(bucket.URL, result.variant)
+			}
+		}
+	}
+
+	// Determine if this is a local or remote bucket
+	switch {
+	case result != nil:
+		// Already reconstructed from deltas
+	case strings.HasPrefix(bucket.URL, "file://"):
 		// Local bucket
-		data, err = b.fetchLocalManifest(bucket.URL, bucketName)
-	} else {
-		// Remote bucket
-		manifestURL := bucket.URL + "/apkhub_manifest.json"
-		data, err = b.fetchWithRetry(manifestURL, bucketName)
+		result, err = b.fetchLocalManifest(bucket.URL, bucketName)
+	default:
+		// Remote bucket, revalidating the cached copy if there is one
+		var validators *ManifestValidators
+		if cached != nil {
+			validators = b.cacheManager.GetManifestValidators(bucketName)
+		}
+
+		result, err = b.fetchRemoteManifest(bucket.URL, bucketName, validators)
+		if err == nil && result.notModified {
+			if manifest, err := b.acceptUnchangedManifest(bucket, bucketName, cached, validators); err == nil {
+				return manifest, ManifestUnchanged, nil
+			} else if !errors.Is(err, ErrManifestExpired) {
+				return nil, "", err
+			}
+			// An expired manifest cannot be refreshed by a 304: download it again
+			result, err = b.fetchRemoteManifest(bucket.URL, bucketName, &ManifestValidators{Variant: validators.Variant})
+		}
 	}
 
 	if err != nil {
 		b.updateHealthOnError(bucketName, err)
 
 		// Try to return stale cache if available
-		if manifest, _, cacheErr := b.cacheManager.GetManifest(bucketName, true); cacheErr == nil && manifest != nil {
+		if cached != nil {
 			fmt.Printf("⚠️  Using stale cache for bucket '%s' due to error: %v\n", bucketName, err)
-			return manifest, nil
+			return cached, ManifestStale, nil
 		}
 
-		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+		return nil, "", fmt.Errorf("failed to fetch manifest: %w", err)
 	}
 
+	data := result.data
+
 	// Parse manifest
 	var manifest models.ManifestIndex
 	if err := json.Unmarshal(data, &manifest); err != nil {
 		b.updateHealthOnError(bucketName, fmt.Errorf("parse error: %w", err))
-		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		return nil, "", fmt.Errorf("failed to parse manifest: %w", err)
 	}
 
 	if b.verifySignature {
-		if err := b.verifyManifestSignature(&manifest); err != nil {
+		if err := b.verifyManifestSignature(bucket, bucketName, data); err != nil {
 			policy := strings.ToLower(b.config.Security.SignaturePolicy)
 			if policy == "" {
 				policy = "lenient"
@@ -164,38 +289,219 @@ func (b *BucketManager) FetchManifest(bucketName string) (*models.ManifestIndex,
 				// Try to use stale cache as a safe downgrade path
 				if manifest, _, cacheErr := b.cacheManager.GetManifest(bucketName, true); cacheErr == nil && manifest != nil {
 					fmt.Printf("⚠️  Using stale cache for bucket '%s' due to signature verification failure: %v\n", bucketName, err)
-					return manifest, nil
+					return manifest, ManifestStale, nil
 				}
 
-				return nil, fmt.Errorf("manifest signature verification failed: %w. Add the signer fingerprint to security.trusted_keys or rerun with --verify-signature=false.", err)
+				return nil, "", fmt.Errorf("manifest signature verification failed: %w. Add the signer fingerprint to security.trusted_keys or rerun with --verify-signature=false.", err)
 			}
 
 			fmt.Printf("⚠️  Manifest signature verification failed: %v (continuing due to lenient policy)\n", err)
 		}
 	}
 
+	if err := b.checkManifestFreshness(bucketName, &manifest); err != nil {
+		b.updateHealthOnError(bucketName, err)
+
+		if errors.Is(err, ErrManifestRollback) {
+			// Keep serving the newer manifest we already accepted
+			if cached, _, cacheErr := b.cacheManager.GetManifest(bucketName, true); cacheErr == nil && cached != nil && !manifestExpired(cached, time.Now()) {
+				fmt.Printf("⚠️  Using cached manifest for bucket '%s': %v\n", bucketName, err)
+				return cached, ManifestStale, nil
+			}
+		}
+
+		return nil, "", err
+	}
+
 	// Save to cache
-	cacheTTL := time.Duration(b.config.Client.CacheTTL) * time.Second
-	if cacheTTL <= 0 {
-		cacheTTL = 24 * time.Hour // Default 24 hours
+	cacheTTL := b.manifestCacheTTL()
+
+	validators := &ManifestValidators{
+		ETag:         result.etag,
+		LastModified: result.lastModified,
+		Size:         result.size,
+		Variant:      result.variant,
+	}
+	if result.deltas > 0 {
+		// Remember what a full download costs, not what the deltas did
+		validators.Size = result.size + result.saved
 	}
 
-	if err := b.cacheManager.SetManifest(bucketName, &manifest, cacheTTL); err != nil {
+	if err := b.cacheManager.SetManifestWithValidators(bucketName, &manifest, cacheTTL, validators); err != nil {
 		fmt.Printf("⚠️  Failed to cache manifest for '%s': %v\n", bucketName, err)
 	}
 
+	if err := b.recordManifestState(bucketName, &manifest); err != nil {
+		fmt.Printf("⚠️  Failed to record manifest state for '%s': %v\n", bucketName, err)
+	}
+
 	// Update success metrics
 	b.updateHealthOnSuccess(bucketName)
+	if result.deltas > 0 {
+		fmt.Printf("📦 Applied %d manifest delta(s) to bucket '%s'\n", result.deltas, bucketName)
+		health.DeltaUpdates++
+		health.BytesSaved += result.saved
+	}
 
 	// Update last updated time
 	bucket.LastUpdated = time.Now()
 	b.config.Save()
 
-	return &manifest, nil
+	return &manifest, ManifestUpdated, nil
+}
+
+// acceptUnchangedManifest handles a 304 response: the cached manifest is kept,
+// its cache lifetime extended and the saved transfer recorded
+func (b *BucketManager) acceptUnchangedManifest(bucket *Bucket, bucketName string, cached *models.ManifestIndex, validators *ManifestValidators) (*models.ManifestIndex, error) {
+	if err := b.checkManifestFreshness(bucketName, cached); err != nil {
+		b.updateHealthOnError(bucketName, err)
+		return nil, err
+	}
+
+	if err := b.cacheManager.RefreshManifest(bucketName, b.manifestCacheTTL()); err != nil {
+		fmt.Printf("⚠️  Failed to refresh cached manifest for '%s': %v\n", bucketName, err)
+	}
+
+	b.updateHealthOnSuccess(bucketName)
+	if health := b.healthMap[bucketName]; health != nil {
+		health.NotModified++
+		if validators != nil {
+			health.BytesSaved += validators.Size
+		}
+	}
+
+	bucket.LastUpdated = time.Now()
+	b.config.Save()
+
+	return cached, nil
+}
+
+// manifestCacheTTL returns how long fetched manifests are cached
+func (b *BucketManager) manifestCacheTTL() time.Duration {
+	cacheTTL := time.Duration(b.config.Client.CacheTTL) * time.Second
+	if cacheTTL <= 0 {
+		cacheTTL = 24 * time.Hour // Default 24 hours
+	}
+	return cacheTTL
+}
+
+// readBucketFile reads a file given relative to the bucket root
+func (b *BucketManager) readBucketFile(bucket *Bucket, bucketName, relPath string) ([]byte, error) {
+	cleaned := path.Clean(relPath)
+	if relPath == "" || path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return nil, fmt.Errorf("invalid bucket file path %q", relPath)
+	}
+
+	if strings.HasPrefix(bucket.URL, "file://") {
+		localPath := filepath.Join(strings.TrimPrefix(bucket.URL, "file://"), filepath.FromSlash(cleaned))
+		return os.ReadFile(localPath)
+	}
+
+	return b.fetchWithRetry(strings.TrimSuffix(bucket.URL, "/")+"/"+cleaned, bucketName)
+}
+
+// sha256Hex returns the hex SHA256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
 
 // fetchWithRetry performs HTTP request with exponential backoff retry
 func (b *BucketManager) fetchWithRetry(url, bucketName string) ([]byte, error) {
+	result, err := b.fetchConditional(url, bucketName, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result.data, nil
+}
+
+// manifestVariants returns the manifest copies to try: the one that worked last
+// time (if known) first, then compressed copies before the plain JSON
+func manifestVariants(validators *ManifestValidators) []string {
+	all := append(utils.CompressionFormats(), "")
+	if validators == nil {
+		return all
+	}
+
+	variants := []string{validators.Variant}
+	for _, variant := range all {
+		if variant != validators.Variant {
+			variants = append(variants, variant)
+		}
+	}
+	return variants
+}
+
+// fetchRemoteManifest fetches the manifest of a remote bucket, preferring a
+// compressed copy when the bucket publishes one. A compressed copy that cannot be
+// fetched or decompressed is skipped, so only a failing plain manifest is an
+// error; the returned data is always the decompressed JSON.
+func (b *BucketManager) fetchRemoteManifest(bucketURL, bucketName string, validators *ManifestValidators) (*fetchResult, error) {
+	for _, variant := range manifestVariants(validators) {
+		// Validators only apply to the copy they were recorded for
+		var conditional *ManifestValidators
+		if validators != nil && validators.Variant == variant && (validators.ETag != "" || validators.LastModified != "") {
+			conditional = validators
+		}
+
+		manifestURL := bucketURL + "/apkhub_manifest.json" + utils.CompressionExtension(variant)
+		result, err := b.fetchConditional(manifestURL, bucketName, conditional)
+		if err == nil && !result.notModified {
+			result.data, err = decompressManifest(result.data)
+		}
+		if err != nil {
+			// A compressed copy may be unpublished (404, or 403 from object
+			// stores and CDNs) or broken: only the plain manifest must work
+			if variant != "" {
+				continue
+			}
+			return nil, err
+		}
+
+		result.variant = variant
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("manifest not found")
+}
+
+// fetchManifestValidators returns the ETag and Last-Modified of a published
+// manifest copy, or empty values if the bucket does not answer
+func (b *BucketManager) fetchManifestValidators(bucketURL, variant string) (etag, lastModified string) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.retryConfig.Timeout)
+	defer cancel()
+
+	manifestURL := bucketURL + "/apkhub_manifest.json" + utils.CompressionExtension(variant)
+	req, err := http.NewRequestWithContext(ctx, "HEAD", manifestURL, nil)
+	if err != nil {
+		return "", ""
+	}
+	req.Header.Set("User-Agent", "ApkHub-CLI/1.0")
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return "", ""
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", ""
+	}
+	return resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+}
+
+// decompressManifest returns the JSON of a manifest that may be compressed
+func decompressManifest(data []byte) ([]byte, error) {
+	format := utils.DetectCompression(data)
+	if format == "" {
+		return data, nil
+	}
+	return utils.Decompress(format, data, maxManifestSize)
+}
+
+// fetchConditional performs an HTTP request with exponential backoff retry. With
+// validators the request is conditional and a 304 response is reported as notModified.
+func (b *BucketManager) fetchConditional(url, bucketName string, validators *ManifestValidators) (*fetchResult, error) {
 	var lastErr error
 
 	for attempt := 0; attempt <= b.retryConfig.MaxRetries; attempt++ {
@@ -219,8 +525,17 @@ func (b *BucketManager) fetchWithRetry(url, bucketName string) ([]byte, error) {
 			continue
 		}
 
-		// Set user agent
+		// Set user agent; compressed transfer encodings are decoded below
 		req.Header.Set("User-Agent", "ApkHub-CLI/1.0")
+		req.Header.Set("Accept-Encoding", "zstd, gzip")
+		if validators != nil {
+			if validators.ETag != "" {
+				req.Header.Set("If-None-Match", validators.ETag)
+			}
+			if validators.LastModified != "" {
+				req.Header.Set("If-Modified-Since", validators.LastModified)
+			}
+		}
 
 		// Record start time for response time measurement
 		startTime := time.Now()
@@ -228,18 +543,26 @@ func (b *BucketManager) fetchWithRetry(url, bucketName string) ([]byte, error) {
 		// Perform request
 		resp, err := b.httpClient.Do(req)
 		responseTime := time.Since(startTime)
-		cancel()
 
 		if err != nil {
+			cancel()
 			lastErr = fmt.Errorf("network error: %w", err)
 			b.updateResponseTime(bucketName, responseTime)
 			continue
 		}
 
+		if resp.StatusCode == http.StatusNotModified && validators != nil {
+			resp.Body.Close()
+			cancel()
+			b.updateResponseTime(bucketName, responseTime)
+			return &fetchResult{notModified: true}, nil
+		}
+
 		// Check status code
 		if resp.StatusCode != http.StatusOK {
 			resp.Body.Close()
-			lastErr = fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+			cancel()
+			lastErr = &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 			b.updateResponseTime(bucketName, responseTime)
 
 			// Don't retry on client errors (4xx)
@@ -249,25 +572,40 @@ func (b *BucketManager) fetchWithRetry(url, bucketName string) ([]byte, error) {
 			continue
 		}
 
-		// Read response
+		// Read response; the context must stay alive until the body is read
 		data, err := io.ReadAll(resp.Body)
 		resp.Body.Close()
+		cancel()
 
 		if err != nil {
 			lastErr = fmt.Errorf("read error: %w", err)
 			continue
 		}
 
+		size := int64(len(data))
+		if encoding := strings.ToLower(resp.Header.Get("Content-Encoding")); encoding == "gzip" || encoding == "zstd" {
+			if data, err = utils.Decompress(encoding, data, maxManifestSize); err != nil {
+				lastErr = err
+				continue
+			}
+		}
+
 		// Success - update response time
 		b.updateResponseTime(bucketName, responseTime)
-		return data, nil
+		return &fetchResult{
+			data:         data,
+			size:         size,
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+		}, nil
 	}
 
 	return nil, fmt.Errorf("failed after %d attempts: %w", b.retryConfig.MaxRetries+1, lastErr)
 }
 
-// fetchLocalManifest fetches manifest from local file system
-func (b *BucketManager) fetchLocalManifest(fileURL, bucketName string) ([]byte, error) {
+// fetchLocalManifest fetches manifest from local file system, preferring a
+// compressed copy when one is published
+func (b *BucketManager) fetchLocalManifest(fileURL, bucketName string) (*fetchResult, error) {
 	// Convert file:// URL to local path
 	localPath := strings.TrimPrefix(fileURL, "file://")
 	manifestPath := filepath.Join(localPath, "apkhub_manifest.json")
@@ -275,13 +613,24 @@ func (b *BucketManager) fetchLocalManifest(fileURL, bucketName string) ([]byte,
 	// Record start time for response time measurement
 	startTime := time.Now()
 
+	// Find the first available copy, plain JSON last
+	variant := ""
+	for _, format := range utils.CompressionFormats() {
+		if _, err := os.Stat(manifestPath + utils.CompressionExtension(format)); err == nil {
+			variant = format
+			break
+		}
+	}
+
 	// Check if manifest file exists
-	if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("manifest file not found: %s", manifestPath)
+	if variant == "" {
+		if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
+			return nil, fmt.Errorf("manifest file not found: %s", manifestPath)
+		}
 	}
 
 	// Read manifest file
-	data, err := os.ReadFile(manifestPath)
+	data, err := os.ReadFile(manifestPath + utils.CompressionExtension(variant))
 	responseTime := time.Since(startTime)
 
 	// Update response time
@@ -291,7 +640,12 @@ func (b *BucketManager) fetchLocalManifest(fileURL, bucketName string) ([]byte,
 		return nil, fmt.Errorf("failed to read manifest file: %w", err)
 	}
 
-	return data, nil
+	size := int64(len(data))
+	if data, err = decompressManifest(data); err != nil {
+		return nil, fmt.Errorf("failed to decompress manifest file: %w", err)
+	}
+
+	return &fetchResult{data: data, size: size, variant: variant}, nil
 }
 
 // getOrCreateHealth gets or creates health tracking for a bucket
@@ -391,6 +745,7 @@ func (b *BucketManager) UpdateAll() error {
 	type updateResult struct {
 		name   string
 		bucket *Bucket
+		status ManifestUpdateStatus
 		err    error
 	}
 
@@ -400,14 +755,16 @@ func (b *BucketManager) UpdateAll() error {
 	for name, bucket := range enabledBuckets {
 		go func(n string, bkt *Bucket) {
 			fmt.Printf("📡 Fetching '%s' from %s...\n", n, bkt.URL)
-			_, err := b.FetchManifest(n)
-			results <- updateResult{name: n, bucket: bkt, err: err}
+			status, err := b.UpdateManifest(n)
+			results <- updateResult{name: n, bucket: bkt, status: status, err: err}
 		}(name, bucket)
 	}
 
 	// Collect results
 	var errors []error
 	var successful []string
+	var unchanged []string
+	var stale []string
 	var failed []string
 
 	for i := 0; i < len(enabledBuckets); i++ {
@@ -417,6 +774,12 @@ func (b *BucketManager) UpdateAll() error {
 			errors = append(errors, fmt.Errorf("%s: %w", result.name, result.err))
 			failed = append(failed, result.name)
 			fmt.Printf("❌ Failed to update '%s': %v\n", result.name, result.err)
+		} else if result.status == ManifestUnchanged {
+			unchanged = append(unchanged, result.name)
+			fmt.Printf("✓ Unchanged '%s'\n", result.name)
+		} else if result.status == ManifestStale {
+			stale = append(stale, result.name)
+			fmt.Printf("⚠️  Kept cached manifest for '%s'\n", result.name)
 		} else {
 			successful = append(successful, result.name)
 			fmt.Printf("✅ Updated '%s'\n", result.name)
@@ -425,7 +788,13 @@ func (b *BucketManager) UpdateAll() error {
 
 	// Print summary
 	fmt.Printf("\n📊 Update Summary:\n")
-	fmt.Printf("   ✅ Successful: %d (%s)\n", len(successful), strings.Join(successful, ", "))
+	fmt.Printf("   ✅ Updated: %d (%s)\n", len(successful), strings.Join(successful, ", "))
+	if len(unchanged) > 0 {
+		fmt.Printf("   ✓ Unchanged: %d (%s)\n", len(unchanged), strings.Join(unchanged, ", "))
+	}
+	if len(stale) > 0 {
+		fmt.Printf("   ⚠️  Stale: %d (%s)\n", len(stale), strings.Join(stale, ", "))
+	}
 	if len(failed) > 0 {
 		fmt.Printf("   ❌ Failed: %d (%s)\n", len(failed), strings.Join(failed, ", "))
 	}
@@ -450,99 +819,236 @@ func (b *BucketManager) GetMergedManifest() (*models.ManifestIndex, error) {
 		Packages:    make(map[string]*models.AppPackage),
 	}
 
-	// Load each bucket's manifest
-	for name := range b.config.GetEnabledBuckets() {
+	// Load each bucket's manifest, most preferred bucket first
+	for _, name := range b.config.OrderedEnabledBuckets() {
 		manifest, err := b.FetchManifest(name)
 		if err != nil {
 			fmt.Printf("Warning: failed to load bucket %s: %v\n", name, err)
 			continue
 		}
 
-		// Merge packages
-		for pkgID, pkg := range manifest.Packages {
-			if existing, exists := merged.Packages[pkgID]; exists {
-				// Merge versions
-				var latestPrefixedKey string
-				for versionKey, version := range pkg.Versions {
-					// Prefix version key with bucket name to avoid conflicts
-					prefixedKey := fmt.Sprintf("%s_%s", name, versionKey)
-					existing.Versions[prefixedKey] = version
-					// Update base URL if needed
-					if version.DownloadURL != "" && !isAbsoluteURL(version.DownloadURL) {
-						version.DownloadURL = b.resolveDownloadURL(name, version.DownloadURL)
-					}
-
-					// Track the prefixed key for the latest version
-					if versionKey == pkg.Latest {
-						latestPrefixedKey = prefixedKey
-					}
-				}
-				// Update latest if newer (compare version strings or use the new one if existing is empty)
-				if existing.Latest == "" || (latestPrefixedKey != "" && pkg.Latest > existing.Latest) {
-					existing.Latest = latestPrefixedKey
-				}
-			} else {
-				// Clone package
-				clonedPkg := &models.AppPackage{
-					PackageID:   pkg.PackageID,
-					Name:        pkg.Name,
-					Description: pkg.Description,
-					Icon:        pkg.Icon,
-					Category:    pkg.Category,
-					Latest:      pkg.Latest,
-					Versions:    make(map[string]*models.AppVersion),
-				}
-				// Clone versions with bucket prefix
-				for versionKey, version := range pkg.Versions {
-					prefixedKey := fmt.Sprintf("%s_%s", name, versionKey)
-					clonedVersion := *version // Copy
-					// Update base URL if needed
-					if clonedVersion.DownloadURL != "" && !isAbsoluteURL(clonedVersion.DownloadURL) {
-						clonedVersion.DownloadURL = b.resolveDownloadURL(name, clonedVersion.DownloadURL)
-					}
-					clonedPkg.Versions[prefixedKey] = &clonedVersion
-
-					// Update Latest key if this is the latest version
-					if versionKey == pkg.Latest {
-						clonedPkg.Latest = prefixedKey
-					}
-				}
-				merged.Packages[pkgID] = clonedPkg
+		bucketName := name
+		mergeBucketPackages(b.config, merged, bucketName, manifest, func(url string) string {
+			return b.resolveDownloadURL(bucketName, url)
+		})
+	}
+
+	selectMergedLatest(b.config, merged)
+
+	return merged, nil
+}
+
+// mergeBucketPackages adds a bucket's packages to a merged manifest. Version keys
+// are prefixed with the bucket name to avoid conflicts, and package metadata comes
+// from the first (most preferred) bucket merged. Packages pinned to another bucket
+// are skipped. resolveURL may be nil.
+func mergeBucketPackages(config *Config, merged *models.ManifestIndex, bucketName string, manifest *models.ManifestIndex, resolveURL func(string) string) {
+	for pkgID, pkg := range manifest.Packages {
+		if pinned := config.PinnedBucket(pkgID); pinned != "" && pinned != bucketName {
+			continue
+		}
+
+		mergedPkg, exists := merged.Packages[pkgID]
+		if !exists {
+			mergedPkg = &models.AppPackage{
+				PackageID:   pkg.PackageID,
+				Name:        pkg.Name,
+				Description: pkg.Description,
+				Icon:        pkg.Icon,
+				Category:    pkg.Category,
+				Versions:    make(map[string]*models.AppVersion),
 			}
+			merged.Packages[pkgID] = mergedPkg
 		}
 
-		merged.TotalAPKs += manifest.TotalAPKs
-		merged.TotalSize += manifest.TotalSize
+		for versionKey, version := range pkg.Versions {
+			prefixedKey := fmt.Sprintf("%s_%s", bucketName, versionKey)
+			clonedVersion := *version // Copy
+			clonedVersion.Bucket = bucketName
+			// Update base URL if needed
+			if resolveURL != nil && clonedVersion.DownloadURL != "" && !isAbsoluteURL(clonedVersion.DownloadURL) {
+				clonedVersion.DownloadURL = resolveURL(clonedVersion.DownloadURL)
+			}
+			mergedPkg.Versions[prefixedKey] = &clonedVersion
+		}
 	}
 
-	return merged, nil
+	merged.TotalAPKs += manifest.TotalAPKs
+	merged.TotalSize += manifest.TotalSize
 }
 
-func (b *BucketManager) verifyManifestSignature(manifest *models.ManifestIndex) error {
-	if manifest == nil {
-		return fmt.Errorf("manifest is empty")
+// selectMergedLatest sets each merged package's Latest to the version with the
+// highest VersionCode, breaking ties by signer trust and then bucket preference
+func selectMergedLatest(config *Config, merged *models.ManifestIndex) {
+	for _, pkg := range merged.Packages {
+		var latest *models.AppVersion
+		pkg.Latest = ""
+
+		for key, version := range pkg.Versions {
+			if latest == nil || compareMergedVersions(config, key, version, pkg.Latest, latest) > 0 {
+				pkg.Latest, latest = key, version
+			}
+		}
 	}
+}
 
-	sig := manifest.Signature
-	if sig == nil {
-		return fmt.Errorf("manifest missing signature metadata")
+// compareMergedVersions orders two merged versions, returning a positive value when a is preferred
+func compareMergedVersions(config *Config, aKey string, a *models.AppVersion, bKey string, b *models.AppVersion) int {
+	if a.VersionCode != b.VersionCode {
+		if a.VersionCode > b.VersionCode {
+			return 1
+		}
+		return -1
 	}
 
-	if sig.PublicKeyFingerprint == "" {
-		return fmt.Errorf("manifest signature missing public key fingerprint")
+	if ta, tb := signerTrust(a), signerTrust(b); ta != tb {
+		return ta - tb
 	}
 
-	if len(b.config.Security.TrustedKeys) > 0 && !containsString(b.config.Security.TrustedKeys, sig.PublicKeyFingerprint) {
-		return fmt.Errorf("untrusted manifest signer: %s", sig.PublicKeyFingerprint)
+	if cmp := config.CompareBucketPreference(a.Bucket, b.Bucket); cmp != 0 {
+		return cmp
 	}
 
-	if sig.SignedAt.IsZero() {
-		return fmt.Errorf("manifest signature timestamp is missing")
+	// Keep the choice stable across runs
+	return strings.Compare(bKey, aKey)
+}
+
+// signerTrust ranks a version's signer: cryptographically verified signatures
+// first, then signed but unverified, then unsigned
+func signerTrust(version *models.AppVersion) int {
+	switch {
+	case version.SignatureVerification != nil && version.SignatureVerification.Verified:
+		return 2
+	case version.SignatureInfo != nil && version.SignatureInfo.SHA256 != "":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// checkManifestFreshness rejects manifests that are older than the newest one
+// accepted from the bucket, or whose expiry has passed
+func (b *BucketManager) checkManifestFreshness(bucketName string, manifest *models.ManifestIndex) error {
+	if manifestExpired(manifest, time.Now()) {
+		return fmt.Errorf("%w: bucket '%s' manifest expired at %s", ErrManifestExpired,
+			bucketName, manifest.Expires.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	// Without the state file rollbacks go unnoticed, so an unreadable one refuses
+	// the manifest; only a bucket that was never accepted before passes
+	state, err := b.cacheManager.GetManifestState(bucketName)
+	if err != nil {
+		return fmt.Errorf("cannot check bucket '%s' for rollback: %w (remove %s to trust the next manifest of every bucket)",
+			bucketName, err, b.cacheManager.manifestStatePath())
+	}
+	if state == nil {
+		return nil
+	}
+
+	// Generations only grow, whatever the timestamps of a replayed manifest claim
+	if manifest.Generation < state.Generation {
+		return fmt.Errorf("%w: bucket '%s' served manifest generation %d, older than accepted generation %d",
+			ErrManifestRollback, bucketName, manifest.Generation, state.Generation)
+	}
+
+	if manifest.UpdatedAt.Before(state.UpdatedAt) || compareManifestVersions(manifest.Version, state.Version) < 0 {
+		return fmt.Errorf("%w: bucket '%s' served manifest %s (updated %s), older than accepted %s (updated %s)",
+			ErrManifestRollback, bucketName,
+			manifest.Version, manifest.UpdatedAt.Local().Format("2006-01-02 15:04:05"),
+			state.Version, state.UpdatedAt.Local().Format("2006-01-02 15:04:05"))
 	}
 
 	return nil
 }
 
+// recordManifestState remembers the newest accepted manifest of a bucket
+func (b *BucketManager) recordManifestState(bucketName string, manifest *models.ManifestIndex) error {
+	return b.cacheManager.SetManifestState(bucketName, &ManifestState{
+		Version:    manifest.Version,
+		Generation: manifest.Generation,
+		UpdatedAt:  manifest.UpdatedAt,
+		Expires:    manifest.Expires,
+		AcceptedAt: time.Now(),
+	})
+}
+
+// GetManifestStates returns the accepted manifest state of every bucket
+func (b *BucketManager) GetManifestStates() (map[string]*ManifestState, error) {
+	return b.cacheManager.GetManifestStates()
+}
+
+// ForgetManifestState drops the accepted manifest state of a bucket
+func (b *BucketManager) ForgetManifestState(bucketName string) error {
+	return b.cacheManager.DeleteManifestState(bucketName)
+}
+
+// manifestExpired reports whether a manifest's optional expiry has passed
+func manifestExpired(manifest *models.ManifestIndex, now time.Time) bool {
+	return manifest.Expires != nil && !manifest.Expires.IsZero() && now.After(*manifest.Expires)
+}
+
+// compareManifestVersions compares dotted numeric manifest versions
+func compareManifestVersions(a, b string) int {
+	if a == "" || b == "" {
+		return 0
+	}
+
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aNum, bNum int
+		if i < len(aParts) {
+			aNum, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			bNum, _ = strconv.Atoi(bParts[i])
+		}
+		if aNum != bNum {
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// verifyManifestSignature verifies the detached signature over the raw manifest bytes
+func (b *BucketManager) verifyManifestSignature(bucket *Bucket, bucketName string, data []byte) error {
+	sigData, err := b.fetchManifestSignature(bucket, bucketName)
+	if err != nil {
+		return fmt.Errorf("manifest signature unavailable: %w", err)
+	}
+
+	sigFile, err := signing.ParseSignatureFile(sigData)
+	if err != nil {
+		return err
+	}
+
+	if _, err := signing.Verify(data, sigFile, b.config.Security.TrustedKeys); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// fetchManifestSignature fetches the detached apkhub_manifest.json.sig for a bucket
+func (b *BucketManager) fetchManifestSignature(bucket *Bucket, bucketName string) ([]byte, error) {
+	sigName := "apkhub_manifest.json" + models.ManifestSignatureSuffix
+
+	if strings.HasPrefix(bucket.URL, "file://") {
+		sigPath := filepath.Join(strings.TrimPrefix(bucket.URL, "file://"), sigName)
+		data, err := os.ReadFile(sigPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signature file: %w", err)
+		}
+		return data, nil
+	}
+
+	return b.fetchWithRetry(bucket.URL+"/"+sigName, bucketName)
+}
+
 // loadCachedManifest loads manifest from cache if not expired
 func (b *BucketManager) loadCachedManifest(cachePath string, ttl int) (*models.ManifestIndex, error) {
 	info, err := os.Stat(cachePath)
@@ -631,6 +1137,13 @@ func (b *BucketManager) PrintHealthStatus() {
 		fmt.Printf("%-20s %s%-9s %-8s %-12s %-6d %s\n",
 			name, statusIcon, status, responseTime, lastSuccess, health.ConsecutiveFails, lastError)
 	}
+
+	for name, health := range b.healthMap {
+		if health.NotModified > 0 || health.DeltaUpdates > 0 {
+			fmt.Printf("💾 %s: %d unchanged response(s), %d delta update(s), %s not downloaded\n",
+				name, health.NotModified, health.DeltaUpdates, formatBytes(health.BytesSaved))
+		}
+	}
 }
 
 // CheckBucketHealth performs a health check on a specific bucket
@@ -0,0 +1,496 @@
+package client
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+	"github.com/huanfeng/apkhub/pkg/signing"
+	"github.com/huanfeng/apkhub/pkg/utils"
+)
+
+// testBucket serves the files of a remote bucket over HTTP
+type testBucket struct {
+	mu       sync.Mutex
+	files    map[string][]byte // By name relative to the bucket URL
+	status   map[string]int    // Status answered instead of the file
+	requests map[string]int    // Requests received per name
+	server   *httptest.Server
+}
+
+// newTestBucket starts a bucket server that answers with ETags and honours
+// conditional requests
+func newTestBucket(t *testing.T) *testBucket {
+	t.Helper()
+
+	tb := &testBucket{
+		files:    make(map[string][]byte),
+		status:   make(map[string]int),
+		requests: make(map[string]int),
+	}
+	tb.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+
+		tb.mu.Lock()
+		tb.requests[name]++
+		data, ok := tb.files[name]
+		status := tb.status[name]
+		tb.mu.Unlock()
+
+		switch {
+		case status != 0:
+			w.WriteHeader(status)
+		case !ok:
+			http.NotFound(w, r)
+		default:
+			sum := sha256.Sum256(data)
+			w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:8])+`"`)
+			http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+		}
+	}))
+	t.Cleanup(tb.server.Close)
+	return tb
+}
+
+// set publishes a file, or removes it when data is nil
+func (tb *testBucket) set(name string, data []byte) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	if data == nil {
+		delete(tb.files, name)
+	} else {
+		tb.files[name] = data
+	}
+}
+
+// setStatus makes requests for name fail with status, or serve the file again
+// when status is 0
+func (tb *testBucket) setStatus(name string, status int) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.status[name] = status
+}
+
+// requestCount returns how many requests for name were received
+func (tb *testBucket) requestCount(name string) int {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.requests[name]
+}
+
+// publish writes the manifest and, when keys are given, its detached signature
+func (tb *testBucket) publish(t *testing.T, manifest *models.ManifestIndex, keys ...ed25519.PrivateKey) []byte {
+	t.Helper()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb.set("apkhub_manifest.json", data)
+
+	if len(keys) > 0 {
+		sigData, err := json.Marshal(signing.Sign(data, keys...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tb.set("apkhub_manifest.json"+models.ManifestSignatureSuffix, sigData)
+	}
+	return data
+}
+
+// testManifest returns a manifest with one package, published at updatedAt
+func testManifest(name string, updatedAt time.Time) *models.ManifestIndex {
+	return &models.ManifestIndex{
+		Version:   "1.0",
+		Name:      name,
+		UpdatedAt: updatedAt.UTC(),
+		TotalAPKs: 1,
+		Packages: map[string]*models.AppPackage{
+			"com.example.app": {
+				PackageID: "com.example.app",
+				Name:      map[string]string{"en": "Example"},
+				Versions: map[string]*models.AppVersion{
+					"1.0": {Version: "1.0", VersionCode: 1, DownloadURL: "apks/com.example.app_1.apk"},
+				},
+				Latest: "1.0",
+			},
+		},
+	}
+}
+
+// newTestBucketManager returns a bucket manager for a single bucket named
+// "test", with its cache and configuration file in a temporary directory
+func newTestBucketManager(t *testing.T, url string) (*BucketManager, *Config) {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	config := DefaultConfig()
+	config.Client.CacheDir = filepath.Join(dir, "cache")
+	config.Buckets = map[string]*Bucket{
+		"test": {Name: "test", URL: url, Enabled: true},
+	}
+	config.Security.VerifySignature = false
+
+	retry := DefaultRetryConfig()
+	retry.InitialDelay = time.Millisecond
+	retry.MaxDelay = time.Millisecond
+	return NewBucketManagerWithRetry(config, retry), config
+}
+
+// trustKey enables strict signature checking with pub as the only trusted key
+func trustKey(b *BucketManager, config *Config, pub ed25519.PublicKey) {
+	config.Security.VerifySignature = true
+	config.Security.SignaturePolicy = "strict"
+	config.Security.TrustedKeys = []string{signing.Fingerprint(pub)}
+	b.SetSignatureVerification(true)
+}
+
+func TestFetchManifestVerifiesSignature(t *testing.T) {
+	pub, priv, err := signing.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb := newTestBucket(t)
+	tb.publish(t, testManifest("signed", time.Now()), priv)
+
+	b, config := newTestBucketManager(t, tb.server.URL)
+	trustKey(b, config, pub)
+
+	manifest, err := b.FetchManifest("test")
+	if err != nil {
+		t.Fatalf("FetchManifest: %v", err)
+	}
+	if manifest.Name != "signed" {
+		t.Errorf("manifest name = %q, want signed", manifest.Name)
+	}
+}
+
+func TestFetchManifestRejectsBadSignatureUnderStrictPolicy(t *testing.T) {
+	pub, priv, err := signing.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPriv, err := signing.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		publish func(tb *testBucket)
+	}{
+		{"tampered manifest", func(tb *testBucket) {
+			data := tb.publish(t, testManifest("signed", time.Now()), priv)
+			tb.set("apkhub_manifest.json", bytes.Replace(data, []byte("signed"), []byte("forged"), 1))
+		}},
+		{"tampered signature", func(tb *testBucket) {
+			data := tb.publish(t, testManifest("signed", time.Now()))
+			sigFile := signing.Sign(data, priv)
+			sigFile.Signatures[0].Signature = signing.Sign([]byte("other"), priv).Signatures[0].Signature
+			sigData, err := json.Marshal(sigFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tb.set("apkhub_manifest.json"+models.ManifestSignatureSuffix, sigData)
+		}},
+		{"untrusted signer", func(tb *testBucket) {
+			tb.publish(t, testManifest("signed", time.Now()), otherPriv)
+		}},
+		{"unsigned", func(tb *testBucket) {
+			tb.publish(t, testManifest("signed", time.Now()))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := newTestBucket(t)
+			tt.publish(tb)
+
+			b, config := newTestBucketManager(t, tb.server.URL)
+			trustKey(b, config, pub)
+			if _, err := b.FetchManifest("test"); err == nil {
+				t.Fatal("strict policy accepted the manifest")
+			}
+
+			// The lenient policy only warns
+			config.Security.SignaturePolicy = "lenient"
+			if _, err := b.FetchManifest("test"); err != nil {
+				t.Errorf("lenient policy: %v", err)
+			}
+		})
+	}
+}
+
+// expireCachedManifest marks the cached manifest of the test bucket stale, so
+// the next fetch asks the bucket again
+func expireCachedManifest(t *testing.T, b *BucketManager) {
+	t.Helper()
+
+	cached, _, err := b.cacheManager.GetManifest("test", true)
+	if err != nil || cached == nil {
+		t.Fatalf("no cached manifest: %v", err)
+	}
+	if err := b.cacheManager.SetManifest("test", cached, time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFetchManifestRejectsRollback(t *testing.T) {
+	now := time.Now()
+	newer := testManifest("newer", now)
+	newer.Version = "1.1"
+
+	tests := []struct {
+		name  string
+		older *models.ManifestIndex
+	}{
+		{"earlier update", testManifest("older", now.Add(-time.Hour))},
+		{"lower version", func() *models.ManifestIndex {
+			m := testManifest("older", now)
+			m.Version = "1.0"
+			return m
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := newTestBucket(t)
+			tb.publish(t, newer)
+			b, _ := newTestBucketManager(t, tb.server.URL)
+			if _, err := b.FetchManifest("test"); err != nil {
+				t.Fatalf("FetchManifest: %v", err)
+			}
+
+			// The newer manifest already accepted keeps being served
+			tb.publish(t, tt.older)
+			expireCachedManifest(t, b)
+			manifest, err := b.FetchManifest("test")
+			if err != nil {
+				t.Fatalf("FetchManifest with a cached manifest: %v", err)
+			}
+			if manifest.Name != "newer" {
+				t.Errorf("served manifest %q, want the newer one", manifest.Name)
+			}
+
+			// Clearing the cache does not forget what was accepted
+			if err := b.cacheManager.Clear(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := b.FetchManifest("test"); !errors.Is(err, ErrManifestRollback) {
+				t.Errorf("FetchManifest without a cache: error %v, want %v", err, ErrManifestRollback)
+			}
+		})
+	}
+}
+
+func TestFetchManifestRejectsExpiredManifest(t *testing.T) {
+	tb := newTestBucket(t)
+	manifest := testManifest("expired", time.Now().Add(-2*time.Hour))
+	expires := time.Now().Add(-time.Hour).UTC()
+	manifest.Expires = &expires
+	tb.publish(t, manifest)
+
+	b, _ := newTestBucketManager(t, tb.server.URL)
+	if _, err := b.FetchManifest("test"); !errors.Is(err, ErrManifestExpired) {
+		t.Errorf("FetchManifest: error %v, want %v", err, ErrManifestExpired)
+	}
+
+	// A manifest re-signed with a later expiry is accepted
+	expires = time.Now().Add(time.Hour).UTC()
+	manifest.UpdatedAt = time.Now().UTC()
+	tb.publish(t, manifest)
+	if _, err := b.FetchManifest("test"); err != nil {
+		t.Errorf("FetchManifest after renewal: %v", err)
+	}
+}
+
+func TestFetchManifestRefusesUnreadableState(t *testing.T) {
+	tb := newTestBucket(t)
+	tb.publish(t, testManifest("test", time.Now()))
+	b, _ := newTestBucketManager(t, tb.server.URL)
+
+	if err := os.MkdirAll(b.cacheManager.cacheDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b.cacheManager.manifestStatePath(), []byte("{corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the state a rollback would go unnoticed
+	if _, err := b.FetchManifest("test"); err == nil {
+		t.Error("FetchManifest accepted a manifest without a readable state file")
+	}
+}
+
+func TestSetManifestStateConcurrently(t *testing.T) {
+	b, _ := newTestBucketManager(t, "http://127.0.0.1:0")
+
+	const buckets = 20
+	var wg sync.WaitGroup
+	for i := 0; i < buckets; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			state := &ManifestState{Version: "1.0", UpdatedAt: time.Now(), AcceptedAt: time.Now()}
+			if err := b.cacheManager.SetManifestState(fmt.Sprintf("bucket%d", i), state); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	states, err := b.GetManifestStates()
+	if err != nil {
+		t.Fatalf("GetManifestStates: %v", err)
+	}
+	if len(states) != buckets {
+		t.Errorf("%d bucket states recorded, want %d", len(states), buckets)
+	}
+}
+
+func TestUpdateManifestReusesUnchangedManifest(t *testing.T) {
+	tb := newTestBucket(t)
+	data := tb.publish(t, testManifest("first", time.Now().Add(-time.Hour)))
+	b, _ := newTestBucketManager(t, tb.server.URL)
+
+	if _, err := b.FetchManifest("test"); err != nil {
+		t.Fatalf("FetchManifest: %v", err)
+	}
+	if n := tb.requestCount("apkhub_manifest.json"); n != 1 {
+		t.Fatalf("%d manifest requests, want 1", n)
+	}
+
+	// A fresh cache is used without asking the bucket
+	if _, err := b.FetchManifest("test"); err != nil {
+		t.Fatalf("FetchManifest from cache: %v", err)
+	}
+	if n := tb.requestCount("apkhub_manifest.json"); n != 1 {
+		t.Errorf("%d manifest requests with a fresh cache, want 1", n)
+	}
+
+	// A forced update is answered with 304 and keeps the cached manifest
+	status, err := b.UpdateManifest("test")
+	if err != nil {
+		t.Fatalf("UpdateManifest: %v", err)
+	}
+	if status != ManifestUnchanged {
+		t.Errorf("status = %s, want %s", status, ManifestUnchanged)
+	}
+	health := b.GetBucketHealth("test")
+	if health.NotModified != 1 || health.BytesSaved != int64(len(data)) {
+		t.Errorf("health records %d unchanged responses saving %d bytes, want 1 saving %d",
+			health.NotModified, health.BytesSaved, len(data))
+	}
+
+	// A changed manifest is downloaded again
+	tb.publish(t, testManifest("second", time.Now()))
+	status, err = b.UpdateManifest("test")
+	if err != nil {
+		t.Fatalf("UpdateManifest after a change: %v", err)
+	}
+	if status != ManifestUpdated {
+		t.Errorf("status after a change = %s, want %s", status, ManifestUpdated)
+	}
+	manifest, err := b.FetchManifest("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Name != "second" {
+		t.Errorf("cached manifest %q, want second", manifest.Name)
+	}
+}
+
+func TestFetchManifestPrefersCompressedCopies(t *testing.T) {
+	pub, priv, err := signing.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		plain = "apkhub_manifest.json"
+		zst   = plain + ".zst"
+		gz    = plain + ".gz"
+	)
+
+	tests := []struct {
+		name    string
+		publish func(t *testing.T, tb *testBucket, data []byte)
+		variant string
+	}{
+		{"zstd", func(t *testing.T, tb *testBucket, data []byte) {
+			tb.set(zst, mustCompress(t, utils.CompressionZstd, data))
+			tb.set(gz, mustCompress(t, utils.CompressionGzip, data))
+		}, utils.CompressionZstd},
+		{"gzip only", func(t *testing.T, tb *testBucket, data []byte) {
+			tb.set(gz, mustCompress(t, utils.CompressionGzip, data))
+		}, utils.CompressionGzip},
+		{"zstd failing", func(t *testing.T, tb *testBucket, data []byte) {
+			tb.set(zst, mustCompress(t, utils.CompressionZstd, data))
+			tb.setStatus(zst, http.StatusInternalServerError)
+			tb.set(gz, mustCompress(t, utils.CompressionGzip, data))
+		}, utils.CompressionGzip},
+		{"compressed copies forbidden", func(t *testing.T, tb *testBucket, data []byte) {
+			tb.setStatus(zst, http.StatusForbidden)
+			tb.setStatus(gz, http.StatusBadGateway)
+		}, ""},
+		{"corrupt compressed copies", func(t *testing.T, tb *testBucket, data []byte) {
+			compressed := mustCompress(t, utils.CompressionZstd, data)
+			tb.set(zst, compressed[:len(compressed)/2])
+			tb.set(gz, append(mustCompress(t, utils.CompressionGzip, data)[:10], "garbage"...))
+		}, ""},
+		{"no compressed copies", func(t *testing.T, tb *testBucket, data []byte) {}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := newTestBucket(t)
+			// The signature covers the plain JSON whichever copy is fetched
+			data := tb.publish(t, testManifest("compressed", time.Now()), priv)
+			tt.publish(t, tb, data)
+
+			b, config := newTestBucketManager(t, tb.server.URL)
+			trustKey(b, config, pub)
+			manifest, err := b.FetchManifest("test")
+			if err != nil {
+				t.Fatalf("FetchManifest: %v", err)
+			}
+			if manifest.Name != "compressed" {
+				t.Errorf("manifest name = %q, want compressed", manifest.Name)
+			}
+
+			validators := b.cacheManager.GetManifestValidators("test")
+			if validators == nil || validators.Variant != tt.variant {
+				t.Errorf("cached validators %+v, want variant %q", validators, tt.variant)
+			}
+			if n := tb.requestCount(plain); tt.variant != "" && n != 0 {
+				t.Errorf("plain manifest requested %d times", n)
+			}
+		})
+	}
+}
+
+// mustCompress compresses data with format
+func mustCompress(t *testing.T, format string, data []byte) []byte {
+	t.Helper()
+
+	compressed, err := utils.Compress(format, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return compressed
+}
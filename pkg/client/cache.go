@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/huanfeng/apkhub/pkg/models"
@@ -27,8 +28,37 @@ type CacheEntry struct {
 	Size        int64       `json:"size"`
 	AccessCount int         `json:"access_count"`
 	LastAccess  time.Time   `json:"last_access"`
+
+	// Validators of the HTTP response the data came from, for conditional refreshes
+	Validators *ManifestValidators `json:"validators,omitempty"`
+}
+
+// ManifestValidators are the HTTP cache validators of a downloaded manifest
+type ManifestValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Size         int64  `json:"size"`              // Bytes of the manifest body, saved by every 304 response
+	Variant      string `json:"variant,omitempty"` // Compression of the fetched copy, "" for plain JSON
 }
 
+// ManifestState records the newest manifest accepted from a bucket. It is kept
+// next to the cache but survives cache clears, so an older manifest served later
+// (rollback) or a manifest past its expiry (freeze) can be rejected.
+type ManifestState struct {
+	Version    string     `json:"version"`
+	Generation int64      `json:"generation,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Expires    *time.Time `json:"expires,omitempty"`
+	AcceptedAt time.Time  `json:"accepted_at"`
+}
+
+// manifestStateFile holds the ManifestState of every bucket
+const manifestStateFile = "manifest_state.json"
+
+// manifestStateMu serializes access to the manifest state file, which buckets
+// updating in parallel read and rewrite
+var manifestStateMu sync.Mutex
+
 // CacheStats contains cache statistics
 type CacheStats struct {
 	TotalEntries   int           `json:"total_entries"`
@@ -76,10 +106,9 @@ func (c *CacheManager) Get(key string, target interface{}) (bool, error) {
 		return false, err
 	}
 
-	// Check if expired
+	// Check if expired. The entry is kept as a stale fallback and for conditional
+	// refreshes; CleanExpired removes it.
 	if time.Now().After(entry.ExpiresAt) {
-		// Remove expired entry
-		os.Remove(cachePath)
 		return false, nil
 	}
 
@@ -289,8 +318,163 @@ func (c *CacheManager) GetManifest(bucketName string, allowStale bool) (*models.
 
 // SetManifest stores a manifest in cache
 func (c *CacheManager) SetManifest(bucketName string, manifest *models.ManifestIndex, ttl time.Duration) error {
+	return c.SetManifestWithValidators(bucketName, manifest, ttl, nil)
+}
+
+// SetManifestWithValidators stores a manifest in cache together with the HTTP
+// validators used to revalidate it later
+func (c *CacheManager) SetManifestWithValidators(bucketName string, manifest *models.ManifestIndex, ttl time.Duration, validators *ManifestValidators) error {
+	if ttl <= 0 {
+		ttl = c.defaultTTL
+	}
+
 	key := fmt.Sprintf("manifest_%s", bucketName)
-	return c.Set(key, manifest, ttl)
+	entry := &CacheEntry{
+		Key:        key,
+		Data:       manifest,
+		CreatedAt:  time.Now(),
+		ExpiresAt:  time.Now().Add(ttl),
+		LastAccess: time.Now(),
+		Validators: validators,
+	}
+
+	return c.saveCacheEntry(c.getCachePath(key), entry)
+}
+
+// GetManifestValidators returns the HTTP validators of a cached manifest, fresh or
+// stale, or nil if none were stored
+func (c *CacheManager) GetManifestValidators(bucketName string) *ManifestValidators {
+	entry, err := c.loadCacheEntry(c.getCachePath(fmt.Sprintf("manifest_%s", bucketName)))
+	if err != nil {
+		return nil
+	}
+	return entry.Validators
+}
+
+// RefreshManifest extends the lifetime of a cached manifest that the bucket
+// reported as unchanged
+func (c *CacheManager) RefreshManifest(bucketName string, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = c.defaultTTL
+	}
+
+	cachePath := c.getCachePath(fmt.Sprintf("manifest_%s", bucketName))
+	entry, err := c.loadCacheEntry(cachePath)
+	if err != nil {
+		return err
+	}
+
+	entry.ExpiresAt = time.Now().Add(ttl)
+	return c.saveCacheEntry(cachePath, entry)
+}
+
+// GetManifestStates returns the accepted manifest state of every bucket
+func (c *CacheManager) GetManifestStates() (map[string]*ManifestState, error) {
+	manifestStateMu.Lock()
+	defer manifestStateMu.Unlock()
+
+	return c.loadManifestStates()
+}
+
+// loadManifestStates reads the manifest state file; the caller holds manifestStateMu
+func (c *CacheManager) loadManifestStates() (map[string]*ManifestState, error) {
+	states := make(map[string]*ManifestState)
+
+	data, err := os.ReadFile(c.manifestStatePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return states, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest state: %w", err)
+	}
+
+	return states, nil
+}
+
+// GetManifestState returns the accepted manifest state of a bucket, or nil if none is recorded
+func (c *CacheManager) GetManifestState(bucketName string) (*ManifestState, error) {
+	states, err := c.GetManifestStates()
+	if err != nil {
+		return nil, err
+	}
+	return states[bucketName], nil
+}
+
+// SetManifestState records the accepted manifest state of a bucket
+func (c *CacheManager) SetManifestState(bucketName string, state *ManifestState) error {
+	manifestStateMu.Lock()
+	defer manifestStateMu.Unlock()
+
+	states, err := c.loadManifestStates()
+	if err != nil {
+		return err
+	}
+
+	states[bucketName] = state
+	return c.saveManifestStates(states)
+}
+
+// DeleteManifestState forgets the accepted manifest state of a bucket
+func (c *CacheManager) DeleteManifestState(bucketName string) error {
+	manifestStateMu.Lock()
+	defer manifestStateMu.Unlock()
+
+	states, err := c.loadManifestStates()
+	if err != nil {
+		return err
+	}
+
+	if _, exists := states[bucketName]; !exists {
+		return nil
+	}
+
+	delete(states, bucketName)
+	return c.saveManifestStates(states)
+}
+
+// saveManifestStates replaces the manifest state file through a temporary file,
+// so a crash never leaves it torn; the caller holds manifestStateMu
+func (c *CacheManager) saveManifestStates(states map[string]*ManifestState) error {
+	if err := os.MkdirAll(c.cacheDir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(c.cacheDir, manifestStateFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.manifestStatePath())
+}
+
+// manifestStatePath returns the path of the manifest state file
+func (c *CacheManager) manifestStatePath() string {
+	return filepath.Join(c.cacheDir, manifestStateFile)
 }
 
 // getCachePath generates cache file path for a key
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -16,6 +17,9 @@ type Config struct {
 	Client        ClientSettings     `yaml:"client"`
 	ADB           ADBSettings        `yaml:"adb"`
 	Security      SecuritySettings   `yaml:"security"`
+
+	// Pins maps package IDs to the only bucket they may be taken from
+	Pins map[string]string `yaml:"pins,omitempty"`
 }
 
 // Bucket represents a repository source
@@ -23,14 +27,17 @@ type Bucket struct {
 	Name        string    `yaml:"name"`
 	URL         string    `yaml:"url"`
 	Enabled     bool      `yaml:"enabled"`
+	Priority    int       `yaml:"priority,omitempty"` // Higher priority buckets are preferred
 	LastUpdated time.Time `yaml:"last_updated,omitempty"`
 }
 
 // ClientSettings contains client-specific settings
 type ClientSettings struct {
-	DownloadDir string `yaml:"download_dir"`
-	CacheDir    string `yaml:"cache_dir"`
-	CacheTTL    int    `yaml:"cache_ttl"` // seconds
+	DownloadDir      string `yaml:"download_dir"`
+	CacheDir         string `yaml:"cache_dir"`
+	CacheTTL         int    `yaml:"cache_ttl"`         // seconds
+	DownloadSegments int    `yaml:"download_segments"` // concurrent connections per download, 1 = disabled
+	SegmentMinSize   int64  `yaml:"segment_min_size"`  // bytes; smaller files use a single connection
 }
 
 // SecuritySettings controls how manifests and downloads are validated
@@ -42,8 +49,9 @@ type SecuritySettings struct {
 
 // ADBSettings contains ADB configuration
 type ADBSettings struct {
-	Path          string `yaml:"path"`
+	Path          string `yaml:"path"` // adb binary, used to start the adb server
 	DefaultDevice string `yaml:"default_device"`
+	ServerAddress string `yaml:"server_address"` // adb server host:port, empty for 127.0.0.1:5037
 }
 
 // DefaultConfig returns a default configuration
@@ -55,9 +63,11 @@ func DefaultConfig() *Config {
 		DefaultBucket: "main",
 		Buckets:       make(map[string]*Bucket),
 		Client: ClientSettings{
-			DownloadDir: filepath.Join(apkhubDir, "downloads"),
-			CacheDir:    filepath.Join(apkhubDir, "cache"),
-			CacheTTL:    3600, // 1 hour
+			DownloadDir:      filepath.Join(apkhubDir, "downloads"),
+			CacheDir:         filepath.Join(apkhubDir, "cache"),
+			CacheTTL:         3600, // 1 hour
+			DownloadSegments: 4,
+			SegmentMinSize:   16 * 1024 * 1024,
 		},
 		Security: SecuritySettings{
 			VerifySignature: true,
@@ -67,6 +77,7 @@ func DefaultConfig() *Config {
 		ADB: ADBSettings{
 			Path:          "adb",
 			DefaultDevice: "",
+			ServerAddress: "",
 		},
 	}
 }
@@ -199,6 +210,79 @@ func (c *Config) RemoveBucket(name string) error {
 	return c.Save()
 }
 
+// CompareBucketPreference returns a positive value when bucket a is preferred over b,
+// that is when it has the higher priority
+func (c *Config) CompareBucketPreference(a, b string) int {
+	var pa, pb int
+	if bucket, exists := c.Buckets[a]; exists {
+		pa = bucket.Priority
+	}
+	if bucket, exists := c.Buckets[b]; exists {
+		pb = bucket.Priority
+	}
+	return pa - pb
+}
+
+// OrderedEnabledBuckets returns the enabled bucket names in priority order,
+// falling back to alphabetical order for buckets of equal priority
+func (c *Config) OrderedEnabledBuckets() []string {
+	names := make([]string, 0, len(c.Buckets))
+	for name := range c.GetEnabledBuckets() {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if cmp := c.CompareBucketPreference(names[i], names[j]); cmp != 0 {
+			return cmp > 0
+		}
+		return names[i] < names[j]
+	})
+
+	return names
+}
+
+// SetBucketPriority sets a bucket's priority (higher is preferred)
+func (c *Config) SetBucketPriority(name string, priority int) error {
+	bucket, exists := c.Buckets[name]
+	if !exists {
+		return fmt.Errorf("bucket %s not found", name)
+	}
+
+	bucket.Priority = priority
+
+	return c.Save()
+}
+
+// PinnedBucket returns the bucket a package is pinned to, or "" if it is not pinned
+func (c *Config) PinnedBucket(packageID string) string {
+	return c.Pins[packageID]
+}
+
+// PinPackage pins a package to a bucket
+func (c *Config) PinPackage(packageID, bucketName string) error {
+	if _, exists := c.Buckets[bucketName]; !exists {
+		return fmt.Errorf("bucket %s not found", bucketName)
+	}
+
+	if c.Pins == nil {
+		c.Pins = make(map[string]string)
+	}
+	c.Pins[packageID] = bucketName
+
+	return c.Save()
+}
+
+// UnpinPackage removes a package pin
+func (c *Config) UnpinPackage(packageID string) error {
+	if _, exists := c.Pins[packageID]; !exists {
+		return fmt.Errorf("package %s is not pinned", packageID)
+	}
+
+	delete(c.Pins, packageID)
+
+	return c.Save()
+}
+
 // GetEnabledBuckets returns all enabled buckets
 func (c *Config) GetEnabledBuckets() map[string]*Bucket {
 	enabled := make(map[string]*Bucket)
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+// maxDeltaChain bounds how many generations a cached manifest may be behind to be
+// updated with deltas
+const maxDeltaChain = 20
+
+// errDeltaUnavailable means the cached manifest cannot be brought up to date with
+// the published deltas, and the full manifest has to be downloaded
+var errDeltaUnavailable = errors.New("no usable delta chain")
+
+// fetchManifestDeltas brings a cached manifest up to date by applying the deltas the
+// bucket publishes. It returns the bytes of the resulting manifest, which are
+// identical to the published apkhub_manifest.json, or nil if the cached manifest is
+// already current. applied is the number of deltas used and size the bytes read.
+func (b *BucketManager) fetchManifestDeltas(bucket *Bucket, bucketName string, cached *models.ManifestIndex) (data []byte, applied int, size int64, err error) {
+	indexData, err := b.readBucketFile(bucket, bucketName, cached.Deltas)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("%w: %v", errDeltaUnavailable, err)
+	}
+	size = int64(len(indexData))
+
+	var index models.ManifestDeltaIndex
+	if err := json.Unmarshal(indexData, &index); err != nil {
+		return nil, 0, size, fmt.Errorf("failed to parse delta index: %w", err)
+	}
+
+	if index.Generation == cached.Generation {
+		return nil, 0, size, nil
+	}
+
+	chain, err := resolveDeltaChain(&index, cached.Generation)
+	if err != nil {
+		return nil, 0, size, err
+	}
+
+	current, err := json.MarshalIndent(cached, "", "  ")
+	if err != nil {
+		return nil, 0, size, err
+	}
+	manifest := cached
+
+	for _, ref := range chain {
+		deltaData, err := b.readBucketFile(bucket, bucketName, ref.Path)
+		if err != nil {
+			return nil, 0, size, fmt.Errorf("failed to fetch delta %d: %w", ref.ToGeneration, err)
+		}
+		size += int64(len(deltaData))
+
+		if sha256Hex(deltaData) != ref.SHA256 {
+			return nil, 0, size, fmt.Errorf("delta %d does not match its checksum", ref.ToGeneration)
+		}
+
+		var delta models.ManifestDelta
+		if err := json.Unmarshal(deltaData, &delta); err != nil {
+			return nil, 0, size, fmt.Errorf("failed to parse delta %d: %w", ref.ToGeneration, err)
+		}
+
+		if delta.BaseSHA256 != sha256Hex(current) {
+			return nil, 0, size, fmt.Errorf("delta %d does not apply to the cached manifest", ref.ToGeneration)
+		}
+
+		if manifest, err = applyManifestDelta(manifest, &delta); err != nil {
+			return nil, 0, size, err
+		}
+
+		if current, err = json.MarshalIndent(manifest, "", "  "); err != nil {
+			return nil, 0, size, err
+		}
+		if sha256Hex(current) != delta.ResultSHA256 {
+			return nil, 0, size, fmt.Errorf("manifest after delta %d does not match the published one", ref.ToGeneration)
+		}
+	}
+
+	return current, len(chain), size, nil
+}
+
+// resolveDeltaChain returns the deltas leading from generation to the index
+// generation, or errDeltaUnavailable when they are not all published or would
+// cost more than the full manifest
+func resolveDeltaChain(index *models.ManifestDeltaIndex, generation int64) ([]models.ManifestDeltaRef, error) {
+	if index.Generation < generation {
+		return nil, fmt.Errorf("delta index generation %d is older than the cached manifest (%d)", index.Generation, generation)
+	}
+
+	byBase := make(map[int64]models.ManifestDeltaRef, len(index.Deltas))
+	for _, ref := range index.Deltas {
+		byBase[ref.FromGeneration] = ref
+	}
+
+	var chain []models.ManifestDeltaRef
+	var total int64
+	for generation < index.Generation {
+		ref, exists := byBase[generation]
+		if !exists || ref.ToGeneration <= generation || len(chain) >= maxDeltaChain {
+			return nil, errDeltaUnavailable
+		}
+		chain = append(chain, ref)
+		total += ref.Size
+		generation = ref.ToGeneration
+	}
+
+	if index.ManifestSize > 0 && total >= index.ManifestSize {
+		return nil, errDeltaUnavailable
+	}
+
+	return chain, nil
+}
+
+// applyManifestDelta returns the manifest produced by applying delta to base
+func applyManifestDelta(base *models.ManifestIndex, delta *models.ManifestDelta) (*models.ManifestIndex, error) {
+	if delta.Header == nil {
+		return nil, fmt.Errorf("delta %d has no manifest header", delta.ToGeneration)
+	}
+
+	result := *delta.Header
+	result.Packages = make(map[string]*models.AppPackage, len(base.Packages))
+	for packageID, pkg := range base.Packages {
+		result.Packages[packageID] = pkg
+	}
+
+	for _, packageID := range delta.Removed {
+		delete(result.Packages, packageID)
+	}
+	for packageID, pkg := range delta.Added {
+		result.Packages[packageID] = pkg
+	}
+	for packageID, pkg := range delta.Changed {
+		result.Packages[packageID] = pkg
+	}
+
+	return &result, nil
+}
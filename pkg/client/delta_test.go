@@ -0,0 +1,265 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+// testDeltaManifests returns two consecutive generations of a manifest with
+// enough packages for a delta to be worth fetching
+func testDeltaManifests() (base, next *models.ManifestIndex) {
+	now := time.Now()
+	base = testManifest("deltas", now.Add(-time.Hour))
+	base.Generation = 1
+	base.Deltas = "deltas/index.json"
+	for i := 0; i < 50; i++ {
+		packageID := fmt.Sprintf("com.example.app%d", i)
+		base.Packages[packageID] = &models.AppPackage{
+			PackageID: packageID,
+			Name:      map[string]string{"en": fmt.Sprintf("Example %d", i)},
+			Versions: map[string]*models.AppVersion{
+				"1.0": {Version: "1.0", VersionCode: 1, DownloadURL: "apks/" + packageID + "_1.apk"},
+			},
+			Latest: "1.0",
+		}
+	}
+
+	next = testManifest("deltas", now)
+	next.Generation = 2
+	next.Deltas = base.Deltas
+	for packageID, pkg := range base.Packages {
+		next.Packages[packageID] = pkg
+	}
+	delete(next.Packages, "com.example.app0")
+	next.Packages["com.example.app1"] = &models.AppPackage{
+		PackageID: "com.example.app1",
+		Name:      map[string]string{"en": "Example 1"},
+		Versions: map[string]*models.AppVersion{
+			"2.0": {Version: "2.0", VersionCode: 2, DownloadURL: "apks/com.example.app1_2.apk"},
+		},
+		Latest: "2.0",
+	}
+	next.Packages["com.example.new"] = &models.AppPackage{
+		PackageID: "com.example.new",
+		Name:      map[string]string{"en": "New"},
+		Versions: map[string]*models.AppVersion{
+			"1.0": {Version: "1.0", VersionCode: 1, DownloadURL: "apks/com.example.new_1.apk"},
+		},
+		Latest: "1.0",
+	}
+
+	return base, next
+}
+
+// writeTestManifest writes a manifest to a local bucket and returns its bytes
+func writeTestManifest(t *testing.T, dir string, manifest *models.ManifestIndex) []byte {
+	t.Helper()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "apkhub_manifest.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// publishTestDelta publishes next in a local bucket together with the delta from
+// base, which corrupt may modify before it is written
+func publishTestDelta(t *testing.T, dir string, base, next *models.ManifestIndex, corrupt func(*models.ManifestDelta)) {
+	t.Helper()
+
+	baseData, err := json.MarshalIndent(base, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextData := writeTestManifest(t, dir, next)
+
+	header := *next
+	header.Packages = nil
+	delta := &models.ManifestDelta{
+		FromGeneration: base.Generation,
+		ToGeneration:   next.Generation,
+		BaseSHA256:     sha256Hex(baseData),
+		ResultSHA256:   sha256Hex(nextData),
+		Header:         &header,
+		Added:          map[string]*models.AppPackage{"com.example.new": next.Packages["com.example.new"]},
+		Changed:        map[string]*models.AppPackage{"com.example.app1": next.Packages["com.example.app1"]},
+		Removed:        []string{"com.example.app0"},
+	}
+	if corrupt != nil {
+		corrupt(delta)
+	}
+
+	deltaData, err := json.Marshal(delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deltaPath := "deltas/2.json"
+	index := &models.ManifestDeltaIndex{
+		Generation:   next.Generation,
+		ManifestSize: int64(len(nextData)),
+		Deltas: []models.ManifestDeltaRef{{
+			FromGeneration: delta.FromGeneration,
+			ToGeneration:   delta.ToGeneration,
+			Path:           deltaPath,
+			Size:           int64(len(deltaData)),
+			SHA256:         sha256Hex(deltaData),
+		}},
+	}
+	indexData, err := json.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "deltas"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(deltaPath)), deltaData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(base.Deltas)), indexData, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFetchManifestAppliesDeltas(t *testing.T) {
+	dir := t.TempDir()
+	base, next := testDeltaManifests()
+	writeTestManifest(t, dir, base)
+
+	b, _ := newTestBucketManager(t, "file://"+dir)
+	if _, err := b.FetchManifest("test"); err != nil {
+		t.Fatalf("FetchManifest: %v", err)
+	}
+
+	publishTestDelta(t, dir, base, next, nil)
+	expireCachedManifest(t, b)
+	manifest, err := b.FetchManifest("test")
+	if err != nil {
+		t.Fatalf("FetchManifest with deltas: %v", err)
+	}
+	if b.GetBucketHealth("test").DeltaUpdates != 1 {
+		t.Error("manifest was not updated with the delta")
+	}
+
+	got, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join(dir, "apkhub_manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Error("manifest built from the delta differs from the published one")
+	}
+}
+
+func TestFetchManifestDeltasRejectsMismatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(*models.ManifestDelta)
+	}{
+		{"base hash", func(delta *models.ManifestDelta) {
+			delta.BaseSHA256 = sha256Hex([]byte("another manifest"))
+		}},
+		{"result hash", func(delta *models.ManifestDelta) {
+			delta.ResultSHA256 = sha256Hex([]byte("another manifest"))
+		}},
+		{"changes differing from the result", func(delta *models.ManifestDelta) {
+			delta.Removed = nil
+		}},
+		{"missing header", func(delta *models.ManifestDelta) {
+			delta.Header = nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			base, next := testDeltaManifests()
+			writeTestManifest(t, dir, base)
+
+			b, config := newTestBucketManager(t, "file://"+dir)
+			cached, err := b.FetchManifest("test")
+			if err != nil {
+				t.Fatalf("FetchManifest: %v", err)
+			}
+
+			publishTestDelta(t, dir, base, next, tt.corrupt)
+			if _, _, _, err := b.fetchManifestDeltas(config.Buckets["test"], "test", cached); err == nil {
+				t.Fatal("fetchManifestDeltas accepted the delta")
+			}
+
+			// The full manifest is downloaded instead
+			expireCachedManifest(t, b)
+			manifest, err := b.FetchManifest("test")
+			if err != nil {
+				t.Fatalf("FetchManifest: %v", err)
+			}
+			if manifest.Generation != next.Generation || len(manifest.Packages) != len(next.Packages) {
+				t.Errorf("fetched generation %d with %d packages, want generation %d with %d",
+					manifest.Generation, len(manifest.Packages), next.Generation, len(next.Packages))
+			}
+			if b.GetBucketHealth("test").DeltaUpdates != 0 {
+				t.Error("the rejected delta was counted as applied")
+			}
+		})
+	}
+}
+
+func TestFetchManifestDeltasRejectsTamperedDeltaFile(t *testing.T) {
+	dir := t.TempDir()
+	base, next := testDeltaManifests()
+	writeTestManifest(t, dir, base)
+
+	b, config := newTestBucketManager(t, "file://"+dir)
+	cached, err := b.FetchManifest("test")
+	if err != nil {
+		t.Fatalf("FetchManifest: %v", err)
+	}
+
+	publishTestDelta(t, dir, base, next, nil)
+	deltaPath := filepath.Join(dir, "deltas", "2.json")
+	data, err := os.ReadFile(deltaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(deltaPath, append(data, ' '), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := b.fetchManifestDeltas(config.Buckets["test"], "test", cached); err == nil {
+		t.Error("fetchManifestDeltas accepted a delta not matching its checksum")
+	}
+}
+
+func TestFetchManifestRejectsLowerGeneration(t *testing.T) {
+	dir := t.TempDir()
+	base, next := testDeltaManifests()
+	writeTestManifest(t, dir, next)
+
+	b, _ := newTestBucketManager(t, "file://"+dir)
+	if _, err := b.FetchManifest("test"); err != nil {
+		t.Fatalf("FetchManifest: %v", err)
+	}
+
+	// A replayed older generation is refused even with a current timestamp
+	base.UpdatedAt = time.Now().UTC()
+	writeTestManifest(t, dir, base)
+	if err := b.cacheManager.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.FetchManifest("test"); !errors.Is(err, ErrManifestRollback) {
+		t.Errorf("FetchManifest: error %v, want %v", err, ErrManifestRollback)
+	}
+}
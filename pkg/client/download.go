@@ -4,6 +4,8 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -50,6 +52,7 @@ type ProgressWriter struct {
 	writer       io.Writer
 	total        int64
 	written      int64
+	offset       int64 // Bytes already present before this session (resumed downloads)
 	lastUpdate   time.Time
 	startTime    time.Time
 	showProgress bool
@@ -57,9 +60,16 @@ type ProgressWriter struct {
 
 // NewProgressWriter creates a new progress writer
 func NewProgressWriter(writer io.Writer, total int64, showProgress bool) *ProgressWriter {
+	return NewProgressWriterAt(writer, total, 0, showProgress)
+}
+
+// NewProgressWriterAt creates a progress writer that continues from offset bytes
+func NewProgressWriterAt(writer io.Writer, total, offset int64, showProgress bool) *ProgressWriter {
 	return &ProgressWriter{
 		writer:       writer,
 		total:        total,
+		written:      offset,
+		offset:       offset,
 		startTime:    time.Now(),
 		lastUpdate:   time.Now(),
 		showProgress: showProgress,
@@ -93,8 +103,8 @@ func (pw *ProgressWriter) updateProgress() {
 	percentage := float64(pw.written) / float64(pw.total) * 100
 	elapsed := time.Since(pw.startTime)
 
-	// Calculate speed and ETA
-	speed := float64(pw.written) / elapsed.Seconds()
+	// Calculate speed and ETA from the bytes transferred in this session
+	speed := float64(pw.written-pw.offset) / elapsed.Seconds()
 	remaining := pw.total - pw.written
 	eta := time.Duration(float64(remaining)/speed) * time.Second
 
@@ -117,7 +127,7 @@ func (pw *ProgressWriter) Finish() {
 	if pw.showProgress {
 		elapsed := time.Since(pw.startTime)
 		totalMB := float64(pw.written) / (1024 * 1024)
-		avgSpeed := float64(pw.written) / elapsed.Seconds() / (1024 * 1024)
+		avgSpeed := float64(pw.written-pw.offset) / elapsed.Seconds() / (1024 * 1024)
 
 		fmt.Printf("\r✅ Download completed: %.1f MB in %v (avg: %.1f MB/s)\n",
 			totalMB, elapsed.Round(time.Second), avgSpeed)
@@ -126,15 +136,15 @@ func (pw *ProgressWriter) Finish() {
 
 // Download downloads an APK by package ID
 func (d *DownloadManager) Download(packageID string, options DownloadOptions) (string, error) {
-	// Get merged manifest
-	manifest, err := d.bucketMgr.GetMergedManifest()
+	// Find package, with all versions of sharded buckets
+	pkg, err := d.bucketMgr.GetMergedPackage(packageID)
 	if err != nil {
 		return "", fmt.Errorf("failed to get manifest: %w", err)
 	}
-
-	// Find package
-	pkg, exists := manifest.Packages[packageID]
-	if !exists {
+	if pkg == nil {
+		if pinned := d.config.PinnedBucket(packageID); pinned != "" {
+			return "", fmt.Errorf("package '%s' is pinned to bucket '%s', which does not provide it", packageID, pinned)
+		}
 		return "", fmt.Errorf("package '%s' not found", packageID)
 	}
 
@@ -142,11 +152,14 @@ func (d *DownloadManager) Download(packageID string, options DownloadOptions) (s
 	var version *models.AppVersion
 
 	if options.Version != "" {
-		// Find specific version
-		for _, ver := range pkg.Versions {
-			if ver.Version == options.Version || ver.VersionCode == parseVersionCode(options.Version) {
-				version = ver
-				break
+		// Find specific version, preferring the same bucket as the merged latest would
+		var versionKey string
+		for key, ver := range pkg.Versions {
+			if ver.Version != options.Version && ver.VersionCode != parseVersionCode(options.Version) {
+				continue
+			}
+			if version == nil || compareMergedVersions(d.config, key, ver, versionKey, version) > 0 {
+				versionKey, version = key, ver
 			}
 		}
 		if version == nil {
@@ -160,6 +173,10 @@ func (d *DownloadManager) Download(packageID string, options DownloadOptions) (s
 		version = pkg.Versions[pkg.Latest]
 	}
 
+	if pinned := d.config.PinnedBucket(packageID); pinned != "" {
+		fmt.Printf("📌 %s is pinned to bucket '%s'\n", packageID, pinned)
+	}
+
 	// Construct filename
 	filename := fmt.Sprintf("%s_%d.apk", packageID, version.VersionCode)
 	targetPath := filepath.Join(d.config.Client.DownloadDir, filename)
@@ -232,6 +249,9 @@ func (d *DownloadManager) downloadFileWithRetry(url, targetPath string, expected
 		timeout = 30 * time.Minute
 	}
 
+	// Large files are fetched over several connections when the server supports ranges
+	segments := d.segmentCount(expectedSize)
+
 	var lastErr error
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
@@ -246,7 +266,16 @@ func (d *DownloadManager) downloadFileWithRetry(url, targetPath string, expected
 			time.Sleep(delay)
 		}
 
-		err := d.downloadFile(url, targetPath, expectedSize, timeout, options.ShowProgress)
+		var err error
+		if segments > 1 {
+			err = d.downloadSegmented(url, targetPath, expectedSize, segments, timeout, options.ShowProgress)
+			if errors.Is(err, errRangesUnsupported) {
+				segments = 1
+			}
+		}
+		if segments <= 1 {
+			err = d.downloadFile(url, targetPath, expectedSize, timeout, options.ShowProgress)
+		}
 		if err == nil {
 			return nil
 		}
@@ -254,8 +283,7 @@ func (d *DownloadManager) downloadFileWithRetry(url, targetPath string, expected
 		lastErr = err
 		fmt.Printf("❌ Download attempt %d failed: %v\n", attempt+1, err)
 
-		// Clean up partial file
-		os.Remove(targetPath + ".tmp")
+		// The partial file is kept so the next attempt can resume from it
 	}
 
 	return fmt.Errorf("download failed after %d attempts: %w", maxRetries+1, lastErr)
@@ -321,7 +349,73 @@ func (d *DownloadManager) copyLocalFile(fileURL, targetPath string, expectedSize
 	return nil
 }
 
-// downloadFile downloads a file with progress reporting
+// partialDownload records how a .part file was fetched so it can be resumed safely
+type partialDownload struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// validator returns the If-Range value for the partial file, preferring a strong ETag
+func (p *partialDownload) validator() string {
+	if p.ETag != "" && !strings.HasPrefix(p.ETag, "W/") {
+		return p.ETag
+	}
+	return p.LastModified
+}
+
+// loadPartialDownload returns the resume offset and metadata of a partial download,
+// or zero when the partial file cannot be resumed for url
+func loadPartialDownload(partPath, url string) (int64, *partialDownload) {
+	info, err := os.Stat(partPath)
+	if err != nil || info.Size() == 0 {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(partPath + ".meta")
+	if err != nil {
+		return 0, nil
+	}
+
+	var meta partialDownload
+	if err := json.Unmarshal(data, &meta); err != nil || meta.URL != url || meta.validator() == "" {
+		return 0, nil
+	}
+
+	return info.Size(), &meta
+}
+
+// savePartialDownload stores the validators of a response for later resumption
+func savePartialDownload(partPath, url string, resp *http.Response) error {
+	data, err := json.Marshal(partialDownload{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(partPath+".meta", data, 0644)
+}
+
+// removePartialDownload deletes a partial file and its metadata
+func removePartialDownload(partPath string) {
+	os.Remove(partPath)
+	os.Remove(partPath + ".meta")
+}
+
+// parseContentRangeStart returns the first byte position of a Content-Range header
+func parseContentRangeStart(header string) (int64, bool) {
+	var start, end int64
+	if _, err := fmt.Sscanf(header, "bytes %d-%d/", &start, &end); err != nil {
+		return 0, false
+	}
+	return start, true
+}
+
+// downloadFile downloads a file with progress reporting. Data is written to a .part
+// file that is resumed with a Range request on the next attempt when the server
+// supports it and the file has not changed since (If-Range).
 func (d *DownloadManager) downloadFile(url, targetPath string, expectedSize int64, timeout time.Duration, showProgress bool) error {
 	// Ensure download directory exists
 	downloadDir := filepath.Dir(targetPath)
@@ -329,13 +423,8 @@ func (d *DownloadManager) downloadFile(url, targetPath string, expectedSize int6
 		return fmt.Errorf("failed to create download directory: %w", err)
 	}
 
-	// Create temp file
-	tempPath := targetPath + ".tmp"
-	out, err := os.Create(tempPath)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
+	partPath := targetPath + ".part"
+	offset, meta := loadPartialDownload(partPath, url)
 
 	// Create request with context for timeout
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
@@ -343,38 +432,73 @@ func (d *DownloadManager) downloadFile(url, targetPath string, expectedSize int6
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		os.Remove(tempPath)
 		return err
 	}
 
 	req.Header.Set("User-Agent", "ApkHub-CLI/1.0")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", meta.validator())
+	}
 
 	// Download
 	resp, err := d.client.Do(req)
 	if err != nil {
-		os.Remove(tempPath)
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		os.Remove(tempPath)
+	var out *os.File
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, ok := parseContentRangeStart(resp.Header.Get("Content-Range"))
+		if offset == 0 || !ok || start != offset {
+			removePartialDownload(partPath)
+			return fmt.Errorf("unexpected content range %q for offset %d", resp.Header.Get("Content-Range"), offset)
+		}
+		if showProgress {
+			fmt.Printf("⏩ Resuming download at %.2f MB\n", float64(offset)/(1024*1024))
+		}
+		out, err = os.OpenFile(partPath, os.O_WRONLY|os.O_APPEND, 0644)
+	case http.StatusOK:
+		// Server ignored the range or the file changed: start over
+		if offset > 0 && showProgress {
+			fmt.Println("⚠️  Cannot resume (server ignored the range or the file changed), restarting download")
+		}
+		offset = 0
+		if err := savePartialDownload(partPath, url, resp); err != nil {
+			return fmt.Errorf("failed to save download state: %w", err)
+		}
+		out, err = os.Create(partPath)
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is already complete or no longer matches the remote file
+		if offset > 0 && offset == expectedSize {
+			return finishPartialDownload(partPath, targetPath)
+		}
+		removePartialDownload(partPath)
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	default:
 		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
 	}
+	if err != nil {
+		return err
+	}
+	defer out.Close()
 
 	// Get content length
 	contentLength := resp.ContentLength
-	if contentLength <= 0 && expectedSize > 0 {
+	if contentLength > 0 {
+		contentLength += offset
+	} else if expectedSize > 0 {
 		contentLength = expectedSize
 	}
 
 	// Create progress writer
-	progressWriter := NewProgressWriter(out, contentLength, showProgress)
+	progressWriter := NewProgressWriterAt(out, contentLength, offset, showProgress)
 
-	// Copy with progress
+	// Copy with progress; on failure the partial file is kept for the next attempt
 	written, err := io.Copy(progressWriter, resp.Body)
 	if err != nil {
-		os.Remove(tempPath)
 		return err
 	}
 
@@ -385,17 +509,21 @@ func (d *DownloadManager) downloadFile(url, targetPath string, expectedSize int6
 	out.Close()
 
 	// Verify size if expected
-	if expectedSize > 0 && written != expectedSize {
-		os.Remove(tempPath)
-		return fmt.Errorf("size mismatch: expected %d bytes, got %d bytes", expectedSize, written)
+	if total := offset + written; expectedSize > 0 && total != expectedSize {
+		removePartialDownload(partPath)
+		return fmt.Errorf("size mismatch: expected %d bytes, got %d bytes", expectedSize, total)
 	}
 
-	// Rename temp to final
-	if err := os.Rename(tempPath, targetPath); err != nil {
-		os.Remove(tempPath)
+	return finishPartialDownload(partPath, targetPath)
+}
+
+// finishPartialDownload moves a completed .part file to its final path
+func finishPartialDownload(partPath, targetPath string) error {
+	if err := os.Rename(partPath, targetPath); err != nil {
+		removePartialDownload(partPath)
 		return err
 	}
-
+	os.Remove(partPath + ".meta")
 	return nil
 }
 
@@ -425,23 +553,25 @@ func parseVersionCode(s string) int64 {
 
 // GetPackageInfo retrieves detailed package information
 func (d *DownloadManager) GetPackageInfo(packageID string) (*models.AppPackage, error) {
-	// Get merged manifest
-	manifest, err := d.bucketMgr.GetMergedManifest()
+	// Find package, with all versions of sharded buckets
+	pkg, err := d.bucketMgr.GetMergedPackage(packageID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get manifest: %w", err)
 	}
+	if pkg != nil {
+		return pkg, nil
+	}
 
-	// Find package
-	pkg, exists := manifest.Packages[packageID]
-	if !exists {
-		// Try case-insensitive search
-		for pkgID, p := range manifest.Packages {
-			if strings.EqualFold(pkgID, packageID) {
-				return p, nil
-			}
+	// Try case-insensitive search
+	manifest, err := d.bucketMgr.GetMergedManifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest: %w", err)
+	}
+	for pkgID := range manifest.Packages {
+		if strings.EqualFold(pkgID, packageID) {
+			return d.bucketMgr.GetMergedPackage(pkgID)
 		}
-		return nil, fmt.Errorf("package '%s' not found", packageID)
 	}
 
-	return pkg, nil
+	return nil, fmt.Errorf("package '%s' not found", packageID)
 }
@@ -0,0 +1,195 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+// testAPKContent returns deterministic file content of the given size
+func testAPKContent(size int) []byte {
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	return content
+}
+
+// newTestDownloadManager returns a download manager whose directories, and the
+// configuration file syncing a bucket saves, are in a temporary directory
+func newTestDownloadManager(t *testing.T) (*DownloadManager, *Config) {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	config := DefaultConfig()
+	config.Client.DownloadDir = filepath.Join(dir, "downloads")
+	config.Client.CacheDir = filepath.Join(dir, "cache")
+	config.Security.VerifySignature = false
+
+	return NewDownloadManager(config, NewBucketManager(config)), config
+}
+
+// writePartialDownload leaves a .part file and its metadata as an interrupted
+// download would
+func writePartialDownload(t *testing.T, targetPath, url string, data []byte, meta partialDownload) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(targetPath+".part", data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	meta.URL = url
+	metaData, err := json.Marshal(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(targetPath+".part.meta", metaData, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertDownloaded checks the final file and that no partial state is left
+func assertDownloaded(t *testing.T, targetPath string, want []byte) {
+	t.Helper()
+
+	got, err := os.ReadFile(targetPath)
+	if err != nil {
+		t.Fatalf("downloaded file missing: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("downloaded %d bytes, want the %d bytes served", len(got), len(want))
+	}
+	for _, leftover := range []string{targetPath + ".part", targetPath + ".part.meta"} {
+		if _, err := os.Stat(leftover); err == nil {
+			t.Errorf("%s was not removed", filepath.Base(leftover))
+		}
+	}
+}
+
+func TestDownloadFileResumesPartialFile(t *testing.T) {
+	content := testAPKContent(64 * 1024)
+	const etag = `"v1"`
+
+	var rangeHeader, ifRangeHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader, ifRangeHeader = r.Header.Get("Range"), r.Header.Get("If-Range")
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "app.apk", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	d, config := newTestDownloadManager(t)
+	url := server.URL + "/app.apk"
+	targetPath := filepath.Join(config.Client.DownloadDir, "app.apk")
+	offset := len(content) / 3
+	writePartialDownload(t, targetPath, url, content[:offset], partialDownload{ETag: etag})
+
+	if err := d.downloadFile(url, targetPath, int64(len(content)), time.Minute, false); err != nil {
+		t.Fatalf("downloadFile: %v", err)
+	}
+
+	if want := "bytes=" + strconv.Itoa(offset) + "-"; rangeHeader != want {
+		t.Errorf("Range = %q, want %q", rangeHeader, want)
+	}
+	if ifRangeHeader != etag {
+		t.Errorf("If-Range = %q, want %q", ifRangeHeader, etag)
+	}
+	assertDownloaded(t, targetPath, content)
+}
+
+func TestDownloadFileRestartsWhenServerIgnoresRange(t *testing.T) {
+	content := testAPKContent(64 * 1024)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// No range support: always the whole file with 200
+		w.Header().Set("ETag", `"v2"`)
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	d, config := newTestDownloadManager(t)
+	url := server.URL + "/app.apk"
+	targetPath := filepath.Join(config.Client.DownloadDir, "app.apk")
+	// The partial data must not end up in front of the full response
+	stale := bytes.Repeat([]byte{0xff}, 1000)
+	writePartialDownload(t, targetPath, url, stale, partialDownload{ETag: `"v1"`})
+
+	if err := d.downloadFile(url, targetPath, int64(len(content)), time.Minute, false); err != nil {
+		t.Fatalf("downloadFile: %v", err)
+	}
+
+	if requests != 1 {
+		t.Errorf("made %d requests, want 1", requests)
+	}
+	assertDownloaded(t, targetPath, content)
+}
+
+func TestDownloadRejectsChecksumMismatch(t *testing.T) {
+	content := testAPKContent(8 * 1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	d, config := newTestDownloadManager(t)
+
+	// A local bucket whose manifest lists a different digest than the file served
+	bucketDir := t.TempDir()
+	otherDigest := sha256.Sum256([]byte("something else"))
+	manifest := models.ManifestIndex{
+		Version: "1.0",
+		Packages: map[string]*models.AppPackage{
+			"com.example.app": {
+				PackageID: "com.example.app",
+				Name:      map[string]string{"default": "Example"},
+				Latest:    "1.0",
+				Versions: map[string]*models.AppVersion{
+					"1.0": {
+						Version:     "1.0",
+						VersionCode: 1,
+						Size:        int64(len(content)),
+						SHA256:      hex.EncodeToString(otherDigest[:]),
+						DownloadURL: server.URL + "/app.apk",
+					},
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bucketDir, "apkhub_manifest.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.Buckets["main"] = &Bucket{Name: "main", URL: "file://" + bucketDir, Enabled: true}
+
+	path, err := d.Download("com.example.app", DownloadOptions{MaxRetries: 1})
+	if err == nil {
+		t.Fatalf("Download succeeded with a mismatching checksum: %s", path)
+	}
+	if !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("error = %v, want a checksum failure", err)
+	}
+
+	targetPath := filepath.Join(config.Client.DownloadDir, "com.example.app_1.apk")
+	if _, err := os.Stat(targetPath); err == nil {
+		t.Error("file with a mismatching checksum was kept")
+	}
+}
@@ -3,7 +3,6 @@ package client
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -61,22 +60,10 @@ func (a *ADBManager) CaptureLogs(opts LogCaptureOptions) (*LogCaptureResult, err
 	}
 
 	start := time.Now()
-	args := []string{}
-	if opts.DeviceID != "" {
-		args = append(args, "-s", opts.DeviceID)
-	}
-
-	args = append(args, "shell", "pidof", opts.PackageID)
-	pidCmd := exec.Command(a.config.ADB.Path, args...)
-	pidOutput, _ := pidCmd.Output()
-	pid := strings.TrimSpace(string(pidOutput))
-
-	logArgs := []string{}
-	if opts.DeviceID != "" {
-		logArgs = append(logArgs, "-s", opts.DeviceID)
-	}
+	pidOutput, _ := a.backend.Shell(opts.DeviceID, shellCommand("pidof", opts.PackageID))
+	pid := strings.TrimSpace(pidOutput)
 
-	logArgs = append(logArgs, "logcat", "-d")
+	logArgs := []string{"logcat", "-d"}
 	note := ""
 	if pid != "" {
 		logArgs = append(logArgs, "--pid", pid)
@@ -88,12 +75,12 @@ func (a *ADBManager) CaptureLogs(opts LogCaptureOptions) (*LogCaptureResult, err
 		logArgs = append(logArgs, fmt.Sprintf("*:%s", level))
 	}
 
-	cmd := exec.Command(a.config.ADB.Path, logArgs...)
-	output, err := cmd.CombinedOutput()
+	logOutput, err := a.backend.Shell(opts.DeviceID, shellCommand(logArgs...))
 	duration := time.Since(start)
 	if err != nil {
-		return nil, fmt.Errorf("failed to capture logs: %w - %s", err, string(output))
+		return nil, fmt.Errorf("failed to capture logs: %w", err)
 	}
+	output := []byte(logOutput)
 
 	if err := os.WriteFile(outputPath, output, 0o644); err != nil {
 		return nil, fmt.Errorf("failed to write logs: %w", err)
@@ -2,6 +2,7 @@ package client
 
 import (
 	"fmt"
+	"math"
 	"net"
 	"sort"
 	"strings"
@@ -113,10 +114,9 @@ func (o *OfflineManager) getMergedOfflineManifest() (*models.ManifestIndex, erro
 		Packages:    make(map[string]*models.AppPackage),
 	}
 
-	enabledBuckets := o.config.GetEnabledBuckets()
 	availableBuckets := 0
 
-	for bucketName := range enabledBuckets {
+	for _, bucketName := range o.config.OrderedEnabledBuckets() {
 		manifest, err := o.GetOfflineManifest(bucketName)
 		if err != nil {
 			fmt.Printf("⚠️  Skipping bucket '%s': %v\n", bucketName, err)
@@ -124,42 +124,11 @@ func (o *OfflineManager) getMergedOfflineManifest() (*models.ManifestIndex, erro
 		}
 
 		availableBuckets++
-
-		// Merge packages (simplified version)
-		for pkgID, pkg := range manifest.Packages {
-			if existing, exists := merged.Packages[pkgID]; exists {
-				// Merge versions
-				for versionKey, version := range pkg.Versions {
-					prefixedKey := fmt.Sprintf("%s_%s", bucketName, versionKey)
-					existing.Versions[prefixedKey] = version
-				}
-				if pkg.Latest > existing.Latest {
-					existing.Latest = pkg.Latest
-				}
-			} else {
-				// Clone package
-				clonedPkg := &models.AppPackage{
-					PackageID:   pkg.PackageID,
-					Name:        pkg.Name,
-					Description: pkg.Description,
-					Icon:        pkg.Icon,
-					Category:    pkg.Category,
-					Latest:      pkg.Latest,
-					Versions:    make(map[string]*models.AppVersion),
-				}
-				for versionKey, version := range pkg.Versions {
-					prefixedKey := fmt.Sprintf("%s_%s", bucketName, versionKey)
-					clonedVersion := *version
-					clonedPkg.Versions[prefixedKey] = &clonedVersion
-				}
-				merged.Packages[pkgID] = clonedPkg
-			}
-		}
-
-		merged.TotalAPKs += manifest.TotalAPKs
-		merged.TotalSize += manifest.TotalSize
+		mergeBucketPackages(o.config, merged, bucketName, manifest, nil)
 	}
 
+	selectMergedLatest(o.config, merged)
+
 	if availableBuckets == 0 {
 		return nil, fmt.Errorf("no cached manifests available for offline mode")
 	}
@@ -193,7 +162,7 @@ func (o *OfflineManager) performOfflineSearch(query string, options SearchOption
 		}
 
 		// Get app name and latest version
-		appName := getDefaultName(pkg.Name)
+		appName := models.LocalizedName(pkg.Name, options.Languages)
 		latestVersion := ""
 		var latestVersionInfo *models.AppVersion
 
@@ -211,9 +180,16 @@ func (o *OfflineManager) performOfflineSearch(query string, options SearchOption
 			}
 		}
 
-		// Get bucket name from version key
+		if !matchesManifest(latestVersionInfo, options) || !matchesLibraries(latestVersionInfo, options) ||
+			!matchesPermissions(latestVersionInfo, options) {
+			continue
+		}
+
+		// Get bucket name from the latest version
 		bucketName := ""
-		if pkg.Latest != "" && strings.Contains(pkg.Latest, "_") {
+		if latestVersionInfo != nil && latestVersionInfo.Bucket != "" {
+			bucketName = latestVersionInfo.Bucket
+		} else if pkg.Latest != "" && strings.Contains(pkg.Latest, "_") {
 			parts := strings.SplitN(pkg.Latest, "_", 2)
 			bucketName = parts[0]
 		}
@@ -228,8 +204,9 @@ func (o *OfflineManager) performOfflineSearch(query string, options SearchOption
 			PackageID:   pkgID,
 			AppName:     appName,
 			Version:     latestVersion,
-			Description: getDefaultName(pkg.Description),
+			Description: models.LocalizedName(pkg.Description, options.Languages),
 			BucketName:  bucketName,
+			Pinned:      o.config.PinnedBucket(pkgID) != "",
 			Category:    pkg.Category,
 			Score:       score,
 		}
@@ -239,6 +216,9 @@ func (o *OfflineManager) performOfflineSearch(query string, options SearchOption
 			result.Size = latestVersionInfo.Size
 			result.MinSDK = latestVersionInfo.MinSDK
 			result.TargetSDK = latestVersionInfo.TargetSDK
+			result.addManifestInfo(latestVersionInfo.Manifest)
+			result.addLibraries(latestVersionInfo.Libraries)
+			result.RiskScore = VersionRiskScore(latestVersionInfo)
 		}
 
 		results = append(results, result)
@@ -273,16 +253,20 @@ func (o *OfflineManager) calculateOfflineScore(query string, pkgID string, pkg *
 		score += 50.0
 	}
 
-	// App name match
-	appName := strings.ToLower(getDefaultName(pkg.Name))
-	if appName == query {
-		score += 80.0
-	} else if strings.Contains(appName, query) {
-		score += 40.0
+	// App name match, in whichever language matches best
+	nameScore := 0.0
+	for _, name := range pkg.Name {
+		appName := strings.ToLower(name)
+		if appName == query {
+			nameScore = math.Max(nameScore, 80.0)
+		} else if strings.Contains(appName, query) {
+			nameScore = math.Max(nameScore, 40.0)
+		}
 	}
+	score += nameScore
 
 	// Description match
-	desc := strings.ToLower(getDefaultName(pkg.Description))
+	desc := strings.ToLower(models.LocalizedName(pkg.Description, nil))
 	if strings.Contains(desc, query) {
 		score += 10.0
 	}
@@ -299,10 +283,11 @@ func (o *OfflineManager) calculateExactOfflineScore(query string, pkgID string,
 		return 100.0
 	}
 
-	// Exact app name match
-	appName := strings.ToLower(getDefaultName(pkg.Name))
-	if appName == query {
-		return 90.0
+	// Exact app name match, in any language
+	for _, name := range pkg.Name {
+		if strings.ToLower(name) == query {
+			return 90.0
+		}
 	}
 
 	return 0.0
@@ -2,9 +2,11 @@ package client
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 
+	"github.com/huanfeng/apkhub/pkg/apk"
 	"github.com/huanfeng/apkhub/pkg/models"
 )
 
@@ -21,6 +23,13 @@ type SearchResult struct {
 	MinSDK      int     `json:"min_sdk"`
 	TargetSDK   int     `json:"target_sdk"`
 	IsInstalled bool    `json:"is_installed"`
+	Pinned      bool    `json:"pinned,omitempty"` // Package is pinned to BucketName
+
+	LauncherActivity string   `json:"launcher_activity,omitempty"`
+	DeepLinks        []string `json:"deep_links,omitempty"`
+	Debuggable       bool     `json:"debuggable,omitempty"`
+	Libraries        []string `json:"libraries,omitempty"` // Names of the SDKs detected in the code
+	RiskScore        int      `json:"risk_score"`          // Permission risk, 0 to 100
 }
 
 // SearchEngine handles application searches
@@ -86,9 +95,16 @@ func (s *SearchEngine) Search(query string, options SearchOptions) ([]SearchResu
 			continue
 		}
 
+		if !matchesManifest(latestVersionInfo, options) || !matchesLibraries(latestVersionInfo, options) ||
+			!matchesPermissions(latestVersionInfo, options) {
+			continue
+		}
+
 		// Filter by bucket if specified
 		bucketName := ""
-		if pkg.Latest != "" && strings.Contains(pkg.Latest, "_") {
+		if latestVersionInfo != nil && latestVersionInfo.Bucket != "" {
+			bucketName = latestVersionInfo.Bucket
+		} else if pkg.Latest != "" && strings.Contains(pkg.Latest, "_") {
 			parts := strings.SplitN(pkg.Latest, "_", 2)
 			bucketName = parts[0]
 		}
@@ -98,17 +114,18 @@ func (s *SearchEngine) Search(query string, options SearchOptions) ([]SearchResu
 		}
 
 		// Get app name
-		appName := getDefaultName(pkg.Name)
+		appName := models.LocalizedName(pkg.Name, options.Languages)
 
 		// Create result
 		result := SearchResult{
 			PackageID:   pkgID,
 			AppName:     appName,
 			Version:     latestVersion,
-			Description: getDefaultName(pkg.Description),
+			Description: models.LocalizedName(pkg.Description, options.Languages),
 			BucketName:  bucketName,
 			Category:    pkg.Category,
 			Score:       score,
+			Pinned:      s.bucketMgr.config.PinnedBucket(pkgID) != "",
 		}
 
 		// Add version-specific info if available
@@ -116,6 +133,9 @@ func (s *SearchEngine) Search(query string, options SearchOptions) ([]SearchResu
 			result.Size = latestVersionInfo.Size
 			result.MinSDK = latestVersionInfo.MinSDK
 			result.TargetSDK = latestVersionInfo.TargetSDK
+			result.addManifestInfo(latestVersionInfo.Manifest)
+			result.addLibraries(latestVersionInfo.Libraries)
+			result.RiskScore = VersionRiskScore(latestVersionInfo)
 		}
 
 		// Check installation status if requested
@@ -139,13 +159,205 @@ func (s *SearchEngine) Search(query string, options SearchOptions) ([]SearchResu
 
 // SearchOptions contains search options
 type SearchOptions struct {
-	Bucket        string
-	MinSDK        int
-	Category      string
-	Limit         int
-	Sort          string
-	Exact         bool
-	ShowInstalled bool
+	Bucket                 string
+	MinSDK                 int
+	Category               string
+	Limit                  int
+	Sort                   string
+	Exact                  bool
+	ShowInstalled          bool
+	DeepLink               string   // Only packages opening links to this host, scheme or URI
+	Component              string   // Only packages declaring a component whose name contains this
+	Debuggable             bool     // Only debuggable builds
+	Library                string   // Only packages embedding an SDK whose name contains this
+	LibraryCategory        string   // Only packages embedding an SDK of this category
+	WithoutLibraryCategory string   // Only packages in which no SDK of this category was detected
+	MaxRisk                *int     // Only packages whose permission risk score is at most this
+	WithoutPermissions     []string // Only packages requesting none of these permissions
+	Languages              []string // Preferred languages of the displayed names, most preferred first
+}
+
+// addLibraries copies the names of the detected SDKs
+func (r *SearchResult) addLibraries(libraries []models.Library) {
+	for _, library := range libraries {
+		r.Libraries = append(r.Libraries, library.Name)
+	}
+}
+
+// matchesLibraries applies the SDK filters of the options to the latest version
+// of a package
+func matchesLibraries(version *models.AppVersion, options SearchOptions) bool {
+	if options.Library == "" && options.LibraryCategory == "" && options.WithoutLibraryCategory == "" {
+		return true
+	}
+	var libraries []models.Library
+	if version != nil {
+		libraries = version.Libraries
+	}
+
+	hasLibrary := func(matches func(models.Library) bool) bool {
+		for _, library := range libraries {
+			if matches(library) {
+				return true
+			}
+		}
+		return false
+	}
+
+	name := strings.ToLower(options.Library)
+	if name != "" && !hasLibrary(func(library models.Library) bool {
+		return strings.Contains(strings.ToLower(library.Name), name)
+	}) {
+		return false
+	}
+	if category := strings.ToLower(options.LibraryCategory); category != "" && !hasLibrary(func(library models.Library) bool {
+		return library.HasCategory(category)
+	}) {
+		return false
+	}
+	if category := strings.ToLower(options.WithoutLibraryCategory); category != "" && hasLibrary(func(library models.Library) bool {
+		return library.HasCategory(category)
+	}) {
+		return false
+	}
+
+	return true
+}
+
+// matchesPermissions applies the permission filters of the options to the
+// latest version of a package
+func matchesPermissions(version *models.AppVersion, options SearchOptions) bool {
+	if options.MaxRisk == nil && len(options.WithoutPermissions) == 0 {
+		return true
+	}
+	if version == nil {
+		return false
+	}
+
+	if options.MaxRisk != nil && VersionRiskScore(version) > *options.MaxRisk {
+		return false
+	}
+	for _, excluded := range options.WithoutPermissions {
+		for _, permission := range version.Permissions {
+			if matchesPermission(permission, excluded) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// VersionRiskScore returns the permission risk score of a version, scoring its
+// permissions when the manifest was published before risk scores were
+func VersionRiskScore(version *models.AppVersion) int {
+	if version.RiskScore != nil {
+		return *version.RiskScore
+	}
+	return apk.PermissionRiskScore(version.Permissions)
+}
+
+// matchesPermission reports whether a permission is the one a pattern names,
+// either in full ("android.permission.CAMERA") or by its last segment
+// ("CAMERA"), ignoring case
+func matchesPermission(permission, pattern string) bool {
+	if strings.EqualFold(permission, pattern) {
+		return true
+	}
+	if strings.Contains(pattern, ".") {
+		return false
+	}
+	return strings.EqualFold(permission[strings.LastIndex(permission, ".")+1:], pattern)
+}
+
+// addManifestInfo copies the manifest details shown in results
+func (r *SearchResult) addManifestInfo(manifest *models.ManifestInfo) {
+	if manifest == nil {
+		return
+	}
+	r.LauncherActivity = manifest.LauncherActivity
+	r.DeepLinks = manifest.DeepLinks()
+	r.Debuggable = manifest.Debuggable
+}
+
+// matchesManifest applies the manifest filters of the options to the latest
+// version of a package. Versions without manifest details only match when no
+// such filter is set.
+func matchesManifest(version *models.AppVersion, options SearchOptions) bool {
+	if options.DeepLink == "" && options.Component == "" && !options.Debuggable {
+		return true
+	}
+	if version == nil || version.Manifest == nil {
+		return false
+	}
+	manifest := version.Manifest
+
+	if options.Debuggable && !manifest.Debuggable {
+		return false
+	}
+	if options.DeepLink != "" && !matchesDeepLink(manifest.DeepLinks(), options.DeepLink) {
+		return false
+	}
+	if options.Component != "" && !hasComponent(manifest, options.Component) {
+		return false
+	}
+
+	return true
+}
+
+// matchesDeepLink reports whether any deep link matches a scheme ("myapp"), a
+// host ("example.com", also matching its subdomains) or a URI
+// ("https://example.com")
+func matchesDeepLink(links []string, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "/"))
+	patternScheme, patternHost, isURI := strings.Cut(pattern, "://")
+
+	for _, link := range links {
+		scheme, host, _ := strings.Cut(strings.ToLower(link), ":")
+		host = strings.TrimPrefix(host, "//")
+
+		switch {
+		case isURI:
+			if scheme == patternScheme && matchesHost(host, patternHost) {
+				return true
+			}
+		case strings.TrimSuffix(pattern, ":") == scheme:
+			return true
+		case matchesHost(host, pattern):
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesHost reports whether a deep link host, which may be a wildcard such
+// as "*.example.com", covers the queried host or one of its subdomains
+func matchesHost(host, query string) bool {
+	if host == "" || query == "" {
+		return false
+	}
+	if host == query || strings.HasSuffix(host, "."+query) {
+		return true
+	}
+	if domain, isWildcard := strings.CutPrefix(host, "*."); isWildcard {
+		return query == domain || strings.HasSuffix(query, "."+domain)
+	}
+	return false
+}
+
+// hasComponent reports whether the manifest declares a component whose class
+// name contains the query
+func hasComponent(manifest *models.ManifestInfo, query string) bool {
+	query = strings.ToLower(query)
+	for _, group := range [][]models.Component{manifest.Activities, manifest.Services, manifest.Receivers, manifest.Providers} {
+		for _, component := range group {
+			if strings.Contains(strings.ToLower(component.Name), query) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // calculateScore calculates relevance score for a package
@@ -162,24 +374,29 @@ func (s *SearchEngine) calculateScore(query string, pkgID string, pkg *models.Ap
 		score += 50.0
 	}
 
-	// App name match
-	appName := strings.ToLower(getDefaultName(pkg.Name))
-	if appName == query {
-		score += 80.0
-	} else if strings.Contains(appName, query) {
-		score += 40.0
-		// Bonus for word boundary match
-		words := strings.Fields(appName)
-		for _, word := range words {
-			if strings.HasPrefix(word, query) {
-				score += 10.0
-				break
+	// App name match, in whichever language matches best
+	nameScore := 0.0
+	for _, name := range pkg.Name {
+		appName := strings.ToLower(name)
+		if appName == query {
+			nameScore = math.Max(nameScore, 80.0)
+		} else if strings.Contains(appName, query) {
+			languageScore := 40.0
+			// Bonus for word boundary match
+			words := strings.Fields(appName)
+			for _, word := range words {
+				if strings.HasPrefix(word, query) {
+					languageScore += 10.0
+					break
+				}
 			}
+			nameScore = math.Max(nameScore, languageScore)
 		}
 	}
+	score += nameScore
 
 	// Description match
-	desc := strings.ToLower(getDefaultName(pkg.Description))
+	desc := strings.ToLower(models.LocalizedName(pkg.Description, nil))
 	if strings.Contains(desc, query) {
 		score += 10.0
 	}
@@ -192,29 +409,6 @@ func (s *SearchEngine) calculateScore(query string, pkgID string, pkg *models.Ap
 	return score
 }
 
-// getDefaultName returns the default name from a multi-language map
-func getDefaultName(names map[string]string) string {
-	if names == nil {
-		return ""
-	}
-
-	// Try common language codes
-	for _, lang := range []string{"default", "en", "en-US", "en_US"} {
-		if name, ok := names[lang]; ok && name != "" {
-			return name
-		}
-	}
-
-	// Return first available
-	for _, name := range names {
-		if name != "" {
-			return name
-		}
-	}
-
-	return ""
-}
-
 // calculateExactScore calculates score for exact matching
 func (s *SearchEngine) calculateExactScore(query string, pkgID string, pkg *models.AppPackage) float64 {
 	query = strings.ToLower(query)
@@ -224,17 +418,19 @@ func (s *SearchEngine) calculateExactScore(query string, pkgID string, pkg *mode
 		return 100.0
 	}
 
-	// Exact app name match
-	appName := strings.ToLower(getDefaultName(pkg.Name))
-	if appName == query {
-		return 90.0
+	// Exact app name match, in any language
+	for _, name := range pkg.Name {
+		if strings.ToLower(name) == query {
+			return 90.0
+		}
 	}
 
 	// Exact word match in app name
-	words := strings.Fields(appName)
-	for _, word := range words {
-		if word == query {
-			return 80.0
+	for _, name := range pkg.Name {
+		for _, word := range strings.Fields(strings.ToLower(name)) {
+			if word == query {
+				return 80.0
+			}
 		}
 	}
 
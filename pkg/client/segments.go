@@ -0,0 +1,389 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/huanfeng/apkhub/pkg/utils"
+)
+
+// minSegmentSize keeps segments from becoming too small to be worth a connection
+const minSegmentSize = 1024 * 1024
+
+var (
+	// errRangesUnsupported means the server cannot serve a file in segments
+	errRangesUnsupported = errors.New("server does not support range requests")
+
+	// errRemoteChanged means the remote file changed while segments were downloaded
+	errRemoteChanged = errors.New("remote file changed during download")
+)
+
+// segmentedDownload records which remote file a set of segment files belongs to
+type segmentedDownload struct {
+	URL       string `json:"url"`
+	Validator string `json:"validator"`
+	Size      int64  `json:"size"`
+	Segments  int    `json:"segments"`
+}
+
+// downloadSegment is one byte range of a segmented download
+type downloadSegment struct {
+	index int
+	start int64
+	end   int64 // inclusive
+	path  string
+}
+
+// size returns the number of bytes in the segment
+func (s *downloadSegment) size() int64 {
+	return s.end - s.start + 1
+}
+
+// progressBarWriter reports bytes written to a shared progress bar
+type progressBarWriter struct {
+	writer io.Writer
+	bar    *utils.ProgressBar
+}
+
+// Write implements io.Writer interface
+func (w *progressBarWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.bar.Add(int64(n))
+	return n, err
+}
+
+// segmentCount returns how many concurrent segments to use for a file of size bytes
+func (d *DownloadManager) segmentCount(size int64) int {
+	if d.config == nil || size <= 0 {
+		return 1
+	}
+
+	segments := d.config.Client.DownloadSegments
+	if segments <= 1 || size < d.config.Client.SegmentMinSize {
+		return 1
+	}
+
+	if limit := size / minSegmentSize; int64(segments) > limit {
+		segments = int(limit)
+	}
+	if segments < 1 {
+		segments = 1
+	}
+
+	return segments
+}
+
+// probeRangeSupport checks with a one byte range request whether url can be downloaded
+// in segments. It returns the remote size and the validator used for If-Range.
+func (d *DownloadManager) probeRangeSupport(ctx context.Context, url string) (int64, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, "", err
+	}
+
+	req.Header.Set("User-Agent", "ApkHub-CLI/1.0")
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1))
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, "", errRangesUnsupported
+	}
+
+	var start, end, total int64
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &total); err != nil || total <= 0 {
+		return 0, "", errRangesUnsupported
+	}
+
+	// Segments fetched at different times can only be combined safely with a validator
+	validator := (&partialDownload{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}).validator()
+	if validator == "" {
+		return 0, "", errRangesUnsupported
+	}
+
+	return total, validator, nil
+}
+
+// downloadSegmented downloads url over several concurrent range requests and
+// reassembles the segments into targetPath. Segment files are kept on failure so
+// the next attempt resumes them. errRangesUnsupported is returned when the server
+// cannot serve ranges, in which case the caller should use a single connection.
+func (d *DownloadManager) downloadSegmented(url, targetPath string, expectedSize int64, count int, timeout time.Duration, showProgress bool) error {
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return fmt.Errorf("failed to create download directory: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	size, validator, err := d.probeRangeSupport(ctx, url)
+	if err != nil {
+		return err
+	}
+	if expectedSize > 0 && size != expectedSize {
+		return fmt.Errorf("size mismatch: expected %d bytes, server reports %d bytes", expectedSize, size)
+	}
+
+	state := segmentedDownload{URL: url, Validator: validator, Size: size, Segments: count}
+	if err := prepareSegmentedDownload(targetPath, state); err != nil {
+		return err
+	}
+
+	segments := splitSegments(targetPath, size, count)
+
+	// Bytes already present from an earlier attempt
+	var existing int64
+	for _, seg := range segments {
+		if info, err := os.Stat(seg.path); err == nil && info.Size() <= seg.size() {
+			existing += info.Size()
+		}
+	}
+
+	var bar *utils.ProgressBar
+	if showProgress {
+		config := utils.DefaultProgressConfig()
+		config.Total = size
+		config.Width = 30
+		config.ShowSpeed = false
+		config.Prefix = fmt.Sprintf("📥 %d segments", count)
+		bar = utils.NewProgressBar(config)
+		bar.Set(existing)
+	}
+
+	errs := make([]error, len(segments))
+	var wg sync.WaitGroup
+	for i, seg := range segments {
+		wg.Add(1)
+		go func(i int, seg *downloadSegment) {
+			defer wg.Done()
+			if err := d.downloadSegment(ctx, url, validator, seg, bar); err != nil {
+				errs[i] = fmt.Errorf("segment %d: %w", seg.index+1, err)
+				cancel()
+			}
+		}(i, seg)
+	}
+	wg.Wait()
+
+	if err := firstSegmentError(errs); err != nil {
+		if bar != nil {
+			fmt.Println()
+		}
+		if errors.Is(err, errRemoteChanged) {
+			removeSegmentedDownload(targetPath, count)
+		}
+		return err
+	}
+
+	if bar != nil {
+		bar.Finish()
+	}
+
+	return assembleSegments(targetPath, segments)
+}
+
+// downloadSegment fetches the missing tail of one segment, appending to its file
+func (d *DownloadManager) downloadSegment(ctx context.Context, url, validator string, seg *downloadSegment, bar *utils.ProgressBar) error {
+	var have int64
+	if info, err := os.Stat(seg.path); err == nil {
+		have = info.Size()
+	}
+	if have > seg.size() {
+		os.Remove(seg.path)
+		have = 0
+	}
+	if have == seg.size() {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", "ApkHub-CLI/1.0")
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", seg.start+have, seg.end))
+	req.Header.Set("If-Range", validator)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// If-Range did not match: the file is no longer the one being assembled
+		return errRemoteChanged
+	default:
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	if start, ok := parseContentRangeStart(resp.Header.Get("Content-Range")); !ok || start != seg.start+have {
+		return fmt.Errorf("unexpected content range %q", resp.Header.Get("Content-Range"))
+	}
+
+	out, err := os.OpenFile(seg.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var writer io.Writer = out
+	if bar != nil {
+		writer = &progressBarWriter{writer: out, bar: bar}
+	}
+
+	remaining := seg.size() - have
+	written, err := io.Copy(writer, io.LimitReader(resp.Body, remaining))
+	if err != nil {
+		return err
+	}
+	if written != remaining {
+		return fmt.Errorf("incomplete segment: expected %d bytes, got %d bytes", remaining, written)
+	}
+
+	return nil
+}
+
+// splitSegments divides size bytes into count contiguous segments
+func splitSegments(targetPath string, size int64, count int) []*downloadSegment {
+	segmentSize := size / int64(count)
+	segments := make([]*downloadSegment, count)
+
+	for i := range segments {
+		start := int64(i) * segmentSize
+		end := start + segmentSize - 1
+		if i == count-1 {
+			end = size - 1
+		}
+		segments[i] = &downloadSegment{
+			index: i,
+			start: start,
+			end:   end,
+			path:  segmentPath(targetPath, i),
+		}
+	}
+
+	return segments
+}
+
+// segmentPath returns the file holding segment i of targetPath
+func segmentPath(targetPath string, i int) string {
+	return fmt.Sprintf("%s.part%d", targetPath, i)
+}
+
+// prepareSegmentedDownload discards segment files left by a different remote file or
+// segment layout and records the state of the new download
+func prepareSegmentedDownload(targetPath string, state segmentedDownload) error {
+	statePath := targetPath + ".segments"
+
+	if data, err := os.ReadFile(statePath); err == nil {
+		var previous segmentedDownload
+		if json.Unmarshal(data, &previous) == nil {
+			if previous == state {
+				return nil
+			}
+			removeSegmentedDownload(targetPath, previous.Segments)
+		}
+	}
+
+	// Without a matching state file, any segment files present are stale
+	removeSegmentedDownload(targetPath, state.Segments)
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(statePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to save download state: %w", err)
+	}
+
+	return nil
+}
+
+// removeSegmentedDownload deletes segment files and the segmented download state
+func removeSegmentedDownload(targetPath string, count int) {
+	for i := 0; i < count; i++ {
+		os.Remove(segmentPath(targetPath, i))
+	}
+	os.Remove(targetPath + ".segments")
+}
+
+// assembleSegments concatenates completed segments into targetPath
+func assembleSegments(targetPath string, segments []*downloadSegment) error {
+	partPath := targetPath + ".part"
+	removePartialDownload(partPath)
+
+	out, err := os.Create(partPath)
+	if err != nil {
+		return err
+	}
+
+	for _, seg := range segments {
+		if err := appendFile(out, seg.path); err != nil {
+			out.Close()
+			os.Remove(partPath)
+			return fmt.Errorf("failed to assemble segment %d: %w", seg.index+1, err)
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(partPath)
+		return err
+	}
+
+	if err := finishPartialDownload(partPath, targetPath); err != nil {
+		return err
+	}
+
+	removeSegmentedDownload(targetPath, len(segments))
+	return nil
+}
+
+// appendFile copies the contents of path to out
+func appendFile(out io.Writer, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// firstSegmentError returns the error that caused a segmented download to stop,
+// skipping cancellations triggered by that error in the other segments
+func firstSegmentError(errs []error) error {
+	var canceled error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, context.Canceled) {
+			if canceled == nil {
+				canceled = err
+			}
+			continue
+		}
+		return err
+	}
+	return canceled
+}
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// segmentedTestSize is large enough to be split into four segments
+const segmentedTestSize = 4*minSegmentSize + 123
+
+// newSegmentedDownloadManager returns a download manager that splits files into
+// four segments
+func newSegmentedDownloadManager(t *testing.T) (*DownloadManager, *Config) {
+	t.Helper()
+
+	d, config := newTestDownloadManager(t)
+	config.Client.DownloadSegments = 4
+	config.Client.SegmentMinSize = 1
+	return d, config
+}
+
+// rangeLog records the Range headers a test server received
+type rangeLog struct {
+	mu     sync.Mutex
+	ranges []string
+}
+
+// add records one request
+func (l *rangeLog) add(r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ranges = append(l.ranges, r.Header.Get("Range"))
+}
+
+// count returns how many requests asked for a range starting at start
+func (l *rangeLog) count(start int64) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	prefix := fmt.Sprintf("bytes=%d-", start)
+	n := 0
+	for _, r := range l.ranges {
+		if strings.HasPrefix(r, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// assertNoSegmentFiles checks that a finished download left no segment state
+func assertNoSegmentFiles(t *testing.T, targetPath string) {
+	t.Helper()
+
+	matches, err := filepath.Glob(targetPath + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) > 0 {
+		t.Errorf("download state left behind: %v", matches)
+	}
+}
+
+func TestSegmentedDownloadReassemblesInOrder(t *testing.T) {
+	content := testAPKContent(segmentedTestSize)
+	segments := splitSegments("", int64(len(content)), 4)
+
+	var log rangeLog
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		// Earlier segments finish last, so they arrive out of order
+		for i, seg := range segments {
+			if r.Header.Get("Range") == fmt.Sprintf("bytes=%d-%d", seg.start, seg.end) {
+				time.Sleep(time.Duration(len(segments)-i) * 20 * time.Millisecond)
+			}
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "app.apk", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	d, config := newSegmentedDownloadManager(t)
+	targetPath := filepath.Join(config.Client.DownloadDir, "app.apk")
+
+	err := d.downloadFileWithRetry(server.URL+"/app.apk", targetPath, int64(len(content)), DownloadOptions{MaxRetries: 1})
+	if err != nil {
+		t.Fatalf("downloadFileWithRetry: %v", err)
+	}
+
+	for _, seg := range segments {
+		if log.count(seg.start) == 0 {
+			t.Errorf("segment %d was not requested", seg.index+1)
+		}
+	}
+	assertDownloaded(t, targetPath, content)
+	assertNoSegmentFiles(t, targetPath)
+}
+
+func TestSegmentedDownloadFallsBackWithoutRangeSupport(t *testing.T) {
+	content := testAPKContent(segmentedTestSize)
+
+	var log rangeLog
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	d, config := newSegmentedDownloadManager(t)
+	targetPath := filepath.Join(config.Client.DownloadDir, "app.apk")
+
+	err := d.downloadFileWithRetry(server.URL+"/app.apk", targetPath, int64(len(content)), DownloadOptions{MaxRetries: 1})
+	if err != nil {
+		t.Fatalf("downloadFileWithRetry: %v", err)
+	}
+
+	// The range probe, then the whole file over a single stream
+	if len(log.ranges) != 2 || log.ranges[0] != "bytes=0-0" || log.ranges[1] != "" {
+		t.Errorf("requests with ranges %q, want the probe and one plain request", log.ranges)
+	}
+	assertDownloaded(t, targetPath, content)
+	assertNoSegmentFiles(t, targetPath)
+}
+
+func TestSegmentedDownloadRetriesFailingSegment(t *testing.T) {
+	content := testAPKContent(segmentedTestSize)
+	segments := splitSegments("", int64(len(content)), 4)
+	failing := segments[2]
+
+	var log rangeLog
+	var failOnce sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		if strings.HasPrefix(r.Header.Get("Range"), fmt.Sprintf("bytes=%d-", failing.start)) {
+			failed := false
+			failOnce.Do(func() { failed = true })
+			if failed {
+				http.Error(w, "temporarily unavailable", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "app.apk", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	d, config := newSegmentedDownloadManager(t)
+	targetPath := filepath.Join(config.Client.DownloadDir, "app.apk")
+
+	err := d.downloadFileWithRetry(server.URL+"/app.apk", targetPath, int64(len(content)), DownloadOptions{MaxRetries: 1})
+	if err != nil {
+		t.Fatalf("downloadFileWithRetry: %v", err)
+	}
+
+	if n := log.count(failing.start); n != 2 {
+		t.Errorf("failing segment requested %d times, want 2", n)
+	}
+	assertDownloaded(t, targetPath, content)
+	assertNoSegmentFiles(t, targetPath)
+}
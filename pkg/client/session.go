@@ -0,0 +1,245 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huanfeng/apkhub/pkg/utils"
+)
+
+// DefaultInstallTimeout bounds an install session when InstallOptions.Timeout is not set
+const DefaultInstallTimeout = 10 * time.Minute
+
+// sessionCleanupTimeout bounds abandoning a session after a failure or Ctrl-C
+const sessionCleanupTimeout = 30 * time.Second
+
+// installSessionPattern extracts the ID from "Success: created install session [1234]"
+var installSessionPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// obbTransfer is an OBB file copied to the device as part of an install session
+type obbTransfer struct {
+	localPath  string
+	remotePath string
+}
+
+// installSession streams APKs into one package installer session and commits it.
+// OBB files are copied after the APKs are written and before the commit, so the
+// installation either completes as a whole or leaves nothing behind: on failure,
+// timeout or Ctrl-C the session is abandoned and the OBB files it created are
+// removed. OBB files that were on the device before are left alone.
+// It returns the pm output, which reports "Success" or the failure reason.
+func (a *ADBManager) installSession(apkPaths []string, obbs []obbTransfer, deviceID string, options InstallOptions) (string, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultInstallTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	sizes := make([]int64, len(apkPaths))
+	var total int64
+	for i, apkPath := range apkPaths {
+		info, err := os.Stat(apkPath)
+		if err != nil {
+			return "", err
+		}
+		sizes[i] = info.Size()
+		total += info.Size()
+	}
+
+	args := append([]string{"pm", "install-create", "-S", strconv.FormatInt(total, 10)}, installFlags(options)...)
+	output, err := a.backend.Exec(ctx, deviceID, shellCommand(args...), nil)
+	if err != nil {
+		return "", sessionError(ctx, err, timeout)
+	}
+
+	matches := installSessionPattern.FindStringSubmatch(output)
+	if !pmSucceeded(output) || matches == nil {
+		return output, nil
+	}
+	sessionID := matches[1]
+
+	committed := false
+	var createdOBBs []string
+	defer func() {
+		if committed {
+			return
+		}
+
+		// The install context may be cancelled already
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), sessionCleanupTimeout)
+		defer cancel()
+
+		a.backend.Exec(cleanupCtx, deviceID, shellCommand("pm", "install-abandon", sessionID), nil)
+		if len(createdOBBs) > 0 {
+			a.backend.Exec(cleanupCtx, deviceID, shellCommand(append([]string{"rm", "-f"}, createdOBBs...)...), nil)
+		}
+		if ctx.Err() != nil {
+			fmt.Printf("   🗑️  Abandoned install session %s\n", sessionID)
+		}
+	}()
+
+	for i, apkPath := range apkPaths {
+		splitName := fmt.Sprintf("%d_%s", i, filepath.Base(apkPath))
+		output, err := a.writeSessionSplit(ctx, deviceID, sessionID, splitName, apkPath, sizes[i])
+		if err != nil {
+			return "", sessionError(ctx, err, timeout)
+		}
+		if !pmSucceeded(output) {
+			return output, nil
+		}
+	}
+
+	if len(obbs) > 0 {
+		obbDir := path.Dir(obbs[0].remotePath)
+		if err := a.createDeviceDirectory(obbDir, deviceID); err != nil {
+			return "", fmt.Errorf("failed to create OBB directory: %w", err)
+		}
+	}
+	for _, obb := range obbs {
+		// Only a file this session creates may be removed on abandon, also if it
+		// was copied in part. A file that cannot be checked is treated as existing.
+		if existing, err := a.backend.Stat(deviceID, obb.remotePath); err == nil && existing == nil {
+			createdOBBs = append(createdOBBs, obb.remotePath)
+		}
+		if err := a.copyOBB(ctx, deviceID, obb); err != nil {
+			return "", sessionError(ctx, fmt.Errorf("failed to copy OBB file %s: %w", filepath.Base(obb.localPath), err), timeout)
+		}
+	}
+
+	output, err = a.backend.Exec(ctx, deviceID, shellCommand("pm", "install-commit", sessionID), nil)
+	if err != nil {
+		return "", sessionError(ctx, err, timeout)
+	}
+	committed = pmSucceeded(output)
+
+	return output, nil
+}
+
+// writeSessionSplit streams one APK into an install session through the stdin
+// of "pm install-write", showing its progress
+func (a *ADBManager) writeSessionSplit(ctx context.Context, deviceID, sessionID, splitName, apkPath string, size int64) (string, error) {
+	file, err := os.Open(apkPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	bar := newTransferBar(filepath.Base(apkPath), size)
+	reader := &progressReader{reader: io.LimitReader(file, size), bar: bar}
+
+	output, err := a.backend.Exec(ctx, deviceID, shellCommand("pm", "install-write", "-S",
+		strconv.FormatInt(size, 10), sessionID, splitName, "-"), reader)
+	if err != nil {
+		fmt.Println()
+		return "", err
+	}
+	if reader.read != size {
+		fmt.Println()
+		return "", fmt.Errorf("%s changed while it was being installed", filepath.Base(apkPath))
+	}
+	bar.Finish()
+
+	return output, nil
+}
+
+// copyOBB copies one OBB file to the device, showing its progress
+func (a *ADBManager) copyOBB(ctx context.Context, deviceID string, obb obbTransfer) error {
+	file, err := os.Open(obb.localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	bar := newTransferBar(filepath.Base(obb.localPath), info.Size())
+	reader := &progressReader{reader: file, bar: bar}
+	if err := a.backend.PushReader(ctx, deviceID, reader, obb.remotePath, 0644, info.ModTime()); err != nil {
+		fmt.Println()
+		return err
+	}
+	bar.Finish()
+
+	return nil
+}
+
+// newTransferBar creates the progress bar of one file sent to the device
+func newTransferBar(name string, size int64) *utils.ProgressBar {
+	config := utils.DefaultProgressConfig()
+	config.Total = size
+	config.Width = 30
+	config.ShowETA = false
+	config.Prefix = fmt.Sprintf("   📤 %s", name)
+	return utils.NewProgressBar(config)
+}
+
+// progressReader advances a progress bar as it is read
+type progressReader struct {
+	reader io.Reader
+	bar    *utils.ProgressBar
+	read   int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.read += int64(n)
+		r.bar.Add(int64(n))
+	}
+	return n, err
+}
+
+// installFlags returns the pm install flags for the options
+func installFlags(options InstallOptions) []string {
+	var flags []string
+	if options.Replace {
+		flags = append(flags, "-r")
+	}
+	if options.Downgrade {
+		flags = append(flags, "-d")
+	}
+	if options.GrantPermissions {
+		flags = append(flags, "-g")
+	}
+	return flags
+}
+
+// pmSucceeded reports whether pm printed a success status. Failures are printed
+// as "Failure [REASON: details]", which parseInstallError understands.
+func pmSucceeded(output string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "Success") {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionError explains why an install session was interrupted
+func sessionError(ctx context.Context, err error, timeout time.Duration) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("installation timed out after %v: %w", timeout, context.DeadlineExceeded)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("installation cancelled: %w", context.Canceled)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+// GetMergedPackage returns one package merged across all enabled buckets. Unlike
+// the entries of GetMergedManifest it holds every version: the per-package index
+// of sharded buckets is fetched on demand. It returns nil if no bucket has the package.
+func (b *BucketManager) GetMergedPackage(packageID string) (*models.AppPackage, error) {
+	merged := &models.ManifestIndex{
+		Packages: make(map[string]*models.AppPackage),
+	}
+
+	for _, name := range b.config.OrderedEnabledBuckets() {
+		manifest, err := b.FetchManifest(name)
+		if err != nil {
+			fmt.Printf("Warning: failed to load bucket %s: %v\n", name, err)
+			continue
+		}
+
+		pkg, exists := manifest.Packages[packageID]
+		if !exists || pkg == nil {
+			continue
+		}
+
+		if pkg.Shard != nil {
+			full, err := b.FetchPackageShard(name, packageID, pkg.Shard)
+			if err != nil {
+				fmt.Printf("⚠️  Failed to load package index of %s from bucket '%s': %v (only the latest version is available)\n",
+					packageID, name, err)
+			} else {
+				pkg = full
+			}
+		}
+
+		bucketName := name
+		single := &models.ManifestIndex{Packages: map[string]*models.AppPackage{packageID: pkg}}
+		mergeBucketPackages(b.config, merged, bucketName, single, func(url string) string {
+			return b.resolveDownloadURL(bucketName, url)
+		})
+	}
+
+	selectMergedLatest(b.config, merged)
+
+	return merged.Packages[packageID], nil
+}
+
+// FetchPackageShard returns the full entry of a package from the per-package index
+// of a sharded bucket. The file is checked against the hash in the signed root
+// index, and cached under that hash, so an unchanged package is fetched only once.
+func (b *BucketManager) FetchPackageShard(bucketName, packageID string, shard *models.PackageShard) (*models.AppPackage, error) {
+	bucket, exists := b.config.Buckets[bucketName]
+	if !exists {
+		return nil, fmt.Errorf("bucket %s not found", bucketName)
+	}
+
+	cacheKey := fmt.Sprintf("shard_%s_%s", bucketName, shard.SHA256)
+
+	var pkg models.AppPackage
+	if found, err := b.cacheManager.Get(cacheKey, &pkg); err == nil && found {
+		return &pkg, nil
+	}
+
+	if shard.Size > maxManifestSize {
+		return nil, fmt.Errorf("package index is too large (%d bytes)", shard.Size)
+	}
+
+	data, err := b.readBucketFile(bucket, bucketName, shard.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if sha256Hex(data) != shard.SHA256 {
+		return nil, fmt.Errorf("%s does not match its hash in the manifest", shard.Path)
+	}
+
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", shard.Path, err)
+	}
+	if pkg.PackageID != packageID {
+		return nil, fmt.Errorf("%s describes %s instead of %s", shard.Path, pkg.PackageID, packageID)
+	}
+
+	if err := b.cacheManager.Set(cacheKey, &pkg, b.manifestCacheTTL()); err != nil {
+		fmt.Printf("⚠️  Failed to cache package index of %s: %v\n", packageID, err)
+	}
+
+	return &pkg, nil
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+// publishTestShard publishes the full entry of a package as its per-package index
+// and returns the shard pointer of the root index
+func publishTestShard(t *testing.T, tb *testBucket, pkg *models.AppPackage) *models.PackageShard {
+	t.Helper()
+
+	data, err := json.Marshal(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shard := &models.PackageShard{
+		Path:     "packages/" + pkg.PackageID + ".json",
+		Size:     int64(len(data)),
+		SHA256:   sha256Hex(data),
+		Versions: len(pkg.Versions),
+	}
+	tb.set(shard.Path, data)
+	return shard
+}
+
+// testShardedPackage returns a package with two versions
+func testShardedPackage() *models.AppPackage {
+	pkg := testManifest("", time.Now()).Packages["com.example.app"]
+	pkg.Versions["2.0"] = &models.AppVersion{Version: "2.0", VersionCode: 2, DownloadURL: "apks/com.example.app_2.apk"}
+	pkg.Latest = "2.0"
+	return pkg
+}
+
+func TestFetchPackageShard(t *testing.T) {
+	tb := newTestBucket(t)
+	shard := publishTestShard(t, tb, testShardedPackage())
+	b, _ := newTestBucketManager(t, tb.server.URL)
+
+	for i := 0; i < 2; i++ {
+		pkg, err := b.FetchPackageShard("test", "com.example.app", shard)
+		if err != nil {
+			t.Fatalf("FetchPackageShard: %v", err)
+		}
+		if len(pkg.Versions) != 2 {
+			t.Errorf("shard has %d versions, want 2", len(pkg.Versions))
+		}
+	}
+
+	// The second call is answered from the cache
+	if n := tb.requestCount(shard.Path); n != 1 {
+		t.Errorf("shard requested %d times, want 1", n)
+	}
+}
+
+func TestFetchPackageShardRejectsInvalidShards(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(tb *testBucket, shard *models.PackageShard)
+	}{
+		{"hash mismatch", func(tb *testBucket, shard *models.PackageShard) {
+			shard.SHA256 = sha256Hex([]byte("another index"))
+		}},
+		{"modified file", func(tb *testBucket, shard *models.PackageShard) {
+			tb.set(shard.Path, []byte(`{"package_id":"com.example.app","versions":{}}`))
+		}},
+		{"other package", func(tb *testBucket, shard *models.PackageShard) {
+			other := testShardedPackage()
+			other.PackageID = "com.example.other"
+			*shard = *publishTestShard(t, tb, other)
+		}},
+		{"path outside the bucket", func(tb *testBucket, shard *models.PackageShard) {
+			shard.Path = "../" + shard.Path
+		}},
+		{"too large", func(tb *testBucket, shard *models.PackageShard) {
+			shard.Size = maxManifestSize + 1
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := newTestBucket(t)
+			shard := publishTestShard(t, tb, testShardedPackage())
+			tt.modify(tb, shard)
+
+			b, _ := newTestBucketManager(t, tb.server.URL)
+			if _, err := b.FetchPackageShard("test", "com.example.app", shard); err == nil {
+				t.Error("FetchPackageShard accepted the shard")
+			}
+		})
+	}
+}
+
+func TestGetMergedPackageFetchesShard(t *testing.T) {
+	tb := newTestBucket(t)
+	manifest := testManifest("sharded", time.Now())
+	full := testShardedPackage()
+
+	// The root index only lists the latest version
+	root := *full
+	root.Versions = map[string]*models.AppVersion{"2.0": full.Versions["2.0"]}
+	root.Shard = publishTestShard(t, tb, full)
+	manifest.Packages[root.PackageID] = &root
+	tb.publish(t, manifest)
+
+	b, _ := newTestBucketManager(t, tb.server.URL)
+	pkg, err := b.GetMergedPackage("com.example.app")
+	if err != nil {
+		t.Fatalf("GetMergedPackage: %v", err)
+	}
+	if pkg == nil || len(pkg.Versions) != 2 {
+		t.Fatalf("merged package %+v, want both versions", pkg)
+	}
+
+	// Without its shard the package still offers the latest version
+	tb.set(root.Shard.Path, nil)
+	b, _ = newTestBucketManager(t, tb.server.URL)
+	pkg, err = b.GetMergedPackage("com.example.app")
+	if err != nil {
+		t.Fatalf("GetMergedPackage without the shard: %v", err)
+	}
+	if pkg == nil || len(pkg.Versions) != 1 {
+		t.Fatalf("merged package %+v, want the latest version only", pkg)
+	}
+	for _, version := range pkg.Versions {
+		if version.Version != "2.0" {
+			t.Errorf("merged version %s, want 2.0", version.Version)
+		}
+	}
+}
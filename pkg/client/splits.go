@@ -0,0 +1,499 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/huanfeng/apkhub/pkg/apk"
+)
+
+// DeviceSpec is the device configuration config splits are selected for
+type DeviceSpec struct {
+	SDK     int      // API level, 0 if unknown
+	ABIs    []string // Supported ABIs, preferred first
+	Density int      // Screen density in dpi, 0 if unknown
+	Locales []string // Configured locales, e.g. "en-US"
+}
+
+// SplitChoice records whether one APK of a split package is installed, and why
+type SplitChoice struct {
+	Path     string
+	Split    string // Split name, empty for the base APK
+	Selected bool
+	Reason   string
+}
+
+// InstallPlan describes what installing a package on a device would push
+type InstallPlan struct {
+	PackageID string
+	Device    *DeviceSpec
+	Splits    []SplitChoice
+	OBBFiles  []string // Device paths of the OBB files
+}
+
+// splitDimension is the device property a config split targets
+type splitDimension int
+
+const (
+	dimensionOther splitDimension = iota
+	dimensionABI
+	dimensionDensity
+	dimensionLanguage
+)
+
+// splitABIs maps the ABI qualifiers of split names to ABI names
+var splitABIs = map[string]string{
+	"armeabi":     "armeabi",
+	"armeabi_v7a": "armeabi-v7a",
+	"arm64_v8a":   "arm64-v8a",
+	"x86":         "x86",
+	"x86_64":      "x86_64",
+	"mips":        "mips",
+	"mips64":      "mips64",
+	"riscv64":     "riscv64",
+}
+
+// splitDensities maps the density qualifiers of split names to dpi
+var splitDensities = map[string]int{
+	"ldpi":    120,
+	"mdpi":    160,
+	"tvdpi":   213,
+	"hdpi":    240,
+	"xhdpi":   320,
+	"xxhdpi":  480,
+	"xxxhdpi": 640,
+}
+
+// splitTextureFormats are texture compression qualifiers, which look like
+// language codes but are not selected by device
+var splitTextureFormats = map[string]bool{
+	"3dc": true, "astc": true, "atc": true, "dxt1": true, "etc1_rgb8": true,
+	"etc2": true, "latc": true, "paletted": true, "pvrtc": true, "s3tc": true,
+}
+
+// splitLanguagePattern matches language qualifiers such as "en" or "fil"
+var splitLanguagePattern = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// legacyLanguageCodes maps the language codes Android keeps for compatibility
+var legacyLanguageCodes = map[string]string{
+	"iw": "he",
+	"in": "id",
+	"ji": "yi",
+}
+
+// classifyConfigSplit returns the dimension a config split qualifier targets
+func classifyConfigSplit(qualifier string) splitDimension {
+	switch {
+	case splitABIs[qualifier] != "":
+		return dimensionABI
+	case splitDensities[qualifier] != 0:
+		return dimensionDensity
+	case splitTextureFormats[qualifier]:
+		return dimensionOther
+	case splitLanguagePattern.MatchString(qualifier):
+		return dimensionLanguage
+	default:
+		return dimensionOther
+	}
+}
+
+// GetDeviceSpec queries the API level, ABIs, screen density and locales of a
+// device. Properties that cannot be read are left empty.
+func (a *ADBManager) GetDeviceSpec(deviceID string) *DeviceSpec {
+	spec := &DeviceSpec{}
+
+	if sdk, err := a.getDeviceProperty(deviceID, "ro.build.version.sdk"); err == nil {
+		spec.SDK, _ = strconv.Atoi(strings.TrimSpace(sdk))
+	}
+
+	if abiList, err := a.getDeviceProperty(deviceID, "ro.product.cpu.abilist"); err == nil {
+		for _, abi := range strings.Split(strings.TrimSpace(abiList), ",") {
+			if abi = strings.TrimSpace(abi); abi != "" {
+				spec.ABIs = append(spec.ABIs, abi)
+			}
+		}
+	}
+	if len(spec.ABIs) == 0 {
+		if abi, err := a.getDeviceABI(deviceID); err == nil && abi != "" {
+			spec.ABIs = []string{abi}
+		}
+	}
+
+	spec.Density = a.getDeviceDensity(deviceID)
+	spec.Locales = a.getDeviceLocales(deviceID)
+
+	return spec
+}
+
+// wmDensityPattern matches "Physical density: 420" and "Override density: 480"
+var wmDensityPattern = regexp.MustCompile(`(Physical|Override) density: (\d+)`)
+
+// getDeviceDensity returns the screen density in dpi, preferring a user override
+func (a *ADBManager) getDeviceDensity(deviceID string) int {
+	density := 0
+
+	if output, err := a.backend.Shell(deviceID, shellCommand("wm", "density")); err == nil {
+		for _, match := range wmDensityPattern.FindAllStringSubmatch(output, -1) {
+			value, _ := strconv.Atoi(match[2])
+			if match[1] == "Override" || density == 0 {
+				density = value
+			}
+		}
+	}
+
+	if density == 0 {
+		if output, err := a.getDeviceProperty(deviceID, "ro.sf.lcd_density"); err == nil {
+			density, _ = strconv.Atoi(strings.TrimSpace(output))
+		}
+	}
+
+	return density
+}
+
+// getDeviceLocales returns the configured locales, preferred first
+func (a *ADBManager) getDeviceLocales(deviceID string) []string {
+	var locales []string
+	seen := make(map[string]bool)
+	add := func(list string) {
+		for _, locale := range strings.Split(strings.TrimSpace(list), ",") {
+			locale = strings.TrimSpace(locale)
+			if locale == "" || locale == "null" || seen[locale] {
+				continue
+			}
+			seen[locale] = true
+			locales = append(locales, locale)
+		}
+	}
+
+	if output, err := a.backend.Shell(deviceID, shellCommand("settings", "get", "system", "system_locales")); err == nil {
+		add(output)
+	}
+	for _, property := range []string{"persist.sys.locale", "ro.product.locale"} {
+		if output, err := a.getDeviceProperty(deviceID, property); err == nil {
+			add(output)
+		}
+	}
+
+	return locales
+}
+
+// SelectSplits picks the APKs of a split package to install on a device, the way
+// bundletool does: the base APK and feature modules, and for each of them the
+// config split of the best matching ABI, the best matching screen density and
+// the languages of the device. Config splits of unknown kind are kept. It
+// returns an error, along with the choices, when no ABI split fits the device.
+func SelectSplits(apkPaths []string, spec *DeviceSpec) ([]SplitChoice, error) {
+	choices := make([]SplitChoice, len(apkPaths))
+	qualifiers := make([]string, len(apkPaths))
+
+	// Config splits by the split they configure, then by dimension
+	groups := make(map[string]map[splitDimension][]int)
+
+	for i, apkPath := range apkPaths {
+		info, err := apk.ReadSplitInfo(apkPath)
+		if err != nil {
+			info = apk.SplitInfoFromFileName(apkPath)
+		}
+
+		choices[i] = SplitChoice{Path: apkPath, Split: info.Name}
+
+		switch {
+		case info.IsBase():
+			choices[i].Selected = true
+			choices[i].Reason = "base APK"
+		case info.IsFeature:
+			choices[i].Selected = true
+			choices[i].Reason = "feature module"
+		default:
+			qualifiers[i] = info.ConfigQualifier()
+			dimension := classifyConfigSplit(qualifiers[i])
+			if dimension == dimensionOther {
+				choices[i].Selected = true
+				choices[i].Reason = fmt.Sprintf("config split %q is not selected by device, kept", qualifiers[i])
+				continue
+			}
+			if groups[info.ConfigForSplit] == nil {
+				groups[info.ConfigForSplit] = make(map[splitDimension][]int)
+			}
+			groups[info.ConfigForSplit][dimension] = append(groups[info.ConfigForSplit][dimension], i)
+		}
+	}
+
+	var problems []string
+	for group, dimensions := range groups {
+		if err := selectABISplit(choices, qualifiers, dimensions[dimensionABI], spec); err != nil {
+			if group != "" {
+				err = fmt.Errorf("feature %s: %w", group, err)
+			}
+			problems = append(problems, err.Error())
+		}
+		selectDensitySplit(choices, qualifiers, dimensions[dimensionDensity], spec)
+		selectLanguageSplits(choices, qualifiers, dimensions[dimensionLanguage], spec)
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return choices, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return choices, nil
+}
+
+// selectABISplit selects the split of the most preferred device ABI
+func selectABISplit(choices []SplitChoice, qualifiers []string, candidates []int, spec *DeviceSpec) error {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	if len(spec.ABIs) == 0 {
+		for _, i := range candidates {
+			choices[i].Selected = true
+			choices[i].Reason = "device ABIs unknown, kept"
+		}
+		return nil
+	}
+
+	best := -1
+	bestRank := len(spec.ABIs)
+	for _, i := range candidates {
+		for rank, abi := range spec.ABIs {
+			if abi == splitABIs[qualifiers[i]] && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+	}
+
+	deviceABIs := strings.Join(spec.ABIs, ", ")
+	for _, i := range candidates {
+		abi := splitABIs[qualifiers[i]]
+		switch {
+		case i == best && bestRank == 0:
+			choices[i].Selected = true
+			choices[i].Reason = fmt.Sprintf("ABI %s is the device's primary ABI", abi)
+		case i == best:
+			choices[i].Selected = true
+			choices[i].Reason = fmt.Sprintf("ABI %s is the best supported ABI (device: %s)", abi, deviceABIs)
+		case best >= 0 && containsString(spec.ABIs, abi):
+			choices[i].Reason = fmt.Sprintf("ABI %s is supported, but %s is preferred", abi, splitABIs[qualifiers[best]])
+		default:
+			choices[i].Reason = fmt.Sprintf("ABI %s is not supported by the device (%s)", abi, deviceABIs)
+		}
+	}
+
+	if best < 0 {
+		return fmt.Errorf("no ABI split matches the device ABIs (%s)", deviceABIs)
+	}
+
+	return nil
+}
+
+// selectDensitySplit selects the split of the lowest density at least the
+// device density, or of the highest density when all are lower
+func selectDensitySplit(choices []SplitChoice, qualifiers []string, candidates []int, spec *DeviceSpec) {
+	if len(candidates) == 0 {
+		return
+	}
+
+	if spec.Density <= 0 {
+		for _, i := range candidates {
+			choices[i].Selected = true
+			choices[i].Reason = "device density unknown, kept"
+		}
+		return
+	}
+
+	best := -1
+	for _, i := range candidates {
+		density := splitDensities[qualifiers[i]]
+		if best < 0 {
+			best = i
+			continue
+		}
+		bestDensity := splitDensities[qualifiers[best]]
+		switch {
+		case density >= spec.Density && (bestDensity < spec.Density || density < bestDensity):
+			best = i
+		case density < spec.Density && bestDensity < spec.Density && density > bestDensity:
+			best = i
+		}
+	}
+
+	for _, i := range candidates {
+		if i == best {
+			choices[i].Selected = true
+			choices[i].Reason = fmt.Sprintf("density %s (%ddpi) is the best match for the device's %ddpi",
+				qualifiers[i], splitDensities[qualifiers[i]], spec.Density)
+		} else {
+			choices[i].Reason = fmt.Sprintf("density %s: %s is a better match for %ddpi",
+				qualifiers[i], qualifiers[best], spec.Density)
+		}
+	}
+}
+
+// selectLanguageSplits selects the splits of every language the device uses
+func selectLanguageSplits(choices []SplitChoice, qualifiers []string, candidates []int, spec *DeviceSpec) {
+	if len(candidates) == 0 {
+		return
+	}
+
+	if len(spec.Locales) == 0 {
+		for _, i := range candidates {
+			choices[i].Selected = true
+			choices[i].Reason = "device locales unknown, kept"
+		}
+		return
+	}
+
+	languages := make(map[string]string, len(spec.Locales))
+	for _, locale := range spec.Locales {
+		language := locale
+		if i := strings.IndexAny(locale, "-_"); i >= 0 {
+			language = locale[:i]
+		}
+		language = normalizeLanguage(language)
+		if _, exists := languages[language]; !exists {
+			languages[language] = locale
+		}
+	}
+
+	for _, i := range candidates {
+		if locale, exists := languages[normalizeLanguage(qualifiers[i])]; exists {
+			choices[i].Selected = true
+			choices[i].Reason = fmt.Sprintf("language %s matches device locale %s", qualifiers[i], locale)
+		} else {
+			choices[i].Reason = fmt.Sprintf("language %s is not used by the device (%s)",
+				qualifiers[i], strings.Join(spec.Locales, ", "))
+		}
+	}
+}
+
+// normalizeLanguage lowercases a language code and maps legacy codes
+func normalizeLanguage(language string) string {
+	language = strings.ToLower(language)
+	if current, exists := legacyLanguageCodes[language]; exists {
+		return current
+	}
+	return language
+}
+
+// PlanInstall works out what installing a file on a device would push, without
+// installing anything: the APKs selected from XAPK/APKM packages and APK sets
+// and why, and the OBB files
+func (a *ADBManager) PlanInstall(apkPath string, deviceID string) (*InstallPlan, error) {
+	plan := &InstallPlan{
+		Device: a.GetDeviceSpec(deviceID),
+	}
+
+	if apk.IsAppBundleFile(apkPath) {
+		return nil, fmt.Errorf("app bundles (.aab) cannot be installed directly, build an APK set with bundletool build-apks first")
+	}
+
+	if apk.IsAPKSetFile(apkPath) {
+		tempDir, err := os.MkdirTemp("", "apks_plan_*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
+
+		plan.PackageID, plan.Splits, err = selectAPKSetSplits(apkPath, tempDir, plan.Device)
+		for i := range plan.Splits {
+			// The extraction directory is removed on return
+			plan.Splits[i].Path = filepath.Base(plan.Splits[i].Path)
+		}
+		return plan, err
+	}
+
+	if !isXAPKFile(apkPath) {
+		if info, err := apk.ReadSplitInfo(apkPath); err == nil {
+			plan.PackageID = info.PackageID
+		}
+		plan.Splits = []SplitChoice{{Path: apkPath, Selected: true, Reason: "single APK"}}
+		return plan, nil
+	}
+
+	tempDir, err := os.MkdirTemp("", "xapk_plan_*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	parser := apk.NewXAPKParser(tempDir)
+	xapkInfo, err := a.parseXAPKQuietly(parser, apkPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XAPK: %w", err)
+	}
+	if err := parser.ExtractXAPK(apkPath, tempDir); err != nil {
+		return nil, fmt.Errorf("failed to extract XAPK: %w", err)
+	}
+	plan.PackageID = xapkInfo.PackageID
+
+	obbs, err := xapkOBBTransfers(xapkInfo, tempDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, obb := range obbs {
+		plan.OBBFiles = append(plan.OBBFiles, obb.remotePath)
+	}
+
+	plan.Splits, err = selectXAPKSplits(xapkInfo, tempDir, plan.Device)
+	for i := range plan.Splits {
+		// The extraction directory is removed on return
+		plan.Splits[i].Path = filepath.Base(plan.Splits[i].Path)
+	}
+
+	return plan, err
+}
+
+// selectXAPKSplits selects the extracted APKs of an XAPK to install on a device
+func selectXAPKSplits(xapkInfo *apk.XAPKInfo, tempDir string, spec *DeviceSpec) ([]SplitChoice, error) {
+	var apkPaths []string
+	for _, apkFile := range xapkInfo.APKFiles {
+		apkPath := filepath.Join(tempDir, apkFile)
+		if _, err := os.Stat(apkPath); err != nil {
+			continue
+		}
+		apkPaths = append(apkPaths, apkPath)
+	}
+
+	return SelectSplits(apkPaths, spec)
+}
+
+// selectAPKSetSplits extracts the variant of an APK set that fits the device and
+// selects its splits. It returns the package ID along with the choices.
+func selectAPKSetSplits(apksPath, tempDir string, spec *DeviceSpec) (string, []SplitChoice, error) {
+	selection, err := apk.ExtractAPKSet(apksPath, tempDir, spec.SDK, spec.ABIs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if selection.Standalone {
+		var choices []SplitChoice
+		for _, apkPath := range selection.APKs {
+			choices = append(choices, SplitChoice{
+				Path:     apkPath,
+				Selected: true,
+				Reason:   "standalone APK for the device's SDK version and ABI",
+			})
+		}
+		return selection.PackageID, choices, nil
+	}
+
+	choices, err := SelectSplits(selection.APKs, spec)
+	return selection.PackageID, choices, err
+}
+
+// selectedSplitPaths returns the paths of the selected APKs
+func selectedSplitPaths(choices []SplitChoice) []string {
+	var paths []string
+	for _, choice := range choices {
+		if choice.Selected {
+			paths = append(paths, choice.Path)
+		}
+	}
+	return paths
+}
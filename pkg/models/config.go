@@ -13,10 +13,19 @@ type RepositoryConfig struct {
 	BaseURL               string   `mapstructure:"base_url" json:"base_url"`
 	KeepVersions          int      `mapstructure:"keep_versions" json:"keep_versions"`                     // 0 = keep all
 	SignatureHandling     string   `mapstructure:"signature_handling" json:"signature_handling"`           // "mark", "separate", "reject"
+	SigningKey            string   `mapstructure:"signing_key" json:"signing_key"`                         // Path to Ed25519 private key (PEM) used to sign the manifest
 	SigningKeyFingerprint string   `mapstructure:"signing_key_fingerprint" json:"signing_key_fingerprint"` // Fingerprint used when signing manifest
+	PreviousSigningKeys   []string `mapstructure:"previous_signing_keys" json:"previous_signing_keys"`     // Old keys still co-signing during a rotation
 	Signer                string   `mapstructure:"signer" json:"signer"`                                   // Human-readable signer name
 	TrustedKeys           []string `mapstructure:"trusted_keys" json:"trusted_keys"`
-	SignaturePolicy       string   `mapstructure:"signature_policy" json:"signature_policy"` // "strict" or "lenient"
+	SignaturePolicy       string   `mapstructure:"signature_policy" json:"signature_policy"`         // "strict" or "lenient"
+	ManifestExpiryDays    int      `mapstructure:"manifest_expiry_days" json:"manifest_expiry_days"` // 0 = manifest never expires
+	ManifestCompression   []string `mapstructure:"manifest_compression" json:"manifest_compression"` // "gzip", "zstd": compressed copies published next to the manifest
+	ManifestDeltas        int      `mapstructure:"manifest_deltas" json:"manifest_deltas"`           // Number of delta generations to keep, 0 = no deltas
+	ShardedIndex          bool     `mapstructure:"sharded_index" json:"sharded_index"`               // Publish a root index plus one file per package under index/
+	IconMask              string   `mapstructure:"icon_mask" json:"icon_mask"`                       // "circle", "squircle", "rounded-square", "none": shape of adaptive icons
+	IconSizes             []int    `mapstructure:"icon_sizes" json:"icon_sizes"`                     // Sizes in pixels of the icons written to infos/ next to the standard one
+	Policy                string   `mapstructure:"policy" json:"policy"`                             // Path to the admission policy (YAML), relative to the repository; empty = admit everything
 }
 
 // ScanningConfig contains scanning-related configuration
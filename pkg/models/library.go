@@ -0,0 +1,41 @@
+package models
+
+// Library categories, after the Exodus Privacy tracker categories plus push
+// messaging
+const (
+	LibraryAdvertisement  = "advertisement"
+	LibraryAnalytics      = "analytics"
+	LibraryCrashReporting = "crash-reporting"
+	LibraryIdentification = "identification"
+	LibraryLocation       = "location"
+	LibraryProfiling      = "profiling"
+	LibraryPush           = "push"
+)
+
+// LibraryCategories lists the library categories
+var LibraryCategories = []string{
+	LibraryAdvertisement,
+	LibraryAnalytics,
+	LibraryCrashReporting,
+	LibraryIdentification,
+	LibraryLocation,
+	LibraryProfiling,
+	LibraryPush,
+}
+
+// Library is an SDK embedded in an APK, detected from the class names of its code
+type Library struct {
+	Name       string   `json:"name"`
+	Categories []string `json:"categories,omitempty"`
+	Website    string   `json:"website,omitempty"`
+}
+
+// HasCategory reports whether the library belongs to a category
+func (l Library) HasCategory(category string) bool {
+	for _, c := range l.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
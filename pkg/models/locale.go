@@ -0,0 +1,74 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// LocalizedName picks the value of a multi-language map (keyed "default" and by
+// BCP-47 tag) for the first preferred language that has one: the exact tag, its
+// base language, then any region of the language. Without a match it returns
+// the default entry, English, or else the first entry.
+func LocalizedName(names map[string]string, languages []string) string {
+	lookup := make(map[string]string, len(names))
+	for key, name := range names {
+		if name != "" {
+			lookup[normalizeLanguageTag(key)] = name
+		}
+	}
+	if len(lookup) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(lookup))
+	for key := range lookup {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, language := range languages {
+		tag := normalizeLanguageTag(language)
+		if tag == "" || tag == "c" || tag == "posix" {
+			continue
+		}
+		if name, exists := lookup[tag]; exists {
+			return name
+		}
+		base, _, _ := strings.Cut(tag, "-")
+		if name, exists := lookup[base]; exists {
+			return name
+		}
+		for _, key := range keys {
+			if strings.HasPrefix(key, base+"-") {
+				return lookup[key]
+			}
+		}
+	}
+
+	for _, key := range []string{"default", "en", "en-us"} {
+		if name, exists := lookup[key]; exists {
+			return name
+		}
+	}
+	return lookup[keys[0]]
+}
+
+// normalizeLanguageTag lowercases a language tag and converts locale
+// environment values ("zh_CN.UTF-8") and Android qualifiers ("zh-rCN",
+// "b+sr+Latn") to BCP-47 form
+func normalizeLanguageTag(tag string) string {
+	tag = strings.TrimSpace(tag)
+	if i := strings.IndexAny(tag, ".@"); i >= 0 {
+		tag = tag[:i]
+	}
+	tag = strings.TrimPrefix(tag, "b+")
+	tag = strings.NewReplacer("_", "-", "+", "-").Replace(tag)
+
+	parts := strings.Split(strings.ToLower(tag), "-")
+	for i, part := range parts {
+		if i > 0 && len(part) == 3 && part[0] == 'r' {
+			parts[i] = part[1:]
+		}
+	}
+	return strings.Join(parts, "-")
+}
@@ -0,0 +1,123 @@
+package models
+
+import "sort"
+
+// ManifestInfo describes the components and application flags declared in
+// AndroidManifest.xml
+type ManifestInfo struct {
+	LauncherActivity      string      `json:"launcher_activity,omitempty"`
+	SharedUserID          string      `json:"shared_user_id,omitempty"`
+	InstallLocation       string      `json:"install_location,omitempty"` // auto, internalOnly or preferExternal
+	Debuggable            bool        `json:"debuggable"`
+	AllowBackup           bool        `json:"allow_backup"`
+	UsesCleartextTraffic  bool        `json:"uses_cleartext_traffic"`            // Effective value, defaults by target SDK
+	NetworkSecurityConfig string      `json:"network_security_config,omitempty"` // Resource path of the config
+	LargeHeap             bool        `json:"large_heap"`
+	Activities            []Component `json:"activities,omitempty"` // Including activity aliases
+	Services              []Component `json:"services,omitempty"`
+	Receivers             []Component `json:"receivers,omitempty"`
+	Providers             []Component `json:"providers,omitempty"`
+}
+
+// Component is an activity, service, broadcast receiver or content provider
+type Component struct {
+	Name           string         `json:"name"`
+	Exported       bool           `json:"exported"`                  // Effective value, defaults by intent filters
+	Permission     string         `json:"permission,omitempty"`      // Permission callers need
+	TargetActivity string         `json:"target_activity,omitempty"` // Activity aliases only
+	Authorities    []string       `json:"authorities,omitempty"`     // Content providers only
+	IntentFilters  []IntentFilter `json:"intent_filters,omitempty"`
+}
+
+// IntentFilter is an intent filter of a component
+type IntentFilter struct {
+	Actions    []string     `json:"actions,omitempty"`
+	Categories []string     `json:"categories,omitempty"`
+	Data       []IntentData `json:"data,omitempty"`
+	AutoVerify bool         `json:"auto_verify,omitempty"` // Verified app links
+}
+
+// IntentData is a <data> element of an intent filter
+type IntentData struct {
+	Scheme      string `json:"scheme,omitempty"`
+	Host        string `json:"host,omitempty"`
+	Port        string `json:"port,omitempty"`
+	Path        string `json:"path,omitempty"`
+	PathPrefix  string `json:"path_prefix,omitempty"`
+	PathPattern string `json:"path_pattern,omitempty"`
+	MimeType    string `json:"mime_type,omitempty"`
+}
+
+// IsDeepLink reports whether the filter lets browsers open the component
+// from a URI: it handles VIEW intents in the BROWSABLE category
+func (f *IntentFilter) IsDeepLink() bool {
+	return containsValue(f.Actions, "android.intent.action.VIEW") &&
+		containsValue(f.Categories, "android.intent.category.BROWSABLE")
+}
+
+// DeepLinks returns the "scheme://host" URIs the activities handle, sorted.
+// Within a filter every scheme combines with every host.
+func (m *ManifestInfo) DeepLinks() []string {
+	seen := make(map[string]bool)
+	var links []string
+
+	for _, activity := range m.Activities {
+		for _, filter := range activity.IntentFilters {
+			if !filter.IsDeepLink() {
+				continue
+			}
+
+			var schemes, hosts []string
+			for _, data := range filter.Data {
+				if data.Scheme != "" {
+					schemes = append(schemes, data.Scheme)
+				}
+				if data.Host != "" {
+					hosts = append(hosts, data.Host)
+				}
+			}
+
+			for _, scheme := range schemes {
+				if len(hosts) == 0 {
+					if link := scheme + ":"; !seen[link] {
+						seen[link] = true
+						links = append(links, link)
+					}
+					continue
+				}
+				for _, host := range hosts {
+					if link := scheme + "://" + host; !seen[link] {
+						seen[link] = true
+						links = append(links, link)
+					}
+				}
+			}
+		}
+	}
+
+	sort.Strings(links)
+	return links
+}
+
+// ExportedComponents returns the names of the components other apps can start
+func (m *ManifestInfo) ExportedComponents() []string {
+	var names []string
+	for _, group := range [][]Component{m.Activities, m.Services, m.Receivers, m.Providers} {
+		for _, component := range group {
+			if component.Exported {
+				names = append(names, component.Name)
+			}
+		}
+	}
+	return names
+}
+
+// containsValue reports whether values contains value
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -19,26 +19,41 @@ type AppPackage struct {
 	Category    string                 `json:"category,omitempty"`
 	Description map[string]string      `json:"description,omitempty"` // Multi-language support
 	Versions    map[string]*AppVersion `json:"versions"`
-	Latest      string                 `json:"latest"` // Latest version string
+	Latest      string                 `json:"latest"`          // Latest version string
+	Shard       *PackageShard          `json:"shard,omitempty"` // Sharded root index only: Versions holds just the latest version
+}
+
+// PackageShard points to the per-package index file holding all versions of a
+// package in a sharded repository
+type PackageShard struct {
+	Path     string `json:"path"` // Relative to the repository root
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	Versions int    `json:"versions"` // Number of versions in the shard
 }
 
 // AppVersion represents a specific version of an application
 type AppVersion struct {
-	Version          string         `json:"version"`
-	VersionCode      int64          `json:"version_code"`
-	MinSDK           int            `json:"min_sdk"`
-	TargetSDK        int            `json:"target_sdk"`
-	Size             int64          `json:"size"`
-	SHA256           string         `json:"sha256"`
-	SignatureInfo    *SignatureInfo `json:"signature"`
-	DownloadURL      string         `json:"download_url"`
-	ReleaseDate      time.Time      `json:"release_date"`
-	Permissions      []string       `json:"permissions,omitempty"`
-	Features         []string       `json:"features,omitempty"`
-	ABIs             []string       `json:"abis,omitempty"`
-	ScreenDPIs       []string       `json:"screen_dpis,omitempty"`
-	Locales          []string       `json:"locales,omitempty"`
-	SignatureVariant string         `json:"signature_variant,omitempty"` // For different signatures
+	Version               string                 `json:"version"`
+	VersionCode           int64                  `json:"version_code"`
+	MinSDK                int                    `json:"min_sdk"`
+	TargetSDK             int                    `json:"target_sdk"`
+	Size                  int64                  `json:"size"`
+	SHA256                string                 `json:"sha256"`
+	SignatureInfo         *SignatureInfo         `json:"signature"`
+	DownloadURL           string                 `json:"download_url"`
+	ReleaseDate           time.Time              `json:"release_date"`
+	Permissions           []string               `json:"permissions,omitempty"`
+	RiskScore             *int                   `json:"risk_score,omitempty"` // Permission risk, 0 to 100; absent from manifests published before scoring
+	Features              []string               `json:"features,omitempty"`
+	ABIs                  []string               `json:"abis,omitempty"`
+	ScreenDPIs            []string               `json:"screen_dpis,omitempty"`
+	Locales               []string               `json:"locales,omitempty"`
+	SignatureVariant      string                 `json:"signature_variant,omitempty"` // For different signatures
+	SignatureVerification *SignatureVerification `json:"signature_verification,omitempty"`
+	Manifest              *ManifestInfo          `json:"manifest,omitempty"`
+	Libraries             []Library              `json:"libraries,omitempty"`
+	Bucket                string                 `json:"bucket,omitempty"` // Source bucket, set when merging buckets on the client
 }
 
 // SignatureInfo contains APK signature information
@@ -49,3 +64,18 @@ type SignatureInfo struct {
 	Issuer  string `json:"issuer,omitempty"`
 	Subject string `json:"subject,omitempty"`
 }
+
+// SignatureVerification contains the result of verifying APK signatures
+type SignatureVerification struct {
+	Verified   bool                 `json:"verified"`          // All present schemes verified
+	Schemes    []SchemeVerification `json:"schemes"`           // Per-scheme results
+	Lineage    []string             `json:"lineage,omitempty"` // v3 signer lineage (SHA256, oldest first)
+	VerifiedAt time.Time            `json:"verified_at"`
+}
+
+// SchemeVerification contains the verification result of one signature scheme
+type SchemeVerification struct {
+	Scheme string `json:"scheme"` // v1, v2, v3, v3.1
+	Valid  bool   `json:"valid"`
+	Error  string `json:"error,omitempty"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// PolicyAction is what happens to an APK that breaks a policy rule
+type PolicyAction string
+
+const (
+	PolicyWarn       PolicyAction = "warn"       // Admit the APK and print the violation
+	PolicyQuarantine PolicyAction = "quarantine" // Keep the APK in quarantine/ instead of the repository
+	PolicyReject     PolicyAction = "reject"     // Refuse the APK
+)
+
+// Policy checks
+const (
+	PolicyCheckDebuggable           = "debuggable"            // No debuggable builds
+	PolicyCheckMinTargetSDK         = "min_target_sdk"        // Target SDK at least target_sdk
+	PolicyCheckForbiddenPermissions = "forbidden_permissions" // None of permissions requested
+	PolicyCheckAllowedSigners       = "allowed_signers"       // Signer SHA256 in signers
+	PolicyCheckMaxSize              = "max_size"              // File size at most max_size_mb
+	PolicyCheckCleartextTraffic     = "cleartext_traffic"     // No cleartext network traffic
+)
+
+// PolicyChecks lists the policy checks
+var PolicyChecks = []string{
+	PolicyCheckDebuggable,
+	PolicyCheckMinTargetSDK,
+	PolicyCheckForbiddenPermissions,
+	PolicyCheckAllowedSigners,
+	PolicyCheckMaxSize,
+	PolicyCheckCleartextTraffic,
+}
+
+// Policy is the set of rules an APK must pass to be admitted to the repository,
+// read from the file repository.policy points to
+type Policy struct {
+	Rules []PolicyRule `yaml:"rules" json:"rules"`
+}
+
+// PolicyRule is one admission rule. Only the parameter of its check is used.
+type PolicyRule struct {
+	Name        string       `yaml:"name" json:"name"` // Defaults to the check
+	Check       string       `yaml:"check" json:"check"`
+	Action      PolicyAction `yaml:"action" json:"action"` // Defaults to reject
+	TargetSDK   int          `yaml:"target_sdk,omitempty" json:"target_sdk,omitempty"`
+	Permissions []string     `yaml:"permissions,omitempty" json:"permissions,omitempty"`
+	Signers     []string     `yaml:"signers,omitempty" json:"signers,omitempty"` // Certificate SHA256 fingerprints
+	MaxSizeMB   float64      `yaml:"max_size_mb,omitempty" json:"max_size_mb,omitempty"`
+}
+
+// PolicyViolation is a rule an APK breaks
+type PolicyViolation struct {
+	Rule    string       `json:"rule"`
+	Check   string       `json:"check"`
+	Action  PolicyAction `json:"action"`
+	Found   string       `json:"found,omitempty"`   // Offending value: target SDK, permissions, signer or size
+	Limit   string       `json:"limit,omitempty"`   // Value the rule requires
+	Unknown bool         `json:"unknown,omitempty"` // The APK info lacks the data the check needs
+}
+
+// QuarantineRecord is written to quarantine/ next to a quarantined APK
+type QuarantineRecord struct {
+	APKInfo       *APKInfo          `json:"apk_info"`
+	Violations    []PolicyViolation `json:"violations"`
+	QuarantinedAt time.Time         `json:"quarantined_at"`
+}
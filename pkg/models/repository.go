@@ -4,33 +4,43 @@ import "time"
 
 // RepositoryLayout defines the standard repository directory structure
 type RepositoryLayout struct {
-	RootDir      string
-	APKsDir      string // apks/
-	InfosDir     string // infos/
-	ManifestFile string // apkhub_manifest.json
+	RootDir       string
+	APKsDir       string // apks/
+	InfosDir      string // infos/
+	ManifestFile  string // apkhub_manifest.json
+	DeltasDir     string // deltas/
+	IndexDir      string // index/ (per-package files of a sharded manifest)
+	QuarantineDir string // quarantine/ (APKs held back by the admission policy)
 }
 
+// ManifestSignatureSuffix is appended to the manifest name for its detached signature
+const ManifestSignatureSuffix = ".sig"
+
 // APKInfo represents individual APK information stored in infos/ directory
 type APKInfo struct {
-	PackageID     string            `json:"package_id"`
-	AppName       map[string]string `json:"app_name"`
-	Version       string            `json:"version"`
-	VersionCode   int64             `json:"version_code"`
-	MinSDK        int               `json:"min_sdk"`
-	TargetSDK     int               `json:"target_sdk"`
-	Size          int64             `json:"size"`
-	SHA256        string            `json:"sha256"`
-	SignatureInfo *SignatureInfo    `json:"signature"`
-	Permissions   []string          `json:"permissions,omitempty"`
-	Features      []string          `json:"features,omitempty"`
-	ABIs          []string          `json:"abis,omitempty"`
-	AddedAt       time.Time         `json:"added_at"`
-	UpdatedAt     time.Time         `json:"updated_at"`
-	OriginalName  string            `json:"original_name"`
-	FileName      string            `json:"file_name"`           // Normalized filename
-	FilePath      string            `json:"file_path"`           // Relative path in apks/
-	InfoPath      string            `json:"info_path"`           // Relative path in infos/
-	IconPath      string            `json:"icon_path,omitempty"` // Relative path to icon in infos/
+	PackageID             string                 `json:"package_id"`
+	AppName               map[string]string      `json:"app_name"`
+	Version               string                 `json:"version"`
+	VersionCode           int64                  `json:"version_code"`
+	MinSDK                int                    `json:"min_sdk"`
+	TargetSDK             int                    `json:"target_sdk"`
+	Size                  int64                  `json:"size"`
+	SHA256                string                 `json:"sha256"`
+	SignatureInfo         *SignatureInfo         `json:"signature"`
+	SignatureVerification *SignatureVerification `json:"signature_verification,omitempty"`
+	Permissions           []string               `json:"permissions,omitempty"`
+	Features              []string               `json:"features,omitempty"`
+	ABIs                  []string               `json:"abis,omitempty"`
+	Manifest              *ManifestInfo          `json:"manifest,omitempty"`
+	Libraries             []Library              `json:"libraries,omitempty"`
+	AddedAt               time.Time              `json:"added_at"`
+	UpdatedAt             time.Time              `json:"updated_at"`
+	OriginalName          string                 `json:"original_name"`
+	FileName              string                 `json:"file_name"`           // Normalized filename
+	FilePath              string                 `json:"file_path"`           // Relative path in apks/
+	InfoPath              string                 `json:"info_path"`           // Relative path in infos/
+	IconPath              string                 `json:"icon_path,omitempty"` // Relative path to icon in infos/
+	Icons                 map[string]string      `json:"icons,omitempty"`     // Relative paths to the icon in infos/, by size in pixels
 }
 
 // ManifestIndex is the main index file (apkhub_manifest.json)
@@ -39,12 +49,43 @@ type ManifestIndex struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
 	UpdatedAt   time.Time              `json:"updated_at"`
+	Expires     *time.Time             `json:"expires,omitempty"`    // Clients refuse the manifest after this time
+	Generation  int64                  `json:"generation,omitempty"` // Incremented every time the manifest is published
+	Deltas      string                 `json:"deltas,omitempty"`     // Path of the delta index when deltas are published
 	TotalAPKs   int                    `json:"total_apks"`
 	TotalSize   int64                  `json:"total_size"`
 	Packages    map[string]*AppPackage `json:"packages"`
 	Signature   *ManifestSignature     `json:"signature,omitempty"`
 }
 
+// ManifestDelta lists the package changes between two consecutive manifest generations
+type ManifestDelta struct {
+	FromGeneration int64                  `json:"from_generation"`
+	ToGeneration   int64                  `json:"to_generation"`
+	BaseSHA256     string                 `json:"base_sha256"`   // SHA256 of the manifest bytes the delta applies to
+	ResultSHA256   string                 `json:"result_sha256"` // SHA256 of the manifest bytes after applying it
+	Header         *ManifestIndex         `json:"header"`        // New manifest without packages
+	Added          map[string]*AppPackage `json:"added,omitempty"`
+	Changed        map[string]*AppPackage `json:"changed,omitempty"` // Full entries of changed packages
+	Removed        []string               `json:"removed,omitempty"`
+}
+
+// ManifestDeltaIndex lists the published deltas (deltas/index.json)
+type ManifestDeltaIndex struct {
+	Generation   int64              `json:"generation"`    // Generation of the current manifest
+	ManifestSize int64              `json:"manifest_size"` // Bytes of the current apkhub_manifest.json
+	Deltas       []ManifestDeltaRef `json:"deltas"`        // Oldest first
+}
+
+// ManifestDeltaRef points to one published delta
+type ManifestDeltaRef struct {
+	FromGeneration int64  `json:"from_generation"`
+	ToGeneration   int64  `json:"to_generation"`
+	Path           string `json:"path"` // Relative to the repository root
+	Size           int64  `json:"size"`
+	SHA256         string `json:"sha256"`
+}
+
 // ManifestSignature captures the signing metadata for a manifest index
 type ManifestSignature struct {
 	PublicKeyFingerprint string    `json:"public_key_fingerprint,omitempty"`
@@ -52,12 +93,30 @@ type ManifestSignature struct {
 	Signer               string    `json:"signer,omitempty"`
 }
 
+// ManifestSignatureFile is the detached signature published next to the manifest
+type ManifestSignatureFile struct {
+	Version    int                 `json:"version"`
+	Signatures []DetachedSignature `json:"signatures"`
+}
+
+// DetachedSignature is a signature over the exact manifest bytes
+type DetachedSignature struct {
+	Algorithm      string    `json:"algorithm"`       // "ed25519"
+	PublicKey      string    `json:"public_key"`      // Base64 encoded raw public key
+	KeyFingerprint string    `json:"key_fingerprint"` // SHA256 of the raw public key
+	Signature      string    `json:"signature"`       // Base64 encoded signature
+	SignedAt       time.Time `json:"signed_at"`
+}
+
 // NewRepositoryLayout creates a new repository layout structure
 func NewRepositoryLayout(rootDir string) *RepositoryLayout {
 	return &RepositoryLayout{
-		RootDir:      rootDir,
-		APKsDir:      "apks",
-		InfosDir:     "infos",
-		ManifestFile: "apkhub_manifest.json",
+		RootDir:       rootDir,
+		APKsDir:       "apks",
+		InfosDir:      "infos",
+		ManifestFile:  "apkhub_manifest.json",
+		DeltasDir:     "deltas",
+		IndexDir:      "index",
+		QuarantineDir: "quarantine",
 	}
 }
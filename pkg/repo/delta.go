@@ -0,0 +1,206 @@
+package repo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+// deltaIndexFile is the name of the delta index inside the deltas directory
+const deltaIndexFile = "index.json"
+
+// loadPublishedManifest returns the raw bytes and parsed form of the currently
+// published manifest, or nils if there is none
+func loadPublishedManifest(manifestPath string) ([]byte, *models.ManifestIndex) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, nil
+	}
+
+	var manifest models.ManifestIndex
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil
+	}
+
+	return data, &manifest
+}
+
+// DiffManifests computes the delta that turns previous into current. Hashes are
+// left for the caller, who has the exact published bytes.
+func DiffManifests(previous, current *models.ManifestIndex) (*models.ManifestDelta, error) {
+	header := *current
+	header.Packages = nil
+
+	delta := &models.ManifestDelta{
+		FromGeneration: previous.Generation,
+		ToGeneration:   current.Generation,
+		Header:         &header,
+		Added:          make(map[string]*models.AppPackage),
+		Changed:        make(map[string]*models.AppPackage),
+	}
+
+	for packageID, pkg := range current.Packages {
+		old, exists := previous.Packages[packageID]
+		if !exists {
+			delta.Added[packageID] = pkg
+			continue
+		}
+
+		same, err := samePackage(old, pkg)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			delta.Changed[packageID] = pkg
+		}
+	}
+
+	for packageID := range previous.Packages {
+		if _, exists := current.Packages[packageID]; !exists {
+			delta.Removed = append(delta.Removed, packageID)
+		}
+	}
+	sort.Strings(delta.Removed)
+
+	return delta, nil
+}
+
+// samePackage reports whether two package entries serialize identically
+func samePackage(a, b *models.AppPackage) (bool, error) {
+	aData, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	bData, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(aData, bData), nil
+}
+
+// writeManifestDelta publishes the delta from the previous manifest generation and
+// keeps only the configured number of deltas. Without deltas configured, any
+// published deltas are removed so clients never apply outdated ones.
+func (r *Repository) writeManifestDelta(previousData []byte, previous, current *models.ManifestIndex, currentData []byte) error {
+	deltasDir := filepath.Join(r.layout.RootDir, r.layout.DeltasDir)
+
+	keep := r.config.Repository.ManifestDeltas
+	if keep <= 0 {
+		if err := os.RemoveAll(deltasDir); err != nil {
+			return fmt.Errorf("failed to remove manifest deltas: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(deltasDir, 0755); err != nil {
+		return fmt.Errorf("failed to create deltas directory: %w", err)
+	}
+
+	index := r.loadDeltaIndex()
+
+	if previous == nil || previous.Generation == 0 {
+		// Nothing to diff against: start a new chain
+		index.Deltas = nil
+	} else {
+		delta, err := DiffManifests(previous, current)
+		if err != nil {
+			return err
+		}
+		delta.BaseSHA256 = sha256Hex(previousData)
+		delta.ResultSHA256 = sha256Hex(currentData)
+
+		deltaData, err := json.MarshalIndent(delta, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal manifest delta: %w", err)
+		}
+
+		relPath := path.Join(r.layout.DeltasDir, fmt.Sprintf("%d.json", current.Generation))
+		if err := os.WriteFile(filepath.Join(r.layout.RootDir, filepath.FromSlash(relPath)), deltaData, 0644); err != nil {
+			return fmt.Errorf("failed to write manifest delta: %w", err)
+		}
+
+		// Older deltas are only useful if they chain into this one
+		if n := len(index.Deltas); n > 0 && index.Deltas[n-1].ToGeneration != previous.Generation {
+			index.Deltas = nil
+		}
+
+		index.Deltas = append(index.Deltas, models.ManifestDeltaRef{
+			FromGeneration: delta.FromGeneration,
+			ToGeneration:   delta.ToGeneration,
+			Path:           relPath,
+			Size:           int64(len(deltaData)),
+			SHA256:         sha256Hex(deltaData),
+		})
+	}
+
+	if len(index.Deltas) > keep {
+		index.Deltas = index.Deltas[len(index.Deltas)-keep:]
+	}
+	index.Generation = current.Generation
+	index.ManifestSize = int64(len(currentData))
+
+	indexData, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal delta index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(deltasDir, deltaIndexFile), indexData, 0644); err != nil {
+		return fmt.Errorf("failed to write delta index: %w", err)
+	}
+
+	return r.pruneDeltas(deltasDir, index)
+}
+
+// loadDeltaIndex reads the published delta index, or returns an empty one
+func (r *Repository) loadDeltaIndex() *models.ManifestDeltaIndex {
+	index := &models.ManifestDeltaIndex{}
+
+	data, err := os.ReadFile(filepath.Join(r.layout.RootDir, r.layout.DeltasDir, deltaIndexFile))
+	if err != nil {
+		return index
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return &models.ManifestDeltaIndex{}
+	}
+
+	return index
+}
+
+// pruneDeltas removes delta files no longer referenced by the index
+func (r *Repository) pruneDeltas(deltasDir string, index *models.ManifestDeltaIndex) error {
+	referenced := make(map[string]bool, len(index.Deltas))
+	for _, ref := range index.Deltas {
+		referenced[path.Base(ref.Path)] = true
+	}
+
+	entries, err := os.ReadDir(deltasDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == deltaIndexFile || !strings.HasSuffix(name, ".json") || referenced[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(deltasDir, name)); err != nil {
+			return fmt.Errorf("failed to remove old manifest delta: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// sha256Hex returns the hex SHA256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,259 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// policyActionOrder ranks actions by severity, the strictest action of the
+// broken rules decides what happens to an APK
+var policyActionOrder = map[models.PolicyAction]int{
+	models.PolicyWarn:       1,
+	models.PolicyQuarantine: 2,
+	models.PolicyReject:     3,
+}
+
+// LoadPolicy reads and validates a policy file
+func LoadPolicy(path string) (*models.Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	var policy models.Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", path, err)
+	}
+
+	for i := range policy.Rules {
+		if err := normalizePolicyRule(&policy.Rules[i]); err != nil {
+			return nil, fmt.Errorf("invalid policy %s: rule %d: %w", path, i+1, err)
+		}
+	}
+
+	return &policy, nil
+}
+
+// normalizePolicyRule fills the defaults of a rule and checks its parameters
+func normalizePolicyRule(rule *models.PolicyRule) error {
+	rule.Check = strings.ToLower(strings.TrimSpace(rule.Check))
+	if rule.Name == "" {
+		rule.Name = rule.Check
+	}
+
+	rule.Action = models.PolicyAction(strings.ToLower(string(rule.Action)))
+	if rule.Action == "" {
+		rule.Action = models.PolicyReject
+	}
+	if _, ok := policyActionOrder[rule.Action]; !ok {
+		return fmt.Errorf("unknown action %q (reject, quarantine or warn)", rule.Action)
+	}
+
+	switch rule.Check {
+	case models.PolicyCheckDebuggable, models.PolicyCheckCleartextTraffic:
+	case models.PolicyCheckMinTargetSDK:
+		if rule.TargetSDK <= 0 {
+			return fmt.Errorf("%s needs target_sdk", rule.Check)
+		}
+	case models.PolicyCheckForbiddenPermissions:
+		if len(rule.Permissions) == 0 {
+			return fmt.Errorf("%s needs permissions", rule.Check)
+		}
+	case models.PolicyCheckAllowedSigners:
+		if len(rule.Signers) == 0 {
+			return fmt.Errorf("%s needs signers", rule.Check)
+		}
+		for i, signer := range rule.Signers {
+			rule.Signers[i] = normalizeFingerprint(signer)
+		}
+	case models.PolicyCheckMaxSize:
+		if rule.MaxSizeMB <= 0 {
+			return fmt.Errorf("%s needs max_size_mb", rule.Check)
+		}
+	default:
+		return fmt.Errorf("unknown check %q (%s)", rule.Check, strings.Join(models.PolicyChecks, ", "))
+	}
+
+	return nil
+}
+
+// LoadPolicy loads the policy file the configuration points to, or returns nil
+// if the repository has no policy
+func (r *Repository) LoadPolicy() (*models.Policy, error) {
+	if r.config.Repository.Policy == "" {
+		return nil, nil
+	}
+
+	policyPath := r.config.Repository.Policy
+	if !filepath.IsAbs(policyPath) {
+		policyPath = filepath.Join(r.rootDir, policyPath)
+	}
+	return LoadPolicy(policyPath)
+}
+
+// EvaluatePolicy returns the rules of a policy an APK breaks. A check whose
+// data the APK info lacks, such as the manifest of imported entries or of
+// versions added before it was recorded, fails as unknown rather than passing.
+func EvaluatePolicy(policy *models.Policy, info *models.APKInfo) []models.PolicyViolation {
+	if policy == nil || info == nil {
+		return nil
+	}
+
+	var violations []models.PolicyViolation
+	for _, rule := range policy.Rules {
+		found, limit, broken, unknown := evaluatePolicyRule(rule, info)
+		if broken {
+			violations = append(violations, models.PolicyViolation{
+				Rule:    rule.Name,
+				Check:   rule.Check,
+				Action:  rule.Action,
+				Found:   found,
+				Limit:   limit,
+				Unknown: unknown,
+			})
+		}
+	}
+	return violations
+}
+
+// evaluatePolicyRule reports whether an APK breaks a rule, with the offending
+// and required values, and whether it did so because the data was missing
+func evaluatePolicyRule(rule models.PolicyRule, info *models.APKInfo) (found, limit string, broken, unknown bool) {
+	switch rule.Check {
+	case models.PolicyCheckDebuggable:
+		if info.Manifest == nil {
+			return "", "", true, true
+		}
+		return "", "", info.Manifest.Debuggable, false
+
+	case models.PolicyCheckCleartextTraffic:
+		if info.Manifest == nil {
+			return "", "", true, true
+		}
+		return "", "", info.Manifest.UsesCleartextTraffic, false
+
+	case models.PolicyCheckMinTargetSDK:
+		if info.TargetSDK == 0 {
+			return "", strconv.Itoa(rule.TargetSDK), true, true
+		}
+		if info.TargetSDK >= rule.TargetSDK {
+			return "", "", false, false
+		}
+		return strconv.Itoa(info.TargetSDK), strconv.Itoa(rule.TargetSDK), true, false
+
+	case models.PolicyCheckForbiddenPermissions:
+		var requested []string
+		for _, permission := range info.Permissions {
+			for _, forbidden := range rule.Permissions {
+				if strings.EqualFold(permission, forbidden) {
+					requested = append(requested, permission)
+					break
+				}
+			}
+		}
+		return strings.Join(requested, ", "), "", len(requested) > 0, false
+
+	case models.PolicyCheckAllowedSigners:
+		signer := ""
+		if info.SignatureInfo != nil {
+			signer = normalizeFingerprint(info.SignatureInfo.SHA256)
+		}
+		if signer == "" {
+			return "", "", true, true
+		}
+		for _, allowed := range rule.Signers {
+			if signer == allowed {
+				return "", "", false, false
+			}
+		}
+		return signer, "", true, false
+
+	case models.PolicyCheckMaxSize:
+		maxSize := int64(rule.MaxSizeMB * 1024 * 1024)
+		if info.Size == 0 {
+			return "", formatPolicySize(maxSize), true, true
+		}
+		if info.Size <= maxSize {
+			return "", "", false, false
+		}
+		return formatPolicySize(info.Size), formatPolicySize(maxSize), true, false
+	}
+
+	return "", "", false, false
+}
+
+// PolicyDecision returns the strictest action of the broken rules, or an empty
+// action if there are none
+func PolicyDecision(violations []models.PolicyViolation) models.PolicyAction {
+	var decision models.PolicyAction
+	for _, violation := range violations {
+		if policyActionOrder[violation.Action] > policyActionOrder[decision] {
+			decision = violation.Action
+		}
+	}
+	return decision
+}
+
+// GetQuarantinePath returns the full path for a file in the quarantine directory
+func (r *Repository) GetQuarantinePath(filename string) string {
+	return filepath.Join(r.layout.RootDir, r.layout.QuarantineDir, filename)
+}
+
+// SaveQuarantineRecord writes the info and violations of a quarantined APK next
+// to it in the quarantine directory and returns the record path
+func (r *Repository) SaveQuarantineRecord(apkInfo *models.APKInfo, violations []models.PolicyViolation) (string, error) {
+	recordName := quarantineRecordName(apkInfo)
+	record := &models.QuarantineRecord{
+		APKInfo:       apkInfo,
+		Violations:    violations,
+		QuarantinedAt: time.Now(),
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal quarantine record: %w", err)
+	}
+
+	recordPath := r.GetQuarantinePath(recordName)
+	if err := os.MkdirAll(filepath.Dir(recordPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+	if err := os.WriteFile(recordPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write quarantine record: %w", err)
+	}
+
+	return filepath.Join(r.layout.QuarantineDir, recordName), nil
+}
+
+// HasQuarantineRecord reports whether an APK has already been quarantined
+func (r *Repository) HasQuarantineRecord(apkInfo *models.APKInfo) bool {
+	_, err := os.Stat(r.GetQuarantinePath(quarantineRecordName(apkInfo)))
+	return err == nil
+}
+
+// quarantineRecordName returns the record file name of a quarantined APK, named
+// after the APK file or, for entries without one, the package and version code
+func quarantineRecordName(apkInfo *models.APKInfo) string {
+	if apkInfo.FileName != "" {
+		return strings.TrimSuffix(apkInfo.FileName, filepath.Ext(apkInfo.FileName)) + ".json"
+	}
+	return fmt.Sprintf("%s_%d.json", apkInfo.PackageID, apkInfo.VersionCode)
+}
+
+// normalizeFingerprint lowercases a certificate fingerprint and drops separators
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+}
+
+// formatPolicySize formats a size in MB for violation reports
+func formatPolicySize(size int64) string {
+	return fmt.Sprintf("%.2f MB", float64(size)/(1024*1024))
+}
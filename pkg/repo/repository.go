@@ -1,15 +1,20 @@
 package repo
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/huanfeng/apkhub/pkg/apk"
 	"github.com/huanfeng/apkhub/pkg/models"
+	"github.com/huanfeng/apkhub/pkg/signing"
+	"github.com/huanfeng/apkhub/pkg/utils"
 )
 
 // Repository manages the APK repository structure
@@ -18,6 +23,10 @@ type Repository struct {
 	config  *models.Config
 	parser  *apk.Parser
 	rootDir string
+
+	signingKeys       []ed25519.PrivateKey // Current key first, then previous keys
+	signingKeysErr    error
+	signingKeysLoaded bool
 }
 
 // NewRepository creates a new repository instance
@@ -91,6 +100,12 @@ func (r *Repository) GenerateNormalizedFileName(info *apk.APKInfo) string {
 		filename = fmt.Sprintf("%s_%s", filename, abi)
 	}
 
+	// App bundles and APK sets keep their container format
+	switch ext := strings.ToLower(filepath.Ext(info.FilePath)); ext {
+	case ".aab", ".apks":
+		return filename + ext
+	}
+
 	// Check for XAPK features
 	for _, feature := range info.Features {
 		if feature == "split_apk" || feature == "has_obb" {
@@ -130,26 +145,62 @@ func (r *Repository) SaveAPKInfo(apkInfo *models.APKInfo) error {
 
 // SaveAPKInfoWithIcon saves APK info and icon from parsed APK data
 func (r *Repository) SaveAPKInfoWithIcon(parsedInfo *apk.APKInfo, apkInfo *models.APKInfo) error {
-	// Save APK info
-	if err := r.SaveAPKInfo(apkInfo); err != nil {
-		return err
+	// Save icons first so that the info file records their paths
+	// An APK without launcher icon is saved without one
+	if parsedInfo.Icon != nil && parsedInfo.Icon.Load() == nil {
+		r.saveIcons(parsedInfo.Icon, apkInfo)
 	}
 
-	// Save icon if available
-	if parsedInfo.IconData != nil && len(parsedInfo.IconData) > 0 {
-		iconFileName := fmt.Sprintf("%s%s", apkInfo.PackageID, parsedInfo.IconExt)
-		iconPath := filepath.Join(r.layout.RootDir, r.layout.InfosDir, iconFileName)
+	// Save APK info
+	return r.SaveAPKInfo(apkInfo)
+}
 
-		if err := os.WriteFile(iconPath, parsedInfo.IconData, 0644); err != nil {
+// saveIcons writes the icon at the standard size as infos/<package>.png and
+// at each configured size as infos/<package>_<size>.png, cut to the configured
+// mask if it is adaptive
+func (r *Repository) saveIcons(icon *apk.Icon, apkInfo *models.APKInfo) {
+	mask, err := apk.ParseIconMask(r.config.Repository.IconMask)
+	if err != nil {
+		fmt.Printf("Warning: %v, using %s\n", err, apk.DefaultIconMask)
+		mask = apk.DefaultIconMask
+	}
+
+	save := func(size int, fileName string) bool {
+		data, err := icon.PNG(size, mask)
+		if err == nil {
+			err = r.writeInfoFile(fileName, data)
+		}
+		if err != nil {
 			// Icon save failure is non-fatal, just log warning
 			fmt.Printf("Warning: Failed to save icon for %s: %v\n", apkInfo.PackageID, err)
-		} else {
-			// Update icon path in APK info
-			apkInfo.IconPath = filepath.Join(r.layout.InfosDir, iconFileName)
+			return false
 		}
+		return true
 	}
 
-	return nil
+	iconFileName := apkInfo.PackageID + ".png"
+	if save(apk.StandardIconSize, iconFileName) {
+		apkInfo.IconPath = filepath.Join(r.layout.InfosDir, iconFileName)
+	}
+
+	apkInfo.Icons = nil
+	for _, size := range r.config.Repository.IconSizes {
+		if size <= 0 {
+			continue
+		}
+		sizedFileName := fmt.Sprintf("%s_%d.png", apkInfo.PackageID, size)
+		if save(size, sizedFileName) {
+			if apkInfo.Icons == nil {
+				apkInfo.Icons = make(map[string]string)
+			}
+			apkInfo.Icons[strconv.Itoa(size)] = filepath.Join(r.layout.InfosDir, sizedFileName)
+		}
+	}
+}
+
+// writeInfoFile writes a file to the infos directory
+func (r *Repository) writeInfoFile(fileName string, data []byte) error {
+	return os.WriteFile(filepath.Join(r.layout.RootDir, r.layout.InfosDir, fileName), data, 0644)
 }
 
 // LoadAPKInfo loads APK information from infos directory
@@ -200,6 +251,45 @@ func (r *Repository) LoadAllAPKInfos() ([]*models.APKInfo, error) {
 	return infos, nil
 }
 
+// FindLatestAPKInfo returns the highest version of a package recorded in infos/,
+// or nil if there is none
+func (r *Repository) FindLatestAPKInfo(packageID string) (*models.APKInfo, error) {
+	infos, err := r.LoadAllAPKInfos()
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *models.APKInfo
+	for _, info := range infos {
+		if info.PackageID == packageID && (latest == nil || info.VersionCode > latest.VersionCode) {
+			latest = info
+		}
+	}
+
+	return latest, nil
+}
+
+// FindLatestSignedAPKInfo returns the highest version of a package recorded in infos/
+// that has a known signer, or nil if there is none
+func (r *Repository) FindLatestSignedAPKInfo(packageID string) (*models.APKInfo, error) {
+	infos, err := r.LoadAllAPKInfos()
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *models.APKInfo
+	for _, info := range infos {
+		if info.PackageID != packageID || info.SignatureInfo == nil || info.SignatureInfo.SHA256 == "" {
+			continue
+		}
+		if latest == nil || info.VersionCode > latest.VersionCode {
+			latest = info
+		}
+	}
+
+	return latest, nil
+}
+
 // BuildManifestFromInfos builds the manifest index from individual APK info files
 func (r *Repository) BuildManifestFromInfos() (*models.ManifestIndex, error) {
 	infos, err := r.LoadAllAPKInfos()
@@ -235,19 +325,24 @@ func (r *Repository) BuildManifestFromInfos() (*models.ManifestIndex, error) {
 		}
 
 		// Create version entry
+		riskScore := apk.PermissionRiskScore(info.Permissions)
 		version := &models.AppVersion{
-			Version:       info.Version,
-			VersionCode:   info.VersionCode,
-			MinSDK:        info.MinSDK,
-			TargetSDK:     info.TargetSDK,
-			Size:          info.Size,
-			SHA256:        info.SHA256,
-			SignatureInfo: info.SignatureInfo,
-			DownloadURL:   r.buildDownloadURL(info.FilePath),
-			ReleaseDate:   info.AddedAt,
-			Permissions:   info.Permissions,
-			Features:      info.Features,
-			ABIs:          info.ABIs,
+			Version:               info.Version,
+			VersionCode:           info.VersionCode,
+			MinSDK:                info.MinSDK,
+			TargetSDK:             info.TargetSDK,
+			Size:                  info.Size,
+			SHA256:                info.SHA256,
+			SignatureInfo:         info.SignatureInfo,
+			SignatureVerification: info.SignatureVerification,
+			DownloadURL:           r.buildDownloadURL(info.FilePath),
+			ReleaseDate:           info.AddedAt,
+			Permissions:           info.Permissions,
+			RiskScore:             &riskScore,
+			Features:              info.Features,
+			ABIs:                  info.ABIs,
+			Manifest:              info.Manifest,
+			Libraries:             info.Libraries,
 		}
 
 		// Use version string as key, but handle duplicates
@@ -279,19 +374,94 @@ func (r *Repository) applyManifestSignature(manifest *models.ManifestIndex) {
 		return
 	}
 
+	fingerprint := r.config.Repository.SigningKeyFingerprint
+	if keys, err := r.loadSigningKeys(); err == nil && len(keys) > 0 {
+		fingerprint = signing.Fingerprint(keys[0].Public().(ed25519.PublicKey))
+	}
+
 	// Only attach signature metadata when signer information is provided
-	if r.config.Repository.SigningKeyFingerprint == "" && r.config.Repository.Signer == "" {
+	if fingerprint == "" && r.config.Repository.Signer == "" {
 		manifest.Signature = nil
 		return
 	}
 
 	manifest.Signature = &models.ManifestSignature{
-		PublicKeyFingerprint: r.config.Repository.SigningKeyFingerprint,
+		PublicKeyFingerprint: fingerprint,
 		SignedAt:             time.Now(),
 		Signer:               r.config.Repository.Signer,
 	}
 }
 
+// loadSigningKeys loads the current and previous manifest signing keys once.
+// It returns nil when no signing key is configured.
+func (r *Repository) loadSigningKeys() ([]ed25519.PrivateKey, error) {
+	if r.signingKeysLoaded {
+		return r.signingKeys, r.signingKeysErr
+	}
+	r.signingKeysLoaded = true
+
+	keyPath := r.config.Repository.SigningKey
+	if keyPath == "" && os.Getenv(signing.EnvSigningKey) == "" {
+		return nil, nil
+	}
+
+	current, err := signing.LoadSigningKey(r.ResolveKeyPath(keyPath), signing.PromptPassphrase)
+	if err != nil {
+		r.signingKeysErr = err
+		return nil, err
+	}
+	keys := []ed25519.PrivateKey{current}
+
+	for _, previousPath := range r.config.Repository.PreviousSigningKeys {
+		previous, err := signing.LoadKeyFile(r.ResolveKeyPath(previousPath), signing.PromptPassphrase)
+		if err != nil {
+			r.signingKeysErr = fmt.Errorf("failed to load previous signing key %s: %w", previousPath, err)
+			return nil, r.signingKeysErr
+		}
+		keys = append(keys, previous)
+	}
+
+	r.signingKeys = keys
+	return keys, nil
+}
+
+// ResolveKeyPath resolves key paths relative to the repository root
+func (r *Repository) ResolveKeyPath(keyPath string) string {
+	if keyPath == "" || filepath.IsAbs(keyPath) {
+		return keyPath
+	}
+	return filepath.Join(r.rootDir, keyPath)
+}
+
+// writeManifestSignature writes the detached signature for the manifest bytes
+func (r *Repository) writeManifestSignature(data []byte) error {
+	sigPath := filepath.Join(r.layout.RootDir, r.layout.ManifestFile+models.ManifestSignatureSuffix)
+
+	keys, err := r.loadSigningKeys()
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		// Remove a stale signature so clients never see a mismatching one
+		if err := os.Remove(sigPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale manifest signature: %w", err)
+		}
+		return nil
+	}
+
+	sigData, err := json.MarshalIndent(signing.Sign(data, keys...), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest signature: %w", err)
+	}
+
+	if err := os.WriteFile(sigPath, sigData, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest signature: %w", err)
+	}
+
+	return nil
+}
+
 // buildDownloadURL builds the download URL for an APK
 func (r *Repository) buildDownloadURL(filePath string) string {
 	// Convert backslashes to forward slashes for URLs
@@ -370,6 +540,40 @@ func (r *Repository) updateLatestVersion(pkg *models.AppPackage) {
 func (r *Repository) saveManifest(manifest *models.ManifestIndex) error {
 	manifestPath := filepath.Join(r.layout.RootDir, r.layout.ManifestFile)
 
+	// Unlock the signing keys first so a key error leaves the published manifest untouched
+	if _, err := r.loadSigningKeys(); err != nil {
+		return fmt.Errorf("failed to sign manifest: %w", err)
+	}
+
+	// Large repositories publish a root index and fetch packages lazily
+	if r.config.Repository.ShardedIndex {
+		root, err := r.shardManifest(manifest)
+		if err != nil {
+			return fmt.Errorf("failed to write package index: %w", err)
+		}
+		manifest = root
+	} else if err := os.RemoveAll(filepath.Join(r.layout.RootDir, r.layout.IndexDir)); err != nil {
+		return fmt.Errorf("failed to remove package index: %w", err)
+	}
+
+	// Clients refuse expired manifests, so a mirror cannot freeze them on an old one
+	if days := r.config.Repository.ManifestExpiryDays; days > 0 {
+		expires := manifest.UpdatedAt.Add(time.Duration(days) * 24 * time.Hour)
+		manifest.Expires = &expires
+	}
+
+	// The manifest being replaced is the base of the new delta
+	previousData, previous := loadPublishedManifest(manifestPath)
+	manifest.Generation = 1
+	if previous != nil {
+		manifest.Generation = previous.Generation + 1
+	}
+
+	manifest.Deltas = ""
+	if r.config.Repository.ManifestDeltas > 0 {
+		manifest.Deltas = path.Join(r.layout.DeltasDir, deltaIndexFile)
+	}
+
 	data, err := json.MarshalIndent(manifest, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal manifest: %w", err)
@@ -379,6 +583,53 @@ func (r *Repository) saveManifest(manifest *models.ManifestIndex) error {
 		return fmt.Errorf("failed to write manifest: %w", err)
 	}
 
+	if err := r.writeManifestSignature(data); err != nil {
+		return fmt.Errorf("failed to sign manifest: %w", err)
+	}
+
+	if err := r.writeCompressedManifests(data); err != nil {
+		return fmt.Errorf("failed to compress manifest: %w", err)
+	}
+
+	if err := r.writeManifestDelta(previousData, previous, manifest, data); err != nil {
+		return fmt.Errorf("failed to publish manifest delta: %w", err)
+	}
+
+	return nil
+}
+
+// writeCompressedManifests writes the configured compressed copies of the manifest
+// and removes the others, so clients never pick up an outdated copy. The detached
+// signature covers the uncompressed bytes and applies to every copy.
+func (r *Repository) writeCompressedManifests(data []byte) error {
+	enabled := make(map[string]bool)
+	for _, format := range r.config.Repository.ManifestCompression {
+		normalized, err := utils.NormalizeCompression(format)
+		if err != nil {
+			return err
+		}
+		enabled[normalized] = true
+	}
+
+	for _, format := range utils.CompressionFormats() {
+		compressedPath := filepath.Join(r.layout.RootDir, r.layout.ManifestFile+utils.CompressionExtension(format))
+
+		if !enabled[format] {
+			if err := os.Remove(compressedPath); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove stale %s manifest: %w", format, err)
+			}
+			continue
+		}
+
+		compressed, err := utils.Compress(format, data)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(compressedPath, compressed, 0644); err != nil {
+			return fmt.Errorf("failed to write %s manifest: %w", format, err)
+		}
+	}
+
 	return nil
 }
 
@@ -396,6 +647,23 @@ func (r *Repository) UpdateManifest() error {
 	return nil
 }
 
+// LoadManifest loads the published manifest. Packages of a sharded index get
+// all their versions from their index files, as far as these can be read.
+func (r *Repository) LoadManifest() (*models.ManifestIndex, error) {
+	data, err := os.ReadFile(filepath.Join(r.layout.RootDir, r.layout.ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest models.ManifestIndex
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	LoadPackageShards(r.layout.RootDir, &manifest)
+	return &manifest, nil
+}
+
 // GetAPKPath returns the full path for an APK in the repository
 func (r *Repository) GetAPKPath(filename string) string {
 	return filepath.Join(r.layout.RootDir, r.layout.APKsDir, filename)
@@ -139,27 +139,33 @@ func (s *Scanner) processAPK(apkPath string, result *ScanResult) error {
 	}
 
 	// Create version entry
+	riskScore := apk.PermissionRiskScore(apkInfo.Permissions)
 	version := &models.AppVersion{
-		Version:       apkInfo.Version,
-		VersionCode:   apkInfo.VersionCode,
-		MinSDK:        apkInfo.MinSDK,
-		TargetSDK:     apkInfo.TargetSDK,
-		Size:          apkInfo.Size,
-		SHA256:        apkInfo.SHA256,
-		SignatureInfo: apkInfo.SignatureInfo,
-		DownloadURL:   s.buildDownloadURL(apkInfo.FilePath),
-		ReleaseDate:   apkInfo.ReleaseDate,
-		Permissions:   apkInfo.Permissions,
-		Features:      apkInfo.Features,
-		ABIs:          apkInfo.ABIs,
+		Version:               apkInfo.Version,
+		VersionCode:           apkInfo.VersionCode,
+		MinSDK:                apkInfo.MinSDK,
+		TargetSDK:             apkInfo.TargetSDK,
+		Size:                  apkInfo.Size,
+		SHA256:                apkInfo.SHA256,
+		SignatureInfo:         apkInfo.SignatureInfo,
+		SignatureVerification: apkInfo.SignatureVerification,
+		DownloadURL:           s.buildDownloadURL(apkInfo.FilePath),
+		ReleaseDate:           apkInfo.ReleaseDate,
+		Permissions:           apkInfo.Permissions,
+		RiskScore:             &riskScore,
+		Features:              apkInfo.Features,
+		ABIs:                  apkInfo.ABIs,
+		Manifest:              apkInfo.Manifest,
+		Libraries:             apkInfo.Libraries,
 	}
 
 	// Handle version with same version string but different signature
 	existingVersion, versionExists := pkg.Versions[apkInfo.Version]
 	if versionExists && s.config.Repository.SignatureHandling != "reject" {
-		// Check if signatures differ
-		if existingVersion.SignatureInfo != nil && apkInfo.SignatureInfo != nil &&
-			existingVersion.SignatureInfo.SHA256 != apkInfo.SignatureInfo.SHA256 {
+		// Check if signatures differ. An unknown signer (failed extraction leaves
+		// an empty SHA256) counts as no signature info.
+		if knownSigner(existingVersion.SignatureInfo) && knownSigner(apkInfo.SignatureInfo) &&
+			!strings.EqualFold(existingVersion.SignatureInfo.SHA256, apkInfo.SignatureInfo.SHA256) {
 
 			switch s.config.Repository.SignatureHandling {
 			case "mark":
@@ -189,6 +195,11 @@ func (s *Scanner) processAPK(apkPath string, result *ScanResult) error {
 	return nil
 }
 
+// knownSigner reports whether signature info identifies a signer
+func knownSigner(info *models.SignatureInfo) bool {
+	return info != nil && len(info.SHA256) >= 8
+}
+
 // buildDownloadURL builds the download URL for an APK
 func (s *Scanner) buildDownloadURL(filePath string) string {
 	// Convert backslashes to forward slashes for URLs
@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+// shardManifest writes one index file per package and returns the root index that
+// is published as the manifest: each package keeps only its latest version and
+// points to its shard. Unchanged shards are not rewritten, so their HTTP
+// validators stay valid, and shards of removed packages are deleted.
+func (r *Repository) shardManifest(manifest *models.ManifestIndex) (*models.ManifestIndex, error) {
+	indexDir := filepath.Join(r.layout.RootDir, r.layout.IndexDir)
+	if err := os.MkdirAll(indexDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	root := *manifest
+	root.Packages = make(map[string]*models.AppPackage, len(manifest.Packages))
+
+	written := make(map[string]bool, len(manifest.Packages))
+	for packageID, pkg := range manifest.Packages {
+		if !validShardName(packageID) {
+			return nil, fmt.Errorf("package ID %q cannot be used as an index file name", packageID)
+		}
+
+		full := *pkg
+		full.Shard = nil
+
+		data, err := json.MarshalIndent(&full, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal index of %s: %w", packageID, err)
+		}
+
+		fileName := packageID + ".json"
+		shardPath := filepath.Join(indexDir, fileName)
+		if existing, err := os.ReadFile(shardPath); err != nil || !bytes.Equal(existing, data) {
+			if err := os.WriteFile(shardPath, data, 0644); err != nil {
+				return nil, fmt.Errorf("failed to write index of %s: %w", packageID, err)
+			}
+		}
+		written[fileName] = true
+
+		entry := full
+		entry.Versions = make(map[string]*models.AppVersion, 1)
+		if latest, exists := pkg.Versions[pkg.Latest]; exists {
+			entry.Versions[pkg.Latest] = latest
+		}
+		entry.Shard = &models.PackageShard{
+			Path:     path.Join(r.layout.IndexDir, fileName),
+			Size:     int64(len(data)),
+			SHA256:   sha256Hex(data),
+			Versions: len(pkg.Versions),
+		}
+		root.Packages[packageID] = &entry
+	}
+
+	entries, err := os.ReadDir(indexDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") || written[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(indexDir, name)); err != nil {
+			return nil, fmt.Errorf("failed to remove stale package index: %w", err)
+		}
+	}
+
+	return &root, nil
+}
+
+// validShardName reports whether a package ID is safe to use as a file name
+func validShardName(packageID string) bool {
+	return packageID != "" && !strings.HasPrefix(packageID, ".") && !strings.ContainsAny(packageID, `/\:`)
+}
+
+// LoadPackageShards replaces the entries of a sharded root index with the full
+// packages from their index files under rootDir. Packages whose index file is
+// missing or does not match its hash keep their root entry and are returned
+// with the reason.
+func LoadPackageShards(rootDir string, manifest *models.ManifestIndex) map[string]error {
+	failures := make(map[string]error)
+
+	for packageID, pkg := range manifest.Packages {
+		if pkg == nil || pkg.Shard == nil {
+			continue
+		}
+
+		full, err := loadPackageShard(rootDir, packageID, pkg.Shard)
+		if err != nil {
+			failures[packageID] = err
+			continue
+		}
+		manifest.Packages[packageID] = full
+	}
+
+	return failures
+}
+
+// loadPackageShard reads and checks one package index file
+func loadPackageShard(rootDir, packageID string, shard *models.PackageShard) (*models.AppPackage, error) {
+	cleaned := path.Clean(shard.Path)
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return nil, fmt.Errorf("invalid index path %q", shard.Path)
+	}
+
+	data, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(cleaned)))
+	if err != nil {
+		return nil, err
+	}
+	if sha256Hex(data) != shard.SHA256 {
+		return nil, fmt.Errorf("%s does not match its hash in the manifest", shard.Path)
+	}
+
+	var pkg models.AppPackage
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", shard.Path, err)
+	}
+	if pkg.PackageID != packageID {
+		return nil, fmt.Errorf("%s describes %s instead of %s", shard.Path, pkg.PackageID, packageID)
+	}
+
+	return &pkg, nil
+}
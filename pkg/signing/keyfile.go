@@ -0,0 +1,203 @@
+package signing
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+const (
+	// EnvSigningKey holds the PEM private key, used instead of the key file (e.g. in CI)
+	EnvSigningKey = "APKHUB_SIGNING_KEY"
+
+	// EnvSigningKeyPassphrase holds the passphrase for an encrypted private key
+	EnvSigningKeyPassphrase = "APKHUB_SIGNING_KEY_PASSPHRASE"
+
+	encryptedPrivateKeyPEMType = "APKHUB ENCRYPTED PRIVATE KEY"
+
+	// scrypt parameters for key encryption
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// PassphraseFunc returns the passphrase for an encrypted key
+type PassphraseFunc func(prompt string) ([]byte, error)
+
+// EncryptPrivateKeyPEM encodes a private key as a passphrase protected PEM block
+// (scrypt key derivation, AES-256-GCM)
+func EncryptPrivateKeyPEM(priv ed25519.PrivateKey, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase must not be empty")
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	gcm, err := newKeyCipher(passphrase, salt, scryptN, scryptR, scryptP)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type: encryptedPrivateKeyPEMType,
+		Headers: map[string]string{
+			"KDF":    "scrypt",
+			"N":      strconv.Itoa(scryptN),
+			"R":      strconv.Itoa(scryptR),
+			"P":      strconv.Itoa(scryptP),
+			"Salt":   hex.EncodeToString(salt),
+			"Cipher": "AES-256-GCM",
+			"Nonce":  hex.EncodeToString(nonce),
+		},
+		Bytes: gcm.Seal(nil, nonce, der, nil),
+	}), nil
+}
+
+// IsEncryptedPrivateKeyPEM reports whether data holds a passphrase protected key
+func IsEncryptedPrivateKeyPEM(data []byte) bool {
+	block, _ := pem.Decode(data)
+	return block != nil && block.Type == encryptedPrivateKeyPEMType
+}
+
+// DecryptPrivateKeyPEM decodes a passphrase protected PEM private key
+func DecryptPrivateKeyPEM(data, passphrase []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != encryptedPrivateKeyPEMType {
+		return nil, fmt.Errorf("no %s PEM block found", encryptedPrivateKeyPEMType)
+	}
+
+	if block.Headers["KDF"] != "scrypt" || block.Headers["Cipher"] != "AES-256-GCM" {
+		return nil, fmt.Errorf("unsupported key encryption %s/%s", block.Headers["KDF"], block.Headers["Cipher"])
+	}
+
+	n, errN := strconv.Atoi(block.Headers["N"])
+	r, errR := strconv.Atoi(block.Headers["R"])
+	p, errP := strconv.Atoi(block.Headers["P"])
+	salt, errSalt := hex.DecodeString(block.Headers["Salt"])
+	nonce, errNonce := hex.DecodeString(block.Headers["Nonce"])
+	for _, err := range []error{errN, errR, errP, errSalt, errNonce} {
+		if err != nil {
+			return nil, fmt.Errorf("invalid key encryption header: %w", err)
+		}
+	}
+
+	gcm, err := newKeyCipher(passphrase, salt, n, r, p)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid key encryption nonce")
+	}
+
+	der, err := gcm.Open(nil, nonce, block.Bytes, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key (wrong passphrase?)")
+	}
+
+	return ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: privateKeyPEMType, Bytes: der}))
+}
+
+// newKeyCipher derives the AES-GCM cipher protecting a private key
+func newKeyCipher(passphrase, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, n, r, p, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// LoadSigningKey loads a private key from $APKHUB_SIGNING_KEY or from path. Encrypted
+// keys are unlocked with $APKHUB_SIGNING_KEY_PASSPHRASE or, failing that, passphraseFn.
+func LoadSigningKey(path string, passphraseFn PassphraseFunc) (ed25519.PrivateKey, error) {
+	source := path
+	data := []byte(os.Getenv(EnvSigningKey))
+	if len(data) > 0 {
+		source = EnvSigningKey
+	} else {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+	}
+
+	return parseSigningKey(data, source, passphraseFn)
+}
+
+// LoadKeyFile loads a private key from a file, ignoring $APKHUB_SIGNING_KEY
+func LoadKeyFile(path string, passphraseFn PassphraseFunc) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	return parseSigningKey(data, path, passphraseFn)
+}
+
+// parseSigningKey decodes a plain or encrypted PEM private key
+func parseSigningKey(data []byte, source string, passphraseFn PassphraseFunc) (ed25519.PrivateKey, error) {
+	if !IsEncryptedPrivateKeyPEM(data) {
+		return ParsePrivateKeyPEM(data)
+	}
+
+	passphrase := []byte(os.Getenv(EnvSigningKeyPassphrase))
+	if len(passphrase) == 0 {
+		if passphraseFn == nil {
+			return nil, fmt.Errorf("signing key %s is encrypted; set %s", source, EnvSigningKeyPassphrase)
+		}
+
+		var err error
+		passphrase, err = passphraseFn(fmt.Sprintf("Passphrase for %s: ", source))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return DecryptPrivateKeyPEM(data, passphrase)
+}
+
+// PromptPassphrase reads a passphrase from the terminal without echo
+func PromptPassphrase(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("cannot prompt for passphrase: stdin is not a terminal; set %s", EnvSigningKeyPassphrase)
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+
+	return passphrase, nil
+}
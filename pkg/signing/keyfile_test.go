@@ -0,0 +1,155 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestKeyFile writes priv to a key file, encrypted when passphrase is set
+func writeTestKeyFile(t *testing.T, priv ed25519.PrivateKey, passphrase string) string {
+	t.Helper()
+
+	var data []byte
+	var err error
+	if passphrase != "" {
+		data, err = EncryptPrivateKeyPEM(priv, []byte(passphrase))
+	} else {
+		data, err = MarshalPrivateKeyPEM(priv)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "signing.key")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// noPassphrase fails the test if a passphrase is asked for
+func noPassphrase(t *testing.T) PassphraseFunc {
+	return func(prompt string) ([]byte, error) {
+		t.Errorf("unexpected passphrase prompt %q", prompt)
+		return nil, errors.New("no passphrase")
+	}
+}
+
+func TestEncryptPrivateKeyPEM(t *testing.T) {
+	_, priv := newTestKey(t)
+
+	data, err := EncryptPrivateKeyPEM(priv, []byte("secret"))
+	if err != nil {
+		t.Fatalf("EncryptPrivateKeyPEM: %v", err)
+	}
+	if !IsEncryptedPrivateKeyPEM(data) {
+		t.Fatal("encrypted key not recognized")
+	}
+	if _, err := ParsePrivateKeyPEM(data); err == nil {
+		t.Error("encrypted key parsed as a plain key")
+	}
+
+	decrypted, err := DecryptPrivateKeyPEM(data, []byte("secret"))
+	if err != nil {
+		t.Fatalf("DecryptPrivateKeyPEM: %v", err)
+	}
+	if !decrypted.Equal(priv) {
+		t.Error("decrypted key differs from the original")
+	}
+
+	if _, err := DecryptPrivateKeyPEM(data, []byte("wrong")); err == nil {
+		t.Error("DecryptPrivateKeyPEM accepted a wrong passphrase")
+	}
+
+	// Tampering is detected by the AEAD
+	tampered, err := EncryptPrivateKeyPEM(priv, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered[len(tampered)/2] ^= 0x01
+	if _, err := DecryptPrivateKeyPEM(tampered, []byte("secret")); err == nil {
+		t.Error("DecryptPrivateKeyPEM accepted a modified key")
+	}
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	_, filePriv := newTestKey(t)
+	_, envPriv := newTestKey(t)
+	t.Setenv(EnvSigningKey, "")
+	t.Setenv(EnvSigningKeyPassphrase, "")
+
+	plainPath := writeTestKeyFile(t, filePriv, "")
+	encryptedPath := writeTestKeyFile(t, filePriv, "secret")
+
+	priv, err := LoadSigningKey(plainPath, noPassphrase(t))
+	if err != nil || !priv.Equal(filePriv) {
+		t.Errorf("plain key file: key matches %v, error %v", priv.Equal(filePriv), err)
+	}
+
+	prompted := 0
+	priv, err = LoadSigningKey(encryptedPath, func(string) ([]byte, error) {
+		prompted++
+		return []byte("secret"), nil
+	})
+	if err != nil || !priv.Equal(filePriv) || prompted != 1 {
+		t.Errorf("encrypted key file: key matches %v, %d prompts, error %v", priv.Equal(filePriv), prompted, err)
+	}
+
+	if _, err := LoadSigningKey(encryptedPath, nil); err == nil {
+		t.Error("encrypted key loaded without a passphrase")
+	}
+	if _, err := LoadSigningKey(encryptedPath, func(string) ([]byte, error) { return []byte("wrong"), nil }); err == nil {
+		t.Error("encrypted key loaded with a wrong passphrase")
+	}
+
+	// The passphrase variable is used without prompting
+	t.Setenv(EnvSigningKeyPassphrase, "secret")
+	priv, err = LoadSigningKey(encryptedPath, noPassphrase(t))
+	if err != nil || !priv.Equal(filePriv) {
+		t.Errorf("%s: key matches %v, error %v", EnvSigningKeyPassphrase, priv.Equal(filePriv), err)
+	}
+
+	// The key variable takes precedence over the file, LoadKeyFile ignores it
+	envData, err := EncryptPrivateKeyPEM(envPriv, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvSigningKey, string(envData))
+	priv, err = LoadSigningKey(plainPath, noPassphrase(t))
+	if err != nil || !priv.Equal(envPriv) {
+		t.Errorf("%s: key matches %v, error %v", EnvSigningKey, priv.Equal(envPriv), err)
+	}
+	priv, err = LoadKeyFile(plainPath, noPassphrase(t))
+	if err != nil || !priv.Equal(filePriv) {
+		t.Errorf("LoadKeyFile: key matches %v, error %v", priv.Equal(filePriv), err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldPub, oldPriv := newTestKey(t)
+	newPub, newPriv := newTestKey(t)
+	data := []byte(`{"name":"test"}`)
+	oldClient := []string{Fingerprint(oldPub)}
+	newClient := []string{Fingerprint(newPub)}
+
+	// During rotation the manifest is signed with both keys, so clients that
+	// have not yet trusted the new key keep working
+	rotating := Sign(data, newPriv, oldPriv)
+	for name, trusted := range map[string][]string{"old key": oldClient, "new key": newClient} {
+		if _, err := Verify(data, rotating, trusted); err != nil {
+			t.Errorf("client trusting the %s rejected the rotating manifest: %v", name, err)
+		}
+	}
+
+	// Once rotation is finished only clients trusting the new key accept it
+	finished := Sign(data, newPriv)
+	if _, err := Verify(data, finished, newClient); err != nil {
+		t.Errorf("client trusting the new key: %v", err)
+	}
+	if _, err := Verify(data, finished, oldClient); err == nil {
+		t.Error("client trusting only the old key accepted the manifest signed with the new key")
+	}
+}
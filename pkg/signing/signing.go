@@ -0,0 +1,208 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+const (
+	// AlgorithmEd25519 identifies Ed25519 detached signatures
+	AlgorithmEd25519 = "ed25519"
+
+	// SignatureFileVersion is the current detached signature file format
+	SignatureFileVersion = 1
+
+	privateKeyPEMType = "PRIVATE KEY"
+	publicKeyPEMType  = "PUBLIC KEY"
+)
+
+// GenerateKey creates a new Ed25519 key pair
+func GenerateKey() (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	return ed25519.GenerateKey(rand.Reader)
+}
+
+// Fingerprint returns the hex SHA256 of a raw Ed25519 public key
+func Fingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:])
+}
+
+// EncodePublicKey encodes a public key as base64, the form used in trusted_keys
+func EncodePublicKey(pub ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(pub)
+}
+
+// ParsePublicKey parses a base64 encoded raw public key or a PEM encoded PKIX public key
+func ParsePublicKey(value string) (ed25519.PublicKey, error) {
+	value = strings.TrimSpace(value)
+
+	if block, _ := pem.Decode([]byte(value)); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key is not Ed25519")
+		}
+		return pub, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public key: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 public key length: %d", len(raw))
+	}
+
+	return ed25519.PublicKey(raw), nil
+}
+
+// MarshalPublicKeyPEM encodes a public key as a PEM PKIX block
+func MarshalPublicKeyPEM(pub ed25519.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: publicKeyPEMType, Bytes: der}), nil
+}
+
+// MarshalPrivateKeyPEM encodes a private key as a PEM PKCS#8 block
+func MarshalPrivateKeyPEM(priv ed25519.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: privateKeyPEMType, Bytes: der}), nil
+}
+
+// ParsePrivateKeyPEM decodes a PEM PKCS#8 Ed25519 private key
+func ParsePrivateKeyPEM(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != privateKeyPEMType {
+		return nil, fmt.Errorf("no %s PEM block found", privateKeyPEMType)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not Ed25519")
+	}
+
+	return priv, nil
+}
+
+// Sign creates a detached signature file over data with every given key
+func Sign(data []byte, keys ...ed25519.PrivateKey) *models.ManifestSignatureFile {
+	sigFile := &models.ManifestSignatureFile{
+		Version: SignatureFileVersion,
+	}
+
+	now := time.Now().UTC()
+	for _, key := range keys {
+		pub := key.Public().(ed25519.PublicKey)
+		sigFile.Signatures = append(sigFile.Signatures, models.DetachedSignature{
+			Algorithm:      AlgorithmEd25519,
+			PublicKey:      EncodePublicKey(pub),
+			KeyFingerprint: Fingerprint(pub),
+			Signature:      base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)),
+			SignedAt:       now,
+		})
+	}
+
+	return sigFile
+}
+
+// ParseSignatureFile decodes a detached signature file
+func ParseSignatureFile(data []byte) (*models.ManifestSignatureFile, error) {
+	var sigFile models.ManifestSignatureFile
+	if err := json.Unmarshal(data, &sigFile); err != nil {
+		return nil, fmt.Errorf("failed to parse signature file: %w", err)
+	}
+	if sigFile.Version > SignatureFileVersion {
+		return nil, fmt.Errorf("unsupported signature file version %d", sigFile.Version)
+	}
+	return &sigFile, nil
+}
+
+// Verify checks data against a detached signature file. trustedKeys may contain
+// base64/PEM public keys or key fingerprints. The fingerprint of the first trusted
+// key with a valid signature is returned.
+func Verify(data []byte, sigFile *models.ManifestSignatureFile, trustedKeys []string) (string, error) {
+	if sigFile == nil || len(sigFile.Signatures) == 0 {
+		return "", fmt.Errorf("no signatures present")
+	}
+
+	trusted := TrustedFingerprints(trustedKeys)
+	if len(trusted) == 0 {
+		return "", fmt.Errorf("no trusted keys configured")
+	}
+
+	var lastErr error
+	for _, sig := range sigFile.Signatures {
+		if !strings.EqualFold(sig.Algorithm, AlgorithmEd25519) {
+			lastErr = fmt.Errorf("unsupported signature algorithm %q", sig.Algorithm)
+			continue
+		}
+
+		pub, err := ParsePublicKey(sig.PublicKey)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		fingerprint := Fingerprint(pub)
+		if !trusted[fingerprint] {
+			lastErr = fmt.Errorf("untrusted manifest signer: %s", fingerprint)
+			continue
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to decode signature: %w", err)
+			continue
+		}
+
+		if !ed25519.Verify(pub, data, raw) {
+			lastErr = fmt.Errorf("invalid signature from %s", fingerprint)
+			continue
+		}
+
+		return fingerprint, nil
+	}
+
+	return "", lastErr
+}
+
+// TrustedFingerprints normalizes trusted key entries to a set of fingerprints
+func TrustedFingerprints(trustedKeys []string) map[string]bool {
+	trusted := make(map[string]bool, len(trustedKeys))
+	for _, entry := range trustedKeys {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if pub, err := ParsePublicKey(entry); err == nil {
+			trusted[Fingerprint(pub)] = true
+			continue
+		}
+		trusted[strings.ToLower(entry)] = true
+	}
+	return trusted
+}
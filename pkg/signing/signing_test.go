@@ -0,0 +1,108 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/huanfeng/apkhub/pkg/models"
+)
+
+// newTestKey returns a fresh key pair
+func newTestKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+
+	pub, priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+func TestSignVerify(t *testing.T) {
+	pub, priv := newTestKey(t)
+	data := []byte(`{"name":"test"}`)
+
+	// The signature file survives the round trip through apkhub_manifest.json.sig
+	encoded, err := json.Marshal(Sign(data, priv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigFile, err := ParseSignatureFile(encoded)
+	if err != nil {
+		t.Fatalf("ParseSignatureFile: %v", err)
+	}
+
+	for _, trusted := range []string{EncodePublicKey(pub), Fingerprint(pub)} {
+		fingerprint, err := Verify(data, sigFile, []string{trusted})
+		if err != nil {
+			t.Errorf("Verify trusting %q: %v", trusted, err)
+		} else if fingerprint != Fingerprint(pub) {
+			t.Errorf("Verify returned fingerprint %s, want %s", fingerprint, Fingerprint(pub))
+		}
+	}
+}
+
+func TestVerifyRejectsTamperedManifest(t *testing.T) {
+	pub, priv := newTestKey(t)
+	data := []byte(`{"name":"test"}`)
+	sigFile := Sign(data, priv)
+
+	if _, err := Verify([]byte(`{"name":"evil"}`), sigFile, []string{Fingerprint(pub)}); err == nil {
+		t.Error("Verify accepted modified data")
+	}
+}
+
+func TestVerifyRejectsTamperedSignature(t *testing.T) {
+	pub, priv := newTestKey(t)
+	_, otherPriv := newTestKey(t)
+	data := []byte(`{"name":"test"}`)
+
+	tests := []struct {
+		name   string
+		tamper func(sigFile *models.ManifestSignatureFile)
+	}{
+		{"flipped bit", func(sigFile *models.ManifestSignatureFile) {
+			raw, _ := base64.StdEncoding.DecodeString(sigFile.Signatures[0].Signature)
+			raw[0] ^= 0x01
+			sigFile.Signatures[0].Signature = base64.StdEncoding.EncodeToString(raw)
+		}},
+		{"not base64", func(sigFile *models.ManifestSignatureFile) {
+			sigFile.Signatures[0].Signature = "not base64!"
+		}},
+		{"signature of another key", func(sigFile *models.ManifestSignatureFile) {
+			sigFile.Signatures[0].Signature = Sign(data, otherPriv).Signatures[0].Signature
+		}},
+		{"unsupported algorithm", func(sigFile *models.ManifestSignatureFile) {
+			sigFile.Signatures[0].Algorithm = "rsa"
+		}},
+		{"no signatures", func(sigFile *models.ManifestSignatureFile) {
+			sigFile.Signatures = nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sigFile := Sign(data, priv)
+			tt.tamper(sigFile)
+			if _, err := Verify(data, sigFile, []string{Fingerprint(pub)}); err == nil {
+				t.Error("Verify accepted the tampered signature")
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsUntrustedKey(t *testing.T) {
+	_, priv := newTestKey(t)
+	trustedPub, _ := newTestKey(t)
+	data := []byte(`{"name":"test"}`)
+	sigFile := Sign(data, priv)
+
+	if _, err := Verify(data, sigFile, []string{Fingerprint(trustedPub)}); err == nil {
+		t.Error("Verify accepted a signature from an untrusted key")
+	}
+	if _, err := Verify(data, sigFile, nil); err == nil {
+		t.Error("Verify succeeded without trusted keys")
+	}
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// CompressionGzip identifies gzip compressed data (.gz)
+	CompressionGzip = "gzip"
+
+	// CompressionZstd identifies zstd compressed data (.zst)
+	CompressionZstd = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// CompressionFormats lists the supported formats, most efficient first
+func CompressionFormats() []string {
+	return []string{CompressionZstd, CompressionGzip}
+}
+
+// NormalizeCompression maps a format name or alias to its canonical name
+func NormalizeCompression(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "gzip", "gz":
+		return CompressionGzip, nil
+	case "zstd", "zst":
+		return CompressionZstd, nil
+	default:
+		return "", fmt.Errorf("unsupported compression format %q (use gzip or zstd)", format)
+	}
+}
+
+// CompressionExtension returns the file extension for a format, or "" for none
+func CompressionExtension(format string) string {
+	switch format {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// DetectCompression returns the format of data from its magic bytes, or "" if
+// data is not compressed
+func DetectCompression(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, zstdMagic):
+		return CompressionZstd
+	case bytes.HasPrefix(data, gzipMagic):
+		return CompressionGzip
+	default:
+		return ""
+	}
+}
+
+// Compress compresses data with the given format
+func Compress(format string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case CompressionGzip:
+		w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case CompressionZstd:
+		w, err := zstd.NewWriter(&buf, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression format %q", format)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress decompresses data with the given format, refusing output larger
+// than maxSize bytes (0 = no limit)
+func Decompress(format string, data []byte, maxSize int64) ([]byte, error) {
+	var r io.Reader
+
+	switch format {
+	case CompressionGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	case CompressionZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd data: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("unsupported compression format %q", format)
+	}
+
+	if maxSize > 0 {
+		r = io.LimitReader(r, maxSize+1)
+	}
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s data: %w", format, err)
+	}
+	if maxSize > 0 && int64(len(out)) > maxSize {
+		return nil, fmt.Errorf("decompressed data exceeds %d bytes", maxSize)
+	}
+
+	return out, nil
+}